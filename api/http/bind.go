@@ -55,12 +55,14 @@ const (
 	DefaultIncludeEmptyDirective     = "includeempty"
 	DefaultEmptyValueHandlingDefault = OmitEmpty
 
-	ContentTypeHeader = "Content-Type"
-	AcceptsHeader     = "Accepts"
+	ContentTypeHeader    = "Content-Type"
+	AcceptHeader         = "Accept"
+	AcceptLanguageHeader = "Accept-Language"
 
 	AcceptLanguageKey = "$_accept_language"
 
 	pathPartsKey   = "$_path_parts"
+	pathValueKey   = "$_path_value"
 	queryParamsKey = "$_query_params"
 	headersKey     = "$_headers"
 	bodyBytesKey   = "$_body_bytes"
@@ -9,6 +9,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/jt0/gomer/bind"
 	"github.com/jt0/gomer/constraint"
@@ -67,6 +68,7 @@ func BindFromRequest(request *http.Request, resource any, scope string) gomerr.G
 
 	tc := structs.ToolContextWithScope(scope).
 		With(pathPartsKey, strings.Split(strings.Trim(request.URL.Path, "/"), "/")). // remove any leading or trailing slashes
+		With(pathValueKey, request.PathValue).
 		With(queryParamsKey, request.URL.Query()).
 		With(headersKey, request.Header)
 
@@ -83,14 +85,10 @@ func BindFromRequest(request *http.Request, resource any, scope string) gomerr.G
 		if len(bodyBytes) > 0 {
 			// based on content type, and the absence of any "body" attributes use the proper unmarshaler to put the
 			// data into the new resource
-			// TODO:p3 Allow applications to provide alternative means to choose an unmarshaler
 			contentType := request.Header.Get(ContentTypeHeader)
-			unmarshal, ok := requestConfig.perContentTypeUnmarshalFunctions[contentType]
-			if !ok {
-				if requestConfig.defaultUnmarshalFunction == nil {
-					return gomerr.Unmarshal("unsupported content-type", contentType, nil)
-				}
-				unmarshal = requestConfig.defaultUnmarshalFunction
+			unmarshal, ge := unmarshalFor(contentType)
+			if ge != nil {
+				return ge
 			}
 
 			if err = unmarshal(bodyBytes, &unmarshaled); err != nil {
@@ -106,7 +104,9 @@ func BindFromRequest(request *http.Request, resource any, scope string) gomerr.G
 
 // requestExtension
 //
-// path.<n>      -> <n>th path part from the request's URL
+// path.<name>   -> Value of the wildcard with name <name> in the pattern that matched the request (e.g. {OrderId})
+// path.<n>      -> <n>th path part from the request's URL (deprecated: prefer path.<name>, as <n> changes if routes
+// are mounted under a prefix or nested differently)
 // query.<name>  -> Query parameter with name <name>
 // header.<name> -> Header with name <name>
 // body          -> Body of the request
@@ -114,11 +114,17 @@ type requestExtension struct{}
 
 func (requestExtension) Applier(structType reflect.Type, structField reflect.StructField, directive string, _ string) (structs.Applier, gomerr.Gomerr) {
 	if strings.HasPrefix(directive, requestConfig.PathBindingPrefix) {
-		index, err := strconv.Atoi(directive[len(requestConfig.PathBindingPrefix):])
-		if err != nil {
-			return nil, gomerr.Configuration("expected numeric index value for path binding, received: " + directive)
+		wildcard := directive[len(requestConfig.PathBindingPrefix):]
+		if index, err := strconv.Atoi(wildcard); err == nil {
+			return bindPathApplier{index}, nil
 		}
-		return bindPathApplier{index}, nil
+		if wildcard == requestConfig.IncludeField {
+			wildcard = structField.Name
+		}
+		if !isWildcardName(wildcard) {
+			return nil, gomerr.Configuration("expected a wildcard name or numeric index for path binding, received: " + directive)
+		}
+		return bindPathValueApplier{wildcard}, nil
 	} else if strings.HasPrefix(directive, requestConfig.QueryParamBindingPrefix) {
 		queryParamName := directive[len(requestConfig.QueryParamBindingPrefix):]
 		if queryParamName == requestConfig.IncludeField {
@@ -165,6 +171,33 @@ func (b bindPathApplier) Apply(_ reflect.Value, fv reflect.Value, tc structs.Too
 	return nil
 }
 
+type bindPathValueApplier struct {
+	wildcard string
+}
+
+func (b bindPathValueApplier) Apply(_ reflect.Value, fv reflect.Value, tc structs.ToolContext) gomerr.Gomerr {
+	value := tc.Get(pathValueKey).(func(string) string)(b.wildcard)
+	if value == "" {
+		return nil
+	}
+
+	if ge := flect.SetValue(fv, value); ge != nil {
+		return ge.AddAttributes("pathWildcard", b.wildcard)
+	}
+
+	return nil
+}
+
+// isWildcardName returns true if the name is valid for a wildcard in an http.ServeMux pattern, i.e. a Go identifier.
+func isWildcardName(name string) bool {
+	for i, r := range name {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return name != ""
+}
+
 type bindQueryParamApplier struct {
 	name string
 }
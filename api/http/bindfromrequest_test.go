@@ -13,7 +13,9 @@ import (
 	"github.com/jt0/gomer/_test/helpers/stores"
 	. "github.com/jt0/gomer/api/http"
 	"github.com/jt0/gomer/auth"
+	"github.com/jt0/gomer/gomerr"
 	"github.com/jt0/gomer/resource"
+	"github.com/jt0/gomer/structs"
 )
 
 var (
@@ -27,18 +29,21 @@ var (
 type Greeting struct {
 	resource.BaseInstance[*Greeting] `structs:"ignore"`
 
-	Style_path       string `in:"path.0"`
-	Recipient_path   string `in:"path.1"`
-	Style_query      string `in:"query.+"` // same name as attribute
-	Recipient_query  string `in:"query.recipient"`
-	Style_header     string `in:"header.+"` // same name as attribute
-	Recipient_header string `in:"header.x-recipient"`
-	Style_body       string `in:"Style"`
-	Recipient_body   string `in:"Recipient"`
+	Style_path         string `in:"path.0"`
+	Recipient_path     string `in:"path.1"`
+	Style_wildcard     string `in:"path.Style"`
+	Recipient_wildcard string `in:"path.+"`  // same name as attribute
+	Style_query        string `in:"query.+"` // same name as attribute
+	Recipient_query    string `in:"query.recipient"`
+	Style_header       string `in:"header.+"` // same name as attribute
+	Recipient_header   string `in:"header.x-recipient"`
+	Style_body         string `in:"Style"`
+	Recipient_body     string `in:"Recipient"`
 }
 
 const (
 	Path = iota
+	PathWildcard
 	Query
 	Header
 	Body
@@ -48,6 +53,8 @@ func (g Greeting) style(location int) string {
 	switch location {
 	case Path:
 		return g.Style_path
+	case PathWildcard:
+		return g.Style_wildcard
 	case Query:
 		return g.Style_query
 	case Header:
@@ -61,6 +68,8 @@ func (g Greeting) recipient(location int) string {
 	switch location {
 	case Path:
 		return g.Recipient_path
+	case PathWildcard:
+		return g.Recipient_wildcard
 	case Query:
 		return g.Recipient_query
 	case Header:
@@ -83,8 +92,13 @@ func TestBindInTypes(t *testing.T) {
 		location int
 		request  *http.Request
 	}
+	wildcardRequest := &http.Request{URL: &url.URL{Path: "/greetings/" + hello + "/" + kitty}, Body: body("")}
+	wildcardRequest.SetPathValue("Style", hello)
+	wildcardRequest.SetPathValue("Recipient_wildcard", kitty)
+
 	tests := []testcase{
 		{"BindFromPath", Path, &http.Request{URL: &url.URL{Path: "/" + hello + "/" + kitty}, Body: body("")}},
+		{"BindFromPathWildcard", PathWildcard, wildcardRequest},
 		{"BindFromQuery", Query, &http.Request{URL: &url.URL{RawQuery: "Style_query=" + hello + "&recipient=" + kitty}, Body: body("")}},
 		// NB: header names can have different casing from the 'in' header config
 		{"BindFromHeader", Header, &http.Request{URL: &url.URL{Path: "/"}, Header: http.Header{"Style_header": []string{hello}, "X-Recipient": []string{kitty}}, Body: body("")}},
@@ -106,3 +120,12 @@ func TestBindInTypes(t *testing.T) {
 func body(input string) io.ReadCloser {
 	return ioutil.NopCloser(strings.NewReader(input))
 }
+
+func TestBindFromPath_InvalidWildcard(t *testing.T) {
+	type Invalid struct {
+		OrderId string `in:"path.Order-Id"`
+	}
+
+	ge := structs.Preprocess(&Invalid{}, DefaultBindFromRequestTool)
+	assert.ErrorType(t, ge, &gomerr.ConfigurationError{}, "")
+}
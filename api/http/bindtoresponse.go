@@ -54,9 +54,11 @@ func SetBindToResponseConfiguration(responseConfiguration BindToResponseConfigur
 	return bindToResponseTool
 }
 
-// BindToResponse
-// TODO: add support for data format type
-func BindToResponse(result reflect.Value, header http.Header, scope string, acceptLanguage string, statusCode int) ([]byte, int) {
+// BindToResponse binds the result to the response header and body. The body is marshaled to the media type negotiated
+// for the accept value (see NegotiateContentType), which is set as the response's Content-Type. The accept value may
+// be a media type that was already negotiated. If no media type is acceptable, the status code is
+// http.StatusNotAcceptable.
+func BindToResponse(result reflect.Value, header http.Header, scope string, accept string, acceptLanguage string, statusCode int) ([]byte, int) {
 	tc := structs.ToolContextWithScope(scope).
 		With(headersKey, header).
 		With(AcceptLanguageKey, acceptLanguage).
@@ -72,19 +74,11 @@ func BindToResponse(result reflect.Value, header http.Header, scope string, acce
 		return body.([]byte), statusCode
 	}
 
-	// based on content type, and the absence of any "body" attributes use the proper marshaler to put the
-	// data into the response bytes
-	// TODO:p3 Allow applications to provide alternative means to choose a marshaler
-	contentType := header.Get(AcceptsHeader) // TODO:p4 support multi-options
-	marshal, ok := responseConfig.perContentTypeMarshalFunctions[contentType]
-	if !ok {
-		if responseConfig.defaultMarshalFunction == nil {
-			// TODO: Previously returned error: gomerr.Marshal("unsupported Accepts content type", contentType)
-			// Consider logging or otherwise handling this error
-			return nil, http.StatusNotAcceptable
-		}
-		marshal = responseConfig.defaultMarshalFunction
-		contentType = DefaultContentType
+	// based on the negotiated content type, and the absence of any "body" attributes use the proper marshaler to put
+	// the data into the response bytes
+	contentType, marshal, ge := marshalFor(accept)
+	if ge != nil {
+		return nil, http.StatusNotAcceptable
 	}
 
 	outMap := tc.Get(bind.OutKey).(map[string]any)
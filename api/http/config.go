@@ -2,6 +2,7 @@ package http
 
 import (
 	"encoding/json"
+	"strings"
 
 	"github.com/jt0/gomer/bind"
 )
@@ -14,6 +15,10 @@ type BindingConfiguration struct {
 	bindConfig      bind.Configuration
 	directiveConfig BindDirectiveConfiguration
 
+	// Codecs registered for media types other than (or replacing) the default JSON encoding
+	unmarshalFunctions map[string]Unmarshal
+	marshalFunctions   map[string]Marshal
+
 	// Request-specific overrides (applied after common options)
 	requestOverrides []BindingOption
 	// Response-specific overrides (applied after common options)
@@ -23,8 +28,10 @@ type BindingConfiguration struct {
 // NewBindingConfiguration creates a new BindingConfiguration with the provided options.
 func NewBindingConfiguration(options ...BindingOption) *BindingConfiguration {
 	bc := &BindingConfiguration{
-		bindConfig:      bind.NewConfiguration(),
-		directiveConfig: NewBindDirectiveConfiguration(),
+		bindConfig:         bind.NewConfiguration(),
+		directiveConfig:    NewBindDirectiveConfiguration(),
+		unmarshalFunctions: make(map[string]Unmarshal),
+		marshalFunctions:   make(map[string]Marshal),
 	}
 
 	for _, opt := range options {
@@ -42,7 +49,7 @@ func (bc *BindingConfiguration) SetAsDefault() *BindingConfiguration {
 		BindConfiguration:                bc.buildBindConfig(bc.requestOverrides),
 		BindDirectiveConfiguration:       bc.directiveConfig,
 		defaultContentType:               DefaultContentType,
-		perContentTypeUnmarshalFunctions: make(map[string]Unmarshal),
+		perContentTypeUnmarshalFunctions: bc.unmarshalFunctions,
 		defaultUnmarshalFunction:         defaultUnmarshal,
 	}
 	SetBindFromRequestConfiguration(reqConfig)
@@ -52,7 +59,7 @@ func (bc *BindingConfiguration) SetAsDefault() *BindingConfiguration {
 		BindConfiguration:              bc.buildBindConfig(bc.responseOverrides),
 		BindDirectiveConfiguration:     bc.directiveConfig,
 		defaultContentType:             DefaultContentType,
-		perContentTypeMarshalFunctions: make(map[string]Marshal),
+		perContentTypeMarshalFunctions: bc.marshalFunctions,
 		defaultMarshalFunction:         defaultMarshal,
 	}
 	SetBindToResponseConfiguration(respConfig)
@@ -68,8 +75,10 @@ func (bc *BindingConfiguration) buildBindConfig(overrides []BindingOption) bind.
 
 	// Create a temporary BindingConfiguration to collect override options
 	temp := &BindingConfiguration{
-		bindConfig:      bc.bindConfig,
-		directiveConfig: bc.directiveConfig,
+		bindConfig:         bc.bindConfig,
+		directiveConfig:    bc.directiveConfig,
+		unmarshalFunctions: bc.unmarshalFunctions,
+		marshalFunctions:   bc.marshalFunctions,
 	}
 	for _, opt := range overrides {
 		opt(temp)
@@ -105,6 +114,26 @@ var (
 	IncludeEmptyValues BindingOption = wrapBindOption(bind.IncludeEmpty)
 )
 
+// Codec registers the functions that unmarshal request bodies from, and marshal response bodies to, a media type (e.g.
+// "application/xml"). Either function may be nil if the media type is only supported in one direction. Request bodies
+// are unmarshaled per their Content-Type, and response bodies are marshaled to the media type negotiated from the
+// request's Accept header. JSON is supported unless replaced by registering DefaultContentType.
+//
+// Example:
+//
+//	http.Configure(http.CamelCaseFields, http.Codec("application/xml", xmlMarshal, xmlUnmarshal))
+func Codec(mediaType string, marshal Marshal, unmarshal Unmarshal) BindingOption {
+	mediaType = strings.ToLower(mediaType)
+	return func(bc *BindingConfiguration) {
+		if unmarshal != nil {
+			bc.unmarshalFunctions[mediaType] = unmarshal
+		}
+		if marshal != nil {
+			bc.marshalFunctions[mediaType] = marshal
+		}
+	}
+}
+
 // wrapBindOption converts a bind.Configuration option to a BindingOption.
 func wrapBindOption(bindOpt func(*bind.Configuration)) BindingOption {
 	return func(bc *BindingConfiguration) {
@@ -203,7 +203,7 @@ func TestConfigure_ResponseOption(t *testing.T) {
 
 	// Bind to response - should include lastName:null due to ResponseOption(IncludeEmptyValues)
 	header := make(http.Header)
-	bytes, statusCode := BindToResponse(reflect.ValueOf(person), header, "test", "", http.StatusOK)
+	bytes, statusCode := BindToResponse(reflect.ValueOf(person), header, "test", "", "", http.StatusOK)
 	assert.Equals(t, http.StatusOK, statusCode)
 
 	responseBody := string(bytes)
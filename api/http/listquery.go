@@ -0,0 +1,207 @@
+package http
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/resource"
+)
+
+const (
+	FilterQueryParam = "filter"
+	SortQueryParam   = "sort"
+)
+
+var filterOperators = map[string]data.QueryTypes{
+	"eq": data.EQ,
+	"ne": data.NEQ,
+	"gt": data.GT,
+	"ge": data.GTE,
+	"lt": data.LT,
+	"le": data.LTE,
+	"co": data.CONTAINS,
+	"sw": data.PREFIX,
+}
+
+type listQuerier interface {
+	SetListQuery(resource.ListQuery) gomerr.Gomerr
+}
+
+// BindListQuery parses the request's filter and sort query parameters (see ParseListQuery) and applies them to the
+// collection if it has a SetListQuery method, as resource.Collection does. Fields are named, without regard to case, as
+// the collection's items name them in response bodies for the scope (see BindToResponse). Possible errors:
+//
+//	gomerr.BadValue:
+//	    if either parameter doesn't conform to ParseListQuery's grammar or names a field that isn't in the items'
+//	    response bodies
+func BindListQuery(request *http.Request, collection any, scope string) gomerr.Gomerr {
+	lq, ok := collection.(listQuerier)
+	if !ok {
+		return nil
+	}
+
+	params := request.URL.Query()
+	filter, sort := params.Get(FilterQueryParam), params.Get(SortQueryParam)
+	if filter == "" && sort == "" {
+		return nil
+	}
+
+	q, ge := ParseListQuery(filter, sort)
+	if ge != nil {
+		return ge
+	}
+
+	if ge = resolveListQueryFields(&q, collection, scope); ge != nil {
+		return ge
+	}
+
+	return lq.SetListQuery(q)
+}
+
+// resolveListQueryFields replaces the names of the query's fields, as bound in the response bodies of the collection's
+// items, with the names of the struct fields they're bound from.
+func resolveListQueryFields(q *resource.ListQuery, collection any, scope string) gomerr.Gomerr {
+	c, ok := collection.(interface{ ItemTemplate() any })
+	if !ok {
+		return nil
+	}
+	fieldNames := responseFieldNames(reflect.TypeOf(c.ItemTemplate()), scope)
+
+	fieldNamed := func(name string) (string, bool) {
+		if field, ok := fieldNames[name]; ok {
+			return field, true
+		}
+		for bound, field := range fieldNames {
+			if strings.EqualFold(bound, name) {
+				return field, true
+			}
+		}
+		return "", false
+	}
+
+	for i, f := range q.Filters {
+		field, ok := fieldNamed(f.Field)
+		if !ok {
+			return gomerr.InvalidValue(FilterQueryParam, f.Field, nil).WithReason("field is not filterable")
+		}
+		q.Filters[i].Field = field
+	}
+
+	for i, s := range q.Sort {
+		field, ok := fieldNamed(s.Field)
+		if !ok {
+			return gomerr.InvalidValue(SortQueryParam, s.Field, nil).WithReason("field is not sortable")
+		}
+		q.Sort[i].Field = field
+	}
+
+	return nil
+}
+
+// ParseListQuery parses filter and sort parameter values using the following grammar (operators and "and" are
+// case-insensitive):
+//
+//	filter     = comparison *( " and " comparison )
+//	comparison = field " " operator " " value
+//	operator   = "eq" / "ne" / "gt" / "ge" / "lt" / "le" / "co" (contains) / "sw" (starts with)
+//	value      = "'" *( character / "''" ) "'" / token
+//	sort       = [ "+" / "-" ] field *( "," [ "+" / "-" ] field )
+//
+// For example, "status eq 'open' and created gt 2024-01-01" and "-created". A value is a quoted string, in which a
+// quote is escaped by doubling it, or a token without whitespace such as a number or date. A "-" sorts a field in
+// descending order. Possible errors:
+//
+//	gomerr.BadValue:
+//	    if either value doesn't conform to the grammar
+func ParseListQuery(filter, sort string) (resource.ListQuery, gomerr.Gomerr) {
+	var q resource.ListQuery
+
+	if filter != "" {
+		tokens, ge := filterTokens(filter)
+		if ge != nil {
+			return q, ge
+		}
+
+		for i := 0; i < len(tokens); i += 4 {
+			if len(tokens) < i+3 {
+				return q, gomerr.MalformedValue(FilterQueryParam, filter).WithReason("expected: <field> <operator> <value>")
+			}
+
+			field, operator, value := tokens[i], tokens[i+1], tokens[i+2]
+			if field.quoted || operator.quoted {
+				return q, gomerr.MalformedValue(FilterQueryParam, filter).WithReason("expected: <field> <operator> <value>")
+			}
+
+			queryType, ok := filterOperators[strings.ToLower(operator.value)]
+			if !ok {
+				return q, gomerr.MalformedValue(FilterQueryParam, filter).WithReason("unsupported operator: " + operator.value)
+			}
+
+			if len(tokens) > i+3 && (tokens[i+3].quoted || !strings.EqualFold(tokens[i+3].value, "and")) {
+				return q, gomerr.MalformedValue(FilterQueryParam, filter).WithReason("comparisons must be joined by 'and'")
+			} else if len(tokens) == i+4 {
+				return q, gomerr.MalformedValue(FilterQueryParam, filter).WithReason("expected a comparison after 'and'")
+			}
+
+			q.Filters = append(q.Filters, resource.Filter{Field: field.value, Type: queryType, Values: []string{value.value}})
+		}
+	}
+
+	if sort != "" {
+		for _, field := range strings.Split(sort, ",") {
+			s := data.Sort{Field: strings.TrimSpace(field)}
+			if s.Field != "" && (s.Field[0] == '-' || s.Field[0] == '+') {
+				s.Field, s.Descending = s.Field[1:], s.Field[0] == '-'
+			}
+			if s.Field == "" || strings.ContainsAny(s.Field, " +-'") {
+				return q, gomerr.MalformedValue(SortQueryParam, sort).WithReason("expected: [+|-]<field>")
+			}
+			q.Sort = append(q.Sort, s)
+		}
+	}
+
+	return q, nil
+}
+
+type filterToken struct {
+	value  string
+	quoted bool
+}
+
+// filterTokens splits a filter into whitespace-separated tokens, treating a quoted string as a single token.
+func filterTokens(filter string) ([]filterToken, gomerr.Gomerr) {
+	var tokens []filterToken
+	for i := 0; i < len(filter); {
+		switch filter[i] {
+		case ' ', '\t':
+			i++
+		case '\'':
+			var value strings.Builder
+			for i++; ; i++ {
+				if i >= len(filter) {
+					return nil, gomerr.MalformedValue(FilterQueryParam, filter).WithReason("unterminated quoted value")
+				}
+				if filter[i] == '\'' {
+					if i+1 < len(filter) && filter[i+1] == '\'' {
+						i++ // escaped quote
+					} else {
+						i++
+						break
+					}
+				}
+				value.WriteByte(filter[i])
+			}
+			tokens = append(tokens, filterToken{value.String(), true})
+		default:
+			start := i
+			for i < len(filter) && filter[i] != ' ' && filter[i] != '\t' {
+				i++
+			}
+			tokens = append(tokens, filterToken{value: filter[start:i]})
+		}
+	}
+	return tokens, nil
+}
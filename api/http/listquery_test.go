@@ -0,0 +1,43 @@
+package http_test
+
+import (
+	"testing"
+
+	"github.com/jt0/gomer/_test/assert"
+	. "github.com/jt0/gomer/api/http"
+	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/resource"
+)
+
+func TestParseListQuery(t *testing.T) {
+	q, ge := ParseListQuery("status eq 'it''s open' AND created gt 2024-01-01 and Name sw ab", "-created")
+	assert.Success(t, ge)
+	assert.Equals(t, []resource.Filter{
+		{Field: "status", Type: data.EQ, Values: []string{"it's open"}},
+		{Field: "created", Type: data.GT, Values: []string{"2024-01-01"}},
+		{Field: "Name", Type: data.PREFIX, Values: []string{"ab"}},
+	}, q.Filters)
+	assert.Equals(t, []data.Sort{{Field: "created", Descending: true}}, q.Sort)
+
+	q, ge = ParseListQuery("", "name, +created")
+	assert.Success(t, ge)
+	assert.Equals(t, 0, len(q.Filters))
+	assert.Equals(t, []data.Sort{{Field: "name"}, {Field: "created"}}, q.Sort)
+
+	for _, filter := range []string{
+		"status",
+		"status eq",
+		"status is 'open'",
+		"status eq 'open",
+		"status eq 'open' or name eq x",
+		"status eq 'open' and",
+		"'status' eq 'open'",
+	} {
+		_, ge = ParseListQuery(filter, "")
+		assert.ErrorType(t, ge, gomerr.MalformedValue("", nil), filter)
+	}
+
+	_, ge = ParseListQuery("", "created,")
+	assert.ErrorType(t, ge, gomerr.MalformedValue("", nil))
+}
@@ -0,0 +1,218 @@
+package http
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jt0/gomer/gomerr"
+)
+
+// NotAcceptableError indicates that none of the media types a request accepts can be produced.
+type NotAcceptableError struct {
+	gomerr.Gomerr
+	Accept    string
+	Available []string
+}
+
+func NotAcceptable(accept string, available []string) *NotAcceptableError {
+	return gomerr.Build(new(NotAcceptableError), accept, available).(*NotAcceptableError)
+}
+
+func (*NotAcceptableError) StatusCode() int {
+	return http.StatusNotAcceptable
+}
+
+// UnsupportedMediaTypeError indicates that a request's body has a media type that can't be unmarshaled.
+type UnsupportedMediaTypeError struct {
+	gomerr.Gomerr
+	ContentType string
+	Supported   []string
+}
+
+func UnsupportedMediaType(contentType string, supported []string) *UnsupportedMediaTypeError {
+	return gomerr.Build(new(UnsupportedMediaTypeError), contentType, supported).(*UnsupportedMediaTypeError)
+}
+
+func (*UnsupportedMediaTypeError) StatusCode() int {
+	return http.StatusUnsupportedMediaType
+}
+
+// RequestContentTypes returns the media types that request bodies can be unmarshaled from, starting with the default.
+func RequestContentTypes() []string {
+	return mediaTypes(requestConfig.defaultContentType, requestConfig.defaultUnmarshalFunction != nil, requestConfig.perContentTypeUnmarshalFunctions)
+}
+
+// ResponseContentTypes returns the media types that response bodies can be marshaled to, starting with the default.
+func ResponseContentTypes() []string {
+	return mediaTypes(responseConfig.defaultContentType, responseConfig.defaultMarshalFunction != nil, responseConfig.perContentTypeMarshalFunctions)
+}
+
+func mediaTypes[F any](defaultContentType string, hasDefault bool, perContentType map[string]F) []string {
+	var types []string
+	for mediaType := range perContentType {
+		if mediaType != defaultContentType {
+			types = append(types, mediaType)
+		}
+	}
+	sort.Strings(types)
+
+	if _, ok := perContentType[defaultContentType]; ok || hasDefault {
+		types = append([]string{defaultContentType}, types...)
+	}
+
+	return types
+}
+
+// unmarshalFor returns the Unmarshal function for a request's Content-Type. Media type parameters (e.g. charset) are
+// ignored. A request without a Content-Type is unmarshaled as the default content type.
+func unmarshalFor(contentType string) (Unmarshal, gomerr.Gomerr) {
+	if contentType == "" {
+		contentType = requestConfig.defaultContentType
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, UnsupportedMediaType(contentType, RequestContentTypes()).Wrap(err)
+	}
+
+	if unmarshal, ok := requestConfig.perContentTypeUnmarshalFunctions[mediaType]; ok {
+		return unmarshal, nil
+	}
+	if mediaType == requestConfig.defaultContentType && requestConfig.defaultUnmarshalFunction != nil {
+		return requestConfig.defaultUnmarshalFunction, nil
+	}
+
+	return nil, UnsupportedMediaType(contentType, RequestContentTypes())
+}
+
+// NegotiateContentType returns the response media type that best satisfies an Accept header value per RFC 9110: the
+// available type with the highest quality value (q) of the most specific media range that matches it. Ties favor the
+// default content type. An empty Accept value accepts any type. A NotAcceptableError is returned if no type is
+// acceptable.
+func NegotiateContentType(accept string) (string, gomerr.Gomerr) {
+	available := ResponseContentTypes()
+	if len(available) == 0 {
+		return "", NotAcceptable(accept, available)
+	}
+	if strings.TrimSpace(accept) == "" {
+		return available[0], nil
+	}
+
+	ranges := parseAccept(accept)
+	best, bestQ := "", 0.0
+	for _, mediaType := range available {
+		if q := quality(mediaType, ranges); q > bestQ {
+			best, bestQ = mediaType, q
+		}
+	}
+
+	if best == "" {
+		return "", NotAcceptable(accept, available)
+	}
+
+	return best, nil
+}
+
+// marshalFor returns the media type negotiated for an Accept header value and its Marshal function.
+func marshalFor(accept string) (string, Marshal, gomerr.Gomerr) {
+	mediaType, ge := NegotiateContentType(accept)
+	if ge != nil {
+		return "", nil, ge
+	}
+
+	if marshal, ok := responseConfig.perContentTypeMarshalFunctions[mediaType]; ok {
+		return mediaType, marshal, nil
+	}
+
+	return mediaType, responseConfig.defaultMarshalFunction, nil
+}
+
+type mediaRange struct {
+	type_       string
+	subtype     string
+	q           float64
+	specificity int // 0 for "*/*", 1 for "type/*", and 2 for "type/subtype"
+	params      map[string]string
+}
+
+// parseAccept returns the media ranges of an Accept header value. Malformed ranges are ignored.
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, element := range strings.Split(accept, ",") {
+		if strings.TrimSpace(element) == "" {
+			continue
+		}
+
+		mediaType, params, err := mime.ParseMediaType(element)
+		if err != nil {
+			continue
+		}
+
+		type_, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok || type_ == "*" && subtype != "*" {
+			continue
+		}
+
+		mr := mediaRange{type_: type_, subtype: subtype, q: 1, specificity: 2, params: params}
+		if qValue, hasQ := params["q"]; hasQ {
+			if mr.q, err = strconv.ParseFloat(qValue, 64); err != nil || mr.q < 0 || mr.q > 1 {
+				continue
+			}
+			delete(params, "q")
+		}
+		switch {
+		case type_ == "*":
+			mr.specificity = 0
+		case subtype == "*":
+			mr.specificity = 1
+		}
+
+		ranges = append(ranges, mr)
+	}
+
+	return ranges
+}
+
+// quality returns the q value of the most specific range that matches the media type, or 0 if none does. Only the
+// parameters the media type has are considered: a range that has one with a different value doesn't match, and one that
+// has them all is more specific than a range with none. Other parameters (e.g. a charset for a media type without one)
+// are ignored.
+func quality(mediaType string, ranges []mediaRange) float64 {
+	mediaType, params, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return 0
+	}
+	type_, subtype, _ := strings.Cut(mediaType, "/")
+
+	q, specificity := 0.0, -1
+	for _, mr := range ranges {
+		if !(mr.type_ == "*" || mr.type_ == type_ && (mr.subtype == "*" || mr.subtype == subtype)) {
+			continue
+		}
+
+		rangeSpecificity, matches := mr.specificity, true
+		if mr.specificity == 2 && len(params) > 0 {
+			matched := 0
+			for name, value := range params {
+				if rangeValue, ok := mr.params[name]; ok {
+					if !strings.EqualFold(rangeValue, value) {
+						matches = false
+					}
+					matched++
+				}
+			}
+			if matched == len(params) {
+				rangeSpecificity++
+			}
+		}
+
+		if matches && rangeSpecificity > specificity {
+			q, specificity = mr.q, rangeSpecificity
+		}
+	}
+
+	return q
+}
@@ -0,0 +1,136 @@
+package http_test
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jt0/gomer/_test/assert"
+	. "github.com/jt0/gomer/api/http"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/resource"
+)
+
+func xmlMarshal(v any) ([]byte, error) {
+	return []byte("<person/>"), nil
+}
+
+func xmlUnmarshal(data []byte, v any) error {
+	return xml.Unmarshal(data, &struct{}{})
+}
+
+func TestNegotiateContentType(t *testing.T) {
+	Configure(Codec("application/XML", xmlMarshal, xmlUnmarshal), Codec("text/csv", nil, xmlUnmarshal))
+	defer Configure()
+
+	assert.Equals(t, []string{DefaultContentType, "application/xml"}, ResponseContentTypes())
+	assert.Equals(t, []string{DefaultContentType, "application/xml", "text/csv"}, RequestContentTypes())
+
+	tests := []struct {
+		name     string
+		accept   string
+		expected string
+	}{
+		{"Empty", "", DefaultContentType},
+		{"Any", "*/*", DefaultContentType},
+		{"Exact", "application/xml", "application/xml"},
+		{"CaseInsensitive", "Application/XML", "application/xml"},
+		{"Parameters", "application/xml; charset=utf-8", "application/xml"},
+		{"QValues", "application/json;q=0.5, application/xml;q=0.8", "application/xml"},
+		{"TypeWildcard", "text/html, application/*;q=0.9", DefaultContentType},
+		{"MoreSpecificWins", "application/*;q=0.9, application/json;q=0.1", "application/xml"},
+		{"ExcludedByZero", "*/*, application/json;q=0", "application/xml"},
+		{"MalformedIgnored", "application/json;q=x, application/xml", "application/xml"},
+		{"NotAcceptable", "text/html, image/*", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mediaType, ge := NegotiateContentType(tt.accept)
+			if tt.expected == "" {
+				assert.ErrorType(t, ge, &NotAcceptableError{}, "")
+				return
+			}
+			assert.Success(t, ge)
+			assert.Equals(t, tt.expected, mediaType)
+		})
+	}
+}
+
+func TestNegotiateContentType_Parameters(t *testing.T) {
+	Configure(Codec("application/vnd.widget+json; version=1", xmlMarshal, nil), Codec("application/vnd.widget+json; version=2", xmlMarshal, nil))
+	defer Configure()
+
+	const v1, v2 = "application/vnd.widget+json; version=1", "application/vnd.widget+json; version=2"
+	tests := []struct {
+		name     string
+		accept   string
+		expected string
+	}{
+		{"Matching", "application/vnd.widget+json;version=2", v2},
+		{"Mismatched", "application/vnd.widget+json;version=3", ""},
+		{"Absent", "application/vnd.widget+json;q=0.5, application/vnd.widget+json;version=2;q=0.1", v1},
+		{"OthersIgnored", "application/vnd.widget+json;version=2;charset=utf-8", v2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mediaType, ge := NegotiateContentType(tt.accept)
+			if tt.expected == "" {
+				assert.ErrorType(t, ge, &NotAcceptableError{}, "")
+				return
+			}
+			assert.Success(t, ge)
+			assert.Equals(t, tt.expected, mediaType)
+		})
+	}
+}
+
+func TestBindFromRequest_ContentType(t *testing.T) {
+	Configure(Codec("text/csv", nil, xmlUnmarshal))
+	defer Configure()
+
+	bind := func(contentType string) error {
+		req := &http.Request{
+			URL:    &url.URL{Path: "/"},
+			Header: http.Header{ContentTypeHeader: {contentType}},
+			Body:   io.NopCloser(strings.NewReader(`{"FirstName": "Alice"}`)),
+		}
+		person, _ := resource.NewInstance[*Person](ctxWithRegistry, subject)
+		return BindFromRequest(req, person, "test")
+	}
+
+	assert.Success(t, bind("application/json"))
+	assert.Success(t, bind("application/json; charset=utf-8"))
+	assert.Success(t, bind("Application/JSON"))
+	assert.ErrorType(t, bind("text/plain"), &UnsupportedMediaTypeError{}, "")
+	assert.ErrorType(t, bind("application/json; charset"), &UnsupportedMediaTypeError{}, "")
+	assert.ErrorType(t, bind("text/csv"), &gomerr.UnmarshalError{}, "") // supported, but the body isn't valid XML
+}
+
+func TestBindToResponse_ContentType(t *testing.T) {
+	Configure(Codec("application/xml", xmlMarshal, nil))
+	defer Configure()
+
+	name := "Alice"
+	person := &Person{FirstName: &name}
+
+	header := make(http.Header)
+	bytes, statusCode := BindToResponse(reflect.ValueOf(person), header, "test", "application/xml, application/json;q=0.9", "", http.StatusOK)
+	assert.Equals(t, http.StatusOK, statusCode)
+	assert.Equals(t, "application/xml", header.Get(ContentTypeHeader))
+	assert.Equals(t, "<person/>", string(bytes))
+
+	header = make(http.Header)
+	_, statusCode = BindToResponse(reflect.ValueOf(person), header, "test", "", "", http.StatusOK)
+	assert.Equals(t, http.StatusOK, statusCode)
+	assert.Equals(t, DefaultContentType, header.Get(ContentTypeHeader))
+
+	header = make(http.Header)
+	bytes, statusCode = BindToResponse(reflect.ValueOf(person), header, "test", "text/html", "", http.StatusOK)
+	assert.Equals(t, http.StatusNotAcceptable, statusCode)
+	assert.Equals(t, 0, len(bytes))
+	assert.Equals(t, "", header.Get(ContentTypeHeader))
+}
@@ -0,0 +1,346 @@
+package http
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jt0/gomer/api/openapi"
+	"github.com/jt0/gomer/bind"
+	"github.com/jt0/gomer/constraint"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/structs"
+)
+
+// RequestDescription describes the request data that BindFromRequest binds to a resource type in a scope.
+type RequestDescription struct {
+	Parameters []openapi.Parameter
+	Body       *openapi.Schema // nil if nothing is bound from the body
+	RawBody    bool            // true if the body is bound as-is to a []byte field
+}
+
+// ResponseDescription describes the response data that BindToResponse binds from a resource type in a scope.
+type ResponseDescription struct {
+	Headers map[string]openapi.Header
+	Body    *openapi.Schema // nil if nothing is bound to the body
+	RawBody bool            // true if the body is bound as-is from a []byte field
+}
+
+// DescribeRequest returns the description of the request data bound to the resource type for a request to the path
+// (a pattern such as "/orders/{OrderId}"). Fields bound with a path directive are described by the path wildcard they
+// name or that is at their index. Body properties and parameters are constrained by the fields' validate directives.
+func DescribeRequest(resourceType reflect.Type, scope string, path string) RequestDescription {
+	d := requestDescriber(scope, path)
+
+	var rd RequestDescription
+	rd.Body = d.structSchema(indirectType(resourceType), true)
+	rd.Parameters, rd.RawBody = d.parameters, d.rawBody
+	if len(rd.Body.Properties) == 0 {
+		rd.Body = nil
+	}
+
+	return rd
+}
+
+// MissingPathWildcards returns the names of the path wildcards that the resource type's fields are bound to in the
+// scope, but that the path (a pattern such as "/orders/{OrderId}") doesn't have.
+func MissingPathWildcards(resourceType reflect.Type, scope string, path string) []string {
+	d := requestDescriber(scope, path)
+	d.structSchema(indirectType(resourceType), true)
+
+	return d.missingWildcards
+}
+
+func requestDescriber(scope string, path string) describer {
+	return describer{
+		tagKey:     "in",
+		scope:      scope,
+		directives: requestConfig.BindDirectiveConfiguration,
+		bind:       requestConfig.BindConfiguration,
+		visiting:   make(map[reflect.Type]bool),
+		pathParts:  strings.Split(strings.Trim(path, "/"), "/"),
+	}
+}
+
+// DescribeResponse returns the description of the response data bound from the resource type.
+func DescribeResponse(resourceType reflect.Type, scope string) ResponseDescription {
+	d := responseDescriber(scope)
+
+	var rd ResponseDescription
+	rd.Body = d.structSchema(indirectType(resourceType), true)
+	rd.Headers, rd.RawBody = d.headers, d.rawBody
+	if len(rd.Body.Properties) == 0 && responseConfig.EmptyValueHandlingDefault == OmitEmpty {
+		rd.Body = nil
+	}
+	if len(rd.Headers) == 0 {
+		rd.Headers = nil
+	}
+
+	return rd
+}
+
+// responseFieldNames returns the names of the resource type's fields keyed by the names of the response body properties
+// they're bound to in the scope.
+func responseFieldNames(resourceType reflect.Type, scope string) map[string]string {
+	d := responseDescriber(scope)
+	d.fieldNames = make(map[string]string)
+	d.structSchema(indirectType(resourceType), true)
+
+	return d.fieldNames
+}
+
+func responseDescriber(scope string) describer {
+	return describer{
+		tagKey:     "out",
+		scope:      scope,
+		directives: responseConfig.BindDirectiveConfiguration,
+		bind:       responseConfig.BindConfiguration,
+		visiting:   make(map[reflect.Type]bool),
+		headers:    make(map[string]openapi.Header),
+	}
+}
+
+// describer walks the fields of a resource type the way the bind tools do, collecting the schema of the data in the
+// body and, for the top-level type, the parameters or headers.
+type describer struct {
+	tagKey     string
+	scope      string
+	directives BindDirectiveConfiguration
+	bind       bind.Configuration
+	visiting   map[reflect.Type]bool
+	pathParts  []string
+
+	parameters       []openapi.Parameter
+	headers          map[string]openapi.Header
+	rawBody          bool
+	missingWildcards []string
+	fieldNames       map[string]string // if non-nil, the top-level body properties' field names
+}
+
+func (d *describer) structSchema(st reflect.Type, topLevel bool) *openapi.Schema {
+	schema := &openapi.Schema{Type: "object", Properties: make(map[string]*openapi.Schema)}
+	if d.visiting[st] {
+		return schema // a recursive type; describe the recursion as any object
+	}
+	d.visiting[st] = true
+	defer delete(d.visiting, st)
+
+	d.addFields(schema, st, topLevel)
+	return schema
+}
+
+func (d *describer) addFields(schema *openapi.Schema, st reflect.Type, topLevel bool) {
+	for i := 0; i < st.NumField(); i++ {
+		sf := st.Field(i)
+		if sf.Tag.Get("structs") == "ignore" {
+			continue
+		}
+
+		directive, hasDirective := sf.Tag.Lookup(d.tagKey)
+		if sf.Anonymous && !hasDirective && sf.Type.Kind() == reflect.Struct {
+			d.addFields(schema, sf.Type, topLevel) // embedded fields are bound as if they were the struct's own
+			continue
+		}
+		if !hasDirective || !sf.IsExported() {
+			continue
+		}
+
+		if directive, hasDirective = structs.ScopedDirective(directive, d.scope); !hasDirective {
+			continue
+		}
+
+		omitEmpty := d.bind.OmitsEmpty()
+		if cIndex := strings.IndexByte(directive, ','); cIndex != -1 && d.tagKey == "out" {
+			omitEmpty = directive[cIndex+1:] != d.directives.IncludeEmptyDirective
+			directive = directive[:cIndex]
+		}
+
+		// Each part of a composite directive (e.g. "query.x?header.X") is a possible source or destination
+		for _, part := range strings.FieldsFunc(directive, func(r rune) bool { return r == '?' || r == '&' || r == '!' }) {
+			d.addField(schema, sf, part, omitEmpty, topLevel)
+		}
+		if directive == "" {
+			d.addField(schema, sf, "", omitEmpty, topLevel)
+		}
+	}
+}
+
+func (d *describer) addField(schema *openapi.Schema, sf reflect.StructField, directive string, omitEmpty bool, topLevel bool) {
+	switch {
+	case directive == d.directives.SkipField || strings.HasPrefix(directive, "=") || strings.HasPrefix(directive, "$"):
+		return
+	case directive == d.directives.BindBody:
+		d.rawBody = topLevel || d.rawBody
+		return
+	case strings.HasPrefix(directive, d.directives.HeaderBindingPrefix):
+		if topLevel {
+			name := d.namedOrField(directive[len(d.directives.HeaderBindingPrefix):], sf)
+			if d.tagKey == "in" {
+				d.addParameter(openapi.InHeader, name, sf, false)
+			} else {
+				d.headers[name] = openapi.Header{Schema: d.typeSchema(sf.Type)}
+			}
+		}
+		return
+	case d.tagKey == "in" && strings.HasPrefix(directive, d.directives.QueryParamBindingPrefix):
+		if topLevel {
+			d.addParameter(openapi.InQuery, d.namedOrField(directive[len(d.directives.QueryParamBindingPrefix):], sf), sf, false)
+		}
+		return
+	case d.tagKey == "in" && strings.HasPrefix(directive, d.directives.PathBindingPrefix):
+		if topLevel {
+			d.addPathParameter(d.namedOrField(directive[len(d.directives.PathBindingPrefix):], sf), sf)
+		}
+		return
+	}
+
+	name := directive
+	if name == "" || name == d.directives.IncludeField {
+		name = d.bind.FieldName(sf.Name)
+	}
+
+	fieldSchema := d.typeSchema(sf.Type)
+	required := d.tagKey == "out" && !omitEmpty
+	if d.tagKey == "in" {
+		required = d.constrain(fieldSchema, sf)
+	}
+
+	schema.Properties[name] = fieldSchema
+	if topLevel && d.fieldNames != nil {
+		d.fieldNames[name] = sf.Name
+	}
+	if required {
+		schema.Required = append(schema.Required, name)
+	}
+}
+
+func (d *describer) addParameter(in, name string, sf reflect.StructField, required bool) {
+	for _, p := range d.parameters {
+		if p.In == in && p.Name == name {
+			return
+		}
+	}
+
+	schema := d.typeSchema(sf.Type)
+	required = d.constrain(schema, sf) || required
+	d.parameters = append(d.parameters, openapi.Parameter{Name: name, In: in, Required: required, Schema: schema})
+}
+
+// addPathParameter adds the parameter for the path wildcard with the name, or at the index, if the path has it.
+func (d *describer) addPathParameter(wildcard string, sf reflect.StructField) {
+	if index, err := strconv.Atoi(wildcard); err == nil {
+		if index < len(d.pathParts) {
+			if name, ok := wildcardName(d.pathParts[index]); ok {
+				d.addParameter(openapi.InPath, name, sf, true)
+			}
+		}
+		return
+	}
+
+	for _, part := range d.pathParts {
+		if name, ok := wildcardName(part); ok && name == wildcard {
+			d.addParameter(openapi.InPath, name, sf, true)
+			return
+		}
+	}
+
+	for _, missing := range d.missingWildcards {
+		if missing == wildcard {
+			return
+		}
+	}
+	d.missingWildcards = append(d.missingWildcards, wildcard)
+}
+
+// wildcardName returns the name of the wildcard if the path segment is one, e.g. "OrderId" for "{OrderId}" (or for
+// "{OrderId...}").
+func wildcardName(segment string) (string, bool) {
+	if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") || segment == "{$}" {
+		return "", false
+	}
+	return strings.TrimSuffix(segment[1:len(segment)-1], "..."), true
+}
+
+// namedOrField returns the name unless it's the IncludeField directive, in which case it's the field's name.
+func (d *describer) namedOrField(name string, sf reflect.StructField) string {
+	if name == d.directives.IncludeField {
+		return sf.Name
+	}
+	return name
+}
+
+// constrain applies the field's validate directive to the schema, and returns true if it requires a value, i.e. if the
+// field's zero value doesn't satisfy it.
+func (d *describer) constrain(schema *openapi.Schema, sf reflect.StructField) bool {
+	directive, ok := sf.Tag.Lookup("validate")
+	if !ok {
+		return false
+	}
+	if directive, ok = structs.ScopedDirective(directive, d.scope); !ok || directive == "" {
+		return false
+	}
+
+	c, ge := constraint.Parse(directive, sf)
+	if ge != nil {
+		return false // reported when the validation tool processes the field
+	}
+
+	schema.Constrain(c)
+	return !satisfiedByZero(c, sf.Type)
+}
+
+func satisfiedByZero(c constraint.Constraint, t reflect.Type) (satisfied bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			satisfied = true // e.g. a constraint that depends on another field's value
+		}
+	}()
+
+	return gomerr.ErrorAs[*constraint.NotSatisfiedError](c.Test(reflect.Zero(t).Interface())) == nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (d *describer) typeSchema(t reflect.Type) *openapi.Schema {
+	t = indirectType(t)
+	switch {
+	case t == timeType:
+		return &openapi.Schema{Type: "string", Format: "date-time"}
+	case t == byteSliceType:
+		return &openapi.Schema{Type: "string", ContentEncoding: "base64"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &openapi.Schema{Type: "string"}
+	case reflect.Bool:
+		return &openapi.Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &openapi.Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &openapi.Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		return &openapi.Schema{Type: "integer", Minimum: &zero}
+	case reflect.Float32:
+		return &openapi.Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &openapi.Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		return &openapi.Schema{Type: "array", Items: d.typeSchema(t.Elem())}
+	case reflect.Map:
+		return &openapi.Schema{Type: "object", AdditionalProperties: d.typeSchema(t.Elem())}
+	case reflect.Struct:
+		return d.structSchema(t, false)
+	default:
+		return &openapi.Schema{} // any value
+	}
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
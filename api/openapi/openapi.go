@@ -0,0 +1,67 @@
+// Package openapi defines the parts of an OpenAPI 3.1 document that gomer generates from a resource registry (see
+// rest.OpenApi). Documents marshal to JSON with encoding/json.
+package openapi
+
+const Version = "3.1.0"
+
+type Document struct {
+	OpenApi string              `json:"openapi"`
+	Info    Info                `json:"info"`
+	Servers []Server            `json:"servers,omitempty"`
+	Paths   map[string]PathItem `json:"paths"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Server struct {
+	Url         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations on a path, keyed by lower-case HTTP method (e.g. "get").
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationId string               `json:"operationId,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"` // keyed by status code
+}
+
+// Parameter locations
+const (
+	InPath   = "path"
+	InQuery  = "query"
+	InHeader = "header"
+)
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema,omitempty"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"` // keyed by media type
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"` // keyed by media type
+}
+
+type Header struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
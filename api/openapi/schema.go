@@ -0,0 +1,145 @@
+package openapi
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/jt0/gomer/constraint"
+)
+
+// Schema is a JSON Schema (draft 2020-12) as used by OpenAPI 3.1.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Const                any                `json:"const,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *uint64            `json:"minLength,omitempty"`
+	MaxLength            *uint64            `json:"maxLength,omitempty"`
+	MinItems             *uint64            `json:"minItems,omitempty"`
+	MaxItems             *uint64            `json:"maxItems,omitempty"`
+	MinProperties        *uint64            `json:"minProperties,omitempty"`
+	MaxProperties        *uint64            `json:"maxProperties,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+}
+
+// Constrain adds the JSON Schema keywords that correspond to the constraint. Constraints without a JSON Schema
+// equivalent (e.g. time comparisons or a constraint that depends on another field's value) are ignored, as are those
+// within an "or" or "not" constraint.
+func (s *Schema) Constrain(c constraint.Constraint) {
+	params := c.Parameters()
+
+	switch cType := c.Type(); cType {
+	case "and", "int", "uint", "float": // the last three are the "between" comparisons
+		if constraints, ok := params.([]constraint.Constraint); ok {
+			for _, each := range constraints {
+				s.Constrain(each)
+			}
+		}
+	case "elements":
+		if elements, ok := params.(constraint.Constraint); ok && s.Items != nil {
+			s.Items.Constrain(elements)
+		}
+	case "lengthEquals":
+		if n, ok := params.(uint64); ok {
+			s.length(&n, &n)
+		}
+	case "lengthBetween":
+		if bounds, ok := params.([]any); ok && len(bounds) == 2 {
+			lower, _ := bounds[0].(uint64)
+			upper, _ := bounds[1].(uint64)
+			s.length(&lower, &upper)
+		}
+	case "lengthMin":
+		s.length(params.(*uint64), nil)
+	case "lengthMax":
+		s.length(nil, params.(*uint64))
+	case "empty":
+		zero := uint64(0)
+		s.length(nil, &zero)
+	case "nonEmpty":
+		one := uint64(1)
+		s.length(&one, nil)
+	case "oneOf":
+		s.Enum, _ = params.([]any)
+	case "equals":
+		s.Const = params
+	case "regexp":
+		s.Pattern, _ = params.(string)
+	case "startsWith":
+		if prefix, ok := params.(*string); ok && prefix != nil {
+			s.Pattern = "^" + regexp.QuoteMeta(*prefix)
+		}
+	case "endsWith":
+		if suffix, ok := params.(*string); ok && suffix != nil {
+			s.Pattern = regexp.QuoteMeta(*suffix) + "$"
+		}
+	default:
+		if i := strings.IndexByte(cType, '_'); i > 0 && cType[:i] != "time" {
+			cType = cType[i+1:] // e.g. "int_gte" -> "gte"
+		}
+		if value, ok := number(params); ok {
+			switch cType {
+			case constraint.EQ:
+				s.Const = reflect.ValueOf(params).Elem().Interface()
+			case constraint.GT:
+				s.ExclusiveMinimum = &value
+			case constraint.GTE:
+				s.Minimum = &value
+			case constraint.LT:
+				s.ExclusiveMaximum = &value
+			case constraint.LTE:
+				s.Maximum = &value
+			}
+		}
+	}
+}
+
+// length sets the keywords that constrain the length of the schema's type.
+func (s *Schema) length(min, max *uint64) {
+	switch s.Type {
+	case "array":
+		s.MinItems, s.MaxItems = or(min, s.MinItems), or(max, s.MaxItems)
+	case "object":
+		s.MinProperties, s.MaxProperties = or(min, s.MinProperties), or(max, s.MaxProperties)
+	default:
+		s.MinLength, s.MaxLength = or(min, s.MinLength), or(max, s.MaxLength)
+	}
+}
+
+func or(value, current *uint64) *uint64 {
+	if value != nil {
+		return value
+	}
+	return current
+}
+
+// number returns the value of a comparison constraint's parameter, which is a pointer to a number.
+func number(param any) (float64, bool) {
+	pv := reflect.ValueOf(param)
+	if pv.Kind() != reflect.Ptr || pv.IsNil() {
+		return 0, false
+	}
+
+	switch v := pv.Elem(); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	default:
+		return 0, false
+	}
+}
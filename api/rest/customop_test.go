@@ -0,0 +1,77 @@
+package rest_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jt0/gomer/_test/assert"
+	. "github.com/jt0/gomer/api/http"
+	"github.com/jt0/gomer/api/rest"
+	"github.com/jt0/gomer/auth"
+	"github.com/jt0/gomer/data/memory"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/resource"
+)
+
+type Order struct {
+	resource.BaseInstance[*Order] `structs:"ignore"`
+
+	OrderId string `db.keys:"pk" id:"+" in:"create:+;path.OrderId" out:"+"`
+	Status  string `in:"create:+;-" out:"+"`
+	Reason  string `in:"CancelAction:+;-" out:"+"`
+}
+
+type cancelAction struct {
+	resource.NoOpAction[*Order]
+}
+
+func (*cancelAction) Name() string {
+	return "CancelAction"
+}
+
+func (*cancelAction) FieldAccessPermissions() auth.AccessPermissions {
+	return auth.NoPermissions
+}
+
+func (*cancelAction) Retry(_ context.Context, _ *Order, ge gomerr.Gomerr) gomerr.Gomerr {
+	return ge
+}
+
+func (*cancelAction) Do(ctx context.Context, o *Order) gomerr.Gomerr {
+	o.Status = "cancelled"
+	_, ge := o.Update(ctx)
+	return ge
+}
+
+func (a *cancelAction) ExecuteOn(ctx context.Context, r any) (any, gomerr.Gomerr) {
+	return r.(*Order).DoAction(ctx, a)
+}
+
+func TestCustomOp(t *testing.T) {
+	store, ge := memory.Store(nil, &Order{})
+	assert.Success(t, ge)
+
+	actions := rest.CrudlActions[*Order]()
+	actions[rest.NewCustomOp(MethodPost, resource.InstanceCategory, "cancel", http.StatusAccepted)] = func() resource.AnyAction { return &cancelAction{} }
+
+	registry := resource.NewRegistry()
+	resource.Register[*Order](registry, resource.WithStore(store), resource.WithActions(actions))
+	handler := rest.BuildRoutes(registry)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"OrderId":"o1","Status":"open"}`)))
+	assert.Equals(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/orders/o1/cancel", strings.NewReader(`{"Reason":"changed my mind"}`)))
+	assert.Equals(t, http.StatusAccepted, rr.Code, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/orders/o1", nil))
+	assert.Equals(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Assert(t, strings.Contains(rr.Body.String(), `"cancelled"`), "unexpected body: %s", rr.Body.String())
+	assert.Assert(t, strings.Contains(rr.Body.String(), `"changed my mind"`), "unexpected body: %s", rr.Body.String())
+}
@@ -0,0 +1,87 @@
+package rest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jt0/gomer/_test/assert"
+	"github.com/jt0/gomer/api/rest"
+	"github.com/jt0/gomer/data/memory"
+	"github.com/jt0/gomer/resource"
+)
+
+type Ticket struct {
+	resource.BaseInstance[*Ticket] `structs:"ignore"`
+
+	TicketId string    `db.keys:"pk='TICKET',sk,gsi_1:pk='TICKET'" id:"+" in:"create:+;path.TicketId" out:"+"`
+	Status   string    `in:"create:+;-" out:"+"`
+	Created  time.Time `db.keys:"gsi_1:sk" in:"create:+;-" out:"+"`
+	Priority int       `in:"create:+;-" out:"+"`
+	Assignee string    `in:"create:assigned_to;-" out:"assigned_to"`
+}
+
+func TestListQuery(t *testing.T) {
+	store, ge := memory.Store(nil, &Ticket{})
+	assert.Success(t, ge)
+
+	registry := resource.NewRegistry()
+	resource.Register[*Ticket](registry,
+		resource.WithStore(store),
+		resource.WithActions(rest.CrudlActions[*Ticket]()),
+		resource.WithFilterableFields("Status", "Created", "Assignee"),
+		resource.WithSortableFields("Created", "Status"),
+	)
+	handler := rest.BuildRoutes(registry)
+
+	for _, body := range []string{
+		`{"TicketId":"t1","Status":"open","Created":"2024-01-01T00:00:00Z","assigned_to":"ann"}`,
+		`{"TicketId":"t2","Status":"open","Created":"2024-01-03T00:00:00Z"}`,
+		`{"TicketId":"t3","Status":"closed","Created":"2024-01-04T00:00:00Z"}`,
+		`{"TicketId":"t4","Status":"open","Created":"2024-01-02T00:00:00Z"}`,
+	} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/tickets", strings.NewReader(body)))
+		assert.Equals(t, http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	list := func(filter, sort string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/tickets?"+url.Values{"filter": {filter}, "sort": {sort}}.Encode(), nil))
+		return rr
+	}
+
+	rr := list("status eq 'open' and created gt 2024-01-01", "-created")
+	assert.Equals(t, http.StatusOK, rr.Code, rr.Body.String())
+	body := rr.Body.String()
+	t2, t4 := strings.Index(body, `"t2"`), strings.Index(body, `"t4"`)
+	assert.Assert(t, t2 >= 0 && t4 > t2, "expected t2 then t4: %s", body)
+	assert.Assert(t, !strings.Contains(body, `"t1"`) && !strings.Contains(body, `"t3"`), "unexpected tickets: %s", body)
+
+	rr = list("", "created")
+	assert.Equals(t, http.StatusOK, rr.Code, rr.Body.String())
+	body = rr.Body.String()
+	assert.Assert(t, strings.Index(body, `"t1"`) < strings.Index(body, `"t4"`) && strings.Index(body, `"t4"`) < strings.Index(body, `"t2"`), "expected ascending order: %s", body)
+
+	// Fields are named as they are in response bodies
+	rr = list("assigned_to eq ann", "")
+	assert.Equals(t, http.StatusOK, rr.Code, rr.Body.String())
+	body = rr.Body.String()
+	assert.Assert(t, strings.Contains(body, `"t1"`) && !strings.Contains(body, `"t2"`), "expected only t1: %s", body)
+
+	for _, bad := range [][2]string{
+		{"assignee eq ann", ""},      // not the bound name
+		{"priority eq 1", ""},        // not filterable
+		{"created gt yesterday", ""}, // not a time
+		{"status eq", ""},            // malformed
+		{"", "priority"},             // not sortable
+		{"", "status"},               // no index ordered by status
+		{"", "created,status"},       // only one sort field
+	} {
+		rr = list(bad[0], bad[1])
+		assert.Equals(t, http.StatusBadRequest, rr.Code, bad, rr.Body.String())
+	}
+}
@@ -0,0 +1,169 @@
+package rest
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	. "github.com/jt0/gomer/api/http"
+	"github.com/jt0/gomer/api/openapi"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/resource"
+)
+
+// OpenApi returns an OpenAPI 3.1 document that describes the routes BuildRoutes creates for the registry. Each
+// operation's parameters and request body are derived from the `in` and `validate` directives of the resource's
+// fields, and its response from the `out` directives, in the scope of the operation's action and per the current
+// binding configuration (see Configure). Error responses are those that an unhandled error is rendered as.
+func OpenApi(registry *resource.Registry, info openapi.Info) *openapi.Document {
+	doc := &openapi.Document{
+		OpenApi: openapi.Version,
+		Info:    info,
+		Paths:   make(map[string]openapi.PathItem),
+	}
+
+	for _, rt := range registry.RootTypes() {
+		walkRoutes(rt, "", nil, func(r route) {
+			pathItem, ok := doc.Paths[r.path]
+			if !ok {
+				pathItem = make(openapi.PathItem)
+				doc.Paths[r.path] = pathItem
+			}
+			pathItem[strings.ToLower(r.op.Method())] = operation(r)
+		})
+	}
+
+	return doc
+}
+
+func operation(r route) *openapi.Operation {
+	action := r.actionFunc()
+	scope := action.Name()
+
+	instance := r.rt.NewInstance(nil)
+	request := DescribeRequest(reflect.TypeOf(instance), scope, r.path)
+
+	result := instance
+	if action.AppliesToCategory() == resource.CollectionCategory {
+		result = r.rt.NewCollection(instance)
+	}
+	response := DescribeResponse(reflect.TypeOf(result), scope)
+
+	op := &openapi.Operation{
+		OperationId: operationId(r),
+		Tags:        []string{r.rt.InstanceName()},
+		Parameters:  request.Parameters,
+		Responses:   errorResponses(r),
+	}
+
+	// Every wildcard in the path is a parameter, whether it's bound or not
+	for _, part := range strings.Split(strings.Trim(r.path, "/"), "/") {
+		if !strings.HasPrefix(part, "{") || !strings.HasSuffix(part, "}") {
+			continue
+		}
+
+		name := part[1 : len(part)-1]
+		bound := false
+		for _, p := range op.Parameters {
+			bound = bound || p.In == openapi.InPath && p.Name == name
+		}
+		if !bound {
+			op.Parameters = append(op.Parameters, openapi.Parameter{Name: name, In: openapi.InPath, Required: true, Schema: &openapi.Schema{Type: "string"}})
+		}
+	}
+
+	if _, ok := result.(interface {
+		SetListQuery(resource.ListQuery) gomerr.Gomerr
+	}); ok {
+		op.Parameters = append(op.Parameters,
+			openapi.Parameter{Name: FilterQueryParam, In: openapi.InQuery, Schema: &openapi.Schema{Type: "string"}},
+			openapi.Parameter{Name: SortQueryParam, In: openapi.InQuery, Schema: &openapi.Schema{Type: "string"}},
+		)
+	}
+
+	if request.RawBody {
+		op.RequestBody = &openapi.RequestBody{Content: map[string]openapi.MediaType{"*/*": {}}}
+	} else if request.Body != nil {
+		op.RequestBody = &openapi.RequestBody{
+			Required: len(request.Body.Required) > 0,
+			Content:  content(RequestContentTypes(), request.Body),
+		}
+		op.Responses[strconv.Itoa(http.StatusUnsupportedMediaType)] = problemResponse("The request body's media type is not supported (http.UnsupportedMediaTypeError)")
+	}
+
+	success := &openapi.Response{Description: http.StatusText(r.successStatus), Headers: response.Headers}
+	if response.RawBody {
+		success.Content = map[string]openapi.MediaType{"*/*": {}}
+	} else if response.Body != nil && r.successStatus != http.StatusNoContent {
+		success.Content = content(ResponseContentTypes(), response.Body)
+	}
+	op.Responses[strconv.Itoa(r.successStatus)] = success
+
+	return op
+}
+
+// content returns the schema as the content of each of the media types.
+func content(mediaTypes []string, schema *openapi.Schema) map[string]openapi.MediaType {
+	c := make(map[string]openapi.MediaType, len(mediaTypes))
+	for _, mediaType := range mediaTypes {
+		c[mediaType] = openapi.MediaType{Schema: schema}
+	}
+	return c
+}
+
+// operationId returns the operation's method followed by the resource's name, and, for a CustomOp, the action's name,
+// e.g. "getOrder", "getOrders", or "postOrderCancel".
+func operationId(r route) string {
+	name := r.rt.InstanceName()
+	if r.op.ResourceType() == resource.CollectionCategory {
+		name = r.rt.CollectionName()
+	}
+
+	id := strings.ToLower(r.op.Method()) + name
+	if r.customName != "" {
+		id += strings.ToUpper(r.customName[:1]) + r.customName[1:]
+	}
+
+	return id
+}
+
+// errorResponses returns the responses for the errors that ProblemFor distinguishes. Each is rendered as a Problem.
+func errorResponses(r route) map[string]*openapi.Response {
+	responses := map[string]*openapi.Response{
+		strconv.Itoa(http.StatusBadRequest):          problemResponse("The request has an invalid value (gomerr.BadValueError or constraint.NotSatisfiedError)"),
+		strconv.Itoa(StatusLimitExceeded):            problemResponse("A limit has been exceeded (limit.ExceededError)"),
+		strconv.Itoa(http.StatusConflict):            problemResponse("The request conflicts with a resource's state (gomerr.ConflictError)"),
+		strconv.Itoa(http.StatusUnprocessableEntity): problemResponse("The request cannot be processed (gomerr.UnprocessableError)"),
+		strconv.Itoa(http.StatusNotAcceptable):       problemResponse("No acceptable media type can be produced (http.NotAcceptableError)"),
+		strconv.Itoa(http.StatusInternalServerError): problemResponse("The request could not be processed"),
+	}
+	if strings.Contains(r.path, "{") {
+		responses[strconv.Itoa(http.StatusNotFound)] = problemResponse("A resource in the path was not found (gomerr.NotFoundError)")
+	}
+
+	return responses
+}
+
+func problemResponse(description string) *openapi.Response {
+	return &openapi.Response{Description: description, Content: map[string]openapi.MediaType{ProblemContentType: {Schema: problemSchema}}}
+}
+
+var problemSchema = &openapi.Schema{
+	Type: "object",
+	Properties: map[string]*openapi.Schema{
+		"type":   {Type: "string"},
+		"title":  {Type: "string"},
+		"status": {Type: "integer", Format: "int64"},
+		"detail": {Type: "string"},
+		"errors": {Type: "array", Items: &openapi.Schema{
+			Type: "object",
+			Properties: map[string]*openapi.Schema{
+				"field":  {Type: "string"},
+				"detail": {Type: "string"},
+			},
+			Required: []string{"detail"},
+		}},
+	},
+	Required: []string{"type", "title", "status"},
+}
@@ -0,0 +1,135 @@
+package rest_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/jt0/gomer/_test/assert"
+	. "github.com/jt0/gomer/api/http"
+	"github.com/jt0/gomer/api/openapi"
+	"github.com/jt0/gomer/api/rest"
+	"github.com/jt0/gomer/data/memory"
+	"github.com/jt0/gomer/resource"
+)
+
+type Account struct {
+	resource.BaseInstance[*Account] `structs:"ignore"`
+
+	AccountId string   `db.keys:"pk" id:"+" in:"create:+;path.AccountId" out:"+"`
+	Name      string   `in:"create:+;update:+;-" out:"+" validate:"nonempty,maxlen(64)"`
+	Tier      string   `in:"create:+;-" out:"+" validate:"oneof(free,pro)"`
+	Seats     int      `in:"create:+;update:+;-" out:"+" validate:"intbetween(1,100)"`
+	Tags      []string `in:"create:+;-" out:"+,includeempty"`
+	RequestId string   `in:"header.X-Request-Id" out:"header.X-Request-Id"`
+	Verbose   bool     `in:"read:query.verbose;-"`
+}
+
+type AccountMember struct {
+	resource.BaseInstance[*AccountMember] `structs:"ignore"`
+
+	AccountId string `db.keys:"pk" in:"path.AccountId" out:"+"`
+	MemberId  string `db.keys:"sk" id:"+" in:"create:+;path.MemberId" out:"+"`
+}
+
+func TestOpenApi(t *testing.T) {
+	store, ge := memory.Store(nil, &Account{}, &AccountMember{})
+	assert.Success(t, ge)
+
+	registry := resource.NewRegistry()
+	resource.Register[*Account](registry, resource.WithStore(store), resource.WithActions(rest.CrudlActions[*Account]()))
+	resource.Register[*AccountMember](registry, resource.WithParent[*Account](), resource.WithActions(rest.CrudlActions[*AccountMember]()))
+
+	doc := rest.OpenApi(registry, openapi.Info{Title: "Accounts", Version: "1.0"})
+	assert.Equals(t, openapi.Version, doc.OpenApi)
+
+	_, err := json.Marshal(doc)
+	assert.Success(t, err)
+
+	t.Run("Create", func(t *testing.T) {
+		create := doc.Paths["/accounts"]["post"]
+		assert.Equals(t, "postAccounts", create.OperationId)
+		assert.Equals(t, []openapi.Parameter{{Name: "X-Request-Id", In: openapi.InHeader, Schema: &openapi.Schema{Type: "string"}}}, create.Parameters)
+
+		body := create.RequestBody.Content[DefaultContentType].Schema
+		assert.Equals(t, 5, len(body.Properties))
+		assert.Equals(t, []string{"Name", "Tier", "Seats"}, body.Required) // their zero values aren't valid
+		assert.Equals(t, uint64(1), *body.Properties["Name"].MinLength)
+		assert.Equals(t, uint64(64), *body.Properties["Name"].MaxLength)
+		assert.Equals(t, []any{"free", "pro"}, body.Properties["Tier"].Enum)
+		assert.Equals(t, 1.0, *body.Properties["Seats"].Minimum)
+		assert.Equals(t, 100.0, *body.Properties["Seats"].Maximum)
+		assert.Equals(t, "array", body.Properties["Tags"].Type)
+
+		created := create.Responses["201"]
+		assert.Equals(t, "string", created.Headers["X-Request-Id"].Schema.Type)
+		result := created.Content[DefaultContentType].Schema
+		assert.Equals(t, []string{"Tags"}, result.Required) // includeempty
+		_, hasVerbose := result.Properties["Verbose"]
+		assert.Assert(t, !hasVerbose, "Verbose isn't output")
+
+		for _, status := range []string{"400", "402", "406", "409", "415", "422", "500"} {
+			assert.NotNil(t, create.Responses[status].Content[rest.ProblemContentType].Schema)
+		}
+	})
+
+	t.Run("Read", func(t *testing.T) {
+		read := doc.Paths["/accounts/{AccountId}"]["get"]
+		assert.Nil(t, read.RequestBody)
+		assert.NotNil(t, read.Responses["404"])
+
+		parameters := make(map[string]openapi.Parameter)
+		for _, p := range read.Parameters {
+			parameters[p.In+":"+p.Name] = p
+		}
+		assert.Equals(t, 3, len(parameters))
+		assert.Assert(t, parameters["path:AccountId"].Required, "path parameters are required")
+		assert.Equals(t, "boolean", parameters["query:verbose"].Schema.Type)
+		assert.Equals(t, "string", parameters["header:X-Request-Id"].Schema.Type)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		list := doc.Paths["/accounts"]["get"]
+		assert.Equals(t, "getAccounts", list.OperationId)
+
+		var names []string
+		for _, p := range list.Parameters {
+			if p.In == openapi.InQuery {
+				names = append(names, p.Name)
+			}
+		}
+		assert.Equals(t, []string{FilterQueryParam, SortQueryParam}, names)
+
+		items := list.Responses["200"].Content[DefaultContentType].Schema.Properties["Items"]
+		assert.Equals(t, "array", items.Type)
+		assert.Equals(t, "string", items.Items.Properties["AccountId"].Type)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		deleted := doc.Paths["/accounts/{AccountId}"]["delete"].Responses["204"]
+		assert.Equals(t, http.StatusText(http.StatusNoContent), deleted.Description)
+		assert.Nil(t, deleted.Content)
+	})
+
+	t.Run("Child", func(t *testing.T) {
+		read := doc.Paths["/accounts/{AccountId}/members/{MemberId}"]["get"]
+		assert.Equals(t, "getAccountMember", read.OperationId)
+
+		var names []string
+		for _, p := range read.Parameters {
+			assert.Equals(t, openapi.InPath, p.In)
+			names = append(names, p.Name)
+		}
+		assert.Equals(t, []string{"AccountId", "MemberId"}, names)
+	})
+
+	t.Run("CamelCase", func(t *testing.T) {
+		Configure(CamelCaseFields)
+		defer Configure()
+
+		doc := rest.OpenApi(registry, openapi.Info{Title: "Accounts", Version: "1.0"})
+		body := doc.Paths["/accounts"]["post"].RequestBody.Content[DefaultContentType].Schema
+		assert.NotNil(t, body.Properties["accountId"])
+		assert.Equals(t, []string{"name", "tier", "seats"}, body.Required)
+	})
+}
@@ -0,0 +1,55 @@
+package rest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jt0/gomer/_test/assert"
+	"github.com/jt0/gomer/api/rest"
+	"github.com/jt0/gomer/data/memory"
+	"github.com/jt0/gomer/resource"
+)
+
+type Widget struct {
+	resource.BaseInstance[*Widget] `structs:"ignore"`
+
+	WidgetID string `db.keys:"pk" in:"create:+;path.WidgetID" out:"+"` // the route's wildcard is {WidgetId}
+}
+
+func TestPathBinding(t *testing.T) {
+	t.Run("MountedUnderPrefix", func(t *testing.T) {
+		store, ge := memory.Store(nil, &Account{}, &AccountMember{})
+		assert.Success(t, ge)
+
+		registry := resource.NewRegistry()
+		resource.Register[*Account](registry, resource.WithStore(store), resource.WithActions(rest.CrudlActions[*Account]()))
+		resource.Register[*AccountMember](registry, resource.WithParent[*Account](), resource.WithStore(store), resource.WithActions(rest.CrudlActions[*AccountMember]()))
+
+		mux := http.NewServeMux()
+		mux.Handle("/api/v1/", http.StripPrefix("/api/v1", rest.BuildRoutes(registry)))
+
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/accounts/a1/members", strings.NewReader(`{"MemberId":"m1"}`)))
+		assert.Equals(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+		rr = httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/accounts/a1/members/m1", nil))
+		assert.Equals(t, http.StatusOK, rr.Code, rr.Body.String())
+		assert.Assert(t, strings.Contains(rr.Body.String(), `"AccountId":"a1"`), "%s", rr.Body.String())
+		assert.Assert(t, strings.Contains(rr.Body.String(), `"MemberId":"m1"`), "%s", rr.Body.String())
+	})
+
+	t.Run("UnknownWildcard", func(t *testing.T) {
+		registry := resource.NewRegistry()
+		resource.Register[*Widget](registry, resource.WithActions(rest.CrudlActions[*Widget]()))
+
+		defer func() {
+			r := recover()
+			message, _ := r.(string)
+			assert.Assert(t, strings.Contains(message, `"wildcard": "WidgetID"`), "expected BuildRoutes to panic: %v", r)
+		}()
+		rest.BuildRoutes(registry)
+	})
+}
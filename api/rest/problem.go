@@ -0,0 +1,206 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	. "github.com/jt0/gomer/api/http"
+	"github.com/jt0/gomer/constraint"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/limit"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Errors lists the individual problems with the request, e.g. one per
+// field that failed validation.
+type Problem struct {
+	Type   string         `json:"type"`
+	Title  string         `json:"title"`
+	Status int            `json:"status"`
+	Detail string         `json:"detail,omitempty"`
+	Errors []ProblemError `json:"errors,omitempty"`
+}
+
+type ProblemError struct {
+	Field  string `json:"field,omitempty"` // the validation target or field name, if the problem is with a field
+	Detail string `json:"detail"`
+}
+
+func (p *Problem) StatusCode() int {
+	return p.Status
+}
+
+// ProblemFor returns the problem details that the error is rendered as when no RenderErrorMiddleware handles it. The
+// status is the error's own if it's a StatusCoder, and otherwise depends on the error's gomerr type:
+//
+//	*gomerr.BadValueError, *gomerr.UnmarshalError, *constraint.NotSatisfiedError: 400 Bad Request
+//	*limit.ExceededError: StatusLimitExceeded
+//	*gomerr.NotFoundError: 404 Not Found
+//	*gomerr.ConflictError: 409 Conflict
+//	*gomerr.UnprocessableError: 422 Unprocessable Entity
+//	*gomerr.DependencyError: 502 Bad Gateway
+//	other errors: 500 Internal Server Error
+//
+// A *gomerr.BatchError has the status its errors share, 400 if they're all client errors, or 500. The problem's
+// Errors includes an entry for each error in a batch or for a single client error. Server errors aren't detailed.
+func ProblemFor(err error) *Problem {
+	var leaves []error
+	if be := gomerr.ErrorAs[*gomerr.BatchError](err); be != nil {
+		leaves = flatten(be, nil)
+	} else {
+		leaves = []error{err}
+	}
+
+	status := 0
+	var problemErrors []ProblemError
+	for _, leaf := range leaves {
+		leafStatus := errorStatusCode(leaf)
+		switch {
+		case status == 0 || status == leafStatus:
+			status = leafStatus
+		case status < 500 && leafStatus < 500:
+			status = http.StatusBadRequest
+		default:
+			status = http.StatusInternalServerError
+		}
+
+		if leafStatus < 500 {
+			field, detail := describeError(leaf)
+			problemErrors = append(problemErrors, ProblemError{Field: field, Detail: detail})
+		}
+	}
+
+	p := &Problem{Type: "about:blank", Title: http.StatusText(status), Status: status}
+	if p.Title == "" {
+		p.Title = "Error"
+	}
+	if status >= 500 {
+		return p
+	}
+
+	if len(problemErrors) == 1 {
+		p.Detail = problemErrors[0].Detail
+		if problemErrors[0].Field == "" {
+			return p
+		}
+	} else {
+		p.Detail = fmt.Sprintf("The request has %d problems", len(problemErrors))
+	}
+	p.Errors = problemErrors
+
+	return p
+}
+
+func flatten(be *gomerr.BatchError, leaves []error) []error {
+	for _, ge := range be.Errors() {
+		if ibe := gomerr.ErrorAs[*gomerr.BatchError](ge); ibe != nil {
+			leaves = flatten(ibe, leaves)
+		} else {
+			leaves = append(leaves, ge)
+		}
+	}
+	return leaves
+}
+
+// errorStatusCode returns the status code for an error that isn't a batch. Errors that provide their own status code
+// are honored; otherwise the first of the types listed by ProblemFor that's in the error's chain determines it.
+func errorStatusCode(err error) int {
+	var sc interface {
+		error
+		StatusCoder
+	}
+	if errors.As(err, &sc) {
+		return sc.StatusCode()
+	}
+
+	switch {
+	case gomerr.ErrorAs[*limit.ExceededError](err) != nil:
+		return StatusLimitExceeded
+	case gomerr.ErrorAs[*gomerr.BadValueError](err) != nil,
+		gomerr.ErrorAs[*constraint.NotSatisfiedError](err) != nil,
+		gomerr.ErrorAs[*gomerr.UnmarshalError](err) != nil:
+		return http.StatusBadRequest
+	case gomerr.ErrorAs[*gomerr.NotFoundError](err) != nil:
+		return http.StatusNotFound
+	case gomerr.ErrorAs[*gomerr.ConflictError](err) != nil:
+		return http.StatusConflict
+	case gomerr.ErrorAs[*gomerr.UnprocessableError](err) != nil:
+		return http.StatusUnprocessableEntity
+	case gomerr.ErrorAs[*gomerr.DependencyError](err) != nil:
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// describeError returns the field a client error relates to, if any, and a description of the error that doesn't
+// reveal more than the client provided.
+func describeError(err error) (field, detail string) {
+	if ge := gomerr.ErrorAs[gomerr.Gomerr](err); ge != nil {
+		if target, ok := ge.Attribute("target").(string); ok {
+			field = target
+		} else {
+			field, _ = ge.Attribute("field").(string)
+		}
+	}
+
+	if nse := gomerr.ErrorAs[*constraint.NotSatisfiedError](err); nse != nil {
+		if nse.Target != "" {
+			field = nse.Target
+		}
+		if nse.Constraint != nil {
+			return field, "The value does not satisfy the constraint: " + nse.Constraint.String()
+		}
+		return field, "The value does not satisfy a constraint"
+	}
+
+	if bve := gomerr.ErrorAs[*gomerr.BadValueError](err); bve != nil {
+		if field == "" {
+			field = bve.Name
+		}
+		switch bve.Type {
+		case gomerr.ExpiredValueType:
+			detail = "The value has expired"
+		case gomerr.InvalidValueType:
+			detail = "The value is invalid"
+		case gomerr.MalformedValueType:
+			detail = "The value is malformed"
+		default:
+			detail = "The value is not acceptable"
+		}
+		if reason, ok := bve.Attribute("Reason").(string); ok && reason != "" {
+			detail += ": " + reason
+		}
+		return field, detail
+	}
+
+	if nfe := gomerr.ErrorAs[*gomerr.NotFoundError](err); nfe != nil {
+		if nfe.Id == "" {
+			return field, nfe.Type + " was not found"
+		}
+		return field, fmt.Sprintf("%s '%s' was not found", nfe.Type, nfe.Id)
+	}
+
+	if ce := gomerr.ErrorAs[*gomerr.ConflictError](err); ce != nil {
+		if ce.Problem != "" {
+			return field, ce.Problem
+		}
+		return field, "The request conflicts with the current state of " + ce.With
+	}
+
+	if ue := gomerr.ErrorAs[*gomerr.UnprocessableError](err); ue != nil && ue.Reason != "" {
+		return field, ue.Reason
+	}
+
+	if gomerr.ErrorAs[*gomerr.UnmarshalError](err) != nil {
+		return field, "The value could not be unmarshaled"
+	}
+
+	if gomerr.ErrorAs[*limit.ExceededError](err) != nil {
+		return field, "A limit has been exceeded"
+	}
+
+	return field, http.StatusText(errorStatusCode(err))
+}
@@ -0,0 +1,100 @@
+package rest_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jt0/gomer/_test/assert"
+	"github.com/jt0/gomer/api/rest"
+	"github.com/jt0/gomer/data/memory"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/limit"
+	"github.com/jt0/gomer/resource"
+)
+
+func TestProblemFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		detail string
+		fields []string
+	}{
+		{"NotFound", gomerr.NotFound("Order", "o1"), http.StatusNotFound, "Order 'o1' was not found", nil},
+		{"Conflict", gomerr.Conflict("Order", "o1", "Order is already shipped"), http.StatusConflict, "Order is already shipped", nil},
+		{"BadValue", gomerr.MalformedValue("filter", "status eq").WithReason("missing operand"), http.StatusBadRequest, "The value is malformed: missing operand", []string{"filter"}},
+		{"Unprocessable", gomerr.Unprocessable("Order has no items", nil), http.StatusUnprocessableEntity, "Order has no items", nil},
+		{"LimitExceeded", limit.UnquantifiedExcess("limiter", "limited"), http.StatusPaymentRequired, "A limit has been exceeded", nil},
+		{"Dependency", gomerr.Dependency("payments", nil), http.StatusBadGateway, "", nil},
+		{"Internal", gomerr.Internal("oops").Wrap(gomerr.Configuration("secret")), http.StatusInternalServerError, "", nil},
+		{"Batch", gomerr.Batch(gomerr.NotFound("Order", "o1"), gomerr.InvalidValue("Qty", -1, nil)), http.StatusBadRequest, "The request has 2 problems", []string{"", "Qty"}},
+		{"BatchWithServerError", gomerr.Batch(gomerr.NotFound("Order", "o1"), gomerr.Internal("oops")), http.StatusInternalServerError, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := rest.ProblemFor(tt.err)
+			assert.Equals(t, tt.status, p.Status)
+			assert.Equals(t, http.StatusText(tt.status), p.Title)
+			assert.Equals(t, tt.detail, p.Detail)
+
+			var fields []string
+			for _, pe := range p.Errors {
+				fields = append(fields, pe.Field)
+			}
+			assert.Equals(t, tt.fields, fields)
+		})
+	}
+}
+
+func TestProblemRendering(t *testing.T) {
+	store, ge := memory.Store(nil, &Account{})
+	assert.Success(t, ge)
+
+	registry := resource.NewRegistry()
+	resource.Register[*Account](registry, resource.WithStore(store), resource.WithActions(rest.CrudlActions[*Account]()))
+	handler := rest.BuildRoutes(registry)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/accounts", strings.NewReader(`{"Name":"","Tier":"gold","Seats":10}`)))
+	assert.Equals(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+	assert.Equals(t, rest.ProblemContentType, rr.Header().Get("Content-Type"))
+
+	var p rest.Problem
+	assert.Success(t, json.Unmarshal(rr.Body.Bytes(), &p))
+	assert.Equals(t, http.StatusBadRequest, p.Status)
+
+	failed := make(map[string]string)
+	for _, pe := range p.Errors {
+		failed[pe.Field] = pe.Detail
+	}
+	assert.Equals(t, 2, len(failed), p.Errors)
+	assert.Assert(t, strings.Contains(failed["Name"], "nonEmpty"), "%s", failed["Name"])
+	assert.Assert(t, strings.Contains(failed["Tier"], "oneOf"), "%s", failed["Tier"])
+
+	rr = httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/accounts", strings.NewReader(`Name=Acme`))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler.ServeHTTP(rr, request)
+	assert.Equals(t, http.StatusUnsupportedMediaType, rr.Code, rr.Body.String())
+	assert.Equals(t, rest.ProblemContentType, rr.Header().Get("Content-Type"))
+
+	rr = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodPost, "/accounts", strings.NewReader(`{"AccountId":"a1","Name":"Acme","Tier":"pro","Seats":10}`))
+	request.Header.Set("Accept", "text/html")
+	handler.ServeHTTP(rr, request)
+	assert.Equals(t, http.StatusNotAcceptable, rr.Code, rr.Body.String())
+	assert.Equals(t, rest.ProblemContentType, rr.Header().Get("Content-Type"))
+
+	// The unacceptable request wasn't executed, so the account can still be created
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/accounts", strings.NewReader(`{"AccountId":"a1","Name":"Acme","Tier":"pro","Seats":10}`)))
+	assert.Equals(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+	assert.Equals(t, http.StatusNotFound, rr.Code, rr.Body.String())
+	assert.Equals(t, rest.ProblemContentType, rr.Header().Get("Content-Type"))
+}
@@ -1,6 +1,7 @@
 package rest
 
 import (
+	"fmt"
 	"net/http"
 	"reflect"
 	"strings"
@@ -51,13 +52,31 @@ func CrudlActions[I resource.Instance[I]]() map[any]func() resource.AnyAction {
 	}
 }
 
+// CustomOp is an action map key for a named, application-defined action (e.g. "cancel" or "approve"). The action is
+// exposed at its own sub-path beneath the instance or collection path of the resource, such as
+// POST /orders/{OrderId}/cancel. As with the built-in actions, the action's Name() is the scope used when binding the
+// request and response, so `in` and `out` tags can be specific to the action.
+type CustomOp struct {
+	Op
+	Name              string // The path segment for the action
+	SuccessStatusCode int    // If zero, http.StatusOK is used
+}
+
+// NewCustomOp returns a CustomOp for the given method, category, and name. The name must be a single, non-empty path
+// segment.
+func NewCustomOp(method Method, category resource.Category, name string, successStatusCode int) CustomOp {
+	return CustomOp{NewOp(method, category), name, successStatusCode}
+}
+
 // NoActions is an empty action map for resources that don't expose REST endpoints.
 var NoActions = map[any]func() resource.AnyAction{}
 
 func BuildRoutes(registry *resource.Registry, middleware ...func(http.Handler) http.Handler) http.Handler {
 	mux := http.NewServeMux()
 	for _, rt := range registry.RootTypes() {
-		buildRoutes(mux, rt, "", nil)
+		walkRoutes(rt, "", nil, func(r route) {
+			mux.Handle(r.op.Method()+" "+r.path, handler(r.rt, r.actionFunc, r.successStatus))
+		})
 	}
 
 	// Register catchall handler for unmatched routes
@@ -66,6 +85,16 @@ func BuildRoutes(registry *resource.Registry, middleware ...func(http.Handler) h
 	return withMiddleware(registry, mux, middleware)
 }
 
+// route is an action of a registered type exposed at a path.
+type route struct {
+	rt            resource.RegisteredType
+	op            Op
+	path          string // The pattern's path, e.g. "/orders/{OrderId}/cancel"
+	customName    string // The CustomOp's name (e.g. "cancel"), if the action has one
+	actionFunc    func() resource.AnyAction
+	successStatus int
+}
+
 // noRouteHandler returns a handler for requests that don't match any registered route.
 func noRouteHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -79,7 +108,8 @@ func noRouteHandler() http.Handler {
 	})
 }
 
-func buildRoutes(mux *http.ServeMux, rt resource.RegisteredType, parentPath string, ancestors []ancestorContext) {
+// walkRoutes calls fn with each route of the registered type's actions, and then those of its children.
+func walkRoutes(rt resource.RegisteredType, parentPath string, ancestors []ancestorContext, fn func(route)) {
 	if ge := structs.Preprocess(rt.NewInstance(nil), DefaultBindFromRequestTool, constraint.DefaultValidationTool); ge != nil {
 		panic(ge.String())
 	}
@@ -89,39 +119,36 @@ func buildRoutes(mux *http.ServeMux, rt resource.RegisteredType, parentPath stri
 
 	hasCollectionAction := false
 	for key := range rt.Actions() {
-		if key.(Op).ResourceType() == resource.CollectionCategory {
+		if op, _, _ := routeFor(key); op.ResourceType() == resource.CollectionCategory {
 			hasCollectionAction = true
 			break
 		}
 	}
 
 	path := make(map[resource.Category]string, 2)
+	var idWildcard string
 	if hasCollectionAction {
 		// Normal CRUD: collections are derived from instance type
 		collectionPathName := pathName(rt.CollectionName(), ancestors)
 		path[resource.CollectionCategory] = parentPath + "/" + strings.ToLower(collectionPathName)
-		path[resource.InstanceCategory] = path[resource.CollectionCategory] + "/{" + instancePathName + "Id}"
+		idWildcard = instancePathName + "Id"
+		path[resource.InstanceCategory] = path[resource.CollectionCategory] + "/{" + idWildcard + "}"
 	} else {
 		// Singleton: use singular path without ID placeholder
 		path[resource.InstanceCategory] = parentPath + "/" + strings.ToLower(instancePathName)
 	}
 
 	for key, actionFunc := range rt.Actions() {
-		op := key.(Op)
+		op, subPath, successStatus := routeFor(key)
 
 		relativePath, ok := path[op.ResourceType()]
 		if !ok {
 			panic("invalid resource type; does not map to a path: " + op.ResourceType())
 		}
 
-		successStatus, ok := successStatusCodes[op]
-		if !ok {
-			successStatus = http.StatusOK
-		}
-
-		// Register with method and path pattern
-		pattern := op.Method() + " " + relativePath
-		mux.Handle(pattern, handler(rt, actionFunc, successStatus))
+		r := route{rt, op, relativePath + subPath, strings.TrimPrefix(subPath, "/"), actionFunc, successStatus}
+		validatePathBindings(r, idWildcard)
+		fn(r)
 	}
 
 	// Prepend this resource's context to ancestors for children (closest ancestor first)
@@ -130,7 +157,50 @@ func buildRoutes(mux *http.ServeMux, rt resource.RegisteredType, parentPath stri
 		pathName: instancePathName,
 	}}, ancestors...)
 	for _, childMetadata := range rt.Children() {
-		buildRoutes(mux, childMetadata, path[resource.InstanceCategory], childAncestors)
+		walkRoutes(childMetadata, path[resource.InstanceCategory], childAncestors, fn)
+	}
+}
+
+// validatePathBindings panics if, in the scope of the route's action, the resource type binds a path wildcard that the
+// route's path doesn't have. The exception is the wildcard for the instance's id, which a collection's paths lack.
+func validatePathBindings(r route, idWildcard string) {
+	action := r.actionFunc()
+	if action == nil {
+		return // handler reports this
+	}
+
+	for _, wildcard := range MissingPathWildcards(reflect.TypeOf(r.rt.NewInstance(nil)), action.Name(), r.path) {
+		if wildcard == idWildcard && r.op.ResourceType() == resource.CollectionCategory {
+			continue
+		}
+		panic(gomerr.Configuration("path binding's wildcard is not in the route's path").AddAttributes("type", r.rt.InstanceName(), "wildcard", wildcard, "route", r.op.Method()+" "+r.path).String())
+	}
+}
+
+// routeFor returns the Op, the path to append to the resource's path, and the success status code for an action key.
+func routeFor(key any) (Op, string, int) {
+	switch k := key.(type) {
+	case Op:
+		successStatus, ok := successStatusCodes[k]
+		if !ok {
+			successStatus = http.StatusOK
+		}
+		return k, "", successStatus
+	case CustomOp:
+		if !k.Op.IsValid() {
+			panic(gomerr.Configuration("invalid op for custom action: " + k.Name).String())
+		}
+		if k.Name == "" || strings.ContainsAny(k.Name, "/{}") {
+			panic(gomerr.Configuration("custom action name must be a single path segment: " + k.Name).String())
+		}
+
+		successStatus := k.SuccessStatusCode
+		if successStatus == 0 {
+			successStatus = http.StatusOK
+		}
+		return k.Op, "/" + k.Name, successStatus
+	default:
+		panic(gomerr.Configuration(fmt.Sprintf("unsupported action key type: %T", key)).String())
 	}
 }
 
@@ -175,6 +245,13 @@ func handler(rt resource.RegisteredType, actionFunc func() resource.AnyAction, s
 			w = rw
 		}
 
+		// The response's media type is negotiated first so that an unacceptable request isn't executed
+		contentType, ge := NegotiateContentType(r.Header.Get(AcceptHeader))
+		if ge != nil {
+			rw.WriteError(ge)
+			return
+		}
+
 		// Bind request data to new instance
 		res := rt.NewInstance(Subject(r))
 		if ge := BindFromRequest(r, res, anyAction.Name()); ge != nil {
@@ -185,21 +262,25 @@ func handler(rt resource.RegisteredType, actionFunc func() resource.AnyAction, s
 		// If CollectionCategory, we use the bound instance as the prototype for its collection type
 		if anyAction.AppliesToCategory() == resource.CollectionCategory {
 			res = rt.NewCollection(res)
+			if ge := BindListQuery(r, res, anyAction.Name()); ge != nil {
+				rw.WriteError(ge)
+				return
+			}
 		}
 
-		// Execute action via DoAction on the resource
-		result, ge := anyAction.ExecuteOn(r.Context(), res)
+		// Execute action via DoAction on the resource. Actions may hold per-request state, so each request gets its own.
+		result, ge := actionFunc().ExecuteOn(r.Context(), res)
 		if ge != nil {
 			rw.WriteError(ge)
 			return
 		}
 
-		renderResult(result, rw, r, anyAction.Name(), successStatus)
+		renderResult(result, rw, r, anyAction.Name(), contentType, successStatus)
 	})
 }
 
-func renderResult(result any, w http.ResponseWriter, r *http.Request, scope string, statusCode int) {
-	bytes, statusCode := BindToResponse(reflect.ValueOf(result), w.Header(), scope, r.Header.Get("Accept-Language"), statusCode)
-	w.WriteHeader(statusCode)
-	w.Write(bytes)
+func renderResult(result any, rw *ResponseWriter, r *http.Request, scope string, contentType string, statusCode int) {
+	bytes, statusCode := BindToResponse(reflect.ValueOf(result), rw.Header(), scope, contentType, r.Header.Get(AcceptLanguageHeader), statusCode)
+	rw.WriteHeader(statusCode)
+	rw.Write(bytes)
 }
@@ -3,9 +3,16 @@ package rest
 import (
 	"context"
 	"encoding/json"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"reflect"
 	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	. "github.com/jt0/gomer/api/http"
 	"github.com/jt0/gomer/gomerr"
@@ -47,7 +54,7 @@ func RenderErrorMiddleware(renderer func(gomerr.Gomerr) StatusCoder) func(http.H
 
 			if ge := gomerr.ErrorAs[gomerr.Gomerr](rw.err); ge != nil {
 				rendered := renderer(ge)
-				bytes, statusCode := BindToResponse(reflect.ValueOf(rendered), rw.Header(), "", r.Header.Get("Accept-Language"), rendered.StatusCode())
+				bytes, statusCode := BindToResponse(reflect.ValueOf(rendered), rw.Header(), "", r.Header.Get(AcceptHeader), r.Header.Get(AcceptLanguageHeader), rendered.StatusCode())
 				rw.statusCode = statusCode
 				rw.body = bytes
 				rw.err = nil
@@ -66,47 +73,178 @@ func chain(middlewares ...func(http.Handler) http.Handler) func(http.Handler) ht
 	}
 }
 
+// Options configures the server that Serve runs. The zero value of a field other than Host, Port, and Listener
+// disables what it configures (e.g. a zero WriteTimeout means no timeout).
 type Options struct {
-	Port int16
+	Host     string       // The host or IP address to listen on; empty listens on all interfaces
+	Port     int          // The TCP port to listen on; 0 chooses an available port
+	Listener net.Listener // If set, the server accepts connections from it, and Host and Port are ignored
+
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration // How long to wait for in-flight requests to complete when shutting down; zero waits until they do
+
+	CertFile string // If set with KeyFile, the server uses TLS with the certificate and key in the files
+	KeyFile  string
+
+	LivenessPath   string                          // Path of the liveness endpoint; empty disables it
+	ReadinessPath  string                          // Path of the readiness endpoint; empty disables it
+	ReadinessCheck func(ctx context.Context) error // If set, the server is only ready while it returns nil
+
+	Logger *slog.Logger // If nil, slog.Default() is used
+
+	invalid gomerr.Gomerr // set by an option function with an invalid value
 }
 
-// Serve starts the HTTP server
-func Serve(handler http.Handler, optFns ...func(*Options)) {
-	o := &Options{
-		Port: 8080,
+// DefaultOptions returns the Options that Serve starts with before applying its option functions.
+func DefaultOptions() Options {
+	return Options{
+		Host:              "localhost",
+		Port:              8080,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+		ShutdownTimeout:   30 * time.Second,
+		LivenessPath:      "/livez",
+		ReadinessPath:     "/readyz",
 	}
+}
 
+// Serve runs an HTTP server for the handler until the context is done or the process receives SIGTERM or SIGINT.
+// It then stops reporting ready, stops accepting connections, and waits up to ShutdownTimeout for in-flight requests
+// to complete. Serve returns nil if the server shut down cleanly, and otherwise the error that stopped it.
+func Serve(ctx context.Context, handler http.Handler, optFns ...func(*Options)) gomerr.Gomerr {
+	o := DefaultOptions()
 	for _, optFn := range optFns {
-		optFn(o)
+		optFn(&o)
+	}
+	if o.invalid != nil {
+		return o.invalid
+	}
+	if o.Port < 0 || o.Port > 65535 {
+		return gomerr.InvalidValue("Port", o.Port, "0-65535")
+	}
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return gomerr.Configuration("CertFile and KeyFile must be provided together")
+	}
+	if o.Logger == nil {
+		o.Logger = slog.Default()
 	}
 
-	// log.Info("Serving on: {}", o.Port)
-	println("serving on", "localhost:"+strconv.Itoa(int(o.Port)))
-	err := http.ListenAndServe("localhost:"+strconv.Itoa(int(o.Port)), handler)
+	listener := o.Listener
+	if listener == nil {
+		var err error
+		if listener, err = net.Listen("tcp", net.JoinHostPort(o.Host, strconv.Itoa(o.Port))); err != nil {
+			return gomerr.Configuration("unable to listen").AddAttributes("Host", o.Host, "Port", o.Port).Wrap(err)
+		}
+	}
 
-	var shutdownInfo string
-	if err != nil {
-		shutdownInfo = " due to: " + err.Error()
-	} else {
-		shutdownInfo = " cleanly"
+	var ready atomic.Bool
+	server := &http.Server{
+		Handler:           withHealthEndpoints(handler, &o, &ready),
+		ReadHeaderTimeout: o.ReadHeaderTimeout,
+		ReadTimeout:       o.ReadTimeout,
+		WriteTimeout:      o.WriteTimeout,
+		IdleTimeout:       o.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(o.Logger.Handler(), slog.LevelWarn),
+	}
+
+	stopped := make(chan error, 1)
+	go func() {
+		if o.CertFile != "" {
+			stopped <- server.ServeTLS(listener, o.CertFile, o.KeyFile)
+		} else {
+			stopped <- server.Serve(listener)
+		}
+	}()
+	ready.Store(true)
+	o.Logger.Info("serving", "address", listener.Addr().String(), "tls", o.CertFile != "")
+
+	signalCtx, stopSignals := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer stopSignals()
+
+	select {
+	case err := <-stopped:
+		return gomerr.Internal("server stopped unexpectedly").Wrap(err)
+	case <-signalCtx.Done():
+	}
+
+	ready.Store(false)
+	o.Logger.Info("shutting down", "cause", context.Cause(signalCtx).Error())
+
+	shutdownCtx := context.WithoutCancel(ctx)
+	if o.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, o.ShutdownTimeout)
+		defer cancel()
+	}
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		server.Close()
+		return gomerr.Internal("in-flight requests did not complete before the shutdown timeout").AddAttribute("ShutdownTimeout", o.ShutdownTimeout).Wrap(err)
+	}
+	<-stopped // http.ErrServerClosed
+
+	o.Logger.Info("server shut down cleanly")
+	return nil
+}
+
+// withHealthEndpoints routes requests for the liveness and readiness paths to handlers for them, and all others to the
+// handler. The server is live while it's running, and ready until it starts shutting down if the ReadinessCheck
+// passes.
+func withHealthEndpoints(handler http.Handler, o *Options, ready *atomic.Bool) http.Handler {
+	if o.LivenessPath == "" && o.ReadinessPath == "" {
+		return handler
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", handler)
+	if o.LivenessPath != "" {
+		mux.HandleFunc("GET "+o.LivenessPath, func(w http.ResponseWriter, _ *http.Request) {
+			healthResponse(w, http.StatusOK, "ok")
+		})
 	}
-	// log.Error("server shutdown{}", shutdownInfo)
-	println("server shutdown", shutdownInfo)
+	if o.ReadinessPath != "" {
+		mux.HandleFunc("GET "+o.ReadinessPath, func(w http.ResponseWriter, r *http.Request) {
+			if !ready.Load() {
+				healthResponse(w, http.StatusServiceUnavailable, "shutting down")
+			} else if o.ReadinessCheck != nil && o.ReadinessCheck(r.Context()) != nil {
+				healthResponse(w, http.StatusServiceUnavailable, "not ready")
+			} else {
+				healthResponse(w, http.StatusOK, "ok")
+			}
+		})
+	}
+
+	return mux
+}
+
+func healthResponse(w http.ResponseWriter, statusCode int, status string) {
+	w.Header().Set(ContentTypeHeader, "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	w.Write([]byte(status))
 }
 
 func noOptFn(*Options) {}
 
+// Port returns an option function that sets the port to the value of p (e.g. from the PORT environment variable). An
+// empty value leaves the port unchanged, and an invalid one causes Serve to fail.
 func Port(p string) func(*Options) {
-	i, err := strconv.ParseInt(p, 10, 16)
-	if err != nil {
-		if p != "" {
-			// log.Error()
-			println("invalid port value, ignoring:", p)
-		}
+	if p == "" {
 		return noOptFn
 	}
+
+	i, err := strconv.Atoi(p)
+	if err != nil || i < 0 || i > 65535 {
+		return func(o *Options) {
+			o.invalid = gomerr.InvalidValue("Port", p, "0-65535")
+		}
+	}
 	return func(o *Options) {
-		o.Port = int16(i)
+		o.Port = i
 	}
 }
 
@@ -166,24 +304,18 @@ func (rw *ResponseWriter) writeTo(w http.ResponseWriter) {
 	w.Write(rw.body)
 }
 
+// defaultErrorRenderer renders an error that no middleware has handled as problem details (see ProblemFor).
 func defaultErrorRenderer(w http.ResponseWriter, err error) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusInternalServerError)
-
-	// TODO: add flag to output details only if running in non-prod
-
-	if ge := gomerr.ErrorAs[gomerr.Gomerr](err); ge != nil {
-		println(ge.String())
-		w.Write([]byte(ge.Error()))
-		return
-	}
-
-	var m map[string]any
-	if me := json.Unmarshal([]byte(err.Error()), &m); me != nil {
-		escaped, _ := json.Marshal(err.Error())
-		w.Write([]byte("{\"error\": " + string(escaped) + "}"))
+	problem := ProblemFor(err)
+	if problem.Status >= 500 {
+		slog.Error("unhandled error", "status", problem.Status, "error", err)
 	} else {
-		out, _ := json.MarshalIndent(m, "", "  ")
-		w.Write(out)
+		slog.Debug("request failed", "status", problem.Status, "error", err)
 	}
+
+	body, _ := json.Marshal(problem)
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	w.Write(body)
 }
@@ -0,0 +1,127 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jt0/gomer/_test/assert"
+	. "github.com/jt0/gomer/api/http"
+	"github.com/jt0/gomer/constraint"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/limit"
+)
+
+func TestErrorStatusCode(t *testing.T) {
+	assert.Equals(t, StatusLimitExceeded, errorStatusCode(limit.UnquantifiedExcess("limiter", "limited")))
+	assert.Equals(t, StatusLimitExceeded, errorStatusCode(gomerr.Internal("wrapper").Wrap(limit.UnquantifiedExcess("limiter", "limited"))))
+	assert.Equals(t, http.StatusBadRequest, errorStatusCode(gomerr.MalformedValue("filter", "status eq")))
+	assert.Equals(t, http.StatusBadRequest, errorStatusCode(constraint.NotSatisfied("")))
+	assert.Equals(t, http.StatusNotFound, errorStatusCode(gomerr.NotFound("Order", "o1")))
+	assert.Equals(t, http.StatusConflict, errorStatusCode(gomerr.Conflict("Order", "o1", "shipped")))
+	assert.Equals(t, http.StatusUnprocessableEntity, errorStatusCode(gomerr.Unprocessable("no items", nil)))
+	assert.Equals(t, http.StatusBadGateway, errorStatusCode(gomerr.Dependency("payments", nil)))
+	assert.Equals(t, http.StatusInternalServerError, errorStatusCode(gomerr.Internal("internal")))
+}
+
+func TestServe(t *testing.T) {
+	t.Run("ShutdownTimeout", func(t *testing.T) {
+		serveAndShutDown(t, func(*Options) {})
+	})
+
+	// A zero ShutdownTimeout waits for in-flight requests rather than failing the shutdown immediately
+	t.Run("NoShutdownTimeout", func(t *testing.T) {
+		serveAndShutDown(t, func(o *Options) { o.ShutdownTimeout = 0 })
+	})
+}
+
+func serveAndShutDown(t *testing.T, option func(*Options)) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Success(t, err)
+	base := "http://" + listener.Addr().String()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan gomerr.Gomerr, 1)
+	go func() {
+		served <- Serve(ctx, handler, func(o *Options) {
+			o.Listener = listener
+			o.Logger = slog.New(slog.DiscardHandler)
+		}, option)
+	}()
+
+	for _, path := range []string{"/livez", "/readyz"} {
+		resp, err := http.Get(base + path)
+		assert.Success(t, err)
+		assert.Equals(t, http.StatusOK, resp.StatusCode, path)
+		resp.Body.Close()
+	}
+
+	// An in-flight request completes after shutdown begins
+	inFlight := make(chan *http.Response, 1)
+	go func() {
+		resp, _ := http.Get(base + "/slow")
+		inFlight <- resp
+	}()
+	<-started
+
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	resp := <-inFlight
+	assert.NotNil(t, resp)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equals(t, http.StatusOK, resp.StatusCode)
+	assert.Equals(t, "done", string(body))
+	assert.Success(t, <-served)
+}
+
+func TestReadiness(t *testing.T) {
+	var ready atomic.Bool
+	var notReady error
+	o := DefaultOptions()
+	o.ReadinessCheck = func(context.Context) error { return notReady }
+	handler := withHealthEndpoints(http.NotFoundHandler(), &o, &ready)
+
+	status := func() int {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, o.ReadinessPath, nil))
+		return rr.Code
+	}
+
+	assert.Equals(t, http.StatusServiceUnavailable, status()) // not yet started
+	ready.Store(true)
+	assert.Equals(t, http.StatusOK, status())
+	notReady = errors.New("database unavailable")
+	assert.Equals(t, http.StatusServiceUnavailable, status())
+}
+
+func TestServeOptions(t *testing.T) {
+	for _, p := range []string{"http", "-1", "65536"} {
+		assert.ErrorType(t, Serve(context.Background(), http.NotFoundHandler(), Port(p)), &gomerr.BadValueError{}, p)
+	}
+
+	tlsWithoutKey := func(o *Options) { o.CertFile = "cert.pem" }
+	assert.ErrorType(t, Serve(context.Background(), http.NotFoundHandler(), tlsWithoutKey), &gomerr.ConfigurationError{})
+
+	o := DefaultOptions()
+	Port("")(&o)
+	assert.Equals(t, 8080, o.Port)
+	Port("40000")(&o)
+	assert.Equals(t, 40000, o.Port) // beyond int16
+}
@@ -60,6 +60,12 @@ func (accessApplierProvider) DefaultDirective() string {
 }
 
 func (ap accessApplierProvider) Applier(_ reflect.Type, sf reflect.StructField, directive string, _ string) (structs.Applier, gomerr.Gomerr) {
+	// An embedded struct's fields are promoted and have their own permissions, so clearing the struct as a whole would
+	// discard values (including unexported state) that the principal may be entitled to.
+	if sf.Anonymous && directive == "" {
+		return nil, nil
+	}
+
 	perPrincipalPermissions := make([]map[string]string, 0)
 	for _, match := range accessRegexp.FindAllStringSubmatch(directive, -1) {
 		values := make(map[string]string)
@@ -151,9 +157,22 @@ type action interface {
 }
 
 func AddClearIfDeniedToContext(subject Subject, accessPermission AccessPermissions, tcs ...structs.ToolContext) structs.ToolContext {
-	// If no access principal, all permissions will be denied
-	accessPrincipal, _ := subject.Principal(fieldAccessPrincipal).(AccessPrincipal)
-	return structs.EnsureContext(tcs...).With(accessToolAction, remover{accessPrincipal, accessPermission})
+	return structs.EnsureContext(tcs...).With(accessToolAction, remover{subjectAccessPrincipal(subject), accessPermission})
+}
+
+// AddCollectDeniedToContext adds an action that, rather than changing any fields, adds the names of the ones that the
+// subject isn't granted the permission for to denied.
+func AddCollectDeniedToContext(subject Subject, accessPermission AccessPermissions, denied map[string]bool, tcs ...structs.ToolContext) structs.ToolContext {
+	return structs.EnsureContext(tcs...).With(accessToolAction, collector{subjectAccessPrincipal(subject), accessPermission, denied})
+}
+
+// subjectAccessPrincipal returns the subject's AccessPrincipal. If it has none, all permissions will be denied.
+func subjectAccessPrincipal(subject Subject) AccessPrincipal {
+	var accessPrincipal AccessPrincipal
+	if subject != nil {
+		accessPrincipal, _ = subject.Principal(fieldAccessPrincipal).(AccessPrincipal)
+	}
+	return accessPrincipal
 }
 
 type remover struct {
@@ -174,6 +193,19 @@ func (r remover) do(fv reflect.Value, aa accessApplier, _ structs.ToolContext) (
 	return nil
 }
 
+type collector struct {
+	principal  AccessPrincipal
+	permission AccessPermissions
+	denied     map[string]bool
+}
+
+func (c collector) do(_ reflect.Value, aa accessApplier, _ structs.ToolContext) gomerr.Gomerr {
+	if !aa.permissions.grants(c.principal, c.permission) {
+		c.denied[aa.fieldName] = true
+	}
+	return nil
+}
+
 func AddCopyProvidedToContext(fromStruct reflect.Value, tcs ...structs.ToolContext) structs.ToolContext {
 	return structs.EnsureContext(tcs...).With(accessToolAction, copyProvided(fromStruct))
 }
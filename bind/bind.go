@@ -82,6 +82,16 @@ func CamelCaseFields(c *Configuration) {
 	c.toCase = &CamelCaseFn
 }
 
+// FieldName returns the name of the data attribute for a struct field when the field's directive doesn't specify one.
+func (bc Configuration) FieldName(structFieldName string) string {
+	return (*bc.toCase)(structFieldName)
+}
+
+// OmitsEmpty returns true if empty values are omitted from output unless a field's directive specifies otherwise.
+func (bc Configuration) OmitsEmpty() bool {
+	return bc.emptyValue == omitEmpty
+}
+
 type ExtensionProvider interface {
 	structs.ApplierProvider
 	Type() string
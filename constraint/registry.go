@@ -107,6 +107,12 @@ func safeCheckBuilderOutput(bv reflect.Value) (ok bool) {
 	return bvt.NumOut() != 1 || !bvt.Out(0).AssignableTo(constraintType)
 }
 
+// Parse returns the constraint that the validation tool builds for a directive (e.g. "and(required,maxlen(64))") on
+// the field.
+func Parse(directive string, field reflect.StructField) (Constraint, gomerr.Gomerr) {
+	return constraintFor(directive, none, field)
+}
+
 func constraintFor(validationsString string, op logicOp /* passing field to support e.g. gte(0) */, field reflect.StructField) (Constraint, gomerr.Gomerr) {
 	var c Constraint
 	var ok bool
@@ -0,0 +1,160 @@
+package data
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Satisfies returns true if the item (a struct or a pointer to one) satisfies every condition. Stores use it for the
+// conditions they can't evaluate as part of reading the items.
+func Satisfies(item any, conditions []Condition) bool {
+	v := reflect.Indirect(reflect.ValueOf(item))
+	for _, c := range conditions {
+		if !c.SatisfiedBy(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// SatisfiedBy returns true if the struct value v has a field satisfying the condition. A condition on a nil pointer
+// field isn't satisfied, nor is one whose value can't be compared with the field's.
+func (c Condition) SatisfiedBy(v reflect.Value) bool {
+	fv := v.FieldByName(c.Field)
+	if !fv.IsValid() || len(c.Values) == 0 {
+		return false
+	}
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return false
+		}
+		fv = fv.Elem()
+	}
+
+	switch c.Type {
+	case CONTAINS:
+		return contains(fv, c.Values[0])
+	case PREFIX:
+		return strings.HasPrefix(formatValue(fv), fmt.Sprint(c.Values[0]))
+	}
+
+	result, ok := compare(fv, c.Values[0])
+	if !ok {
+		return false
+	}
+
+	switch c.Type {
+	case EQ:
+		return result == 0
+	case NEQ:
+		return result != 0
+	case GTE:
+		return result >= 0
+	case GT:
+		return result > 0
+	case LTE:
+		return result <= 0
+	case LT:
+		return result < 0
+	case BETWEEN:
+		if len(c.Values) < 2 {
+			return false
+		}
+		upper, ok := compare(fv, c.Values[1])
+		return result >= 0 && ok && upper <= 0
+	}
+	return false
+}
+
+// compare returns -1, 0, or 1 as v is less than, equal to, or greater than value, and false if they can't be compared.
+// Numbers compare by value regardless of their types, times chronologically, and strings lexically.
+func compare(v reflect.Value, value any) (int, bool) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return 0, false
+		}
+		v = v.Elem()
+	}
+
+	cv := reflect.ValueOf(value)
+	if cv.Kind() == reflect.Ptr {
+		if cv.IsNil() {
+			return 0, false
+		}
+		cv = cv.Elem()
+	}
+	if !cv.IsValid() {
+		return 0, false
+	}
+
+	if t, ok := v.Interface().(time.Time); ok {
+		ct, ok := cv.Interface().(time.Time)
+		if !ok {
+			return 0, false
+		}
+		return t.Compare(ct), true
+	}
+
+	switch {
+	case v.Kind() == reflect.String && cv.Kind() == reflect.String:
+		return strings.Compare(v.String(), cv.String()), true
+	case v.CanInt() && cv.CanInt():
+		return cmp.Compare(v.Int(), cv.Int()), true
+	case v.CanUint() && cv.CanUint():
+		return cmp.Compare(v.Uint(), cv.Uint()), true
+	}
+
+	f, ok := toFloat(v)
+	if !ok {
+		return 0, false
+	}
+	cf, ok := toFloat(cv)
+	if !ok {
+		return 0, false
+	}
+	return cmp.Compare(f, cf), true
+}
+
+func toFloat(v reflect.Value) (float64, bool) {
+	switch {
+	case v.CanInt():
+		return float64(v.Int()), true
+	case v.CanUint():
+		return float64(v.Uint()), true
+	case v.CanFloat():
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// contains returns true if v is a string with value as a substring, or a slice, array, or set (i.e. a map with
+// struct{} or bool values) with value as an element.
+func contains(v reflect.Value, value any) bool {
+	switch v.Kind() {
+	case reflect.String:
+		return strings.Contains(v.String(), fmt.Sprint(value))
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if result, ok := compare(v.Index(i), value); ok && result == 0 {
+				return true
+			}
+		}
+	case reflect.Map:
+		cv := reflect.ValueOf(value)
+		if cv.IsValid() && cv.Type().AssignableTo(v.Type().Key()) {
+			return v.MapIndex(cv).IsValid()
+		}
+	}
+	return false
+}
+
+// formatValue returns v as it appears in a key: times in RFC 3339 format and other values in their default format.
+func formatValue(v reflect.Value) string {
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v.Interface())
+}
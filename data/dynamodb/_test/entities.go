@@ -76,6 +76,52 @@ type StaticKeyEntities struct {
 func (q *StaticKeyEntities) TypeName() string  { return "StaticKeyEntity" }
 func (q *StaticKeyEntities) ItemTemplate() any { return q }
 
+// VersionedEntity - demonstrates optimistic concurrency using a version field
+type VersionedEntity struct {
+	Id      string `db.keys:"pk,sk='VERSIONED'"`
+	Data    string
+	Version int64 `db.version:"+"`
+}
+
+func (v *VersionedEntity) TypeName() string             { return "VersionedEntity" }
+func (v *VersionedEntity) NewQueryable() data.Queryable { return &VersionedEntities{} }
+
+type VersionedEntities struct {
+	data.BaseQueryable
+	Id string
+}
+
+func (q *VersionedEntities) TypeName() string  { return "VersionedEntity" }
+func (q *VersionedEntities) ItemTemplate() any { return q }
+
+// Session - demonstrates an item that expires at a given time
+type Session struct {
+	Id        string `db.keys:"pk,sk='SESSION'"`
+	UserId    string
+	ExpiresAt time.Time `db.ttl:""`
+}
+
+func (s *Session) TypeName() string             { return "Session" }
+func (s *Session) NewQueryable() data.Queryable { return &Sessions{} }
+
+type Sessions struct {
+	data.BaseQueryable
+	Id string
+}
+
+func (q *Sessions) TypeName() string  { return "Session" }
+func (q *Sessions) ItemTemplate() any { return q }
+
+// IdempotencyRecord - demonstrates an item that expires some time after it's written
+type IdempotencyRecord struct {
+	Token    string `db.keys:"pk,sk='IDEMPOTENCY'"`
+	Response string
+	Lifetime time.Duration `db.ttl:"ExpiresAt"`
+}
+
+func (r *IdempotencyRecord) TypeName() string             { return "IdempotencyRecord" }
+func (r *IdempotencyRecord) NewQueryable() data.Queryable { return nil }
+
 // User - concrete domain entity for multi-tenant service
 // Use cases: read by id, list by tenant, lookup by email
 type User struct {
@@ -167,6 +213,24 @@ type NumericKeyEntities struct {
 func (q *NumericKeyEntities) TypeName() string  { return "NumericKeyEntity" }
 func (q *NumericKeyEntities) ItemTemplate() any { return q }
 
+// Player - demonstrates a numeric field in a string sort key, whose values sort lexicographically
+type Player struct {
+	TenantId string `db.keys:"pk.0,pk.1='PLAYER'"`
+	PlayerId string `db.keys:"sk"`
+	Points   int    `db.keys:"lsi_2:sk"`
+}
+
+func (p *Player) TypeName() string             { return "Player" }
+func (p *Player) NewQueryable() data.Queryable { return &Players{} }
+
+type Players struct {
+	data.BaseQueryable
+	TenantId string
+}
+
+func (q *Players) TypeName() string  { return "Player" }
+func (q *Players) ItemTemplate() any { return q }
+
 // EscapedValueEntity - demonstrates separator escaping in keys
 // Separator '#' becomes '$#', escape char '$' becomes '$$'
 type EscapedValueEntity struct {
@@ -11,8 +11,21 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	ddb "github.com/jt0/gomer/data/dynamodb"
+	"github.com/jt0/gomer/data/dynamodb/fake"
 )
 
+// Client is the DynamoDB API used by the tests: the store's operations plus those to manage tables.
+type Client interface {
+	ddb.Client
+	CreateTable(ctx context.Context, input *dynamodb.CreateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error)
+	DeleteTable(ctx context.Context, input *dynamodb.DeleteTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteTableOutput, error)
+}
+
+// fakeClient is shared by the tests, like a DynamoDB Local instance would be.
+var fakeClient = fake.New()
+
 type TableDefinition struct {
 	TableName            string
 	AttributeDefinitions []types.AttributeDefinition
@@ -64,9 +77,9 @@ func (t *TableDefinition) WithGsi(indexName string, keySchema []types.KeySchemaE
 	return t
 }
 
-func (t *TableDefinition) Create(ddb *dynamodb.Client) {
+func (t *TableDefinition) Create(client Client) {
 	// check for table's existence
-	if _, err := ddb.DescribeTable(context.Background(), &dynamodb.DescribeTableInput{
+	if _, err := client.DescribeTable(context.Background(), &dynamodb.DescribeTableInput{
 		TableName: &t.TableName,
 	}); err != nil {
 		var rnf *types.ResourceNotFoundException
@@ -80,7 +93,7 @@ func (t *TableDefinition) Create(ddb *dynamodb.Client) {
 		return
 	}
 
-	if _, err := ddb.CreateTable(context.Background(), &dynamodb.CreateTableInput{
+	if _, err := client.CreateTable(context.Background(), &dynamodb.CreateTableInput{
 		TableName:              &t.TableName,
 		AttributeDefinitions:   t.AttributeDefinitions,
 		KeySchema:              t.KeySchema,
@@ -99,22 +112,17 @@ func Ptr[T any](t T) *T {
 	return &t
 }
 
-// NewClient creates a DynamoDB client configured for either local or AWS usage.
-// If DDB_LOCAL environment variable is set, uses local DynamoDB:
+// NewClient creates a DynamoDB client for the tests. If DDB_LOCAL environment variable is set, uses local DynamoDB:
 //   - Empty string or "true": uses default port 7001
 //   - Specific port number: uses that port (e.g., "8000")
 //
-// If DDB_LOCAL is not set, uses default AWS configuration.
-func NewClient() (*dynamodb.Client, bool, error) {
+// If DDB_LOCAL is not set, uses the in-memory fake client. Either way the returned bool is true, as the client doesn't
+// reach AWS.
+func NewClient() (Client, bool, error) {
 	port, useDdbLocal := os.LookupEnv("DDB_LOCAL")
 
 	if !useDdbLocal {
-		// Use AWS configuration
-		cfg, err := config.LoadDefaultConfig(context.Background())
-		if err != nil {
-			return nil, false, err
-		}
-		return dynamodb.NewFromConfig(cfg), false, nil
+		return fakeClient, true, nil
 	}
 
 	// Use local DynamoDB
@@ -139,8 +147,8 @@ func NewClient() (*dynamodb.Client, bool, error) {
 }
 
 // DeleteAllTableData deletes all items from a table
-func DeleteAllTableData(ddb *dynamodb.Client, tableName string) error {
-	scanOutput, err := ddb.Scan(context.Background(), &dynamodb.ScanInput{
+func DeleteAllTableData(client Client, tableName string) error {
+	scanOutput, err := client.Scan(context.Background(), &dynamodb.ScanInput{
 		TableName: &tableName,
 	})
 	if err != nil {
@@ -148,7 +156,7 @@ func DeleteAllTableData(ddb *dynamodb.Client, tableName string) error {
 	}
 
 	for _, item := range scanOutput.Items {
-		_, err = ddb.DeleteItem(context.Background(), &dynamodb.DeleteItemInput{
+		_, err = client.DeleteItem(context.Background(), &dynamodb.DeleteItemInput{
 			TableName: &tableName,
 			Key: map[string]types.AttributeValue{
 				"PK": item["PK"],
@@ -164,8 +172,8 @@ func DeleteAllTableData(ddb *dynamodb.Client, tableName string) error {
 }
 
 // DeleteTable deletes the overall table
-func DeleteTable(ddb *dynamodb.Client, tableName string) error {
-	_, err := ddb.DeleteTable(context.Background(), &dynamodb.DeleteTableInput{
+func DeleteTable(client Client, tableName string) error {
+	_, err := client.DeleteTable(context.Background(), &dynamodb.DeleteTableInput{
 		TableName: &tableName,
 	})
 	return err
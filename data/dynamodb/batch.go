@@ -0,0 +1,410 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/data/dataerr"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/limit"
+	"github.com/jt0/gomer/structs"
+)
+
+const (
+	batchWriteMaxItems = 25  // BatchWriteItem limit
+	batchGetMaxKeys    = 100 // BatchGetItem limit
+
+	// Unprocessed items are retried with exponential backoff up to batchMaxAttempts times before being failed. Failed
+	// requests are instead retried per the store's RetryPolicy.
+	batchMaxAttempts = 8
+	batchBaseDelay   = 50 * time.Millisecond
+	batchMaxDelay    = 2 * time.Second
+)
+
+// batchItem is a persistable's request within a batch. The index is the persistable's position in the caller's slice
+// and is used to report per-item errors.
+type batchItem struct {
+	index int
+	key   map[string]types.AttributeValue
+	write types.WriteRequest
+}
+
+// BatchCreate writes the persistables using BatchWriteItem. A batch write can't be conditioned, so unlike Create an
+// existing item with the same key is replaced rather than causing an error. Unique constraints are checked for each
+// persistable as they are by Create, though not between the persistables in the batch. Persistables of types that
+// enforce uniqueness with SentinelUniqueness are created individually so their sentinels are claimed transactionally.
+func (t *table) BatchCreate(ctx context.Context, ps []data.Persistable) (ge gomerr.Gomerr) {
+	defer t.recordOperation("BatchCreate", batchTypeName(ps), time.Now(), &ge)
+
+	return batchErrors("BatchCreate", ps, t.batchCreate(ctx, ps))
+}
+
+// batchCreate creates the persistables as BatchCreate does and returns the error, if any, for each of them.
+func (t *table) batchCreate(ctx context.Context, ps []data.Persistable) []gomerr.Gomerr {
+	errs := make([]gomerr.Gomerr, len(ps))
+	items := make([]batchItem, 0, len(ps))
+	keys := make(map[string]bool, len(ps))
+	versions := make(map[int]uint64) // the prior versions of batched versioned persistables
+	for i, p := range ps {
+		pt, ge := t.batchPersistableType(p)
+		if ge != nil {
+			errs[i] = ge
+			continue
+		}
+
+		if t.usesSentinels(pt) {
+			errs[i] = t.put(ctx, p, true, true)
+			continue
+		}
+
+		if t.uniqueness == QueryUniqueness {
+			if errs[i] = structs.ApplyTools(p, structs.EnsureContext().With("ctx", ctx), t.constraintTool); errs[i] != nil {
+				continue
+			}
+		}
+
+		key := make(map[string]types.AttributeValue, 2)
+		if errs[i] = t.populateKeyValues(key, p, t.valueSeparatorChar, true); errs[i] != nil {
+			continue
+		}
+
+		// BatchWriteItem rejects requests that write the same item more than once
+		if id := t.keyId(key); keys[id] {
+			errs[i] = gomerr.Conflict(p.TypeName(), "", "duplicate_key_in_batch").AddAttribute("key", key)
+			continue
+		} else {
+			keys[id] = true
+		}
+
+		pv := reflect.ValueOf(p).Elem()
+		if version, versioned := pt.versionField.Version(pv); versioned {
+			versions[i] = version
+			pt.versionField.SetVersion(pv, 1)
+		}
+
+		av, ge := t.marshalItem(p, pt)
+		if ge != nil {
+			errs[i] = ge
+			continue
+		}
+
+		items = append(items, batchItem{index: i, key: key, write: types.WriteRequest{PutRequest: &types.PutRequest{Item: av}}})
+	}
+
+	t.batchWrite(ctx, batchTypeName(ps), items, errs)
+
+	// Like Create, a failed write leaves the version as it was
+	for i, version := range versions {
+		if errs[i] != nil {
+			t.persistableTypes[ps[i].TypeName()].versionField.SetVersion(reflect.ValueOf(ps[i]).Elem(), version)
+		}
+	}
+
+	return errs
+}
+
+// BatchRead reads the persistables using BatchGetItem. Reads are consistent if any persistable's consistency type calls
+// for it. Persistables whose keys are only partially set (and so must be found by a query) are read individually.
+func (t *table) BatchRead(ctx context.Context, ps []data.Persistable) (ge gomerr.Gomerr) {
+	defer t.recordOperation("BatchRead", batchTypeName(ps), time.Now(), &ge)
+
+	errs := make([]gomerr.Gomerr, len(ps))
+	keys := make([]map[string]types.AttributeValue, 0, len(ps))
+	indexesByKey := make(map[string][]int, len(ps)) // the same item may be requested more than once
+	consistent := false
+	for i, p := range ps {
+		if _, errs[i] = t.batchPersistableType(p); errs[i] != nil {
+			continue
+		}
+
+		key := make(map[string]types.AttributeValue, 2)
+		if errs[i] = t.populateKeyValues(key, p, t.valueSeparatorChar, true); errs[i] != nil {
+			continue
+		}
+
+		if t.partialKey(p.TypeName(), key) {
+			errs[i] = t.Read(ctx, p)
+			continue
+		}
+
+		id := t.keyId(key)
+		if _, ok := indexesByKey[id]; !ok {
+			keys = append(keys, key)
+		}
+		indexesByKey[id] = append(indexesByKey[id], i)
+
+		if cr := consistentRead(t.consistencyType(p), true); cr != nil && *cr {
+			consistent = true
+		}
+	}
+
+	for start := 0; start < len(keys); start += batchGetMaxKeys {
+		chunk := keys[start:min(start+batchGetMaxKeys, len(keys))]
+		found, ge := t.batchGet(ctx, batchTypeName(ps), chunk, consistent)
+
+		foundIds := make(map[string]bool, len(found))
+		now := time.Now()
+		for _, item := range found {
+			id := t.keyId(item)
+			for _, i := range indexesByKey[id] {
+				if t.persistableTypes[ps[i].TypeName()].expired(item, now) {
+					continue
+				}
+				foundIds[id] = true
+				errs[i] = t.unmarshalItem(ps[i], item)
+			}
+		}
+
+		for _, key := range chunk {
+			if id := t.keyId(key); !foundIds[id] {
+				for _, i := range indexesByKey[id] {
+					if ge != nil {
+						errs[i] = ge
+					} else {
+						errs[i] = dataerr.PersistableNotFound(ps[i].TypeName(), key)
+					}
+				}
+			}
+		}
+	}
+
+	for i, p := range ps {
+		if errs[i] != nil {
+			continue
+		}
+		for _, nested := range t.prepareNestedQueryables(p) {
+			if errs[i] = t.querySingleType(ctx, nested.queryable); errs[i] != nil {
+				break
+			}
+		}
+	}
+
+	return batchErrors("BatchRead", ps, errs)
+}
+
+// BatchDelete deletes the persistables using BatchWriteItem. A batch write can't be conditioned, so the table's
+// FailDeleteIfNotPresent setting doesn't apply. Persistables of types that enforce uniqueness with SentinelUniqueness
+// are deleted individually so their sentinels are released transactionally.
+func (t *table) BatchDelete(ctx context.Context, ps []data.Persistable) (ge gomerr.Gomerr) {
+	defer t.recordOperation("BatchDelete", batchTypeName(ps), time.Now(), &ge)
+
+	errs := make([]gomerr.Gomerr, len(ps))
+	items := make([]batchItem, 0, len(ps))
+	keys := make(map[string]bool, len(ps))
+	for i, p := range ps {
+		pt, ge := t.batchPersistableType(p)
+		if ge != nil {
+			errs[i] = ge
+			continue
+		}
+
+		if t.usesSentinels(pt) {
+			errs[i] = t.Delete(ctx, p)
+			continue
+		}
+
+		key := make(map[string]types.AttributeValue, 2)
+		if errs[i] = t.populateKeyValues(key, p, t.valueSeparatorChar, true); errs[i] != nil {
+			continue
+		}
+
+		// Deletes are idempotent, so repeats can be dropped (BatchWriteItem would otherwise reject the request)
+		if id := t.keyId(key); keys[id] {
+			continue
+		} else {
+			keys[id] = true
+		}
+
+		items = append(items, batchItem{index: i, key: key, write: types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: key}}})
+	}
+
+	t.batchWrite(ctx, batchTypeName(ps), items, errs)
+
+	return batchErrors("BatchDelete", ps, errs)
+}
+
+// batchTypeName returns the type of the persistables, or "" if they're of several types.
+func batchTypeName(ps []data.Persistable) string {
+	if len(ps) == 0 {
+		return ""
+	}
+
+	typeName := ps[0].TypeName()
+	for _, p := range ps[1:] {
+		if p.TypeName() != typeName {
+			return ""
+		}
+	}
+	return typeName
+}
+
+func (t *table) batchPersistableType(p data.Persistable) (*persistableType, gomerr.Gomerr) {
+	pt, ok := t.persistableTypes[p.TypeName()]
+	if !ok {
+		return nil, gomerr.Configuration("no persistable type for " + p.TypeName())
+	}
+	return pt, nil
+}
+
+// batchWrite writes the items in chunks of batchWriteMaxItems, retrying unprocessed items. Failures are recorded in
+// errs at each item's index.
+func (t *table) batchWrite(ctx context.Context, typeName string, items []batchItem, errs []gomerr.Gomerr) {
+	for start := 0; start < len(items); start += batchWriteMaxItems {
+		pending := items[start:min(start+batchWriteMaxItems, len(items))]
+
+		for attempt := 1; len(pending) > 0; attempt++ {
+			requests := make([]types.WriteRequest, len(pending))
+			for i, item := range pending {
+				requests[i] = item.write
+			}
+
+			input := &dynamodb.BatchWriteItemInput{
+				RequestItems:           map[string][]types.WriteRequest{*t.tableName: requests},
+				ReturnConsumedCapacity: t.returnConsumedCapacity(),
+			}
+			output, err := t.ddb.BatchWriteItem(ctx, input)
+			if err != nil {
+				ge := t.writeError(err, input)
+				for _, item := range pending {
+					errs[item.index] = ge
+				}
+				break
+			}
+
+			t.recordCapacity(typeName, true, ptrs(output.ConsumedCapacity)...)
+			pending = unprocessedWrites(pending, output.UnprocessedItems[*t.tableName], t)
+			if len(pending) == 0 {
+				break
+			}
+
+			if attempt == batchMaxAttempts || !t.batchWait(ctx, attempt) {
+				ge := throughputExceeded(nil)
+				for _, item := range pending {
+					errs[item.index] = ge
+				}
+				break
+			}
+		}
+	}
+}
+
+// unprocessedWrites returns the pending items whose requests are in unprocessed.
+func unprocessedWrites(pending []batchItem, unprocessed []types.WriteRequest, t *table) []batchItem {
+	if len(unprocessed) == 0 {
+		return nil
+	}
+
+	ids := make(map[string]bool, len(unprocessed))
+	for _, request := range unprocessed {
+		if request.PutRequest != nil {
+			ids[t.keyId(request.PutRequest.Item)] = true
+		} else if request.DeleteRequest != nil {
+			ids[t.keyId(request.DeleteRequest.Key)] = true
+		}
+	}
+
+	remaining := make([]batchItem, 0, len(unprocessed))
+	for _, item := range pending {
+		if ids[t.keyId(item.key)] {
+			remaining = append(remaining, item)
+		}
+	}
+
+	return remaining
+}
+
+// batchGet gets the items for the keys, retrying unprocessed keys.
+func (t *table) batchGet(ctx context.Context, typeName string, keys []map[string]types.AttributeValue, consistent bool) ([]map[string]types.AttributeValue, gomerr.Gomerr) {
+	var found []map[string]types.AttributeValue
+	for attempt := 1; len(keys) > 0; attempt++ {
+		input := &dynamodb.BatchGetItemInput{
+			RequestItems:           map[string]types.KeysAndAttributes{*t.tableName: {Keys: keys, ConsistentRead: &consistent}},
+			ReturnConsumedCapacity: t.returnConsumedCapacity(),
+		}
+		output, err := t.ddb.BatchGetItem(ctx, input)
+		if err != nil {
+			return found, t.writeError(err, input)
+		}
+
+		t.recordCapacity(typeName, false, ptrs(output.ConsumedCapacity)...)
+		found = append(found, output.Responses[*t.tableName]...)
+		keys = output.UnprocessedKeys[*t.tableName].Keys
+		if len(keys) == 0 {
+			break
+		}
+
+		if attempt == batchMaxAttempts || !t.batchWait(ctx, attempt) {
+			return found, throughputExceeded(nil)
+		}
+	}
+
+	return found, nil
+}
+
+func throttled(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "RequestLimitExceeded", "ProvisionedThroughputExceededException", "ThrottlingException":
+			return true
+		}
+	}
+	return false
+}
+
+// throughputExceeded returns the error for items still unprocessed after retrying. The err is the last throttling
+// error, if any.
+func throughputExceeded(err error) gomerr.Gomerr {
+	ge := limit.UnquantifiedExcess("DynamoDB", "throughput")
+	if err != nil {
+		return ge.Wrap(err)
+	}
+	return ge
+}
+
+// batchWait sleeps before retrying unprocessed items. Returns false if the context is done first or its deadline is
+// too near.
+func (t *table) batchWait(ctx context.Context, attempt int) bool {
+	policy := RetryPolicy{BaseDelay: batchBaseDelay, MaxDelay: batchMaxDelay, DeadlineMargin: t.retryPolicy.DeadlineMargin}
+	return policy.wait(ctx, attempt)
+}
+
+// keyId returns a string that identifies the item with the given table key attributes.
+func (t *table) keyId(item map[string]types.AttributeValue) string {
+	id := keyAttributeString(item[t.pk.name])
+	if t.sk != nil {
+		id += "\x00" + keyAttributeString(item[t.sk.name])
+	}
+	return id
+}
+
+func keyAttributeString(av types.AttributeValue) string {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return "S" + v.Value
+	case *types.AttributeValueMemberN:
+		return "N" + v.Value
+	case *types.AttributeValueMemberB:
+		return "B" + string(v.Value)
+	default:
+		return ""
+	}
+}
+
+// batchErrors returns the per-item errors, each identifying the persistable and its index in the batch.
+func batchErrors(operation string, ps []data.Persistable, errs []gomerr.Gomerr) gomerr.Gomerr {
+	var failed []gomerr.Gomerr
+	for i, ge := range errs {
+		if ge != nil {
+			failed = append(failed, dataerr.Store(operation, ps[i]).AddAttribute("index", i).Wrap(ge))
+		}
+	}
+	return gomerr.Batcher(failed)
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"testing"
 
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
 	"github.com/jt0/gomer/_test/assert"
@@ -58,7 +57,7 @@ func (q *Accounts) ItemTemplate() any { return q }
 
 // Test setup
 
-func setupStore(t *testing.T, persistables ...data.Persistable) (data.Store, *dynamodb.Client) {
+func setupStore(t *testing.T, persistables ...data.Persistable) (data.Store, ddbtest.Client) {
 	client, isLocal, err := ddbtest.NewClient()
 	assert.Success(t, err)
 
@@ -96,7 +95,7 @@ func setupStore(t *testing.T, persistables ...data.Persistable) (data.Store, *dy
 	return store, client
 }
 
-func cleanupTable(t *testing.T, client *dynamodb.Client) {
+func cleanupTable(t *testing.T, client ddbtest.Client) {
 	err := ddbtest.DeleteAllTableData(client, testTableName)
 	assert.Success(t, err)
 }
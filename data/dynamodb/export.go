@@ -0,0 +1,256 @@
+package dynamodb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/data/dataerr"
+	"github.com/jt0/gomer/gomerr"
+)
+
+// Export writes the persistables of prototype's type in the store to w as newline-delimited JSON, one per line. Each
+// is written as an object of its stored attributes, named as they are with `db.name`, and with values in DynamoDB's
+// JSON format (e.g. {"Name":{"S":"x"},"Count":{"N":"3"}}). Key fields are written as attributes of their own rather
+// than as the composite key attributes of the table and its indexes, so the persistables can be imported into a table
+// with different keys. The table is scanned in totalSegments parallel segments, so the persistables aren't written in
+// any particular order.
+func Export(ctx context.Context, store data.Store, w io.Writer, prototype data.Persistable, totalSegments int) gomerr.Gomerr {
+	t, pt, ge := exportTable(store, prototype)
+	if ge != nil {
+		return ge
+	}
+
+	var mu sync.Mutex
+	return data.ScanAll(ctx, t, prototype, data.Segments(totalSegments), func(ps []data.Persistable, _ data.ScanSegment) gomerr.Gomerr {
+		var lines bytes.Buffer
+		for _, p := range ps {
+			av, err := attributevalue.MarshalMap(p)
+			if err != nil {
+				return dataerr.Store("Export", p).Wrap(gomerr.Marshal(p.TypeName(), p).Wrap(err))
+			}
+			pt.convertFieldNamesToDbNames(&av)
+
+			line, err := json.Marshal(itemJson(av))
+			if err != nil {
+				return dataerr.Store("Export", p).Wrap(gomerr.Marshal(p.TypeName(), av).Wrap(err))
+			}
+			lines.Write(line)
+			lines.WriteByte('\n')
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if _, err := w.Write(lines.Bytes()); err != nil {
+			return gomerr.Dependency("io.Writer", nil).Wrap(err)
+		}
+		return nil
+	})
+}
+
+// Import reads persistables of prototype's type from r in the format written by Export and writes them to the store
+// with BatchCreate. As with BatchCreate, an existing item with the same key is replaced, so an interrupted import can
+// be repeated. A line that can't be imported doesn't prevent the others from being imported; the failures are returned
+// together in a gomerr.BatchError, each with the number of its line.
+func Import(ctx context.Context, store data.Store, r io.Reader, prototype data.Persistable) gomerr.Gomerr {
+	t, pt, ge := exportTable(store, prototype)
+	if ge != nil {
+		return ge
+	}
+
+	var failed []gomerr.Gomerr
+	ps := make([]data.Persistable, 0, batchWriteMaxItems)
+	lines := make([]int, 0, batchWriteMaxItems)
+	flush := func() {
+		for i, ge := range t.batchCreate(ctx, ps) {
+			if ge != nil {
+				failed = append(failed, dataerr.Store("Import", ps[i]).AddAttribute("line", lines[i]).Wrap(ge))
+			}
+		}
+		ps, lines = ps[:0], lines[:0]
+	}
+
+	reader := bufio.NewReader(r)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			failed = append(failed, gomerr.Dependency("io.Reader", nil).AddAttribute("line", lineNumber).Wrap(err))
+			break
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if p, ge := importLine(line, pt); ge != nil {
+				failed = append(failed, dataerr.Store("Import", prototype).AddAttribute("line", lineNumber).Wrap(ge))
+			} else {
+				ps = append(ps, p)
+				lines = append(lines, lineNumber)
+			}
+		}
+
+		if len(ps) == batchWriteMaxItems || (err != nil && len(ps) > 0) {
+			flush()
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	return gomerr.Batcher(failed)
+}
+
+func exportTable(store data.Store, prototype data.Persistable) (*table, *persistableType, gomerr.Gomerr) {
+	t, ok := store.(*table)
+	if !ok {
+		return nil, nil, gomerr.Configuration("not a DynamoDB store").AddAttribute("store", reflect.TypeOf(store).String())
+	}
+
+	pt, ge := t.batchPersistableType(prototype)
+	if ge != nil {
+		return nil, nil, ge
+	}
+
+	return t, pt, nil
+}
+
+// importLine returns the persistable from one line of an export.
+func importLine(line []byte, pt *persistableType) (data.Persistable, gomerr.Gomerr) {
+	var attributes map[string]json.RawMessage
+	if err := json.Unmarshal(line, &attributes); err != nil {
+		return nil, gomerr.Unmarshal(pt.name, string(line), attributes).Wrap(err)
+	}
+
+	av := make(map[string]types.AttributeValue, len(attributes))
+	for name, value := range attributes {
+		v, err := attributeValueFromJson(value)
+		if err != nil {
+			return nil, gomerr.Unmarshal(name, string(value), v).Wrap(err)
+		}
+		av[pt.dbNameToFieldName(name)] = v
+	}
+
+	resolved, ge := pt.resolver(av)
+	if ge != nil {
+		return nil, ge
+	}
+
+	return resolved.(data.Persistable), nil
+}
+
+// itemJson returns the item's attribute values in DynamoDB's JSON format.
+func itemJson(item map[string]types.AttributeValue) map[string]any {
+	m := make(map[string]any, len(item))
+	for name, av := range item {
+		m[name] = attributeValueJson(av)
+	}
+	return m
+}
+
+func attributeValueJson(av types.AttributeValue) map[string]any {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return map[string]any{"S": v.Value}
+	case *types.AttributeValueMemberN:
+		return map[string]any{"N": v.Value}
+	case *types.AttributeValueMemberB:
+		return map[string]any{"B": v.Value}
+	case *types.AttributeValueMemberBOOL:
+		return map[string]any{"BOOL": v.Value}
+	case *types.AttributeValueMemberNULL:
+		return map[string]any{"NULL": v.Value}
+	case *types.AttributeValueMemberSS:
+		return map[string]any{"SS": v.Value}
+	case *types.AttributeValueMemberNS:
+		return map[string]any{"NS": v.Value}
+	case *types.AttributeValueMemberBS:
+		return map[string]any{"BS": v.Value}
+	case *types.AttributeValueMemberL:
+		l := make([]any, len(v.Value))
+		for i, element := range v.Value {
+			l[i] = attributeValueJson(element)
+		}
+		return map[string]any{"L": l}
+	case *types.AttributeValueMemberM:
+		return map[string]any{"M": itemJson(v.Value)}
+	default:
+		return nil
+	}
+}
+
+func attributeValueFromJson(raw json.RawMessage) (types.AttributeValue, error) {
+	var typed map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &typed); err != nil {
+		return nil, err
+	}
+	if len(typed) != 1 {
+		return nil, fmt.Errorf("expected a single type descriptor, found %d", len(typed))
+	}
+
+	for descriptor, value := range typed {
+		switch descriptor {
+		case "S":
+			v := &types.AttributeValueMemberS{}
+			return v, json.Unmarshal(value, &v.Value)
+		case "N":
+			v := &types.AttributeValueMemberN{}
+			return v, json.Unmarshal(value, &v.Value)
+		case "B":
+			v := &types.AttributeValueMemberB{}
+			return v, json.Unmarshal(value, &v.Value)
+		case "BOOL":
+			v := &types.AttributeValueMemberBOOL{}
+			return v, json.Unmarshal(value, &v.Value)
+		case "NULL":
+			v := &types.AttributeValueMemberNULL{}
+			return v, json.Unmarshal(value, &v.Value)
+		case "SS":
+			v := &types.AttributeValueMemberSS{}
+			return v, json.Unmarshal(value, &v.Value)
+		case "NS":
+			v := &types.AttributeValueMemberNS{}
+			return v, json.Unmarshal(value, &v.Value)
+		case "BS":
+			v := &types.AttributeValueMemberBS{}
+			return v, json.Unmarshal(value, &v.Value)
+		case "L":
+			var elements []json.RawMessage
+			if err := json.Unmarshal(value, &elements); err != nil {
+				return nil, err
+			}
+			v := &types.AttributeValueMemberL{Value: make([]types.AttributeValue, len(elements))}
+			for i, element := range elements {
+				var err error
+				if v.Value[i], err = attributeValueFromJson(element); err != nil {
+					return nil, err
+				}
+			}
+			return v, nil
+		case "M":
+			var attributes map[string]json.RawMessage
+			if err := json.Unmarshal(value, &attributes); err != nil {
+				return nil, err
+			}
+			v := &types.AttributeValueMemberM{Value: make(map[string]types.AttributeValue, len(attributes))}
+			for name, attribute := range attributes {
+				var err error
+				if v.Value[name], err = attributeValueFromJson(attribute); err != nil {
+					return nil, err
+				}
+			}
+			return v, nil
+		default:
+			return nil, fmt.Errorf("unknown type descriptor: %s", descriptor)
+		}
+	}
+
+	return nil, nil
+}
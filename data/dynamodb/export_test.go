@@ -0,0 +1,80 @@
+package dynamodb_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jt0/gomer/_test/assert"
+	ddb "github.com/jt0/gomer/data/dynamodb"
+	ddbtest "github.com/jt0/gomer/data/dynamodb/_test"
+	testentities "github.com/jt0/gomer/data/dynamodb/_test"
+	"github.com/jt0/gomer/gomerr"
+)
+
+func TestExportImport(t *testing.T) {
+	store, client := setupCrudStore(t, &testentities.User{}, &testentities.Product{})
+	defer cleanupCrudTable(t, client)
+
+	ctx := context.Background()
+	for i := 0; i < 30; i++ {
+		assert.Success(t, store.Create(ctx, &testentities.Product{TenantId: "t1", Id: fmt.Sprintf("p%02d", i), Sku: fmt.Sprintf("sku%d", i), Price: float64(i) + 0.5}))
+	}
+	assert.Success(t, store.Create(ctx, &testentities.User{TenantId: "t1", Id: "u1", Email: "u1@example.com"}))
+
+	var exported bytes.Buffer
+	assert.Success(t, ddb.Export(ctx, store, &exported, &testentities.Product{}, 3))
+
+	lines := strings.Split(strings.TrimSuffix(exported.String(), "\n"), "\n")
+	assert.Equals(t, 30, len(lines))
+
+	// Key fields are exported instead of the table's and indexes' key attributes
+	var first map[string]map[string]any
+	assert.Success(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equals(t, "t1", first["TenantId"]["S"])
+	_, hasPrice := first["Price"]["N"]
+	assert.Assert(t, hasPrice, "Price should be exported as a number")
+	for _, name := range []string{"PK", "SK", "LSI_1_SK", "GSI_1_PK", "GSI_1_SK"} {
+		_, ok := first[name]
+		assert.Assert(t, !ok, name+" should not be exported")
+	}
+
+	assert.Success(t, ddbtest.DeleteAllTableData(client, crudTestTableName))
+	assert.Success(t, ddb.Import(ctx, store, &exported, &testentities.Product{}))
+
+	for i := 0; i < 30; i++ {
+		product := &testentities.Product{TenantId: "t1", Id: fmt.Sprintf("p%02d", i)}
+		assert.Success(t, store.Read(ctx, product))
+		assert.Equals(t, fmt.Sprintf("sku%d", i), product.Sku)
+		assert.Equals(t, float64(i)+0.5, product.Price)
+	}
+	assert.Assert(t, verifyEntityExists(t, client, "t1#PRODUCT", "p00"), "imported items should have the table's keys")
+}
+
+func TestImport_Errors(t *testing.T) {
+	store, client := setupCrudStore(t, &testentities.Product{})
+	defer cleanupCrudTable(t, client)
+
+	ctx := context.Background()
+	input := strings.Join([]string{
+		`{"TenantId":{"S":"t1"},"Id":{"S":"p1"}}`,
+		`not json`,
+		``,
+		`{"TenantId":{"S":"t1"},"Id":{"X":"p2"}}`,
+		`{"TenantId":{"S":"t1"},"Id":{"S":"p3"}}`,
+	}, "\n")
+
+	ge := ddb.Import(ctx, store, strings.NewReader(input), &testentities.Product{})
+	be := gomerr.ErrorAs[*gomerr.BatchError](ge)
+	assert.NotNil(t, be)
+	assert.Equals(t, 2, len(be.Errors()))
+	assert.Equals(t, 2, be.Errors()[0].Attributes()["line"])
+	assert.Equals(t, 4, be.Errors()[1].Attributes()["line"])
+
+	// Lines that could be read are imported
+	assert.Success(t, store.Read(ctx, &testentities.Product{TenantId: "t1", Id: "p1"}))
+	assert.Success(t, store.Read(ctx, &testentities.Product{TenantId: "t1", Id: "p3"}))
+}
@@ -0,0 +1,90 @@
+package fake
+
+import (
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Consumed capacity is approximated as DynamoDB documents it: a read unit per 4 KB read (half for an eventually
+// consistent read), and a write unit per 1 KB of the larger of an item's old and new versions, doubled within a
+// transaction. Writes to secondary indexes aren't counted.
+const (
+	readUnitSize  = 4 * 1024
+	writeUnitSize = 1024
+)
+
+func readUnits(size int, consistentRead *bool) float64 {
+	units := float64(max(1, (size+readUnitSize-1)/readUnitSize))
+	if !aws.ToBool(consistentRead) {
+		units /= 2
+	}
+	return units
+}
+
+func (w *write) writeUnits() float64 {
+	return float64(max(1, (max(itemSize(w.before), itemSize(w.after))+writeUnitSize-1)/writeUnitSize))
+}
+
+// consumedCapacity returns the capacity a request consumed from the table or one of its indexes, or nil if the request
+// didn't ask for it.
+func consumedCapacity(rcc types.ReturnConsumedCapacity, t *table, ks *keySchema, read, write float64) *types.ConsumedCapacity {
+	if rcc == "" || rcc == types.ReturnConsumedCapacityNone {
+		return nil
+	}
+
+	c := types.Capacity{CapacityUnits: aws.Float64(read + write)}
+	if read > 0 {
+		c.ReadCapacityUnits = aws.Float64(read)
+	}
+	if write > 0 {
+		c.WriteCapacityUnits = aws.Float64(write)
+	}
+
+	cc := &types.ConsumedCapacity{
+		TableName:          t.description.TableName,
+		CapacityUnits:      c.CapacityUnits,
+		ReadCapacityUnits:  c.ReadCapacityUnits,
+		WriteCapacityUnits: c.WriteCapacityUnits,
+	}
+
+	if rcc == types.ReturnConsumedCapacityIndexes {
+		switch {
+		case ks == nil || ks.name == "":
+			cc.Table = &c
+		case ks.global:
+			cc.GlobalSecondaryIndexes = map[string]types.Capacity{ks.name: c}
+		default:
+			cc.LocalSecondaryIndexes = map[string]types.Capacity{ks.name: c}
+		}
+	}
+
+	return cc
+}
+
+// tableCapacity accumulates the capacity a multi-table request consumes from each table.
+type tableCapacity struct {
+	tables map[*table][2]float64 // read and write units
+}
+
+func (tc *tableCapacity) add(t *table, read, write float64) {
+	if tc.tables == nil {
+		tc.tables = make(map[*table][2]float64)
+	}
+	units := tc.tables[t]
+	tc.tables[t] = [2]float64{units[0] + read, units[1] + write}
+}
+
+func (tc *tableCapacity) consumed(rcc types.ReturnConsumedCapacity) []types.ConsumedCapacity {
+	var consumed []types.ConsumedCapacity
+	for t, units := range tc.tables {
+		if cc := consumedCapacity(rcc, t, nil, units[0], units[1]); cc != nil {
+			consumed = append(consumed, *cc)
+		}
+	}
+	sort.Slice(consumed, func(i, j int) bool {
+		return aws.ToString(consumed[i].TableName) < aws.ToString(consumed[j].TableName)
+	})
+	return consumed
+}
@@ -0,0 +1,1057 @@
+package fake
+
+import (
+	"bytes"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// The fake supports DynamoDB's expression syntax for conditions (key condition, filter, and condition expressions),
+// updates, and projections:
+//
+//	condition  = operand comparator operand | operand BETWEEN operand AND operand | operand IN ( operand, ... ) |
+//	             function | condition AND condition | condition OR condition | NOT condition | ( condition )
+//	function   = attribute_exists(path) | attribute_not_exists(path) | attribute_type(path, type) |
+//	             begins_with(path, operand) | contains(path, operand)
+//	operand    = path | :value | size(path)
+//	update     = SET path = value, ... | REMOVE path, ... | ADD path :value, ... | DELETE path :value, ...
+//	value      = operand | operand + operand | operand - operand | if_not_exists(path, value) | list_append(value, value)
+//	projection = path, ...
+//
+// Paths are attribute names or #name placeholders, followed by .name or [index] elements.
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenName
+	tokenNamePlaceholder
+	tokenValuePlaceholder
+	tokenNumber
+	tokenSymbol
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+func tokenize(expression string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#' || c == ':':
+			start := i
+			for i++; i < len(expression) && isNameChar(expression[i]); i++ {
+			}
+			if i == start+1 {
+				return nil, validation("Invalid expression: syntax error at %q", expression[start:])
+			}
+			kind := tokenNamePlaceholder
+			if c == ':' {
+				kind = tokenValuePlaceholder
+			}
+			tokens = append(tokens, token{kind, expression[start:i]})
+		case c >= '0' && c <= '9':
+			start := i
+			for ; i < len(expression) && expression[i] >= '0' && expression[i] <= '9'; i++ {
+			}
+			tokens = append(tokens, token{tokenNumber, expression[start:i]})
+		case isNameChar(c):
+			start := i
+			for ; i < len(expression) && isNameChar(expression[i]); i++ {
+			}
+			tokens = append(tokens, token{tokenName, expression[start:i]})
+		case c == '<' || c == '>':
+			if i+1 < len(expression) && (expression[i+1] == '=' || c == '<' && expression[i+1] == '>') {
+				tokens = append(tokens, token{tokenSymbol, expression[i : i+2]})
+				i += 2
+			} else {
+				tokens = append(tokens, token{tokenSymbol, string(c)})
+				i++
+			}
+		case strings.IndexByte("=(),.[]+-", c) >= 0:
+			tokens = append(tokens, token{tokenSymbol, string(c)})
+			i++
+		default:
+			return nil, validation("Invalid expression: unexpected character %q", string(c))
+		}
+	}
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+type pathElement struct {
+	name  string
+	index int // -1 for a name element
+}
+
+type path []pathElement
+
+func (p path) String() string {
+	var s strings.Builder
+	for i, e := range p {
+		if e.index >= 0 {
+			s.WriteString("[" + strconv.Itoa(e.index) + "]")
+		} else {
+			if i > 0 {
+				s.WriteByte('.')
+			}
+			s.WriteString(e.name)
+		}
+	}
+	return s.String()
+}
+
+// parser parses one expression, resolving its placeholders and recording which of them it used.
+type parser struct {
+	tokens     []token
+	position   int
+	names      map[string]string
+	values     map[string]types.AttributeValue
+	usedNames  map[string]bool
+	usedValues map[string]bool
+	referenced map[string]bool // top-level attributes used by paths
+}
+
+func newParser(names map[string]string, values map[string]types.AttributeValue) *parser {
+	return &parser{names: names, values: values, usedNames: make(map[string]bool), usedValues: make(map[string]bool), referenced: make(map[string]bool)}
+}
+
+func (p *parser) reset(expression string) error {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return err
+	}
+	p.tokens, p.position = tokens, 0
+	return nil
+}
+
+// checkUsed returns an error if any of the placeholders weren't used by the parsed expressions.
+func (p *parser) checkUsed() error {
+	var unusedNames, unusedValues []string
+	for name := range p.names {
+		if !p.usedNames[name] {
+			unusedNames = append(unusedNames, name)
+		}
+	}
+	for value := range p.values {
+		if !p.usedValues[value] {
+			unusedValues = append(unusedValues, value)
+		}
+	}
+	if len(unusedNames) > 0 {
+		return validation("Value provided in ExpressionAttributeNames unused in expressions: keys: {%s}", strings.Join(sorted(unusedNames), ", "))
+	}
+	if len(unusedValues) > 0 {
+		return validation("Value provided in ExpressionAttributeValues unused in expressions: keys: {%s}", strings.Join(sorted(unusedValues), ", "))
+	}
+	return nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.position]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.position]
+	if t.kind != tokenEOF {
+		p.position++
+	}
+	return t
+}
+
+func (p *parser) isSymbol(symbol string) bool {
+	t := p.peek()
+	return t.kind == tokenSymbol && t.value == symbol
+}
+
+func (p *parser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == tokenName && strings.EqualFold(t.value, keyword)
+}
+
+func (p *parser) expectSymbol(symbol string) error {
+	if !p.isSymbol(symbol) {
+		return p.syntaxError()
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) expectEnd() error {
+	if p.peek().kind != tokenEOF {
+		return p.syntaxError()
+	}
+	return nil
+}
+
+func (p *parser) syntaxError() error {
+	t := p.peek()
+	if t.kind == tokenEOF {
+		return validation("Invalid expression: unexpected end of expression")
+	}
+	return validation("Invalid expression: syntax error; token: %q", t.value)
+}
+
+func (p *parser) parsePath() (path, error) {
+	var result path
+	name, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+	result = append(result, pathElement{name: name, index: -1})
+	p.referenced[name] = true
+
+	for {
+		switch {
+		case p.isSymbol("."):
+			p.next()
+			if name, err = p.parseName(); err != nil {
+				return nil, err
+			}
+			result = append(result, pathElement{name: name, index: -1})
+		case p.isSymbol("["):
+			p.next()
+			t := p.next()
+			if t.kind != tokenNumber {
+				return nil, p.syntaxError()
+			}
+			index, _ := strconv.Atoi(t.value)
+			if err = p.expectSymbol("]"); err != nil {
+				return nil, err
+			}
+			result = append(result, pathElement{index: index})
+		default:
+			return result, nil
+		}
+	}
+}
+
+func (p *parser) parseName() (string, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenName:
+		p.next()
+		if reservedWords[strings.ToUpper(t.value)] {
+			return "", validation("Invalid expression: Attribute name is a reserved keyword; reserved keyword: %s", t.value)
+		}
+		return t.value, nil
+	case tokenNamePlaceholder:
+		p.next()
+		name, ok := p.names[t.value]
+		if !ok {
+			return "", validation("Invalid expression: An expression attribute name used in the document path is not defined; attribute name: %s", t.value)
+		}
+		p.usedNames[t.value] = true
+		return name, nil
+	}
+	return "", p.syntaxError()
+}
+
+// operand evaluates to a value for an item, or to false if it doesn't have one.
+type operand func(i item) (types.AttributeValue, bool)
+
+func (p *parser) parseOperand() (operand, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokenValuePlaceholder:
+		p.next()
+		av, ok := p.values[t.value]
+		if !ok {
+			return nil, validation("Invalid expression: An expression attribute value used in expression is not defined; attribute value: %s", t.value)
+		}
+		p.usedValues[t.value] = true
+		return func(item) (types.AttributeValue, bool) { return av, true }, nil
+	case t.kind == tokenName && strings.EqualFold(t.value, "size") && p.tokens[p.position+1].value == "(":
+		p.next()
+		p.next()
+		pth, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+		return func(i item) (types.AttributeValue, bool) {
+			av, ok := get(i, pth)
+			if !ok {
+				return nil, false
+			}
+			n, ok := size(av)
+			return &types.AttributeValueMemberN{Value: strconv.Itoa(n)}, ok
+		}, nil
+	}
+
+	pth, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+	return func(i item) (types.AttributeValue, bool) { return get(i, pth) }, nil
+}
+
+// condition evaluates to whether an item satisfies it.
+type condition func(i item) bool
+
+func parseCondition(expression string, p *parser) (condition, error) {
+	if err := p.reset(expression); err != nil {
+		return nil, err
+	}
+	c, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	return c, p.expectEnd()
+}
+
+func (p *parser) parseOr() (condition, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(i item) bool { return l(i) || right(i) }
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (condition, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("AND") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(i item) bool { return l(i) && right(i) }
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (condition, error) {
+	if p.isKeyword("NOT") {
+		p.next()
+		c, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return func(i item) bool { return !c(i) }, nil
+	}
+	return p.parsePrimary()
+}
+
+var conditionFunctions = map[string]bool{"attribute_exists": true, "attribute_not_exists": true, "attribute_type": true, "begins_with": true, "contains": true}
+
+func (p *parser) parsePrimary() (condition, error) {
+	if p.isSymbol("(") {
+		p.next()
+		c, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return c, p.expectSymbol(")")
+	}
+
+	if t := p.peek(); t.kind == tokenName && conditionFunctions[strings.ToLower(t.value)] && p.tokens[p.position+1].value == "(" {
+		return p.parseFunction()
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.isKeyword("BETWEEN"):
+		p.next()
+		lower, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isKeyword("AND") {
+			return nil, p.syntaxError()
+		}
+		p.next()
+		upper, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return func(i item) bool {
+			v, vOk := left(i)
+			l, lOk := lower(i)
+			u, uOk := upper(i)
+			if !vOk || !lOk || !uOk {
+				return false
+			}
+			c1, ok1 := compare(v, l)
+			c2, ok2 := compare(v, u)
+			return ok1 && ok2 && c1 >= 0 && c2 <= 0
+		}, nil
+	case p.isKeyword("IN"):
+		p.next()
+		if err = p.expectSymbol("("); err != nil {
+			return nil, err
+		}
+		var candidates []operand
+		for {
+			o, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, o)
+			if !p.isSymbol(",") {
+				break
+			}
+			p.next()
+		}
+		if err = p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+		return func(i item) bool {
+			v, ok := left(i)
+			if !ok {
+				return false
+			}
+			for _, candidate := range candidates {
+				if c, ok := candidate(i); ok && equal(v, c) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+
+	comparator := p.peek().value
+	switch comparator {
+	case "=", "<>", "<", "<=", ">", ">=":
+		p.next()
+	default:
+		return nil, p.syntaxError()
+	}
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(i item) bool {
+		l, lOk := left(i)
+		r, rOk := right(i)
+		if !lOk || !rOk {
+			return false
+		}
+		switch comparator {
+		case "=":
+			return equal(l, r)
+		case "<>":
+			return !equal(l, r)
+		}
+		c, ok := compare(l, r)
+		if !ok {
+			return false
+		}
+		switch comparator {
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		default:
+			return c >= 0
+		}
+	}, nil
+}
+
+func (p *parser) parseFunction() (condition, error) {
+	function := strings.ToLower(p.next().value)
+	p.next() // (
+
+	pth, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+
+	var argument operand
+	if function != "attribute_exists" && function != "attribute_not_exists" {
+		if err = p.expectSymbol(","); err != nil {
+			return nil, err
+		}
+		if argument, err = p.parseOperand(); err != nil {
+			return nil, err
+		}
+	}
+	if err = p.expectSymbol(")"); err != nil {
+		return nil, err
+	}
+
+	switch function {
+	case "attribute_exists":
+		return func(i item) bool { _, ok := get(i, pth); return ok }, nil
+	case "attribute_not_exists":
+		return func(i item) bool { _, ok := get(i, pth); return !ok }, nil
+	case "attribute_type":
+		return func(i item) bool {
+			v, vOk := get(i, pth)
+			a, aOk := argument(i)
+			s, sOk := a.(*types.AttributeValueMemberS)
+			return vOk && aOk && sOk && typeOf(v) == s.Value
+		}, nil
+	case "begins_with":
+		return func(i item) bool {
+			v, vOk := get(i, pth)
+			a, aOk := argument(i)
+			if !vOk || !aOk {
+				return false
+			}
+			switch v := v.(type) {
+			case *types.AttributeValueMemberS:
+				prefix, ok := a.(*types.AttributeValueMemberS)
+				return ok && strings.HasPrefix(v.Value, prefix.Value)
+			case *types.AttributeValueMemberB:
+				prefix, ok := a.(*types.AttributeValueMemberB)
+				return ok && bytes.HasPrefix(v.Value, prefix.Value)
+			}
+			return false
+		}, nil
+	default: // contains
+		return func(i item) bool {
+			v, vOk := get(i, pth)
+			a, aOk := argument(i)
+			if !vOk || !aOk {
+				return false
+			}
+			return contains(v, a)
+		}, nil
+	}
+}
+
+// contains returns true if v is a string containing the substring a, or a set or list with a as an element.
+func contains(v, a types.AttributeValue) bool {
+	switch v := v.(type) {
+	case *types.AttributeValueMemberS:
+		s, ok := a.(*types.AttributeValueMemberS)
+		return ok && strings.Contains(v.Value, s.Value)
+	case *types.AttributeValueMemberB:
+		b, ok := a.(*types.AttributeValueMemberB)
+		return ok && bytes.Contains(v.Value, b.Value)
+	case *types.AttributeValueMemberSS:
+		for _, e := range v.Value {
+			if equal(&types.AttributeValueMemberS{Value: e}, a) {
+				return true
+			}
+		}
+	case *types.AttributeValueMemberNS:
+		for _, e := range v.Value {
+			if equal(&types.AttributeValueMemberN{Value: e}, a) {
+				return true
+			}
+		}
+	case *types.AttributeValueMemberBS:
+		for _, e := range v.Value {
+			if equal(&types.AttributeValueMemberB{Value: e}, a) {
+				return true
+			}
+		}
+	case *types.AttributeValueMemberL:
+		for _, e := range v.Value {
+			if equal(e, a) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// get returns the value at the path in the item.
+func get(i item, pth path) (types.AttributeValue, bool) {
+	av, ok := i[pth[0].name]
+	for _, e := range pth[1:] {
+		if !ok {
+			return nil, false
+		}
+		if e.index >= 0 {
+			l, isList := av.(*types.AttributeValueMemberL)
+			if !isList || e.index >= len(l.Value) {
+				return nil, false
+			}
+			av = l.Value[e.index]
+		} else {
+			m, isMap := av.(*types.AttributeValueMemberM)
+			if !isMap {
+				return nil, false
+			}
+			av, ok = m.Value[e.name]
+		}
+	}
+	return av, ok
+}
+
+// set stores the value at the path in the item. The path's parent must exist. An index past the end of a list appends
+// the value.
+func set(i item, pth path, av types.AttributeValue) error {
+	if len(pth) == 1 {
+		i[pth[0].name] = av
+		return nil
+	}
+
+	parent, ok := get(i, pth[:len(pth)-1])
+	if !ok {
+		return validation("The document path provided in the update expression is invalid for update")
+	}
+
+	last := pth[len(pth)-1]
+	switch p := parent.(type) {
+	case *types.AttributeValueMemberM:
+		if last.index < 0 {
+			p.Value[last.name] = av
+			return nil
+		}
+	case *types.AttributeValueMemberL:
+		if last.index >= 0 {
+			if last.index < len(p.Value) {
+				p.Value[last.index] = av
+			} else {
+				p.Value = append(p.Value, av)
+			}
+			return nil
+		}
+	}
+	return validation("The document path provided in the update expression is invalid for update")
+}
+
+// remove deletes the value at the path from the item, if it's there.
+func remove(i item, pth path) {
+	if len(pth) == 1 {
+		delete(i, pth[0].name)
+		return
+	}
+
+	parent, ok := get(i, pth[:len(pth)-1])
+	if !ok {
+		return
+	}
+
+	last := pth[len(pth)-1]
+	switch p := parent.(type) {
+	case *types.AttributeValueMemberM:
+		delete(p.Value, last.name)
+	case *types.AttributeValueMemberL:
+		if last.index >= 0 && last.index < len(p.Value) {
+			p.Value = append(p.Value[:last.index], p.Value[last.index+1:]...)
+		}
+	}
+}
+
+// update applies an update expression to an item. It returns the paths of the top-level attributes it changed.
+type update func(i item) ([]string, error)
+
+func parseUpdate(expression string, p *parser) (update, error) {
+	if err := p.reset(expression); err != nil {
+		return nil, err
+	}
+
+	// Actions read values from the item as it was before the update (before) and apply them to the updated one
+	var actions []func(before, updated item) error
+	var updated []string
+	seen := make(map[string]bool)
+	addPath := func(pth path) error {
+		s := pth.String()
+		for other := range seen {
+			if other == s || strings.HasPrefix(other, s+".") || strings.HasPrefix(s, other+".") || strings.HasPrefix(other, s+"[") || strings.HasPrefix(s, other+"[") {
+				return validation("Invalid UpdateExpression: Two document paths overlap with each other; must remove or rewrite one of these paths; path one: [%s], path two: [%s]", other, s)
+			}
+		}
+		seen[s] = true
+		updated = append(updated, pth[0].name)
+		return nil
+	}
+
+	clauses := make(map[string]bool)
+	for p.peek().kind != tokenEOF {
+		t := p.peek()
+		clause := strings.ToUpper(t.value)
+		if t.kind != tokenName || clauses[clause] {
+			return nil, p.syntaxError()
+		}
+		switch clause {
+		case "SET", "REMOVE", "ADD", "DELETE":
+		default:
+			return nil, p.syntaxError()
+		}
+		p.next()
+		clauses[clause] = true
+
+		for {
+			pth, err := p.parsePath()
+			if err != nil {
+				return nil, err
+			}
+			if err = addPath(pth); err != nil {
+				return nil, err
+			}
+
+			var action func(before, updated item) error
+			switch clause {
+			case "SET":
+				if err = p.expectSymbol("="); err != nil {
+					return nil, err
+				}
+				value, err := p.parseUpdateValue()
+				if err != nil {
+					return nil, err
+				}
+				action = func(before, updated item) error {
+					av, err := value(before)
+					if err != nil {
+						return err
+					}
+					return set(updated, pth, av)
+				}
+			case "REMOVE":
+				action = func(_, updated item) error {
+					remove(updated, pth)
+					return nil
+				}
+			case "ADD", "DELETE":
+				value, err := p.parseOperand()
+				if err != nil {
+					return nil, err
+				}
+				add := clause == "ADD"
+				action = func(before, updated item) error {
+					av, _ := value(before)
+					existing, exists := get(before, pth)
+					result, err := addOrDelete(existing, exists, av, add)
+					if err != nil {
+						return err
+					}
+					if result == nil {
+						remove(updated, pth)
+						return nil
+					}
+					return set(updated, pth, result)
+				}
+			}
+			actions = append(actions, action)
+
+			if !p.isSymbol(",") {
+				break
+			}
+			p.next()
+		}
+	}
+
+	if len(actions) == 0 {
+		return nil, validation("Invalid UpdateExpression: The expression can not be empty")
+	}
+
+	return func(i item) ([]string, error) {
+		before := copyItem(i)
+		for _, action := range actions {
+			if err := action(before, i); err != nil {
+				return nil, err
+			}
+		}
+		return updated, nil
+	}, nil
+}
+
+// updateValue computes a value for a SET action.
+type updateValue func(i item) (types.AttributeValue, error)
+
+func (p *parser) parseUpdateValue() (updateValue, error) {
+	left, err := p.parseUpdateTerm()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.isSymbol("+") || p.isSymbol("-") {
+		sign := p.next().value
+		right, err := p.parseUpdateTerm()
+		if err != nil {
+			return nil, err
+		}
+		return func(i item) (types.AttributeValue, error) {
+			l, err := left(i)
+			if err != nil {
+				return nil, err
+			}
+			r, err := right(i)
+			if err != nil {
+				return nil, err
+			}
+			ln, lOk := l.(*types.AttributeValueMemberN)
+			rn, rOk := r.(*types.AttributeValueMemberN)
+			if !lOk || !rOk {
+				return nil, validation("An operand in the update expression has an incorrect data type")
+			}
+			a, _ := number(ln.Value)
+			b, _ := number(rn.Value)
+			if sign == "-" {
+				b = new(big.Rat).Neg(b)
+			}
+			return &types.AttributeValueMemberN{Value: formatNumber(new(big.Rat).Add(a, b))}, nil
+		}, nil
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseUpdateTerm() (updateValue, error) {
+	t := p.peek()
+	if t.kind == tokenName && p.tokens[p.position+1].value == "(" {
+		switch strings.ToLower(t.value) {
+		case "if_not_exists":
+			p.next()
+			p.next()
+			pth, err := p.parsePath()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expectSymbol(","); err != nil {
+				return nil, err
+			}
+			fallback, err := p.parseUpdateValue()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expectSymbol(")"); err != nil {
+				return nil, err
+			}
+			return func(i item) (types.AttributeValue, error) {
+				if av, ok := get(i, pth); ok {
+					return copyValue(av), nil
+				}
+				return fallback(i)
+			}, nil
+		case "list_append":
+			p.next()
+			p.next()
+			first, err := p.parseUpdateValue()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expectSymbol(","); err != nil {
+				return nil, err
+			}
+			second, err := p.parseUpdateValue()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expectSymbol(")"); err != nil {
+				return nil, err
+			}
+			return func(i item) (types.AttributeValue, error) {
+				a, err := first(i)
+				if err != nil {
+					return nil, err
+				}
+				b, err := second(i)
+				if err != nil {
+					return nil, err
+				}
+				al, aOk := a.(*types.AttributeValueMemberL)
+				bl, bOk := b.(*types.AttributeValueMemberL)
+				if !aOk || !bOk {
+					return nil, validation("An operand in the update expression has an incorrect data type")
+				}
+				return &types.AttributeValueMemberL{Value: append(append([]types.AttributeValue(nil), al.Value...), bl.Value...)}, nil
+			}, nil
+		}
+	}
+
+	o, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return func(i item) (types.AttributeValue, error) {
+		av, ok := o(i)
+		if !ok {
+			return nil, validation("The provided expression refers to an attribute that does not exist in the item")
+		}
+		return copyValue(av), nil
+	}, nil
+}
+
+// addOrDelete returns the result of an ADD (if add is true) or DELETE action, or nil if the attribute should be
+// removed.
+func addOrDelete(existing types.AttributeValue, exists bool, av types.AttributeValue, add bool) (types.AttributeValue, error) {
+	if n, ok := av.(*types.AttributeValueMemberN); ok && add {
+		sum, _ := number(n.Value)
+		if exists {
+			e, ok := existing.(*types.AttributeValueMemberN)
+			if !ok {
+				return nil, validation("An operand in the update expression has an incorrect data type")
+			}
+			base, _ := number(e.Value)
+			sum = new(big.Rat).Add(base, sum)
+		}
+		return &types.AttributeValueMemberN{Value: formatNumber(sum)}, nil
+	}
+
+	if !exists {
+		if add {
+			return copyValue(av), nil
+		}
+		return nil, nil
+	}
+	if typeOf(existing) != typeOf(av) {
+		return nil, validation("An operand in the update expression has an incorrect data type")
+	}
+
+	var elements []types.AttributeValue
+	var wrap func([]types.AttributeValue) types.AttributeValue
+	switch e := existing.(type) {
+	case *types.AttributeValueMemberSS:
+		for _, s := range e.Value {
+			elements = append(elements, &types.AttributeValueMemberS{Value: s})
+		}
+		wrap = func(values []types.AttributeValue) types.AttributeValue {
+			set := &types.AttributeValueMemberSS{}
+			for _, v := range values {
+				set.Value = append(set.Value, v.(*types.AttributeValueMemberS).Value)
+			}
+			return set
+		}
+	case *types.AttributeValueMemberNS:
+		for _, s := range e.Value {
+			elements = append(elements, &types.AttributeValueMemberN{Value: s})
+		}
+		wrap = func(values []types.AttributeValue) types.AttributeValue {
+			set := &types.AttributeValueMemberNS{}
+			for _, v := range values {
+				set.Value = append(set.Value, v.(*types.AttributeValueMemberN).Value)
+			}
+			return set
+		}
+	case *types.AttributeValueMemberBS:
+		for _, b := range e.Value {
+			elements = append(elements, &types.AttributeValueMemberB{Value: b})
+		}
+		wrap = func(values []types.AttributeValue) types.AttributeValue {
+			set := &types.AttributeValueMemberBS{}
+			for _, v := range values {
+				set.Value = append(set.Value, v.(*types.AttributeValueMemberB).Value)
+			}
+			return set
+		}
+	default:
+		return nil, validation("An operand in the update expression has an incorrect data type")
+	}
+
+	var changes []types.AttributeValue
+	switch v := av.(type) {
+	case *types.AttributeValueMemberSS:
+		for _, s := range v.Value {
+			changes = append(changes, &types.AttributeValueMemberS{Value: s})
+		}
+	case *types.AttributeValueMemberNS:
+		for _, s := range v.Value {
+			changes = append(changes, &types.AttributeValueMemberN{Value: s})
+		}
+	case *types.AttributeValueMemberBS:
+		for _, b := range v.Value {
+			changes = append(changes, &types.AttributeValueMemberB{Value: b})
+		}
+	}
+
+	for _, change := range changes {
+		index := -1
+		for i, e := range elements {
+			if equal(e, change) {
+				index = i
+				break
+			}
+		}
+		if add && index < 0 {
+			elements = append(elements, change)
+		} else if !add && index >= 0 {
+			elements = append(elements[:index], elements[index+1:]...)
+		}
+	}
+
+	if len(elements) == 0 {
+		return nil, nil
+	}
+	return wrap(elements), nil
+}
+
+// projection returns the projected attributes of an item.
+type projection func(i item) item
+
+func parseProjection(expression string, p *parser) (projection, error) {
+	if err := p.reset(expression); err != nil {
+		return nil, err
+	}
+
+	var paths []path
+	for {
+		pth, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, pth)
+		if !p.isSymbol(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expectEnd(); err != nil {
+		return nil, err
+	}
+
+	return func(i item) item {
+		projected := make(item)
+		for _, pth := range paths {
+			av, ok := get(i, pth)
+			if !ok {
+				continue
+			}
+			if len(pth) == 1 {
+				projected[pth[0].name] = copyValue(av)
+				continue
+			}
+			// Nested paths project into maps (list elements are projected into a list of the selected elements)
+			target := projected
+			for j, e := range pth[:len(pth)-1] {
+				if e.index >= 0 {
+					break
+				}
+				next, ok := target[e.name].(*types.AttributeValueMemberM)
+				if !ok {
+					if pth[j+1].index >= 0 {
+						target[e.name] = &types.AttributeValueMemberL{Value: []types.AttributeValue{copyValue(av)}}
+						break
+					}
+					next = &types.AttributeValueMemberM{Value: make(item)}
+					target[e.name] = next
+				}
+				target = next.Value
+				if j == len(pth)-2 {
+					target[pth[len(pth)-1].name] = copyValue(av)
+				}
+			}
+		}
+		return projected
+	}, nil
+}
@@ -0,0 +1,1177 @@
+// Package fake provides an in-memory DynamoDB client for testing code that uses the dynamodb store (or the DynamoDB
+// API directly) without a DynamoDB endpoint. It supports tables with local and global secondary indexes, key condition,
+// filter, condition, update, and projection expressions, pagination, and batch and transactional writes, and returns
+// the same error types DynamoDB does for failed conditions, cancelled transactions, missing tables, and invalid
+// requests.
+//
+// Consumed capacity is returned when requested, though it's only an approximation (see capacity.go). The fake doesn't
+// model provisioned capacity, throttling, or item collection and response size limits, and reads are always strongly
+// consistent.
+package fake
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	batchGetMaxKeys      = 100
+	batchWriteMaxItems   = 25
+	transactionMaxItems  = 100
+	maxItemSize          = 400 * 1024
+	conditionFailedCode  = "ConditionalCheckFailed"
+	conditionFailedError = "The conditional request failed"
+)
+
+// Client is an in-memory DynamoDB client. It's safe for concurrent use.
+type Client struct {
+	mu     sync.Mutex
+	tables map[string]*table
+}
+
+// New returns a Client without any tables.
+func New() *Client {
+	return &Client{tables: make(map[string]*table)}
+}
+
+type table struct {
+	description *types.TableDescription
+	indexes     map[string]*keySchema // "" for the table's own keys
+	items       map[string]item       // primary key -> item
+}
+
+type keySchema struct {
+	name       string
+	pk, sk     string
+	global     bool
+	projection *types.Projection
+}
+
+func (c *Client) table(name *string) (*table, error) {
+	if t, ok := c.tables[aws.ToString(name)]; ok {
+		return t, nil
+	}
+	return nil, &types.ResourceNotFoundException{Message: aws.String("Requested resource not found")}
+}
+
+// CreateTable creates a table that's immediately active.
+func (c *Client) CreateTable(ctx context.Context, input *dynamodb.CreateTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name := aws.ToString(input.TableName)
+	if _, exists := c.tables[name]; exists {
+		return nil, &types.ResourceInUseException{Message: aws.String("Table already exists: " + name)}
+	}
+
+	attributeTypes := make(map[string]types.ScalarAttributeType, len(input.AttributeDefinitions))
+	for _, ad := range input.AttributeDefinitions {
+		attributeTypes[aws.ToString(ad.AttributeName)] = ad.AttributeType
+	}
+
+	used := make(map[string]bool)
+	schema := func(indexName string, elements []types.KeySchemaElement) (*keySchema, error) {
+		ks := &keySchema{name: indexName}
+		for _, e := range elements {
+			attribute := aws.ToString(e.AttributeName)
+			if _, ok := attributeTypes[attribute]; !ok {
+				return nil, validation("One or more parameter values were invalid: Some index key attributes are not defined in AttributeDefinitions. Keys: [%s]", attribute)
+			}
+			used[attribute] = true
+			if e.KeyType == types.KeyTypeHash {
+				ks.pk = attribute
+			} else {
+				ks.sk = attribute
+			}
+		}
+		if ks.pk == "" {
+			return nil, validation("One or more parameter values were invalid: Missing the key HASH in the key schema")
+		}
+		return ks, nil
+	}
+
+	t := &table{indexes: make(map[string]*keySchema), items: make(map[string]item)}
+	tableKeys, err := schema("", input.KeySchema)
+	if err != nil {
+		return nil, err
+	}
+	t.indexes[""] = tableKeys
+
+	now := time.Now()
+	description := &types.TableDescription{
+		TableName:            aws.String(name),
+		TableArn:             aws.String("arn:aws:dynamodb:fake:000000000000:table/" + name),
+		TableStatus:          types.TableStatusActive,
+		CreationDateTime:     &now,
+		AttributeDefinitions: input.AttributeDefinitions,
+		KeySchema:            input.KeySchema,
+		ItemCount:            aws.Int64(0),
+		TableSizeBytes:       aws.Int64(0),
+	}
+	if input.BillingMode != "" {
+		description.BillingModeSummary = &types.BillingModeSummary{BillingMode: input.BillingMode}
+	}
+
+	for _, lsi := range input.LocalSecondaryIndexes {
+		ks, err := schema(aws.ToString(lsi.IndexName), lsi.KeySchema)
+		if err != nil {
+			return nil, err
+		}
+		if ks.pk != tableKeys.pk || ks.sk == "" || tableKeys.sk == "" {
+			return nil, validation("One or more parameter values were invalid: Index KeySchema does not have the same leading hash key as table KeySchema for index: %s", ks.name)
+		}
+		if _, exists := t.indexes[ks.name]; exists {
+			return nil, validation("One or more parameter values were invalid: Duplicate index name: %s", ks.name)
+		}
+		ks.projection = lsi.Projection
+		t.indexes[ks.name] = ks
+		description.LocalSecondaryIndexes = append(description.LocalSecondaryIndexes, types.LocalSecondaryIndexDescription{
+			IndexName:  lsi.IndexName,
+			KeySchema:  lsi.KeySchema,
+			Projection: lsi.Projection,
+		})
+	}
+
+	for _, gsi := range input.GlobalSecondaryIndexes {
+		ks, err := schema(aws.ToString(gsi.IndexName), gsi.KeySchema)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := t.indexes[ks.name]; exists {
+			return nil, validation("One or more parameter values were invalid: Duplicate index name: %s", ks.name)
+		}
+		ks.global, ks.projection = true, gsi.Projection
+		t.indexes[ks.name] = ks
+		description.GlobalSecondaryIndexes = append(description.GlobalSecondaryIndexes, types.GlobalSecondaryIndexDescription{
+			IndexName:             gsi.IndexName,
+			KeySchema:             gsi.KeySchema,
+			Projection:            gsi.Projection,
+			IndexStatus:           types.IndexStatusActive,
+			ProvisionedThroughput: provisionedThroughput(gsi.ProvisionedThroughput),
+		})
+	}
+
+	for attribute := range attributeTypes {
+		if !used[attribute] {
+			return nil, validation("One or more parameter values were invalid: Number of attributes in KeySchema does not exactly match number of attributes defined in AttributeDefinitions")
+		}
+	}
+
+	description.ProvisionedThroughput = provisionedThroughput(input.ProvisionedThroughput)
+	t.description = description
+	c.tables[name] = t
+
+	return &dynamodb.CreateTableOutput{TableDescription: description}, nil
+}
+
+func provisionedThroughput(pt *types.ProvisionedThroughput) *types.ProvisionedThroughputDescription {
+	if pt == nil {
+		return &types.ProvisionedThroughputDescription{ReadCapacityUnits: aws.Int64(0), WriteCapacityUnits: aws.Int64(0)}
+	}
+	return &types.ProvisionedThroughputDescription{ReadCapacityUnits: pt.ReadCapacityUnits, WriteCapacityUnits: pt.WriteCapacityUnits}
+}
+
+// DeleteTable deletes a table and its items.
+func (c *Client) DeleteTable(ctx context.Context, input *dynamodb.DeleteTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteTableOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, err := c.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	delete(c.tables, aws.ToString(input.TableName))
+
+	return &dynamodb.DeleteTableOutput{TableDescription: t.describe()}, nil
+}
+
+// DescribeTable returns a table's description.
+func (c *Client) DescribeTable(ctx context.Context, input *dynamodb.DescribeTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, err := c.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.DescribeTableOutput{Table: t.describe()}, nil
+}
+
+func (t *table) describe() *types.TableDescription {
+	description := *t.description
+	size := 0
+	for _, i := range t.items {
+		size += itemSize(i)
+	}
+	description.ItemCount = aws.Int64(int64(len(t.items)))
+	description.TableSizeBytes = aws.Int64(int64(size))
+	return &description
+}
+
+// GetItem returns the item with the given key, if there is one.
+func (c *Client) GetItem(ctx context.Context, input *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, err := c.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	p := newParser(input.ExpressionAttributeNames, nil)
+	project, err := parseProjectionExpression(input.ProjectionExpression, p)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkExpressionAttributes(p, input.ExpressionAttributeNames, nil); err != nil {
+		return nil, err
+	}
+
+	key, err := t.key(input.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &dynamodb.GetItemOutput{}
+	stored, ok := t.items[key]
+	if ok {
+		output.Item = project(stored)
+	}
+	output.ConsumedCapacity = consumedCapacity(input.ReturnConsumedCapacity, t, nil, readUnits(itemSize(stored), input.ConsistentRead), 0)
+	return output, nil
+}
+
+// PutItem creates or replaces an item.
+func (c *Client) PutItem(ctx context.Context, input *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, err := c.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := t.put(input.Item, input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues, input.ReturnValuesOnConditionCheckFailure)
+	if err != nil {
+		return nil, err
+	}
+	if err = w.check(); err != nil {
+		return nil, err
+	}
+	w.apply()
+
+	output := &dynamodb.PutItemOutput{ConsumedCapacity: consumedCapacity(input.ReturnConsumedCapacity, t, nil, 0, w.writeUnits())}
+	switch input.ReturnValues {
+	case types.ReturnValueNone, "":
+	case types.ReturnValueAllOld:
+		output.Attributes = copyItem(w.before)
+	default:
+		return nil, validation("Return values set to invalid value")
+	}
+	return output, nil
+}
+
+// UpdateItem updates an item, or creates it if it doesn't exist.
+func (c *Client) UpdateItem(ctx context.Context, input *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, err := c.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := t.update(input.Key, input.UpdateExpression, input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues, input.ReturnValuesOnConditionCheckFailure)
+	if err != nil {
+		return nil, err
+	}
+	if err = w.check(); err != nil {
+		return nil, err
+	}
+	w.apply()
+
+	output := &dynamodb.UpdateItemOutput{ConsumedCapacity: consumedCapacity(input.ReturnConsumedCapacity, t, nil, 0, w.writeUnits())}
+	switch input.ReturnValues {
+	case types.ReturnValueNone, "":
+	case types.ReturnValueAllOld:
+		output.Attributes = copyItem(w.before)
+	case types.ReturnValueAllNew:
+		output.Attributes = copyItem(w.after)
+	case types.ReturnValueUpdatedOld, types.ReturnValueUpdatedNew:
+		source := w.after
+		if input.ReturnValues == types.ReturnValueUpdatedOld {
+			source = w.before
+		}
+		output.Attributes = make(item)
+		for _, name := range w.updated {
+			if av, ok := source[name]; ok {
+				output.Attributes[name] = copyValue(av)
+			}
+		}
+	default:
+		return nil, validation("Return values set to invalid value")
+	}
+	return output, nil
+}
+
+// DeleteItem deletes an item, if it exists.
+func (c *Client) DeleteItem(ctx context.Context, input *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, err := c.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := t.delete(input.Key, input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues, input.ReturnValuesOnConditionCheckFailure)
+	if err != nil {
+		return nil, err
+	}
+	if err = w.check(); err != nil {
+		return nil, err
+	}
+	w.apply()
+
+	output := &dynamodb.DeleteItemOutput{ConsumedCapacity: consumedCapacity(input.ReturnConsumedCapacity, t, nil, 0, w.writeUnits())}
+	switch input.ReturnValues {
+	case types.ReturnValueNone, "":
+	case types.ReturnValueAllOld:
+		output.Attributes = copyItem(w.before)
+	default:
+		return nil, validation("Return values set to invalid value")
+	}
+	return output, nil
+}
+
+// Query returns items from one partition of a table or index.
+func (c *Client) Query(ctx context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, err := c.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	ks, err := t.index(input.IndexName, input.ConsistentRead)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.KeyConditionExpression == nil {
+		return nil, validation("Either the KeyConditions or KeyConditionExpression parameter must be specified in the request.")
+	}
+
+	p := newParser(input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	keyCondition, err := parseCondition(*input.KeyConditionExpression, p)
+	if err != nil {
+		return nil, err
+	}
+	for name := range p.referenced {
+		if name != ks.pk && name != ks.sk {
+			return nil, validation("Query condition missed key schema element")
+		}
+	}
+	if !p.referenced[ks.pk] {
+		return nil, validation("Query condition missed key schema element: %s", ks.pk)
+	}
+
+	r, err := newRead(p, input.FilterExpression, input.ProjectionExpression, input.Select, input.Limit)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkExpressionAttributes(p, input.ExpressionAttributeNames, input.ExpressionAttributeValues); err != nil {
+		return nil, err
+	}
+
+	items := t.indexItems(ks, func(i item) bool { return keyCondition(i) })
+	t.sortItems(ks, items, input.ScanIndexForward == nil || *input.ScanIndexForward)
+
+	output := &dynamodb.QueryOutput{}
+	output.Items, output.Count, output.ScannedCount, output.LastEvaluatedKey = r.read(t, ks, items, input.ExclusiveStartKey, input.ScanIndexForward == nil || *input.ScanIndexForward)
+	output.ConsumedCapacity = consumedCapacity(input.ReturnConsumedCapacity, t, ks, readUnits(r.scannedSize, input.ConsistentRead), 0)
+	return output, nil
+}
+
+// Scan returns items from a table or index, optionally from one segment of a parallel scan.
+func (c *Client) Scan(ctx context.Context, input *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, err := c.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	ks, err := t.index(input.IndexName, input.ConsistentRead)
+	if err != nil {
+		return nil, err
+	}
+
+	segment, totalSegments := aws.ToInt32(input.Segment), aws.ToInt32(input.TotalSegments)
+	if (input.Segment == nil) != (input.TotalSegments == nil) || input.TotalSegments != nil && (totalSegments < 1 || segment < 0 || segment >= totalSegments) {
+		return nil, validation("The Segment parameter is required but was not present in the request when parameter TotalSegments is present")
+	}
+
+	p := newParser(input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	r, err := newRead(p, input.FilterExpression, input.ProjectionExpression, input.Select, input.Limit)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkExpressionAttributes(p, input.ExpressionAttributeNames, input.ExpressionAttributeValues); err != nil {
+		return nil, err
+	}
+
+	items := t.indexItems(ks, func(i item) bool {
+		if totalSegments <= 1 {
+			return true
+		}
+		h := fnv.New32a()
+		h.Write([]byte(keyString(i, ks.pk)))
+		return int32(h.Sum32()%uint32(totalSegments)) == segment
+	})
+	t.sortItems(ks, items, true)
+
+	output := &dynamodb.ScanOutput{}
+	output.Items, output.Count, output.ScannedCount, output.LastEvaluatedKey = r.read(t, ks, items, input.ExclusiveStartKey, true)
+	output.ConsumedCapacity = consumedCapacity(input.ReturnConsumedCapacity, t, ks, readUnits(r.scannedSize, input.ConsistentRead), 0)
+	return output, nil
+}
+
+// BatchGetItem returns the items with the given keys. All keys are always processed.
+func (c *Client) BatchGetItem(ctx context.Context, input *dynamodb.BatchGetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	count := 0
+	for _, ka := range input.RequestItems {
+		count += len(ka.Keys)
+	}
+	if count == 0 || count > batchGetMaxKeys {
+		return nil, validation("Too many items requested for the BatchGetItem call")
+	}
+
+	output := &dynamodb.BatchGetItemOutput{Responses: make(map[string][]map[string]types.AttributeValue), UnprocessedKeys: map[string]types.KeysAndAttributes{}}
+	var tc tableCapacity
+	for tableName, ka := range input.RequestItems {
+		t, err := c.table(&tableName)
+		if err != nil {
+			return nil, err
+		}
+
+		p := newParser(ka.ExpressionAttributeNames, nil)
+		project, err := parseProjectionExpression(ka.ProjectionExpression, p)
+		if err != nil {
+			return nil, err
+		}
+		if err = checkExpressionAttributes(p, ka.ExpressionAttributeNames, nil); err != nil {
+			return nil, err
+		}
+
+		seen := make(map[string]bool, len(ka.Keys))
+		responses := make([]map[string]types.AttributeValue, 0, len(ka.Keys))
+		for _, k := range ka.Keys {
+			key, err := t.key(k)
+			if err != nil {
+				return nil, err
+			}
+			if seen[key] {
+				return nil, validation("Provided list of item keys contains duplicates")
+			}
+			seen[key] = true
+
+			stored, ok := t.items[key]
+			if ok {
+				responses = append(responses, project(stored))
+			}
+			tc.add(t, readUnits(itemSize(stored), ka.ConsistentRead), 0)
+		}
+		output.Responses[tableName] = responses
+	}
+
+	output.ConsumedCapacity = tc.consumed(input.ReturnConsumedCapacity)
+	return output, nil
+}
+
+// BatchWriteItem puts and deletes items. All requests are always processed.
+func (c *Client) BatchWriteItem(ctx context.Context, input *dynamodb.BatchWriteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	count := 0
+	for _, requests := range input.RequestItems {
+		count += len(requests)
+	}
+	if count == 0 || count > batchWriteMaxItems {
+		return nil, validation("Too many items requested for the BatchWriteItem call")
+	}
+
+	var writes []*write
+	var tc tableCapacity
+	for tableName, requests := range input.RequestItems {
+		t, err := c.table(&tableName)
+		if err != nil {
+			return nil, err
+		}
+
+		seen := make(map[string]bool, len(requests))
+		for _, request := range requests {
+			var w *write
+			switch {
+			case request.PutRequest != nil:
+				w, err = t.put(request.PutRequest.Item, nil, nil, nil, "")
+			case request.DeleteRequest != nil:
+				w, err = t.delete(request.DeleteRequest.Key, nil, nil, nil, "")
+			default:
+				err = validation("Supplied AttributeValue has more than one datatypes set, must contain exactly one of the supported datatypes")
+			}
+			if err != nil {
+				return nil, err
+			}
+			if seen[w.key] {
+				return nil, validation("Provided list of item keys contains duplicates")
+			}
+			seen[w.key] = true
+			writes = append(writes, w)
+			tc.add(t, 0, w.writeUnits())
+		}
+	}
+
+	for _, w := range writes {
+		w.apply()
+	}
+
+	return &dynamodb.BatchWriteItemOutput{UnprocessedItems: map[string][]types.WriteRequest{}, ConsumedCapacity: tc.consumed(input.ReturnConsumedCapacity)}, nil
+}
+
+// TransactWriteItems applies all the writes if all their conditions are satisfied, or otherwise none of them and
+// returns a types.TransactionCanceledException with a reason for each.
+func (c *Client) TransactWriteItems(ctx context.Context, input *dynamodb.TransactWriteItemsInput, _ ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(input.TransactItems) == 0 || len(input.TransactItems) > transactionMaxItems {
+		return nil, validation("Member must have length less than or equal to %d", transactionMaxItems)
+	}
+
+	writes := make([]*write, len(input.TransactItems))
+	seen := make(map[string]bool, len(input.TransactItems))
+	var tc tableCapacity
+	for i, ti := range input.TransactItems {
+		var t *table
+		var w *write
+		var err error
+		switch {
+		case ti.Put != nil:
+			if t, err = c.table(ti.Put.TableName); err == nil {
+				w, err = t.put(ti.Put.Item, ti.Put.ConditionExpression, ti.Put.ExpressionAttributeNames, ti.Put.ExpressionAttributeValues, ti.Put.ReturnValuesOnConditionCheckFailure)
+			}
+		case ti.Update != nil:
+			if t, err = c.table(ti.Update.TableName); err == nil {
+				w, err = t.update(ti.Update.Key, ti.Update.UpdateExpression, ti.Update.ConditionExpression, ti.Update.ExpressionAttributeNames, ti.Update.ExpressionAttributeValues, ti.Update.ReturnValuesOnConditionCheckFailure)
+			}
+		case ti.Delete != nil:
+			if t, err = c.table(ti.Delete.TableName); err == nil {
+				w, err = t.delete(ti.Delete.Key, ti.Delete.ConditionExpression, ti.Delete.ExpressionAttributeNames, ti.Delete.ExpressionAttributeValues, ti.Delete.ReturnValuesOnConditionCheckFailure)
+			}
+		case ti.ConditionCheck != nil:
+			if t, err = c.table(ti.ConditionCheck.TableName); err == nil {
+				w, err = t.conditionCheck(ti.ConditionCheck.Key, ti.ConditionCheck.ConditionExpression, ti.ConditionCheck.ExpressionAttributeNames, ti.ConditionCheck.ExpressionAttributeValues, ti.ConditionCheck.ReturnValuesOnConditionCheckFailure)
+			}
+		default:
+			err = validation("TransactItems can only contain one of Check, Put, Update or Delete")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		target := aws.ToString(t.description.TableName) + "\x00" + w.key
+		if seen[target] {
+			return nil, validation("Transaction request cannot include multiple operations on one item")
+		}
+		seen[target] = true
+		writes[i] = w
+		tc.add(t, 0, 2*w.writeUnits())
+	}
+
+	reasons := make([]types.CancellationReason, len(writes))
+	codes := make([]string, len(writes))
+	cancelled := false
+	for i, w := range writes {
+		reasons[i], codes[i] = types.CancellationReason{Code: aws.String("None")}, "None"
+		if err := w.check(); err != nil {
+			reasons[i] = types.CancellationReason{Code: aws.String(conditionFailedCode), Message: aws.String(conditionFailedError)}
+			if w.returnOnFailure == types.ReturnValuesOnConditionCheckFailureAllOld {
+				reasons[i].Item = copyItem(w.before)
+			}
+			codes[i], cancelled = conditionFailedCode, true
+		}
+	}
+	if cancelled {
+		return nil, &types.TransactionCanceledException{
+			Message:             aws.String("Transaction cancelled, please refer cancellation reasons for specific reasons [" + strings.Join(codes, ", ") + "]"),
+			CancellationReasons: reasons,
+		}
+	}
+
+	for _, w := range writes {
+		w.apply()
+	}
+
+	return &dynamodb.TransactWriteItemsOutput{ConsumedCapacity: tc.consumed(input.ReturnConsumedCapacity)}, nil
+}
+
+// write is a pending change to one item. check tests its condition, and apply makes the change.
+type write struct {
+	t               *table
+	key             string
+	before          item // nil if the item doesn't exist
+	after           item // nil if the item is deleted (or only checked)
+	updated         []string
+	condition       condition
+	returnOnFailure types.ReturnValuesOnConditionCheckFailure
+	checkOnly       bool
+}
+
+func (w *write) check() error {
+	if w.condition == nil {
+		return nil
+	}
+
+	subject := w.before
+	if subject == nil {
+		subject = item{}
+	}
+	if w.condition(subject) {
+		return nil
+	}
+
+	ccf := &types.ConditionalCheckFailedException{Message: aws.String(conditionFailedError)}
+	if w.returnOnFailure == types.ReturnValuesOnConditionCheckFailureAllOld {
+		ccf.Item = copyItem(w.before)
+	}
+	return ccf
+}
+
+func (w *write) apply() {
+	if w.checkOnly {
+		return
+	}
+	if w.after == nil {
+		delete(w.t.items, w.key)
+	} else {
+		w.t.items[w.key] = w.after
+	}
+}
+
+func (t *table) put(i item, conditionExpression *string, names map[string]string, values map[string]types.AttributeValue, returnOnFailure types.ReturnValuesOnConditionCheckFailure) (*write, error) {
+	if err := t.validateItem(i); err != nil {
+		return nil, err
+	}
+
+	ks := t.indexes[""]
+	key := item{ks.pk: i[ks.pk]}
+	if ks.sk != "" {
+		key[ks.sk] = i[ks.sk]
+	}
+
+	w, err := t.newWrite(key, conditionExpression, names, values, returnOnFailure, nil)
+	if err != nil {
+		return nil, err
+	}
+	w.after = copyItem(i)
+	return w, nil
+}
+
+func (t *table) update(key item, updateExpression, conditionExpression *string, names map[string]string, values map[string]types.AttributeValue, returnOnFailure types.ReturnValuesOnConditionCheckFailure) (*write, error) {
+	var u update
+	w, err := t.newWrite(key, conditionExpression, names, values, returnOnFailure, func(p *parser) (err error) {
+		if updateExpression == nil {
+			return validation("UpdateExpression is required")
+		}
+		u, err = parseUpdate(*updateExpression, p)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	after := copyItem(w.before)
+	if after == nil {
+		after = copyItem(key)
+	}
+	if w.updated, err = u(after); err != nil {
+		return nil, err
+	}
+
+	for _, name := range w.updated {
+		if name == t.indexes[""].pk || name == t.indexes[""].sk {
+			return nil, validation("One or more parameter values were invalid: Cannot update attribute %s. This attribute is part of the key", name)
+		}
+	}
+	if err = t.validateItem(after); err != nil {
+		return nil, err
+	}
+
+	w.after = after
+	return w, nil
+}
+
+func (t *table) delete(key item, conditionExpression *string, names map[string]string, values map[string]types.AttributeValue, returnOnFailure types.ReturnValuesOnConditionCheckFailure) (*write, error) {
+	return t.newWrite(key, conditionExpression, names, values, returnOnFailure, nil)
+}
+
+func (t *table) conditionCheck(key item, conditionExpression *string, names map[string]string, values map[string]types.AttributeValue, returnOnFailure types.ReturnValuesOnConditionCheckFailure) (*write, error) {
+	if conditionExpression == nil {
+		return nil, validation("ConditionExpression is required for a ConditionCheck")
+	}
+	w, err := t.newWrite(key, conditionExpression, names, values, returnOnFailure, nil)
+	if err != nil {
+		return nil, err
+	}
+	w.checkOnly = true
+	return w, nil
+}
+
+// newWrite returns a write for the item with the key, parsing its condition and any other expressions.
+func (t *table) newWrite(keyItem item, conditionExpression *string, names map[string]string, values map[string]types.AttributeValue, returnOnFailure types.ReturnValuesOnConditionCheckFailure, parseOther func(*parser) error) (*write, error) {
+	key, err := t.key(keyItem)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &write{t: t, key: key, before: t.items[key], returnOnFailure: returnOnFailure}
+
+	p := newParser(names, values)
+	if parseOther != nil {
+		if err = parseOther(p); err != nil {
+			return nil, err
+		}
+	}
+	if conditionExpression != nil {
+		if w.condition, err = parseCondition(*conditionExpression, p); err != nil {
+			return nil, err
+		}
+	}
+	if err = checkExpressionAttributes(p, names, values); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// key returns the primary key of the item with the key's attributes, after checking they match the table's schema.
+func (t *table) key(k item) (string, error) {
+	ks := t.indexes[""]
+	expected := 1
+	if ks.sk != "" {
+		expected = 2
+	}
+	if len(k) != expected {
+		return "", validation("The provided key element does not match the schema")
+	}
+	for _, name := range []string{ks.pk, ks.sk} {
+		if name == "" {
+			continue
+		}
+		av, ok := k[name]
+		if !ok || typeOf(av) != string(t.attributeType(name)) {
+			return "", validation("The provided key element does not match the schema")
+		}
+		if empty(av) {
+			return "", validation("One or more parameter values are not valid. The AttributeValue for a key attribute cannot contain an empty string value. Key: %s", name)
+		}
+	}
+	return keyString(k, ks.pk) + "\x00" + keyString(k, ks.sk), nil
+}
+
+func keyString(i item, name string) string {
+	if name == "" {
+		return ""
+	}
+	switch v := i[name].(type) {
+	case *types.AttributeValueMemberS:
+		return "S" + v.Value
+	case *types.AttributeValueMemberN:
+		if n, ok := number(v.Value); ok {
+			return "N" + formatNumber(n)
+		}
+		return "N" + v.Value
+	case *types.AttributeValueMemberB:
+		return "B" + string(v.Value)
+	}
+	return ""
+}
+
+func (t *table) attributeType(name string) types.ScalarAttributeType {
+	for _, ad := range t.description.AttributeDefinitions {
+		if aws.ToString(ad.AttributeName) == name {
+			return ad.AttributeType
+		}
+	}
+	return ""
+}
+
+func empty(av types.AttributeValue) bool {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return v.Value == ""
+	case *types.AttributeValueMemberB:
+		return len(v.Value) == 0
+	}
+	return false
+}
+
+// validateItem checks that an item to be stored has valid keys, valid index key attributes (if it has them), and
+// values DynamoDB accepts.
+func (t *table) validateItem(i item) error {
+	ks := t.indexes[""]
+	for _, name := range []string{ks.pk, ks.sk} {
+		if name == "" {
+			continue
+		}
+		av, ok := i[name]
+		if !ok {
+			return validation("One or more parameter values were invalid: Missing the key %s in the item", name)
+		}
+		if typeOf(av) != string(t.attributeType(name)) {
+			return validation("One or more parameter values were invalid: Type mismatch for key %s expected: %s actual: %s", name, t.attributeType(name), typeOf(av))
+		}
+		if empty(av) {
+			return validation("One or more parameter values are not valid. The AttributeValue for a key attribute cannot contain an empty string value. Key: %s", name)
+		}
+	}
+
+	for _, index := range t.indexes {
+		for _, name := range []string{index.pk, index.sk} {
+			if name == "" {
+				continue
+			}
+			if av, ok := i[name]; ok {
+				if typeOf(av) != string(t.attributeType(name)) {
+					return validation("One or more parameter values were invalid: Type mismatch for Index Key %s Expected: %s Actual: %s IndexName: %s", name, t.attributeType(name), typeOf(av), index.name)
+				}
+				if empty(av) {
+					return validation("One or more parameter values are not valid. A value specified for a secondary index key is not supported. The AttributeValue for a key attribute cannot contain an empty string value. IndexName: %s, IndexKey: %s", index.name, name)
+				}
+			}
+		}
+	}
+
+	for name, av := range i {
+		if err := validateValue(name, av); err != nil {
+			return err
+		}
+	}
+
+	if itemSize(i) > maxItemSize {
+		return validation("Item size has exceeded the maximum allowed size")
+	}
+
+	return nil
+}
+
+func validateValue(name string, av types.AttributeValue) error {
+	switch v := av.(type) {
+	case nil:
+		return validation("Supplied AttributeValue is empty, must contain exactly one of the supported datatypes")
+	case *types.AttributeValueMemberN:
+		if _, ok := number(v.Value); !ok {
+			return validation("A value provided cannot be converted into a number")
+		}
+	case *types.AttributeValueMemberSS:
+		if len(v.Value) == 0 || len(sorted(v.Value)) != len(distinct(v.Value)) {
+			return validation("One or more parameter values were invalid: An string set may not be empty or have duplicates; attribute: %s", name)
+		}
+	case *types.AttributeValueMemberNS:
+		if len(v.Value) == 0 || len(distinct(sortedNumbers(v.Value))) != len(v.Value) {
+			return validation("One or more parameter values were invalid: An number set may not be empty or have duplicates; attribute: %s", name)
+		}
+	case *types.AttributeValueMemberBS:
+		if len(v.Value) == 0 {
+			return validation("One or more parameter values were invalid: An binary set may not be empty; attribute: %s", name)
+		}
+	case *types.AttributeValueMemberL:
+		for _, e := range v.Value {
+			if err := validateValue(name, e); err != nil {
+				return err
+			}
+		}
+	case *types.AttributeValueMemberM:
+		for k, e := range v.Value {
+			if err := validateValue(k, e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func distinct(values []string) map[string]bool {
+	m := make(map[string]bool, len(values))
+	for _, v := range values {
+		m[v] = true
+	}
+	return m
+}
+
+// index returns the key schema of the named index (or the table's if the name is nil).
+func (t *table) index(name *string, consistentRead *bool) (*keySchema, error) {
+	ks, ok := t.indexes[aws.ToString(name)]
+	if !ok {
+		return nil, validation("The table does not have the specified index: %s", aws.ToString(name))
+	}
+	if ks.global && aws.ToBool(consistentRead) {
+		return nil, validation("Consistent reads are not supported on global secondary indexes")
+	}
+	return ks, nil
+}
+
+// indexItems returns the items in the index (i.e. that have its key attributes) that match.
+func (t *table) indexItems(ks *keySchema, match func(item) bool) []item {
+	var items []item
+	for _, i := range t.items {
+		if _, ok := i[ks.pk]; !ok {
+			continue
+		}
+		if _, ok := i[ks.sk]; ks.sk != "" && !ok {
+			continue
+		}
+		if match(i) {
+			items = append(items, i)
+		}
+	}
+	return items
+}
+
+// sortItems orders the items as the index does: by partition key and then sort key, with items that have the same
+// index keys ordered by the table's keys.
+func (t *table) sortItems(ks *keySchema, items []item, ascending bool) {
+	attributes := t.orderAttributes(ks)
+	sort.Slice(items, func(i, j int) bool {
+		c := compareItems(items[i], items[j], attributes)
+		if ascending {
+			return c < 0
+		}
+		return c > 0
+	})
+}
+
+func (t *table) orderAttributes(ks *keySchema) []string {
+	var attributes []string
+	for _, name := range []string{ks.pk, ks.sk, t.indexes[""].pk, t.indexes[""].sk} {
+		if name != "" && !contained(attributes, name) {
+			attributes = append(attributes, name)
+		}
+	}
+	return attributes
+}
+
+func contained(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func compareItems(a, b item, attributes []string) int {
+	for _, name := range attributes {
+		av, bv := a[name], b[name]
+		if av == nil || bv == nil {
+			continue
+		}
+		if c, ok := compare(av, bv); ok && c != 0 {
+			return c
+		} else if !ok {
+			if c = strings.Compare(keyString(a, name), keyString(b, name)); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+// read holds the parts of a query or scan that apply to the items read.
+type read struct {
+	filter      condition
+	project     projection
+	count       bool
+	limit       int
+	scannedSize int // the total size of the items read, before filtering
+}
+
+func newRead(p *parser, filterExpression, projectionExpression *string, sel types.Select, limit *int32) (*read, error) {
+	r := &read{count: sel == types.SelectCount}
+	if limit != nil {
+		if *limit < 1 {
+			return nil, validation("1 validation error detected: Value '%d' at 'limit' failed to satisfy constraint: Member must have value greater than or equal to 1", *limit)
+		}
+		r.limit = int(*limit)
+	}
+
+	var err error
+	if filterExpression != nil {
+		if r.filter, err = parseCondition(*filterExpression, p); err != nil {
+			return nil, err
+		}
+	}
+	if r.project, err = parseProjectionExpression(projectionExpression, p); err != nil {
+		return nil, err
+	}
+	if r.count && projectionExpression != nil {
+		return nil, validation("Cannot specify the AttributesToGet or ProjectionExpression when choosing to get only the Count")
+	}
+	return r, nil
+}
+
+// read returns the page of items that follows the exclusive start key, if there is one.
+func (r *read) read(t *table, ks *keySchema, items []item, exclusiveStartKey item, ascending bool) ([]map[string]types.AttributeValue, int32, int32, map[string]types.AttributeValue) {
+	attributes := t.orderAttributes(ks)
+	if exclusiveStartKey != nil {
+		start := 0
+		for start < len(items) {
+			c := compareItems(items[start], exclusiveStartKey, attributes)
+			if ascending && c > 0 || !ascending && c < 0 {
+				break
+			}
+			start++
+		}
+		items = items[start:]
+	}
+
+	var results []map[string]types.AttributeValue
+	var count, scanned int32
+	var lastEvaluatedKey map[string]types.AttributeValue
+	for _, i := range items {
+		scanned++
+		r.scannedSize += itemSize(i)
+		if r.filter == nil || r.filter(i) {
+			count++
+			if !r.count {
+				results = append(results, r.project(t.projected(ks, i)))
+			}
+		}
+		if r.limit > 0 && int(scanned) == r.limit {
+			lastEvaluatedKey = make(map[string]types.AttributeValue, len(attributes))
+			for _, name := range attributes {
+				lastEvaluatedKey[name] = copyValue(i[name])
+			}
+			break
+		}
+	}
+
+	if results == nil && !r.count {
+		results = []map[string]types.AttributeValue{}
+	}
+	return results, count, scanned, lastEvaluatedKey
+}
+
+// projected returns the attributes of the item that the index projects.
+func (t *table) projected(ks *keySchema, i item) item {
+	if ks.name == "" || ks.projection == nil || ks.projection.ProjectionType == types.ProjectionTypeAll {
+		return i
+	}
+
+	projected := make(item)
+	for _, name := range t.orderAttributes(ks) {
+		projected[name] = i[name]
+	}
+	if ks.projection.ProjectionType == types.ProjectionTypeInclude {
+		for _, name := range ks.projection.NonKeyAttributes {
+			if av, ok := i[name]; ok {
+				projected[name] = av
+			}
+		}
+	}
+	return projected
+}
+
+func parseProjectionExpression(expression *string, p *parser) (projection, error) {
+	if expression == nil {
+		return copyItem, nil
+	}
+	return parseProjection(*expression, p)
+}
+
+// checkExpressionAttributes returns an error if the placeholder maps are empty (rather than nil) or have entries that
+// the expressions didn't use, as DynamoDB does.
+func checkExpressionAttributes(p *parser, names map[string]string, values map[string]types.AttributeValue) error {
+	if names != nil && len(names) == 0 {
+		return validation("ExpressionAttributeNames must not be empty")
+	}
+	if values != nil && len(values) == 0 {
+		return validation("ExpressionAttributeValues must not be empty")
+	}
+	for name, av := range values {
+		if err := validateValue(name, av); err != nil {
+			return err
+		}
+	}
+	return p.checkUsed()
+}
+
+func validation(format string, args ...any) error {
+	return &smithy.GenericAPIError{Code: "ValidationException", Message: fmt.Sprintf(format, args...), Fault: smithy.FaultClient}
+}
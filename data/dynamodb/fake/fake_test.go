@@ -0,0 +1,403 @@
+package fake_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/jt0/gomer/_test/assert"
+	ddb "github.com/jt0/gomer/data/dynamodb"
+	"github.com/jt0/gomer/data/dynamodb/fake"
+)
+
+var _ ddb.Client = (*fake.Client)(nil)
+
+var ctx = context.Background()
+
+func s(v string) types.AttributeValue { return &types.AttributeValueMemberS{Value: v} }
+func n(v int) types.AttributeValue    { return &types.AttributeValueMemberN{Value: strconv.Itoa(v)} }
+
+func newClient(t *testing.T) *fake.Client {
+	client := fake.New()
+	_, err := client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName: aws.String("t"),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("PK"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("SK"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("GPK"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("Rank"), AttributeType: types.ScalarAttributeTypeN},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("PK"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("SK"), KeyType: types.KeyTypeRange},
+		},
+		LocalSecondaryIndexes: []types.LocalSecondaryIndex{{
+			IndexName:  aws.String("lsi"),
+			KeySchema:  []types.KeySchemaElement{{AttributeName: aws.String("PK"), KeyType: types.KeyTypeHash}, {AttributeName: aws.String("Rank"), KeyType: types.KeyTypeRange}},
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+		}},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{{
+			IndexName:  aws.String("gsi"),
+			KeySchema:  []types.KeySchemaElement{{AttributeName: aws.String("GPK"), KeyType: types.KeyTypeHash}},
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeKeysOnly},
+		}},
+	})
+	assert.Success(t, err)
+	return client
+}
+
+func put(t *testing.T, client *fake.Client, items ...map[string]types.AttributeValue) {
+	for _, i := range items {
+		_, err := client.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String("t"), Item: i})
+		assert.Success(t, err)
+	}
+}
+
+func sks(items []map[string]types.AttributeValue) []string {
+	var values []string
+	for _, i := range items {
+		values = append(values, i["SK"].(*types.AttributeValueMemberS).Value)
+	}
+	return values
+}
+
+func errorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return ""
+}
+
+func TestClient_Query(t *testing.T) {
+	client := newClient(t)
+	put(t, client,
+		map[string]types.AttributeValue{"PK": s("a"), "SK": s("x#2"), "Rank": n(3), "GPK": s("g")},
+		map[string]types.AttributeValue{"PK": s("a"), "SK": s("x#1"), "Rank": n(10)},
+		map[string]types.AttributeValue{"PK": s("a"), "SK": s("y#1"), "Rank": n(2), "GPK": s("g")},
+		map[string]types.AttributeValue{"PK": s("b"), "SK": s("x#1"), "Rank": n(1)},
+	)
+
+	tests := []struct {
+		name     string
+		input    dynamodb.QueryInput
+		expected []string
+	}{
+		{"partition", dynamodb.QueryInput{
+			KeyConditionExpression:    aws.String("PK = :pk"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":pk": s("a")},
+		}, []string{"x#1", "x#2", "y#1"}},
+		{"begins_with", dynamodb.QueryInput{
+			KeyConditionExpression:    aws.String("PK = :pk AND begins_with(SK, :prefix)"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":pk": s("a"), ":prefix": s("x#")},
+			ScanIndexForward:          aws.Bool(false),
+		}, []string{"x#2", "x#1"}},
+		{"filter", dynamodb.QueryInput{
+			KeyConditionExpression:    aws.String("PK = :pk"),
+			FilterExpression:          aws.String("#rank BETWEEN :low AND :high"),
+			ExpressionAttributeNames:  map[string]string{"#rank": "Rank"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{":pk": s("a"), ":low": n(2), ":high": n(3)},
+		}, []string{"x#2", "y#1"}},
+		{"local index", dynamodb.QueryInput{
+			IndexName:                 aws.String("lsi"),
+			KeyConditionExpression:    aws.String("PK = :pk AND #rank > :rank"),
+			ExpressionAttributeNames:  map[string]string{"#rank": "Rank"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{":pk": s("a"), ":rank": n(2)},
+		}, []string{"x#2", "x#1"}},
+		{"global index", dynamodb.QueryInput{
+			IndexName:                 aws.String("gsi"),
+			KeyConditionExpression:    aws.String("GPK = :g"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":g": s("g")},
+		}, []string{"x#2", "y#1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.input.TableName = aws.String("t")
+			output, err := client.Query(ctx, &tt.input)
+			assert.Success(t, err)
+			assert.Equals(t, tt.expected, sks(output.Items))
+		})
+	}
+}
+
+func TestClient_QueryPagination(t *testing.T) {
+	client := newClient(t)
+	for i := 0; i < 5; i++ {
+		put(t, client, map[string]types.AttributeValue{"PK": s("a"), "SK": s(strconv.Itoa(i)), "Rank": n(i)})
+	}
+
+	var pages [][]string
+	var startKey map[string]types.AttributeValue
+	for {
+		output, err := client.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 aws.String("t"),
+			KeyConditionExpression:    aws.String("PK = :pk"),
+			FilterExpression:          aws.String("#rank <> :skip"),
+			ExpressionAttributeNames:  map[string]string{"#rank": "Rank"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{":pk": s("a"), ":skip": n(1)},
+			Limit:                     aws.Int32(2),
+			ExclusiveStartKey:         startKey,
+		})
+		assert.Success(t, err)
+		pages = append(pages, sks(output.Items))
+		if startKey = output.LastEvaluatedKey; startKey == nil {
+			break
+		}
+	}
+
+	// The limit applies to the items read before they are filtered
+	assert.Equals(t, [][]string{{"0"}, {"2", "3"}, {"4"}}, pages)
+}
+
+func TestClient_QueryErrors(t *testing.T) {
+	client := newClient(t)
+
+	tests := []struct {
+		name  string
+		input dynamodb.QueryInput
+	}{
+		{"missing partition key", dynamodb.QueryInput{
+			KeyConditionExpression:    aws.String("SK = :sk"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":sk": s("x")},
+		}},
+		{"non-key attribute", dynamodb.QueryInput{
+			KeyConditionExpression:    aws.String("PK = :pk AND Absent = :sk"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":pk": s("a"), ":sk": s("x")},
+		}},
+		{"unused value", dynamodb.QueryInput{
+			KeyConditionExpression:    aws.String("PK = :pk"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":pk": s("a"), ":other": s("x")},
+		}},
+		{"reserved word", dynamodb.QueryInput{
+			KeyConditionExpression:    aws.String("PK = :pk"),
+			FilterExpression:          aws.String("Name = :name"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":pk": s("a"), ":name": s("x")},
+		}},
+		{"consistent global index", dynamodb.QueryInput{
+			IndexName:                 aws.String("gsi"),
+			ConsistentRead:            aws.Bool(true),
+			KeyConditionExpression:    aws.String("GPK = :g"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":g": s("g")},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.input.TableName = aws.String("t")
+			_, err := client.Query(ctx, &tt.input)
+			assert.Equals(t, "ValidationException", errorCode(err))
+		})
+	}
+}
+
+func TestClient_ConditionalWrites(t *testing.T) {
+	client := newClient(t)
+	item := map[string]types.AttributeValue{"PK": s("a"), "SK": s("1"), "Version": n(1)}
+	put(t, client, item)
+
+	_, err := client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String("t"),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(PK)"),
+	})
+	var ccf *types.ConditionalCheckFailedException
+	assert.Assert(t, errors.As(err, &ccf), "expected ConditionalCheckFailedException, got %v", err)
+
+	output, err := client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String("t"),
+		Key:                       map[string]types.AttributeValue{"PK": s("a"), "SK": s("1")},
+		UpdateExpression:          aws.String("SET Version = Version + :one, Tags = list_append(if_not_exists(Tags, :empty), :tags) REMOVE Obsolete"),
+		ConditionExpression:       aws.String("Version = :one"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":one": n(1), ":empty": &types.AttributeValueMemberL{}, ":tags": &types.AttributeValueMemberL{Value: []types.AttributeValue{s("t")}}},
+		ReturnValues:              types.ReturnValueAllNew,
+	})
+	assert.Success(t, err)
+	assert.Equals(t, n(2), output.Attributes["Version"])
+	assert.Equals(t, 1, len(output.Attributes["Tags"].(*types.AttributeValueMemberL).Value))
+
+	_, err = client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String("t"),
+		Key:                       map[string]types.AttributeValue{"PK": s("a"), "SK": s("1")},
+		UpdateExpression:          aws.String("SET SK = :sk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":sk": s("2")},
+	})
+	assert.Equals(t, "ValidationException", errorCode(err))
+}
+
+func TestClient_TransactWriteItems(t *testing.T) {
+	client := newClient(t)
+	put(t, client, map[string]types.AttributeValue{"PK": s("a"), "SK": s("1")})
+
+	_, err := client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: []types.TransactWriteItem{
+		{Put: &types.Put{TableName: aws.String("t"), Item: map[string]types.AttributeValue{"PK": s("b"), "SK": s("1")}}},
+		{Put: &types.Put{TableName: aws.String("t"), Item: map[string]types.AttributeValue{"PK": s("a"), "SK": s("1")}, ConditionExpression: aws.String("attribute_not_exists(PK)")}},
+	}})
+	var tce *types.TransactionCanceledException
+	assert.Assert(t, errors.As(err, &tce), "expected TransactionCanceledException, got %v", err)
+	assert.Equals(t, "None", aws.ToString(tce.CancellationReasons[0].Code))
+	assert.Equals(t, "ConditionalCheckFailed", aws.ToString(tce.CancellationReasons[1].Code))
+
+	output, err := client.GetItem(ctx, &dynamodb.GetItemInput{TableName: aws.String("t"), Key: map[string]types.AttributeValue{"PK": s("b"), "SK": s("1")}})
+	assert.Success(t, err)
+	assert.Assert(t, output.Item == nil, "expected the cancelled transaction to not write any item")
+
+	_, err = client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: []types.TransactWriteItem{
+		{Put: &types.Put{TableName: aws.String("t"), Item: map[string]types.AttributeValue{"PK": s("a"), "SK": s("1")}}},
+		{Delete: &types.Delete{TableName: aws.String("t"), Key: map[string]types.AttributeValue{"PK": s("a"), "SK": s("1")}}},
+	}})
+	assert.Equals(t, "ValidationException", errorCode(err))
+}
+
+func TestClient_MissingTable(t *testing.T) {
+	_, err := fake.New().DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String("missing")})
+	var rnf *types.ResourceNotFoundException
+	assert.Assert(t, errors.As(err, &rnf), "expected ResourceNotFoundException, got %v", err)
+}
+
+func TestClient_ConditionExpressions(t *testing.T) {
+	client := newClient(t)
+	item := map[string]types.AttributeValue{
+		"PK":     s("a"),
+		"SK":     s("1"),
+		"Title":  s("hello world"),
+		"Colors": &types.AttributeValueMemberSS{Value: []string{"red", "blue"}},
+		"Info":   &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"Sizes": &types.AttributeValueMemberL{Value: []types.AttributeValue{n(4), n(8)}}}},
+	}
+	put(t, client, item)
+
+	tests := []struct {
+		condition string
+		satisfied bool
+	}{
+		{"attribute_exists(Title) AND attribute_not_exists(Absent)", true},
+		{"attribute_type(Colors, :ss)", true},
+		{"contains(Colors, :red) AND contains(Title, :world)", true},
+		{"begins_with(Title, :world)", false},
+		{"Info.Sizes[1] = :eight", true},
+		{"Info.Sizes[2] = :eight", false},
+		{"size(Colors) = :two AND size(Info.Sizes) IN (:one, :two)", true},
+		{"NOT (Title = :world OR size(Title) < :two)", true},
+		{"Absent <> :one", false}, // comparisons with a missing attribute aren't satisfied
+	}
+
+	values := map[string]types.AttributeValue{":ss": s("SS"), ":red": s("red"), ":world": s("world"), ":eight": n(8), ":one": n(1), ":two": n(2)}
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			used := make(map[string]types.AttributeValue)
+			for k, v := range values {
+				if strings.Contains(tt.condition, k) {
+					used[k] = v
+				}
+			}
+			if len(used) == 0 {
+				used = nil
+			}
+
+			_, err := client.PutItem(ctx, &dynamodb.PutItemInput{
+				TableName:                 aws.String("t"),
+				Item:                      item,
+				ConditionExpression:       aws.String(tt.condition),
+				ExpressionAttributeValues: used,
+			})
+			if tt.satisfied {
+				assert.Success(t, err)
+			} else {
+				var ccf *types.ConditionalCheckFailedException
+				assert.Assert(t, errors.As(err, &ccf), "expected ConditionalCheckFailedException, got %v", err)
+			}
+		})
+	}
+}
+
+func TestClient_UpdateSets(t *testing.T) {
+	client := newClient(t)
+	key := map[string]types.AttributeValue{"PK": s("a"), "SK": s("1")}
+
+	update := func(expression string, values map[string]types.AttributeValue) map[string]types.AttributeValue {
+		output, err := client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:                 aws.String("t"),
+			Key:                       key,
+			UpdateExpression:          aws.String(expression),
+			ExpressionAttributeValues: values,
+			ReturnValues:              types.ReturnValueUpdatedNew,
+		})
+		assert.Success(t, err)
+		return output.Attributes
+	}
+
+	attributes := update("ADD Colors :colors, Tally :two", map[string]types.AttributeValue{
+		":colors": &types.AttributeValueMemberSS{Value: []string{"red", "blue"}},
+		":two":    n(2),
+	})
+	assert.Equals(t, n(2), attributes["Tally"])
+
+	attributes = update("DELETE Colors :red ADD Tally :two", map[string]types.AttributeValue{
+		":red": &types.AttributeValueMemberSS{Value: []string{"red"}},
+		":two": n(2),
+	})
+	assert.Equals(t, []string{"blue"}, attributes["Colors"].(*types.AttributeValueMemberSS).Value)
+	assert.Equals(t, n(4), attributes["Tally"])
+
+	// Removing the last element of a set removes the attribute
+	attributes = update("DELETE Colors :blue", map[string]types.AttributeValue{":blue": &types.AttributeValueMemberSS{Value: []string{"blue"}}})
+	_, ok := attributes["Colors"]
+	assert.Assert(t, !ok, "expected Colors to be removed")
+}
+
+func TestClient_ConsumedCapacity(t *testing.T) {
+	client := newClient(t)
+
+	// Writes use a unit per KB of the larger of the old and new items
+	big := strings.Repeat("x", 1500)
+	output, err := client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:              aws.String("t"),
+		Item:                   map[string]types.AttributeValue{"PK": s("a"), "SK": s("1"), "GPK": s("g"), "Data": s(big)},
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+	})
+	assert.Success(t, err)
+	assert.Equals(t, 2.0, aws.ToFloat64(output.ConsumedCapacity.WriteCapacityUnits))
+	assert.Equals(t, "t", aws.ToString(output.ConsumedCapacity.TableName))
+	assert.Nil(t, output.ConsumedCapacity.Table)
+
+	// Reads use a unit per 4 KB, or half that when eventually consistent
+	get, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:              aws.String("t"),
+		Key:                    map[string]types.AttributeValue{"PK": s("a"), "SK": s("1")},
+		ConsistentRead:         aws.Bool(true),
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+	})
+	assert.Success(t, err)
+	assert.Equals(t, 1.0, aws.ToFloat64(get.ConsumedCapacity.Table.ReadCapacityUnits))
+
+	query, err := client.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 aws.String("t"),
+		IndexName:                 aws.String("gsi"),
+		KeyConditionExpression:    aws.String("GPK = :g"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":g": s("g")},
+		ReturnConsumedCapacity:    types.ReturnConsumedCapacityIndexes,
+	})
+	assert.Success(t, err)
+	assert.Equals(t, 0.5, aws.ToFloat64(query.ConsumedCapacity.GlobalSecondaryIndexes["gsi"].ReadCapacityUnits))
+
+	// Transactional writes use twice as much
+	transact, err := client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems:          []types.TransactWriteItem{{Delete: &types.Delete{TableName: aws.String("t"), Key: map[string]types.AttributeValue{"PK": s("a"), "SK": s("1")}}}},
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+	})
+	assert.Success(t, err)
+	assert.Equals(t, 1, len(transact.ConsumedCapacity))
+	assert.Equals(t, 4.0, aws.ToFloat64(transact.ConsumedCapacity[0].WriteCapacityUnits))
+
+	// Nothing is returned unless requested
+	scan, err := client.Scan(ctx, &dynamodb.ScanInput{TableName: aws.String("t")})
+	assert.Success(t, err)
+	assert.Nil(t, scan.ConsumedCapacity)
+}
@@ -0,0 +1,282 @@
+package fake
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type item = map[string]types.AttributeValue
+
+// compare returns -1, 0, or 1 as a is less than, equal to, or greater than b, and false if the values aren't both
+// strings, numbers, or binaries. Strings and binaries compare bytewise, as DynamoDB orders them.
+func compare(a, b types.AttributeValue) (int, bool) {
+	switch av := a.(type) {
+	case *types.AttributeValueMemberS:
+		if bv, ok := b.(*types.AttributeValueMemberS); ok {
+			return strings.Compare(av.Value, bv.Value), true
+		}
+	case *types.AttributeValueMemberN:
+		if bv, ok := b.(*types.AttributeValueMemberN); ok {
+			an, aOk := number(av.Value)
+			bn, bOk := number(bv.Value)
+			if aOk && bOk {
+				return an.Cmp(bn), true
+			}
+		}
+	case *types.AttributeValueMemberB:
+		if bv, ok := b.(*types.AttributeValueMemberB); ok {
+			return bytes.Compare(av.Value, bv.Value), true
+		}
+	}
+	return 0, false
+}
+
+// equal returns true if a and b are the same type and value. Sets are equal if they have the same elements.
+func equal(a, b types.AttributeValue) bool {
+	switch av := a.(type) {
+	case *types.AttributeValueMemberS, *types.AttributeValueMemberN, *types.AttributeValueMemberB:
+		c, ok := compare(a, b)
+		return ok && c == 0
+	case *types.AttributeValueMemberBOOL:
+		bv, ok := b.(*types.AttributeValueMemberBOOL)
+		return ok && av.Value == bv.Value
+	case *types.AttributeValueMemberNULL:
+		_, ok := b.(*types.AttributeValueMemberNULL)
+		return ok
+	case *types.AttributeValueMemberSS:
+		bv, ok := b.(*types.AttributeValueMemberSS)
+		return ok && strings.Join(sorted(av.Value), "\x00") == strings.Join(sorted(bv.Value), "\x00")
+	case *types.AttributeValueMemberNS:
+		bv, ok := b.(*types.AttributeValueMemberNS)
+		return ok && strings.Join(sortedNumbers(av.Value), "\x00") == strings.Join(sortedNumbers(bv.Value), "\x00")
+	case *types.AttributeValueMemberBS:
+		bv, ok := b.(*types.AttributeValueMemberBS)
+		if !ok || len(av.Value) != len(bv.Value) {
+			return false
+		}
+		for _, e := range av.Value {
+			if !containsBytes(bv.Value, e) {
+				return false
+			}
+		}
+		return true
+	case *types.AttributeValueMemberL:
+		bv, ok := b.(*types.AttributeValueMemberL)
+		if !ok || len(av.Value) != len(bv.Value) {
+			return false
+		}
+		for i := range av.Value {
+			if !equal(av.Value[i], bv.Value[i]) {
+				return false
+			}
+		}
+		return true
+	case *types.AttributeValueMemberM:
+		bv, ok := b.(*types.AttributeValueMemberM)
+		if !ok || len(av.Value) != len(bv.Value) {
+			return false
+		}
+		for k, v := range av.Value {
+			if w, ok := bv.Value[k]; !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func sorted(values []string) []string {
+	s := append([]string(nil), values...)
+	sort.Strings(s)
+	return s
+}
+
+func sortedNumbers(values []string) []string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		if n, ok := number(v); ok {
+			s[i] = formatNumber(n)
+		} else {
+			s[i] = v
+		}
+	}
+	sort.Strings(s)
+	return s
+}
+
+func containsBytes(values [][]byte, value []byte) bool {
+	for _, v := range values {
+		if bytes.Equal(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func number(s string) (*big.Rat, bool) {
+	return new(big.Rat).SetString(strings.TrimSpace(s))
+}
+
+// formatNumber returns n as DynamoDB would: an integer without a decimal point, or otherwise the shortest exact
+// decimal representation.
+func formatNumber(n *big.Rat) string {
+	if n.IsInt() {
+		return n.Num().String()
+	}
+	s := n.FloatString(38)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// typeOf returns the DynamoDB type descriptor of the value, e.g. "S" or "NS".
+func typeOf(av types.AttributeValue) string {
+	switch av.(type) {
+	case *types.AttributeValueMemberS:
+		return "S"
+	case *types.AttributeValueMemberN:
+		return "N"
+	case *types.AttributeValueMemberB:
+		return "B"
+	case *types.AttributeValueMemberBOOL:
+		return "BOOL"
+	case *types.AttributeValueMemberNULL:
+		return "NULL"
+	case *types.AttributeValueMemberSS:
+		return "SS"
+	case *types.AttributeValueMemberNS:
+		return "NS"
+	case *types.AttributeValueMemberBS:
+		return "BS"
+	case *types.AttributeValueMemberL:
+		return "L"
+	case *types.AttributeValueMemberM:
+		return "M"
+	}
+	return ""
+}
+
+// copyValue returns a deep copy of the value so that stored items don't share state with callers.
+func copyValue(av types.AttributeValue) types.AttributeValue {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return &types.AttributeValueMemberS{Value: v.Value}
+	case *types.AttributeValueMemberN:
+		return &types.AttributeValueMemberN{Value: v.Value}
+	case *types.AttributeValueMemberB:
+		return &types.AttributeValueMemberB{Value: append([]byte(nil), v.Value...)}
+	case *types.AttributeValueMemberBOOL:
+		return &types.AttributeValueMemberBOOL{Value: v.Value}
+	case *types.AttributeValueMemberNULL:
+		return &types.AttributeValueMemberNULL{Value: v.Value}
+	case *types.AttributeValueMemberSS:
+		return &types.AttributeValueMemberSS{Value: append([]string(nil), v.Value...)}
+	case *types.AttributeValueMemberNS:
+		return &types.AttributeValueMemberNS{Value: append([]string(nil), v.Value...)}
+	case *types.AttributeValueMemberBS:
+		values := make([][]byte, len(v.Value))
+		for i, b := range v.Value {
+			values[i] = append([]byte(nil), b...)
+		}
+		return &types.AttributeValueMemberBS{Value: values}
+	case *types.AttributeValueMemberL:
+		values := make([]types.AttributeValue, len(v.Value))
+		for i, e := range v.Value {
+			values[i] = copyValue(e)
+		}
+		return &types.AttributeValueMemberL{Value: values}
+	case *types.AttributeValueMemberM:
+		return &types.AttributeValueMemberM{Value: copyItem(v.Value)}
+	}
+	return av
+}
+
+func copyItem(i item) item {
+	if i == nil {
+		return nil
+	}
+	c := make(item, len(i))
+	for k, v := range i {
+		c[k] = copyValue(v)
+	}
+	return c
+}
+
+// size returns the value's size as the size function reports it: the length of a string or binary, or the number of
+// elements in a set, list, or map.
+func size(av types.AttributeValue) (int, bool) {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return len(v.Value), true
+	case *types.AttributeValueMemberB:
+		return len(v.Value), true
+	case *types.AttributeValueMemberSS:
+		return len(v.Value), true
+	case *types.AttributeValueMemberNS:
+		return len(v.Value), true
+	case *types.AttributeValueMemberBS:
+		return len(v.Value), true
+	case *types.AttributeValueMemberL:
+		return len(v.Value), true
+	case *types.AttributeValueMemberM:
+		return len(v.Value), true
+	}
+	return 0, false
+}
+
+// itemSize approximates DynamoDB's item size: the lengths of the attribute names plus the sizes of their values.
+func itemSize(i item) int {
+	total := 0
+	for name, av := range i {
+		total += len(name) + valueSize(av)
+	}
+	return total
+}
+
+func valueSize(av types.AttributeValue) int {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return len(v.Value)
+	case *types.AttributeValueMemberN:
+		return (len(v.Value)+1)/2 + 1
+	case *types.AttributeValueMemberB:
+		return len(v.Value)
+	case *types.AttributeValueMemberBOOL, *types.AttributeValueMemberNULL:
+		return 1
+	case *types.AttributeValueMemberSS:
+		total := 0
+		for _, s := range v.Value {
+			total += len(s)
+		}
+		return total
+	case *types.AttributeValueMemberNS:
+		total := 0
+		for _, s := range v.Value {
+			total += (len(s)+1)/2 + 1
+		}
+		return total
+	case *types.AttributeValueMemberBS:
+		total := 0
+		for _, b := range v.Value {
+			total += len(b)
+		}
+		return total
+	case *types.AttributeValueMemberL:
+		total := 3
+		for _, e := range v.Value {
+			total += 1 + valueSize(e)
+		}
+		return total
+	case *types.AttributeValueMemberM:
+		total := 3
+		for k, e := range v.Value {
+			total += 1 + len(k) + valueSize(e)
+		}
+		return total
+	}
+	return 0
+}
@@ -33,14 +33,16 @@ type keyField struct {
 	name      string
 	preferred bool
 	ascending bool
+	lexical   bool // whether the field's values are ordered the same way as their string key values
 }
 
 type candidate struct {
-	index     *index
-	preferred bool
-	ascending bool
-	skLength  int
-	skMissing int
+	index      *index
+	preferred  bool
+	ascending  bool
+	orderField string // the sort key field that orders the results
+	skLength   int
+	skMissing  int
 }
 
 func (i *index) friendlyName() string {
@@ -87,13 +89,16 @@ func safeAttributeType(attributeType string) (string, gomerr.Gomerr) {
 // indexFor attempts to find the best index match for the provided queryable. The definition of "best" is the index
 // that has the greatest number of matching attributes present in the query.
 //
+// A sort key field with a range condition (see rangeCondition) counts as present. If the data.Queryable is data.Sorted,
+// only indexes ordered by the sort field are considered, and the sort's direction overrides the key field's.
+//
 // If the data.Queryable implements ConsistencyTyper and it states that the query must be strongly consistent, GSIs
 // will be excluded from consideration. On success, the function returns the matching index (if one), and a boolean
 // to include as the 'consistent' value for the ddb query. Possible errors:
 //
 //	gomerr.Missing:
 //	    if there is no matching index for the query
-func indexFor(t *table, q data.Queryable) (index *index, ascending bool, consistent *bool, ge gomerr.Gomerr) {
+func indexFor(t *table, q data.Queryable, conditions []data.Condition) (index *index, ascending bool, consistent *bool, ge gomerr.Gomerr) {
 	var consistencyType ConsistencyType
 	if c, ok := q.(ConsistencyTyper); ok {
 		consistencyType = c.ConsistencyType()
@@ -103,6 +108,7 @@ func indexFor(t *table, q data.Queryable) (index *index, ascending bool, consist
 
 	candidates := make([]*candidate, 0, len(t.indexes))
 	qv := reflect.ValueOf(q.ItemTemplate()).Elem()
+	sortBy := data.SortOf(q)
 
 	for _, idx := range t.indexes {
 		if consistencyType == Required && !idx.canReadConsistently {
@@ -114,7 +120,7 @@ func indexFor(t *table, q data.Queryable) (index *index, ascending bool, consist
 		//
 		// TODO: revisit - should be the one that covers the least, right? Amongst the viable candidates, choose the
 		//      best match under the (presumption) that fewer missing keys and longer key length are better
-		if c := idx.candidate(qv, q.TypeName()); c != nil {
+		if c := idx.candidate(qv, q.TypeName(), conditions); c != nil && (sortBy == nil || c.orderField == sortBy.Field) {
 			candidates = append(candidates, c)
 		}
 	}
@@ -155,10 +161,15 @@ func indexFor(t *table, q data.Queryable) (index *index, ascending bool, consist
 		})
 	}
 
-	return candidates[0].index, candidates[0].ascending, consistentRead(consistencyType, candidates[0].index.canReadConsistently), nil
+	ascending = candidates[0].ascending
+	if sortBy != nil {
+		ascending = !sortBy.Descending
+	}
+
+	return candidates[0].index, ascending, consistentRead(consistencyType, candidates[0].index.canReadConsistently), nil
 }
 
-func (i *index) candidate(qv reflect.Value, ptName string) *candidate {
+func (i *index) candidate(qv reflect.Value, ptName string, conditions []data.Condition) *candidate {
 	// TODO: validate index sufficiently projects over request. if not, return nil
 	var keyFields []*keyField
 	if keyFields = i.pk.keyFieldsByPersistable[ptName]; keyFields == nil {
@@ -190,7 +201,8 @@ func (i *index) candidate(qv reflect.Value, ptName string) *candidate {
 		}
 		applySort := true
 		hasWildcard := false
-		for _, kf := range keyFields {
+		_, rangePosition := i.rangeCondition(qv, ptName, conditions)
+		for n, kf := range keyFields {
 			// Sort order comes from the first unprovided (or partially-provided) field since that
 			// field contains the data to be ordered. We look one field ahead by continuing to
 			// apply until we find a missing or wildcarded value.
@@ -202,9 +214,20 @@ func (i *index) candidate(qv reflect.Value, ptName string) *candidate {
 				c.preferred = kf.preferred // static values always apply preferred
 				continue
 			}
+			if applySort {
+				c.orderField = kf.name
+			}
 
 			fv := qv.FieldByName(kf.name)
-			if !fv.IsValid() || fv.IsZero() {
+			if n == rangePosition {
+				if hasWildcard {
+					return nil
+				}
+				// As with a wildcard, the condition matches a range of values for the field
+				applySort = false
+				hasWildcard = true
+				c.preferred = kf.preferred
+			} else if !fv.IsValid() || fv.IsZero() {
 				applySort = false // sort indicator already captured from this field
 				c.skMissing++
 			} else if c.skMissing > 0 || hasWildcard { // Cannot have gaps in the middle of the sort key
@@ -224,6 +247,43 @@ func (i *index) candidate(qv reflect.Value, ptName string) *candidate {
 	return c
 }
 
+// rangeCondition returns the condition that can be part of the key condition expression along with the position of its
+// field. The field must be the first non-static sort key field without a value, and the condition must compare a
+// prefix of the sort key's value, so neither NEQ nor CONTAINS qualifies (nor PREFIX if the key is numeric). A string
+// key's values are ordered lexically, so a condition only qualifies for it if the field's values are too (i.e. a
+// string or time.Time field). Returns a position of -1 if there's no such condition.
+func (i *index) rangeCondition(qv reflect.Value, ptName string, conditions []data.Condition) (*data.Condition, int) {
+	if len(conditions) == 0 || i.sk == nil {
+		return nil, -1
+	}
+
+	for n, kf := range i.sk.keyFieldsByPersistable[ptName] {
+		if kf.name[0] == '\'' {
+			continue
+		}
+		if fv := qv.FieldByName(kf.name); fv.IsValid() && !fv.IsZero() {
+			continue
+		}
+		if !kf.lexical && i.sk.attributeType == string(types.ScalarAttributeTypeS) {
+			break
+		}
+
+		for c := range conditions {
+			condition := &conditions[c]
+			if condition.Field != kf.name || condition.Type == data.NEQ || condition.Type == data.CONTAINS {
+				continue
+			}
+			if condition.Type == data.PREFIX && i.sk.attributeType != string(types.ScalarAttributeTypeS) {
+				continue
+			}
+			return condition, n
+		}
+		break
+	}
+
+	return nil, -1
+}
+
 // endsWithWildcard checks if a reflect.Value (string or *string) ends with the wildcard character.
 func endsWithWildcard(fv reflect.Value, wildcardChar byte) bool {
 	if fv.Kind() == reflect.Ptr {
@@ -284,6 +344,11 @@ func (k *keyAttribute) attributeValue(elemValue reflect.Value, persistableTypeNa
 		return nil
 	}
 
+	return k.valueOf(value)
+}
+
+// valueOf returns the attribute value of the key's type for the given key value.
+func (k *keyAttribute) valueOf(value string) types.AttributeValue {
 	switch k.attributeType {
 	case string(types.ScalarAttributeTypeS):
 		return &types.AttributeValueMemberS{Value: value}
@@ -434,22 +499,27 @@ func fieldValue(fieldName string, sv reflect.Value, separator, escape byte) stri
 				v = v.Elem()
 			}
 
-			// Special case for time.Time to match framework RFC3339 standard
-			var value string
-			if t, ok := v.Interface().(time.Time); ok {
-				value = t.Format(time.RFC3339)
-			} else {
-				value = fmt.Sprint(v.Interface())
-			}
-
-			// Escape separator and escape characters to preserve sort order and avoid ambiguity
-			return escapeKeyValue(value, separator, escape)
+			return formatKeyValue(v.Interface(), separator, escape)
 		} else {
 			return ""
 		}
 	}
 }
 
+// formatKeyValue formats a field's value as it appears in a key.
+func formatKeyValue(fv any, separator, escape byte) string {
+	// Special case for time.Time to match framework RFC3339 standard
+	var value string
+	if t, ok := fv.(time.Time); ok {
+		value = t.Format(time.RFC3339)
+	} else {
+		value = fmt.Sprint(fv)
+	}
+
+	// Escape separator and escape characters to preserve sort order and avoid ambiguity
+	return escapeKeyValue(value, separator, escape)
+}
+
 // indexForMultiple finds an index that supports querying multiple types together.
 // This is used when a Queryable has nested Queryables that should be fetched in a single query.
 //
@@ -487,7 +557,7 @@ func indexForMultiple(t *table, parentType string, childTypes []string, q data.Q
 		}
 
 		// Use parent type for candidate evaluation
-		if c := idx.candidate(qv, parentType); c != nil {
+		if c := idx.candidate(qv, parentType, nil); c != nil {
 			candidates = append(candidates, c)
 		}
 	}
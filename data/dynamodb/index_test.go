@@ -4,7 +4,6 @@ import (
 	"context"
 	"testing"
 
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
 	"github.com/jt0/gomer/_test/assert"
@@ -14,7 +13,7 @@ import (
 )
 
 // Test setup helpers
-func setupTestStore(t *testing.T, persistables ...data.Persistable) (data.Store, *dynamodb.Client) {
+func setupTestStore(t *testing.T, persistables ...data.Persistable) (data.Store, ddbtest.Client) {
 	client, isLocal, err := ddbtest.NewClient()
 	assert.Success(t, err)
 
@@ -54,7 +53,7 @@ func setupTestStore(t *testing.T, persistables ...data.Persistable) (data.Store,
 	return store, client
 }
 
-func cleanupTestTable(t *testing.T, client *dynamodb.Client) {
+func cleanupTestTable(t *testing.T, client ddbtest.Client) {
 	err := ddbtest.DeleteAllTableData(client, "gomer_keys_test")
 	assert.Success(t, err)
 }
@@ -0,0 +1,219 @@
+package dynamodb
+
+import (
+	"maps"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/jt0/gomer/gomerr"
+)
+
+// MetricsRecorder receives metrics about a store's operations. It's set with Configuration.Metrics and must be safe for
+// concurrent use. Stores only request consumed capacity from DynamoDB when they have a MetricsRecorder.
+type MetricsRecorder interface {
+	// RecordCapacity is called with the capacity each DynamoDB request consumed from the table or one of its indexes.
+	RecordCapacity(c CapacityMetric)
+
+	// RecordOperation is called as each store operation completes.
+	RecordOperation(o OperationMetric)
+}
+
+// CapacityMetric is the capacity that a request made for a store operation consumed from a table or index.
+type CapacityMetric struct {
+	Table              string
+	Index              string // "" for the table itself
+	TypeName           string // The persistable type the request was made for, or "" if it was made for several
+	ReadCapacityUnits  float64
+	WriteCapacityUnits float64
+}
+
+// OperationMetric describes a completed store operation.
+type OperationMetric struct {
+	Table     string
+	Operation string // e.g. "Create", "Query", or "Commit" for a transaction
+	TypeName  string // "" for a transaction
+	Latency   time.Duration
+	ErrorType string // The type of the operation's error, e.g. "*gomerr.ConflictError", or "" if it succeeded
+}
+
+// returnConsumedCapacity returns the value for requests' ReturnConsumedCapacity parameter.
+func (t *table) returnConsumedCapacity() types.ReturnConsumedCapacity {
+	if t.metrics == nil {
+		return types.ReturnConsumedCapacityNone
+	}
+	return types.ReturnConsumedCapacityIndexes
+}
+
+// recordCapacity records the capacity consumed by a request made for the type. Capacity that DynamoDB reports only as
+// a total is attributed to reads or writes according to the kind of request.
+func (t *table) recordCapacity(typeName string, write bool, consumed ...*types.ConsumedCapacity) {
+	if t.metrics == nil {
+		return
+	}
+
+	for _, cc := range consumed {
+		if cc == nil {
+			continue
+		}
+
+		table := *t.tableName
+		if cc.TableName != nil {
+			table = *cc.TableName
+		}
+
+		if cc.Table == nil && len(cc.LocalSecondaryIndexes) == 0 && len(cc.GlobalSecondaryIndexes) == 0 {
+			t.metrics.RecordCapacity(capacityMetric(table, "", typeName, write, cc.CapacityUnits, cc.ReadCapacityUnits, cc.WriteCapacityUnits))
+			continue
+		}
+
+		if cc.Table != nil {
+			t.metrics.RecordCapacity(capacityMetric(table, "", typeName, write, cc.Table.CapacityUnits, cc.Table.ReadCapacityUnits, cc.Table.WriteCapacityUnits))
+		}
+		for _, indexes := range []map[string]types.Capacity{cc.LocalSecondaryIndexes, cc.GlobalSecondaryIndexes} {
+			for index, c := range indexes {
+				t.metrics.RecordCapacity(capacityMetric(table, index, typeName, write, c.CapacityUnits, c.ReadCapacityUnits, c.WriteCapacityUnits))
+			}
+		}
+	}
+}
+
+func ptrs[T any](values []T) []*T {
+	pointers := make([]*T, len(values))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	return pointers
+}
+
+func capacityMetric(table, index, typeName string, write bool, total, read, written *float64) CapacityMetric {
+	m := CapacityMetric{Table: table, Index: index, TypeName: typeName}
+	if read == nil && written == nil && total != nil {
+		if write {
+			written = total
+		} else {
+			read = total
+		}
+	}
+	if read != nil {
+		m.ReadCapacityUnits = *read
+	}
+	if written != nil {
+		m.WriteCapacityUnits = *written
+	}
+	return m
+}
+
+// recordOperation records an operation that started at the given time and completed with the error ge points to. It's
+// meant to be deferred, before ge is wrapped for the caller.
+func (t *table) recordOperation(operation, typeName string, start time.Time, ge *gomerr.Gomerr) {
+	if t.metrics == nil {
+		return
+	}
+
+	m := OperationMetric{Table: *t.tableName, Operation: operation, TypeName: typeName, Latency: time.Since(start)}
+	if *ge != nil {
+		m.ErrorType = reflect.TypeOf(*ge).String()
+	}
+	t.metrics.RecordOperation(m)
+}
+
+// CapacityKey identifies the capacity aggregated by MemoryMetrics.
+type CapacityKey struct {
+	Table    string
+	Index    string
+	TypeName string
+}
+
+// Capacity is an amount of consumed capacity.
+type Capacity struct {
+	ReadCapacityUnits  float64
+	WriteCapacityUnits float64
+}
+
+// OperationKey identifies the operations aggregated by MemoryMetrics.
+type OperationKey struct {
+	Table     string
+	Operation string
+	TypeName  string
+}
+
+// OperationStats aggregates the metrics of completed operations.
+type OperationStats struct {
+	Count        int
+	TotalLatency time.Duration
+	MaxLatency   time.Duration
+	Errors       map[string]int // error type -> count
+}
+
+// MemoryMetrics is a MetricsRecorder that aggregates the metrics it receives in memory, e.g. for tests.
+type MemoryMetrics struct {
+	mu         sync.Mutex
+	capacity   map[CapacityKey]Capacity
+	operations map[OperationKey]OperationStats
+}
+
+func NewMemoryMetrics() *MemoryMetrics {
+	return &MemoryMetrics{capacity: make(map[CapacityKey]Capacity), operations: make(map[OperationKey]OperationStats)}
+}
+
+func (m *MemoryMetrics) RecordCapacity(c CapacityMetric) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := CapacityKey{c.Table, c.Index, c.TypeName}
+	total := m.capacity[key]
+	total.ReadCapacityUnits += c.ReadCapacityUnits
+	total.WriteCapacityUnits += c.WriteCapacityUnits
+	m.capacity[key] = total
+}
+
+func (m *MemoryMetrics) RecordOperation(o OperationMetric) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := OperationKey{o.Table, o.Operation, o.TypeName}
+	stats := m.operations[key]
+	stats.Count++
+	stats.TotalLatency += o.Latency
+	stats.MaxLatency = max(stats.MaxLatency, o.Latency)
+	if o.ErrorType != "" {
+		if stats.Errors == nil {
+			stats.Errors = make(map[string]int)
+		}
+		stats.Errors[o.ErrorType]++
+	}
+	m.operations[key] = stats
+}
+
+// Capacity returns the capacity consumed from each table or index for each persistable type.
+func (m *MemoryMetrics) Capacity() map[CapacityKey]Capacity {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return maps.Clone(m.capacity)
+}
+
+// Operations returns the statistics of each kind of operation on each persistable type.
+func (m *MemoryMetrics) Operations() map[OperationKey]OperationStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	operations := make(map[OperationKey]OperationStats, len(m.operations))
+	for key, stats := range m.operations {
+		stats.Errors = maps.Clone(stats.Errors)
+		operations[key] = stats
+	}
+	return operations
+}
+
+// Reset discards the metrics received so far.
+func (m *MemoryMetrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clear(m.capacity)
+	clear(m.operations)
+}
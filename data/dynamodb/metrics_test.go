@@ -0,0 +1,62 @@
+package dynamodb_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jt0/gomer/_test/assert"
+	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/data/dataerr"
+	ddb "github.com/jt0/gomer/data/dynamodb"
+	testentities "github.com/jt0/gomer/data/dynamodb/_test"
+)
+
+func TestMetrics(t *testing.T) {
+	_, client := setupCrudStore(t)
+	defer cleanupCrudTable(t, client)
+
+	metrics := ddb.NewMemoryMetrics()
+	store, ge := ddb.Store(crudTestTableName, &ddb.Configuration{
+		DynamoDb:           client,
+		MaxResultsDefault:  100,
+		MaxResultsMax:      1000,
+		ConsistencyDefault: ddb.Required,
+		Metrics:            metrics,
+	}, &testentities.Product{}, &testentities.User{})
+	assert.Success(t, ge)
+
+	ctx := context.Background()
+	assert.Success(t, store.Create(ctx, &testentities.Product{TenantId: "t1", Id: "p1", Sku: "sku1", Category: "Tools", Name: "Hammer"}))
+	assert.Success(t, store.Read(ctx, &testentities.Product{TenantId: "t1", Id: "p1"}))
+	assert.ErrorType(t, store.Read(ctx, &testentities.Product{TenantId: "t1", Id: "p2"}), &dataerr.PersistableNotFoundError{})
+	assert.Success(t, store.Query(ctx, &testentities.Products{TenantId: "t1", Category: "Tools"}))
+	assert.Success(t, store.(data.BatchStore).BatchCreate(ctx, []data.Persistable{
+		&testentities.User{TenantId: "t1", Id: "u1", Email: "u1@example.com"},
+		&testentities.User{TenantId: "t1", Id: "u2", Email: "u2@example.com"},
+	}))
+
+	operations := metrics.Operations()
+	create := operations[ddb.OperationKey{Table: crudTestTableName, Operation: "Create", TypeName: "Product"}]
+	assert.Equals(t, 1, create.Count)
+	assert.Equals(t, 0, len(create.Errors))
+	assert.Assert(t, create.MaxLatency > 0 && create.TotalLatency == create.MaxLatency, "latency should be recorded")
+
+	read := operations[ddb.OperationKey{Table: crudTestTableName, Operation: "Read", TypeName: "Product"}]
+	assert.Equals(t, 2, read.Count)
+	assert.Equals(t, map[string]int{"*dataerr.PersistableNotFoundError": 1}, read.Errors)
+
+	assert.Equals(t, 1, operations[ddb.OperationKey{Table: crudTestTableName, Operation: "BatchCreate", TypeName: "User"}].Count)
+
+	capacity := metrics.Capacity()
+	// The write, two reads, and the query by category (all strongly consistent), on the table
+	assert.Equals(t, ddb.Capacity{ReadCapacityUnits: 3, WriteCapacityUnits: 1}, capacity[ddb.CapacityKey{Table: crudTestTableName, TypeName: "Product"}])
+
+	// The query of the Sku's index that checked that it's unique
+	assert.Equals(t, ddb.Capacity{ReadCapacityUnits: 1}, capacity[ddb.CapacityKey{Table: crudTestTableName, Index: "lsi_1", TypeName: "Product"}])
+
+	assert.Equals(t, 2.0, capacity[ddb.CapacityKey{Table: crudTestTableName, TypeName: "User"}].WriteCapacityUnits)
+
+	metrics.Reset()
+	assert.Equals(t, 0, len(metrics.Capacity()))
+	assert.Equals(t, 0, len(metrics.Operations()))
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
@@ -108,7 +109,7 @@ func (t *table) queryWithNested(ctx context.Context, q data.Queryable, nested []
 	}
 
 	// Execute query
-	output, ge := t.runQuery(ctx, input)
+	output, ge := t.runQuery(ctx, "", input)
 	if ge != nil {
 		return ge
 	}
@@ -145,7 +146,7 @@ func (t *table) querySingleType(ctx context.Context, q data.Queryable) gomerr.Go
 		return ge
 	}
 
-	output, ge := t.runQuery(ctx, input)
+	output, ge := t.runQuery(ctx, q.TypeName(), input)
 	if ge != nil {
 		return ge
 	}
@@ -155,9 +156,18 @@ func (t *table) querySingleType(ctx context.Context, q data.Queryable) gomerr.Go
 		return gomerr.Internal("unable to generate nextToken").Wrap(ge)
 	}
 
-	items := make([]any, len(output.Items))
-	for i, item := range output.Items {
+	keyFieldConditions, ge := t.keyFieldConditions(q)
+	if ge != nil {
+		return ge
+	}
+
+	now := time.Now()
+	items := make([]any, 0, len(output.Items))
+	for _, item := range output.Items {
 		pt := t.persistableTypes[q.TypeName()]
+		if pt.expired(item, now) {
+			continue
+		}
 
 		var resolvedItem any
 		if resolvedItem, ge = pt.resolver(item); ge != nil {
@@ -170,7 +180,9 @@ func (t *table) querySingleType(ctx context.Context, q data.Queryable) gomerr.Go
 			}
 		}
 
-		items[i] = resolvedItem
+		if data.Satisfies(resolvedItem, keyFieldConditions) {
+			items = append(items, resolvedItem)
+		}
 	}
 
 	q.SetResults(items)
@@ -237,11 +249,6 @@ func (t *table) buildMultiTypeQueryInput(ctx context.Context, q data.Queryable,
 // routeQueryResults routes query results to the parent Queryable and nested Queryables.
 // Uses type discrimination to determine which items belong to which Queryable.
 func (t *table) routeQueryResults(ctx context.Context, q data.Queryable, nested []nestedQueryableInfo, idx *index, output *dynamodb.QueryOutput) gomerr.Gomerr {
-	// Build type discriminator if not already built
-	if t.typeDiscriminator == nil {
-		t.typeDiscriminator = t.buildTypeDiscriminator()
-	}
-
 	// Create pagination context
 	pc := newPaginationContext(q.TypeName(), q, nested)
 
@@ -263,6 +270,7 @@ func (t *table) routeQueryResults(ctx context.Context, q data.Queryable, nested
 	}
 
 	// Process each item
+	now := time.Now()
 	for _, item := range output.Items {
 		// Discriminate type
 		typeName, err := t.typeDiscriminator.discriminate(item, indexName, skAttrName)
@@ -271,6 +279,10 @@ func (t *table) routeQueryResults(ctx context.Context, q data.Queryable, nested
 			continue
 		}
 
+		if t.persistableTypes[typeName].expired(item, now) {
+			continue
+		}
+
 		// Check if we should include this item (respects limits)
 		if !pc.tryInclude(typeName) {
 			pc.moreAvailable = true
@@ -330,14 +342,17 @@ type typeDiscriminator struct {
 	// Empty string key represents the base table (no index name).
 	// Patterns are sorted by segment count descending for efficient matching.
 	patternsByIndex map[string][]typePattern
+	pkNamesByIndex  map[string]string // partition key attribute name of each index
 	separator       byte
+	escape          byte
 }
 
 // typePattern holds the discrimination criteria for a single type.
 type typePattern struct {
-	prefix   string // Static prefix from key fields (e.g., "E#" for Extension)
-	skLength int    // Expected number of SK segments for this type
-	typeName string // The persistableType name
+	prefix   string      // Static prefix from key fields (e.g., "E#" for Extension)
+	skLength int         // Expected number of SK segments for this type
+	typeName string      // The persistableType name
+	pkFields []*keyField // The type's partition key fields, to tell apart types whose sort keys have the same form
 }
 
 // buildTypeDiscriminator creates a typeDiscriminator from the table's registered types.
@@ -345,7 +360,9 @@ type typePattern struct {
 func (t *table) buildTypeDiscriminator() *typeDiscriminator {
 	td := &typeDiscriminator{
 		patternsByIndex: make(map[string][]typePattern),
+		pkNamesByIndex:  make(map[string]string),
 		separator:       t.valueSeparatorChar,
+		escape:          t.escapeChar,
 	}
 
 	for _, idx := range t.indexes {
@@ -353,9 +370,15 @@ func (t *table) buildTypeDiscriminator() *typeDiscriminator {
 		if idx.name != nil {
 			indexName = *idx.name
 		}
+		td.pkNamesByIndex[indexName] = idx.pk.name
 
-		// Only process if index has a sort key (SK needed for discrimination)
+		// Without a sort key, types can only be told apart by their partition keys
 		if idx.sk == nil {
+			var patterns []typePattern
+			for typeName, keyFields := range idx.pk.keyFieldsByPersistable {
+				patterns = append(patterns, typePattern{typeName: typeName, pkFields: keyFields})
+			}
+			td.patternsByIndex[indexName] = patterns
 			continue
 		}
 
@@ -369,6 +392,7 @@ func (t *table) buildTypeDiscriminator() *typeDiscriminator {
 				prefix:   prefix,
 				skLength: len(keyFields),
 				typeName: typeName,
+				pkFields: idx.pk.keyFieldsByPersistable[typeName],
 			})
 		}
 
@@ -425,8 +449,9 @@ func buildStaticPrefix(keyFields []*keyField, separator byte) string {
 }
 
 // discriminate determines the type of an item based on its SK value.
-// Uses segment count as primary discriminator (most reliable for STD patterns).
-// Falls back to prefix matching for edge cases.
+// Uses segment count as primary discriminator (most reliable for STD patterns), then the form of the
+// partition key (see matchPartitionKey) if several types' sort keys have that count.
+// Falls back to prefix matching for edge cases. If the index has no SK, only the partition key is used.
 //
 // Parameters:
 //   - item: The DynamoDB item to discriminate
@@ -442,6 +467,13 @@ func (td *typeDiscriminator) discriminate(item map[string]types.AttributeValue,
 		return "", fmt.Errorf("no patterns for index: %s", indexName)
 	}
 
+	if skAttrName == "" {
+		if candidates := td.matchPartitionKey(item, indexName, patterns); len(candidates) == 1 {
+			return candidates[0].typeName, nil
+		}
+		return "", fmt.Errorf("no single matching type for partition key on index: %s", indexName)
+	}
+
 	// Get SK value from item
 	skAttr, ok := item[skAttrName]
 	if !ok {
@@ -475,8 +507,14 @@ func (td *typeDiscriminator) discriminate(item map[string]types.AttributeValue,
 		return candidates[0].typeName, nil
 	}
 
-	// SECONDARY: If multiple types have same segment count, use prefix matching
+	// SECONDARY: If multiple types have same segment count, use the partition key and then prefix matching
 	if len(candidates) > 1 {
+		if matches := td.matchPartitionKey(item, indexName, candidates); len(matches) == 1 {
+			return matches[0].typeName, nil
+		} else if len(matches) > 1 {
+			candidates = matches
+		}
+
 		// Sort candidates by prefix length descending (longest match wins)
 		for i := 0; i < len(candidates); i++ {
 			for j := i + 1; j < len(candidates); j++ {
@@ -511,6 +549,46 @@ func (td *typeDiscriminator) discriminate(item map[string]types.AttributeValue,
 	return "", fmt.Errorf("no matching type for SK: %s (segments: %d)", skValue, segmentCount)
 }
 
+// matchPartitionKey returns the patterns whose partition key fields match the item's partition key: no more segments
+// than the type has fields, and each of the type's static values at its position. If some of them have as many fields
+// as the key has segments, only those are returned.
+func (td *typeDiscriminator) matchPartitionKey(item map[string]types.AttributeValue, indexName string, patterns []typePattern) []typePattern {
+	pk, ok := item[td.pkNamesByIndex[indexName]].(*types.AttributeValueMemberS)
+	if !ok {
+		return nil
+	}
+	segments := unescapeAndSplit(pk.Value, td.separator, td.escape)
+
+	var matches, exact []typePattern
+	for _, p := range patterns {
+		if p.pkFields != nil && keyFieldsMatch(p.pkFields, segments) {
+			matches = append(matches, p)
+			if len(p.pkFields) == len(segments) {
+				exact = append(exact, p)
+			}
+		}
+	}
+
+	if len(exact) > 0 {
+		return exact
+	}
+	return matches
+}
+
+func keyFieldsMatch(keyFields []*keyField, segments []string) bool {
+	if len(segments) > len(keyFields) {
+		return false
+	}
+
+	for i, kf := range keyFields {
+		if kf.name[0] == '\'' && (i >= len(segments) || segments[i] != kf.name[1:len(kf.name)-1]) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // hasPatterns returns true if the discriminator has patterns for any index.
 func (td *typeDiscriminator) hasPatterns() bool {
 	for _, patterns := range td.patternsByIndex {
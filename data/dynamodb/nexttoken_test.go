@@ -9,7 +9,6 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
 	"github.com/jt0/gomer/_test/assert"
@@ -57,7 +56,7 @@ func createTestCipher(_ *testing.T, key []byte) crypto.Cipher {
 
 // Setup/teardown helpers
 
-func setupPaginationStore(t *testing.T, cipher crypto.Cipher, persistables ...data.Persistable) (data.Store, *dynamodb.Client) {
+func setupPaginationStore(t *testing.T, cipher crypto.Cipher, persistables ...data.Persistable) (data.Store, ddbtest.Client) {
 	client, isLocal, err := ddbtest.NewClient()
 	assert.Success(t, err)
 
@@ -108,7 +107,7 @@ func setupPaginationStore(t *testing.T, cipher crypto.Cipher, persistables ...da
 	return store, client
 }
 
-func cleanupPaginationTable(t *testing.T, client *dynamodb.Client) {
+func cleanupPaginationTable(t *testing.T, client ddbtest.Client) {
 	err := ddbtest.DeleteAllTableData(client, paginationTestTableName)
 	assert.Success(t, err)
 }
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
@@ -22,6 +24,11 @@ type persistableType struct {
 	dbNames          map[string]string // field name -> storage name
 	keyFields        map[string]bool   // field names that participate in keys (should not be stored as separate attributes)
 	constraintFields map[string]bool   // field names that participate in any constraint (used for update optimization)
+	uniqueTuples     [][]string        // field tuples from `db.constraints:"unique(...)"`
+	uniqueAttributes []string          // names of the attributes that store the values of the unique tuples' fields
+	versionField     data.VersionField // the field with a `db.version` tag, if any
+	ttlField         string            // name of the field with a `db.ttl` tag, if any
+	ttlAttribute     string            // name of the attribute with the item's expiry time in epoch seconds
 	resolver         ItemResolver
 }
 
@@ -38,6 +45,8 @@ func newPersistableType(table *table, persistableName string, pType reflect.Type
 		return nil, gomerr.Configuration("'db' tag errors found for type: " + persistableName).Wrap(gomerr.Batcher(errors))
 	}
 
+	pt.uniqueAttributes = pt.attributesFor(pt.constraintFields, table.indexes)
+
 	return pt, nil
 }
 
@@ -72,7 +81,15 @@ func (pt *persistableType) processFields(structType reflect.Type, fieldPath stri
 			pt.processNameTag(fieldName, field.Tag.Get("db.name"))
 
 			errors = pt.processConstraintsTag(fieldName, field.Tag.Get("db.constraints"), errors)
-			errors = pt.processKeysTag(fieldName, field.Tag.Get("db.keys"), table.indexes, errors)
+			errors = pt.processKeysTag(fieldName, field.Type, field.Tag.Get("db.keys"), table.indexes, errors)
+
+			if _, ok := field.Tag.Lookup("db.version"); ok {
+				errors = pt.versionField.ProcessVersionTag(field, errors)
+			}
+
+			if tag, ok := field.Tag.Lookup("db.ttl"); ok {
+				errors = pt.processTtlTag(field, tag, errors)
+			}
 		}
 	}
 
@@ -110,18 +127,138 @@ func (pt *persistableType) processConstraintsTag(fieldName string, tag string, e
 			for _, f := range fieldTuple {
 				pt.constraintFields[f] = true
 			}
+			pt.uniqueTuples = append(pt.uniqueTuples, fieldTuple)
 		}
 	}
 
 	return errors
 }
 
+var durationType = reflect.TypeFor[time.Duration]()
+
+// processTtlTag handles a `db.ttl` tag. A time.Time field is the item's expiry time, and is stored in epoch seconds as
+// DynamoDB's TTL requires. A time.Duration field is how long the item lives after each write, and the tag's value names
+// the attribute that the resulting expiry time is stored in.
+func (pt *persistableType) processTtlTag(field reflect.StructField, tag string, errors []gomerr.Gomerr) []gomerr.Gomerr {
+	if pt.ttlField != "" {
+		return append(errors, gomerr.Configuration("multiple `db.ttl` fields").AddAttributes("field", field.Name, "existing", pt.ttlField))
+	}
+
+	switch field.Type {
+	case timeType:
+		if tag != "" {
+			return append(errors, gomerr.Configuration("`db.ttl` time field can't name another attribute; use `db.name`").AddAttribute("field", field.Name))
+		}
+		if field.Tag.Get("db.name") == "-" {
+			return append(errors, gomerr.Configuration("`db.ttl` field must be stored").AddAttribute("field", field.Name))
+		}
+		pt.ttlAttribute = pt.attributeName(field.Name)
+	case durationType:
+		if tag == "" {
+			return append(errors, gomerr.Configuration("`db.ttl` duration field must name the expiry attribute").AddAttribute("field", field.Name))
+		}
+		pt.ttlAttribute = tag
+	default:
+		return append(errors, gomerr.Configuration("`db.ttl` field must be a time.Time or time.Duration").AddAttributes("field", field.Name, "type", field.Type.String()))
+	}
+
+	pt.ttlField = field.Name
+
+	return errors
+}
+
+// expiry returns the expiry time to store for the persistable as an epoch seconds value. Returns false if the type
+// doesn't have a TTL or the field is zero (i.e. the item doesn't expire).
+func (pt *persistableType) expiry(pv reflect.Value, now time.Time) (types.AttributeValue, bool) {
+	if pt.ttlField == "" {
+		return nil, false
+	}
+
+	var expiresAt time.Time
+	switch fv := pv.FieldByName(pt.ttlField).Interface().(type) {
+	case time.Time:
+		expiresAt = fv
+	case time.Duration:
+		if fv != 0 {
+			expiresAt = now.Add(fv)
+		}
+	}
+	if expiresAt.IsZero() {
+		return nil, false
+	}
+
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(expiresAt.Unix(), 10)}, true
+}
+
+// expired returns true if the item's expiry time has passed. DynamoDB deletes expired items some time after they
+// expire, so until then they're hidden from reads.
+func (pt *persistableType) expired(item map[string]types.AttributeValue, now time.Time) bool {
+	if pt == nil || pt.ttlAttribute == "" {
+		return false
+	}
+
+	n, ok := item[pt.ttlAttribute].(*types.AttributeValueMemberN)
+	if !ok {
+		return false
+	}
+
+	expiresAt, err := strconv.ParseInt(n.Value, 10, 64)
+	return err == nil && expiresAt <= now.Unix()
+}
+
+// attributesFor returns the names of the attributes that store the fields' values. Key fields are stored in each of the
+// key attributes they're a part of.
+func (pt *persistableType) attributesFor(fields map[string]bool, indexes map[string]*index) []string {
+	var attributes []string
+	added := make(map[string]bool)
+	add := func(name string) {
+		if !added[name] {
+			added[name] = true
+			attributes = append(attributes, name)
+		}
+	}
+
+	for fieldName := range fields {
+		if !pt.keyFields[fieldName] {
+			add(pt.attributeName(fieldName))
+			continue
+		}
+
+		for _, idx := range indexes {
+			for _, attribute := range idx.keyAttributes() {
+				for _, kf := range attribute.keyFieldsByPersistable[pt.name] {
+					if kf.name == fieldName {
+						add(attribute.name)
+					}
+				}
+			}
+		}
+	}
+
+	sort.Strings(attributes)
+
+	return attributes
+}
+
+// attributeName returns the name of the attribute that stores the field's value.
+func (pt *persistableType) attributeName(fieldName string) string {
+	if dbName, ok := pt.dbNames[fieldName]; ok {
+		return dbName
+	}
+	return fieldName
+}
+
 var ddbKeyStatementRegexp = regexp.MustCompile(`(!)?([+-])?(?:([\w-.]+):)?(pk|sk)(?:.(\d))?(?:=('\w+')(\+)?)?`)
 
-func (pt *persistableType) processKeysTag(fieldName string, tag string, indexes map[string]*index, errors []gomerr.Gomerr) []gomerr.Gomerr {
+func (pt *persistableType) processKeysTag(fieldName string, fieldType reflect.Type, tag string, indexes map[string]*index, errors []gomerr.Gomerr) []gomerr.Gomerr {
 	if tag == "" {
-		return nil
+		return errors
+	}
+
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
 	}
+	lexical := fieldType.Kind() == reflect.String || fieldType == timeType
 
 	for _, keyStatement := range strings.Split(strings.ReplaceAll(tag, " ", ""), ",") {
 		groups := ddbKeyStatementRegexp.FindStringSubmatch(keyStatement)
@@ -156,7 +293,7 @@ func (pt *persistableType) processKeysTag(fieldName string, tag string, indexes
 
 		// TODO: Determine scenarios where skLength/skMissing don't map to desired behavior. May need preferred
 		//       priority levels to compensate
-		kf := keyField{name: kfName, preferred: groups[1] == "!", ascending: groups[2] != "-"}
+		kf := keyField{name: kfName, preferred: groups[1] == "!", ascending: groups[2] != "-", lexical: lexical}
 		key.keyFieldsByPersistable[pt.name] = insertAtIndex(key.keyFieldsByPersistable[pt.name], &kf, partIndex)
 	}
 
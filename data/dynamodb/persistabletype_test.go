@@ -8,7 +8,6 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
 	"github.com/jt0/gomer/_test/assert"
@@ -22,7 +21,7 @@ const queryTestTableName = "gomer_query_test"
 
 // Setup/teardown helpers
 
-func setupQueryStore(t *testing.T, persistables ...data.Persistable) (data.Store, *dynamodb.Client) {
+func setupQueryStore(t *testing.T, persistables ...data.Persistable) (data.Store, ddbtest.Client) {
 	client, isLocal, err := ddbtest.NewClient()
 	assert.Success(t, err)
 
@@ -70,7 +69,7 @@ func setupQueryStore(t *testing.T, persistables ...data.Persistable) (data.Store
 	return store, client
 }
 
-func cleanupQueryTable(t *testing.T, client *dynamodb.Client) {
+func cleanupQueryTable(t *testing.T, client ddbtest.Client) {
 	err := ddbtest.DeleteAllTableData(client, queryTestTableName)
 	assert.Success(t, err)
 }
@@ -0,0 +1,179 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/smithy-go"
+
+	"github.com/jt0/gomer/id"
+	"github.com/jt0/gomer/internal/backoff"
+)
+
+// RetryPolicy controls how a store retries DynamoDB requests that fail because they were throttled or because of a
+// transient (5xx) service error. Before each retry, the store waits for an exponentially increasing, jittered delay.
+// No retry is attempted if the delay would leave less than DeadlineMargin before the context's deadline, so that the
+// caller has time to handle the error. A request that still fails after its last attempt fails the operation as it
+// would without retries, e.g. throttling as a limit.ExceededError.
+//
+// A conditional write (e.g. a versioned update) is only retried if it was throttled: after a server error, it may have
+// been applied, and a retry's condition would then fail as if another write had changed the item. Transactional writes
+// are made with a ClientRequestToken so that they can be retried after either.
+//
+// The policy replaces the DynamoDB client's own retries: while it's enabled, the store's requests are made with the
+// client's RetryMaxAttempts set to 1 so that the attempts of each aren't multiplied. To rely on the client's retries
+// instead, set MaxAttempts to 1.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of times a request may be made, including the first. Values less than 2 disable
+	// retries.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry. It doubles with each subsequent retry up to MaxDelay. If zero,
+	// retries are attempted immediately.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay between retries. If zero, the delay is not capped.
+	MaxDelay time.Duration
+
+	// DeadlineMargin is the time that must remain before the context's deadline after a retry's delay.
+	DeadlineMargin time.Duration
+}
+
+// DefaultRetryPolicy is used by stores whose Configuration doesn't specify a RetryPolicy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	BaseDelay:      25 * time.Millisecond,
+	MaxDelay:       time.Second,
+	DeadlineMargin: 50 * time.Millisecond,
+}
+
+// retryingClient is a Client that retries its requests per the policy.
+type retryingClient struct {
+	Client
+	policy RetryPolicy
+}
+
+func (c retryingClient) DescribeTable(ctx context.Context, input *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	return retry(ctx, c.policy, transient, c.Client.DescribeTable, input, optFns)
+}
+
+func (c retryingClient) GetItem(ctx context.Context, input *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return retry(ctx, c.policy, transient, c.Client.GetItem, input, optFns)
+}
+
+func (c retryingClient) PutItem(ctx context.Context, input *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	return retry(ctx, c.policy, writeRetryable(input.ConditionExpression), c.Client.PutItem, input, optFns)
+}
+
+func (c retryingClient) UpdateItem(ctx context.Context, input *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	return retry(ctx, c.policy, writeRetryable(input.ConditionExpression), c.Client.UpdateItem, input, optFns)
+}
+
+func (c retryingClient) DeleteItem(ctx context.Context, input *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	return retry(ctx, c.policy, writeRetryable(input.ConditionExpression), c.Client.DeleteItem, input, optFns)
+}
+
+func (c retryingClient) Query(ctx context.Context, input *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	return retry(ctx, c.policy, transient, c.Client.Query, input, optFns)
+}
+
+func (c retryingClient) Scan(ctx context.Context, input *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	return retry(ctx, c.policy, transient, c.Client.Scan, input, optFns)
+}
+
+func (c retryingClient) BatchGetItem(ctx context.Context, input *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	return retry(ctx, c.policy, transient, c.Client.BatchGetItem, input, optFns)
+}
+
+func (c retryingClient) BatchWriteItem(ctx context.Context, input *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	return retry(ctx, c.policy, transient, c.Client.BatchWriteItem, input, optFns)
+}
+
+// TransactWriteItems sets the input's ClientRequestToken, if it doesn't have one, so that a retry of a transaction that
+// was applied succeeds without applying it again.
+func (c retryingClient) TransactWriteItems(ctx context.Context, input *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	if input.ClientRequestToken == nil {
+		withToken := *input
+		withToken.ClientRequestToken = aws.String(id.UuidV4.Generate())
+		input = &withToken
+	}
+
+	return retry(ctx, c.policy, transient, c.Client.TransactWriteItems, input, optFns)
+}
+
+// writeRetryable returns how to classify the errors of a write with the given condition. A conditional write is only
+// retried if it was throttled, since after any other failure it may have been applied.
+func writeRetryable(condition *string) func(error) bool {
+	if condition != nil {
+		return throttled
+	}
+
+	return transient
+}
+
+// retry makes the request, retrying it per the policy while it fails with an error that retryable accepts. The client's
+// own retries are disabled for the request.
+func retry[I, O any](ctx context.Context, policy RetryPolicy, retryable func(error) bool, request func(context.Context, I, ...func(*dynamodb.Options)) (O, error), input I, optFns []func(*dynamodb.Options)) (O, error) {
+	optFns = append(optFns[:len(optFns):len(optFns)], disableClientRetries)
+
+	output, err := request(ctx, input, optFns...)
+	for attempt := 1; err != nil && attempt < policy.MaxAttempts && retryable(err); attempt++ {
+		if !policy.wait(ctx, attempt) {
+			break
+		}
+
+		output, err = request(ctx, input, optFns...)
+	}
+
+	return output, err
+}
+
+func disableClientRetries(o *dynamodb.Options) {
+	o.RetryMaxAttempts = 1
+}
+
+// transient returns true if the request was throttled or failed because of a server-side error.
+func transient(err error) bool {
+	if throttled(err) {
+		return true
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorFault() == smithy.FaultServer {
+		return true
+	}
+
+	var statusErr interface{ HTTPStatusCode() int }
+	return errors.As(err, &statusErr) && statusErr.HTTPStatusCode() >= 500
+}
+
+// wait sleeps before the given retry attempt (starting at 1). Returns false if the context is done, or if its deadline
+// would be within DeadlineMargin once the delay elapses.
+func (p RetryPolicy) wait(ctx context.Context, attempt int) bool {
+	delay := p.delay(attempt)
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay+p.DeadlineMargin).After(deadline) {
+		return false
+	}
+
+	if delay <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// delay returns a random duration between zero and the exponential backoff value for the attempt ("full jitter").
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	return backoff.Jittered(p.BaseDelay, p.MaxDelay, attempt)
+}
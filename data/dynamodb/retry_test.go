@@ -0,0 +1,184 @@
+package dynamodb_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/smithy-go"
+
+	"github.com/jt0/gomer/_test/assert"
+	"github.com/jt0/gomer/data"
+	ddb "github.com/jt0/gomer/data/dynamodb"
+	testentities "github.com/jt0/gomer/data/dynamodb/_test"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/limit"
+)
+
+// flakyClient fails the next requests to Query, PutItem, GetItem, UpdateItem, and TransactWriteItems (or if operation
+// is set, to only that one) with err. The failing requests are made before the error is returned if applied is set.
+type flakyClient struct {
+	ddb.Client
+	err       error
+	operation string
+	applied   bool
+	failures  atomic.Int32
+	requests  atomic.Int32
+	tokens    []string
+}
+
+func (c *flakyClient) fail(n int32, err error) {
+	c.err, c.operation, c.applied, c.tokens = err, "", false, nil
+	c.failures.Store(n)
+	c.requests.Store(0)
+}
+
+func (c *flakyClient) failing(operation string) bool {
+	if c.operation != "" && operation != c.operation {
+		return false
+	}
+	c.requests.Add(1)
+	return c.failures.Add(-1) >= 0
+}
+
+func (c *flakyClient) Query(ctx context.Context, input *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	if c.failing("Query") {
+		return nil, c.err
+	}
+	return c.Client.Query(ctx, input, optFns...)
+}
+
+func (c *flakyClient) PutItem(ctx context.Context, input *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	if c.failing("PutItem") {
+		return nil, c.err
+	}
+	return c.Client.PutItem(ctx, input, optFns...)
+}
+
+func (c *flakyClient) GetItem(ctx context.Context, input *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if c.failing("GetItem") {
+		return nil, c.err
+	}
+	return c.Client.GetItem(ctx, input, optFns...)
+}
+
+func (c *flakyClient) UpdateItem(ctx context.Context, input *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	if c.failing("UpdateItem") {
+		if c.applied {
+			_, _ = c.Client.UpdateItem(ctx, input, optFns...)
+		}
+		return nil, c.err
+	}
+	return c.Client.UpdateItem(ctx, input, optFns...)
+}
+
+func (c *flakyClient) TransactWriteItems(ctx context.Context, input *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	if input.ClientRequestToken != nil {
+		c.tokens = append(c.tokens, *input.ClientRequestToken)
+	}
+	if c.failing("TransactWriteItems") {
+		return nil, c.err
+	}
+	return c.Client.TransactWriteItems(ctx, input, optFns...)
+}
+
+func TestRetry(t *testing.T) {
+	_, client := setupCrudStore(t)
+	defer cleanupCrudTable(t, client)
+
+	flaky := &flakyClient{Client: client}
+	store, ge := ddb.Store(crudTestTableName, &ddb.Configuration{
+		DynamoDb:           flaky,
+		MaxResultsDefault:  100,
+		MaxResultsMax:      1000,
+		ConsistencyDefault: ddb.Required,
+		RetryPolicy:        &ddb.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond, DeadlineMargin: 50 * time.Millisecond},
+	}, &testentities.Product{})
+	assert.Success(t, ge)
+
+	throttled := &smithy.GenericAPIError{Code: "ProvisionedThroughputExceededException", Fault: smithy.FaultClient}
+	unavailable := &smithy.GenericAPIError{Code: "InternalServerError", Fault: smithy.FaultServer}
+	invalid := &smithy.GenericAPIError{Code: "ValidationException", Fault: smithy.FaultClient}
+
+	ctx := context.Background()
+
+	t.Run("Throttled", func(t *testing.T) {
+		// The first two requests are the uniqueness check's query for the Sku, and its retry
+		flaky.fail(2, throttled)
+		assert.Success(t, store.Create(ctx, &testentities.Product{TenantId: "t1", Id: "p1", Sku: "sku1"}))
+		assert.Equals(t, int32(4), flaky.requests.Load())
+	})
+
+	t.Run("ServerError", func(t *testing.T) {
+		flaky.fail(2, unavailable)
+		assert.Success(t, store.Read(ctx, &testentities.Product{TenantId: "t1", Id: "p1"}))
+		assert.Equals(t, int32(3), flaky.requests.Load())
+	})
+
+	t.Run("ConditionalServerError", func(t *testing.T) {
+		// The versioned update was applied, so a retry would fail its condition rather than succeed
+		flaky.fail(1, unavailable)
+		flaky.operation, flaky.applied = "UpdateItem", true
+		p := &testentities.Product{TenantId: "t1", Id: "p1"}
+		assert.Success(t, store.Read(ctx, p))
+		ge := store.Update(ctx, p, &testentities.Product{Description: "updated"})
+		assert.ErrorType(t, ge, &gomerr.DependencyError{})
+		assert.Equals(t, int32(1), flaky.requests.Load(), "A conditional write should not be retried after a server error")
+
+		flaky.fail(1, throttled)
+		flaky.operation = "UpdateItem"
+		assert.Success(t, store.Update(ctx, p, &testentities.Product{Description: "throttled"}))
+		assert.Equals(t, int32(2), flaky.requests.Load(), "A throttled conditional write should be retried")
+	})
+
+	t.Run("Transaction", func(t *testing.T) {
+		flaky.fail(1, unavailable)
+		flaky.operation = "TransactWriteItems"
+		tx := store.(data.Transactional).NewTransaction()
+		assert.Success(t, tx.Create(ctx, &testentities.Product{TenantId: "t1", Id: "p2", Sku: "sku2"}))
+		assert.Success(t, tx.Commit(ctx))
+		assert.Equals(t, int32(2), flaky.requests.Load())
+		assert.Equals(t, 2, len(flaky.tokens))
+		assert.Equals(t, flaky.tokens[0], flaky.tokens[1], "A retried transaction should have the same request token")
+	})
+
+	t.Run("Exhausted", func(t *testing.T) {
+		flaky.fail(10, throttled)
+		ge := store.Read(ctx, &testentities.Product{TenantId: "t1", Id: "p1"})
+		assert.ErrorType(t, ge, &limit.ExceededError{})
+		assert.Equals(t, int32(3), flaky.requests.Load())
+	})
+
+	t.Run("NotTransient", func(t *testing.T) {
+		flaky.fail(1, invalid)
+		ge := store.Read(ctx, &testentities.Product{TenantId: "t1", Id: "p1"})
+		assert.ErrorType(t, ge, &gomerr.DependencyError{})
+		assert.Equals(t, int32(1), flaky.requests.Load())
+	})
+
+	t.Run("DeadlineNear", func(t *testing.T) {
+		deadlineCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+
+		flaky.fail(1, throttled)
+		ge := store.Read(deadlineCtx, &testentities.Product{TenantId: "t1", Id: "p1"})
+		assert.ErrorType(t, ge, &limit.ExceededError{})
+		assert.Equals(t, int32(1), flaky.requests.Load())
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		noRetries, ge := ddb.Store(crudTestTableName, &ddb.Configuration{
+			DynamoDb:          flaky,
+			MaxResultsDefault: 100,
+			MaxResultsMax:     1000,
+			RetryPolicy:       &ddb.RetryPolicy{MaxAttempts: 1},
+		}, &testentities.Product{})
+		assert.Success(t, ge)
+
+		flaky.fail(1, throttled)
+		assert.ErrorType(t, noRetries.Query(ctx, &testentities.Products{TenantId: "t1"}), &limit.ExceededError{})
+		assert.Equals(t, int32(1), flaky.requests.Load())
+	})
+}
@@ -0,0 +1,178 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/data/dataerr"
+	"github.com/jt0/gomer/gomerr"
+)
+
+// scanMaxSegments is the largest TotalSegments DynamoDB accepts.
+const scanMaxSegments = 1000000
+
+const ScanCursor = "ScanCursor"
+
+// scanCursor is the position within a scan segment. Unlike a NextPageToken it isn't encrypted and doesn't expire since
+// it's meant to be recorded by operational tasks rather than returned to clients.
+type scanCursor struct {
+	Segment          int               `json:"s"`
+	TotalSegments    int               `json:"ts"`
+	LastEvaluatedKey map[string]string `json:"lek"`
+}
+
+// Scan reads a page of the table's items and returns those of prototype's type: the items that the table's type
+// discriminator attributes to it (see typeDiscriminator.discriminate) and whose keys have its form (see hasKeyForm),
+// since the discriminator only considers the types registered with the store. Uniqueness sentinels and expired items
+// are skipped.
+func (t *table) Scan(ctx context.Context, prototype data.Persistable, segment data.ScanSegment) (ps []data.Persistable, cursor string, ge gomerr.Gomerr) {
+	defer func() {
+		if ge != nil {
+			ge = dataerr.Store("Scan", prototype).Wrap(ge)
+		}
+	}()
+	defer t.recordOperation("Scan", prototype.TypeName(), time.Now(), &ge)
+
+	pt, ge := t.batchPersistableType(prototype)
+	if ge != nil {
+		return nil, "", ge
+	}
+
+	input := &dynamodb.ScanInput{
+		TableName:              t.tableName,
+		Limit:                  t.limit(0),
+		ConsistentRead:         consistentRead(t.consistencyType(prototype), t.canReadConsistently),
+		ReturnConsumedCapacity: t.returnConsumedCapacity(),
+	}
+
+	if segment.TotalSegments > 1 {
+		if segment.TotalSegments > scanMaxSegments {
+			return nil, "", gomerr.InvalidValue("TotalSegments", segment.TotalSegments, scanMaxSegments)
+		}
+		if segment.Segment < 0 || segment.Segment >= segment.TotalSegments {
+			return nil, "", gomerr.InvalidValue("Segment", segment.Segment, segment.TotalSegments)
+		}
+		input.Segment = aws.Int32(int32(segment.Segment))
+		input.TotalSegments = aws.Int32(int32(segment.TotalSegments))
+	}
+
+	if input.ExclusiveStartKey, ge = decodeScanCursor(segment); ge != nil {
+		return nil, "", ge
+	}
+
+	output, err := t.ddb.Scan(ctx, input)
+	if err != nil {
+		var notFoundErr *types.ResourceNotFoundException
+		if errors.As(err, &notFoundErr) {
+			return nil, "", gomerr.Unprocessable("table", *t.tableName).Wrap(err)
+		}
+		if throttled(err) {
+			return nil, "", throughputExceeded(err)
+		}
+		return nil, "", gomerr.Dependency("DynamoDB", input).Wrap(err)
+	}
+	t.recordCapacity(pt.name, false, output.ConsumedCapacity)
+
+	skName := ""
+	if t.sk != nil {
+		skName = t.sk.name
+	}
+
+	now := time.Now()
+	ps = make([]data.Persistable, 0, len(output.Items))
+	for _, item := range output.Items {
+		if _, sentinel := item[ownerAttribute]; sentinel || pt.expired(item, now) {
+			continue
+		}
+		if typeName, err := t.typeDiscriminator.discriminate(item, "", skName); err != nil || typeName != pt.name || !t.hasKeyForm(item, pt) {
+			continue
+		}
+
+		resolved, ge := pt.resolver(item)
+		if ge != nil {
+			return nil, "", ge
+		}
+
+		p := resolved.(data.Persistable)
+		if ge = pt.populateKeyFieldsFromAttributes(p, item, t.indexes, t.valueSeparatorChar, t.validateKeyFieldConsistency); ge != nil {
+			return nil, "", ge
+		}
+
+		ps = append(ps, p)
+	}
+
+	if cursor, ge = encodeScanCursor(segment, output.LastEvaluatedKey); ge != nil {
+		return nil, "", ge
+	}
+
+	return ps, cursor, nil
+}
+
+// hasKeyForm returns true if the item's table keys have the form of the type's: no more segments than the type has key
+// fields, and each of the type's static values at its position.
+func (t *table) hasKeyForm(item map[string]types.AttributeValue, pt *persistableType) bool {
+	for _, keyAttribute := range t.keyAttributes() {
+		keyFields := keyAttribute.keyFieldsByPersistable[pt.name]
+		if keyFields == nil {
+			continue
+		}
+
+		if !keyFieldsMatch(keyFields, unescapeAndSplit(attributeString(item[keyAttribute.name]), t.valueSeparatorChar, t.escapeChar)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func encodeScanCursor(segment data.ScanSegment, lastEvaluatedKey map[string]types.AttributeValue) (string, gomerr.Gomerr) {
+	if lastEvaluatedKey == nil {
+		return "", nil
+	}
+
+	sc := scanCursor{
+		Segment:          segment.Segment,
+		TotalSegments:    segment.TotalSegments,
+		LastEvaluatedKey: encodeLastEvaluatedKey(lastEvaluatedKey),
+	}
+
+	encoded, err := json.Marshal(sc)
+	if err != nil {
+		return "", gomerr.Marshal(ScanCursor, sc).Wrap(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(encoded), nil
+}
+
+// decodeScanCursor returns the key to resume the segment after, or nil to start at its beginning. A cursor can only be
+// used to resume the segment it was returned for.
+func decodeScanCursor(segment data.ScanSegment) (map[string]types.AttributeValue, gomerr.Gomerr) {
+	if segment.Cursor == "" {
+		return nil, nil
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(segment.Cursor)
+	if err != nil {
+		return nil, gomerr.MalformedValue(ScanCursor, segment.Cursor).Wrap(err)
+	}
+
+	sc := scanCursor{}
+	if err = json.Unmarshal(decoded, &sc); err != nil {
+		return nil, gomerr.MalformedValue(ScanCursor, segment.Cursor).Wrap(err)
+	}
+
+	if sc.Segment != segment.Segment || sc.TotalSegments != segment.TotalSegments {
+		return nil, gomerr.InvalidValue(ScanCursor, segment.Cursor, "a cursor for the segment").
+			AddAttributes("segment", sc.Segment, "totalSegments", sc.TotalSegments)
+	}
+
+	return decodeLastEvaluatedKey(sc.LastEvaluatedKey), nil
+}
@@ -0,0 +1,141 @@
+package dynamodb_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jt0/gomer/_test/assert"
+	"github.com/jt0/gomer/data"
+	ddb "github.com/jt0/gomer/data/dynamodb"
+	testentities "github.com/jt0/gomer/data/dynamodb/_test"
+	"github.com/jt0/gomer/gomerr"
+)
+
+func TestScan(t *testing.T) {
+	store, client := setupCrudStore(t, &testentities.User{}, &testentities.Product{}, &testentities.Session{})
+	defer cleanupCrudTable(t, client)
+
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		assert.Success(t, store.Create(ctx, &testentities.User{TenantId: "t1", Id: fmt.Sprintf("u%d", i), Email: fmt.Sprintf("u%d@example.com", i)}))
+		assert.Success(t, store.Create(ctx, &testentities.Product{TenantId: "t1", Id: fmt.Sprintf("p%d", i), Sku: fmt.Sprintf("sku%d", i)}))
+	}
+	assert.Success(t, store.Create(ctx, &testentities.Session{Id: "live", ExpiresAt: time.Now().Add(time.Hour)}))
+	assert.Success(t, store.Create(ctx, &testentities.Session{Id: "expired", ExpiresAt: time.Now().Add(-time.Minute)}))
+
+	scanner := store.(data.Scanner)
+
+	t.Run("Segments", func(t *testing.T) {
+		var mu sync.Mutex
+		var ids []string
+		ge := data.ScanAll(ctx, scanner, &testentities.User{}, data.Segments(4), func(ps []data.Persistable, _ data.ScanSegment) gomerr.Gomerr {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, p := range ps {
+				user := p.(*testentities.User)
+				assert.Equals(t, "t1", user.TenantId)
+				ids = append(ids, user.Id)
+			}
+			return nil
+		})
+		assert.Success(t, ge)
+
+		sort.Strings(ids)
+		assert.Equals(t, []string{"u0", "u1", "u2", "u3", "u4", "u5", "u6", "u7", "u8", "u9"}, ids)
+	})
+
+	t.Run("SkipsExpired", func(t *testing.T) {
+		ps, cursor, ge := scanner.Scan(ctx, &testentities.Session{}, data.ScanSegment{})
+		assert.Success(t, ge)
+		assert.Equals(t, "", cursor)
+		assert.Equals(t, 1, len(ps))
+		assert.Equals(t, "live", ps[0].(*testentities.Session).Id)
+	})
+
+	t.Run("Resume", func(t *testing.T) {
+		pagedStore, ge := ddb.Store(crudTestTableName, &ddb.Configuration{DynamoDb: client, MaxResultsDefault: 3, MaxResultsMax: 3}, &testentities.Product{})
+		assert.Success(t, ge)
+
+		// Each segment's cursor resumes it after the last page that was read, including with a different Scanner
+		segment := data.ScanSegment{Segment: 1, TotalSegments: 2}
+		var ids []string
+		for pages := 0; ; pages++ {
+			assert.Assert(t, pages < 20, "scan should complete")
+
+			ps, cursor, ge := pagedStore.(data.Scanner).Scan(ctx, &testentities.Product{}, segment)
+			assert.Success(t, ge)
+			for _, p := range ps {
+				ids = append(ids, p.(*testentities.Product).Id)
+			}
+
+			if cursor == "" {
+				break
+			}
+
+			if pages == 0 {
+				pagedStore = store
+			}
+			segment.Cursor = cursor
+		}
+
+		others, _, ge := scanner.Scan(ctx, &testentities.Product{}, data.ScanSegment{Segment: 0, TotalSegments: 2})
+		assert.Success(t, ge)
+		assert.Equals(t, 10, len(ids)+len(others))
+	})
+
+	t.Run("InvalidCursor", func(t *testing.T) {
+		_, _, ge := scanner.Scan(ctx, &testentities.User{}, data.ScanSegment{Cursor: "not a cursor"})
+		assert.ErrorType(t, ge, &gomerr.BadValueError{})
+
+		pagedStore, ge := ddb.Store(crudTestTableName, &ddb.Configuration{DynamoDb: client, MaxResultsDefault: 1, MaxResultsMax: 1}, &testentities.User{})
+		assert.Success(t, ge)
+
+		_, cursor, ge := pagedStore.(data.Scanner).Scan(ctx, &testentities.User{}, data.ScanSegment{Segment: 0, TotalSegments: 2})
+		assert.Success(t, ge)
+		assert.NotEquals(t, "", cursor)
+
+		_, _, ge = scanner.Scan(ctx, &testentities.User{}, data.ScanSegment{Segment: 1, TotalSegments: 2, Cursor: cursor})
+		assert.ErrorType(t, ge, &gomerr.BadValueError{})
+	})
+
+	t.Run("InvalidSegment", func(t *testing.T) {
+		_, _, ge := scanner.Scan(ctx, &testentities.User{}, data.ScanSegment{Segment: 2, TotalSegments: 2})
+		assert.ErrorType(t, ge, &gomerr.BadValueError{})
+	})
+}
+
+func TestScan_SharedTable(t *testing.T) {
+	// Neither type has static key values, so their items are only told apart by the form of their keys
+	store, client := setupCrudStore(t, &testentities.CompositeKeyEntity{}, &testentities.MultiPartKeyEntity{})
+	defer cleanupCrudTable(t, client)
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		assert.Success(t, store.Create(ctx, &testentities.CompositeKeyEntity{PartitionKey: fmt.Sprintf("c%d", i), SortKey: "s"}))
+		assert.Success(t, store.Create(ctx, &testentities.MultiPartKeyEntity{TenantId: "t1", EntityType: "USER", Id: fmt.Sprintf("m%d", i)}))
+	}
+
+	scanner := store.(data.Scanner)
+
+	ps, _, ge := scanner.Scan(ctx, &testentities.CompositeKeyEntity{}, data.ScanSegment{})
+	assert.Success(t, ge)
+	var pks []string
+	for _, p := range ps {
+		pks = append(pks, p.(*testentities.CompositeKeyEntity).PartitionKey)
+	}
+	sort.Strings(pks)
+	assert.Equals(t, []string{"c0", "c1", "c2"}, pks)
+
+	ps, _, ge = scanner.Scan(ctx, &testentities.MultiPartKeyEntity{}, data.ScanSegment{})
+	assert.Success(t, ge)
+	var ids []string
+	for _, p := range ps {
+		ids = append(ids, p.(*testentities.MultiPartKeyEntity).Id)
+	}
+	sort.Strings(ids)
+	assert.Equals(t, []string{"m0", "m1", "m2"}, ids)
+}
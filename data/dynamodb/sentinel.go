@@ -0,0 +1,396 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/jt0/gomer/constraint"
+	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/data/dataerr"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/limit"
+)
+
+// UniquenessEnforcement determines how `db.constraints:"unique(...)"` constraints are enforced.
+type UniquenessEnforcement int
+
+const (
+	// QueryUniqueness queries for a conflicting item before each write. Concurrent writes of the same values may both
+	// succeed.
+	QueryUniqueness UniquenessEnforcement = iota
+
+	// SentinelUniqueness writes a sentinel item for each unique tuple of values in the same TransactWriteItems call as
+	// the item that has them. A sentinel is claimed by the first item to write it, so concurrent writes of the same
+	// values can't both succeed. Sentinels are moved when an item's values change and released when it's deleted. They
+	// are stored in the table with a partition key of "UNIQUE", the type name, and the values, which requires the table's
+	// keys to be strings. Updates and deletes that affect sentinels first read the current item and fail with a
+	// gomerr.ConflictError if it changes before the transaction is written.
+	SentinelUniqueness
+)
+
+const (
+	sentinelKeyPrefix    = "UNIQUE"
+	ownerAttribute       = "UniqueOwner"
+	conditionCheckFailed = "ConditionalCheckFailed"
+)
+
+// sentinelOp claims or releases the sentinel for one of a persistable's unique tuples.
+type sentinelOp struct {
+	tuple []string
+	key   map[string]types.AttributeValue
+	claim bool
+}
+
+func (t *table) usesSentinels(pt *persistableType) bool {
+	return t.uniqueness == SentinelUniqueness && len(pt.uniqueTuples) > 0
+}
+
+// sentinelOps returns the operations needed to move the sentinels from the before values to the after ones. An invalid
+// before value (e.g. for a new item) has no sentinels to release, and an invalid after value (e.g. for a deleted item)
+// has none to claim.
+func (t *table) sentinelOps(pt *persistableType, before, after reflect.Value) []sentinelOp {
+	var ops []sentinelOp
+	for _, tuple := range pt.uniqueTuples {
+		beforeKey := t.sentinelKey(pt, tuple, before)
+		afterKey := t.sentinelKey(pt, tuple, after)
+		if beforeKey == afterKey {
+			continue
+		}
+
+		if beforeKey != "" {
+			ops = append(ops, sentinelOp{tuple, t.sentinelItemKey(beforeKey), false})
+		}
+		if afterKey != "" {
+			ops = append(ops, sentinelOp{tuple, t.sentinelItemKey(afterKey), true})
+		}
+	}
+
+	return ops
+}
+
+// sentinelKey returns the partition key value of the tuple's sentinel, or "" if any of the tuple's fields are empty.
+// Such tuples aren't constrained, which matches QueryUniqueness where those items are absent from the queried index.
+func (t *table) sentinelKey(pt *persistableType, tuple []string, pv reflect.Value) string {
+	if !pv.IsValid() {
+		return ""
+	}
+
+	segments := []string{sentinelKeyPrefix, pt.name, strings.Join(tuple, ",")}
+	for _, fieldName := range tuple {
+		value := fieldValue(fieldName, pv, t.valueSeparatorChar, t.escapeChar)
+		if value == "" {
+			return ""
+		}
+		segments = append(segments, value)
+	}
+
+	return strings.Join(segments, string(t.valueSeparatorChar))
+}
+
+func (t *table) sentinelItemKey(keyValue string) map[string]types.AttributeValue {
+	key := map[string]types.AttributeValue{t.pk.name: &types.AttributeValueMemberS{Value: keyValue}}
+	if t.sk != nil {
+		key[t.sk.name] = &types.AttributeValueMemberS{Value: sentinelKeyPrefix}
+	}
+	return key
+}
+
+// owner returns the value stored in a sentinel to identify the item that claimed it.
+func (t *table) owner(key map[string]types.AttributeValue) string {
+	segments := []string{attributeString(key[t.pk.name])}
+	if t.sk != nil {
+		segments = append(segments, attributeString(key[t.sk.name]))
+	}
+	return escapeAndJoin(segments, t.valueSeparatorChar, t.escapeChar)
+}
+
+func attributeString(av types.AttributeValue) string {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return v.Value
+	case *types.AttributeValueMemberN:
+		return v.Value
+	default:
+		return ""
+	}
+}
+
+// storedItem reads the current state of p's item. Returns an invalid value and a nil item if it doesn't exist.
+func (t *table) storedItem(ctx context.Context, p data.Persistable, pt *persistableType, key map[string]types.AttributeValue) (reflect.Value, map[string]types.AttributeValue, gomerr.Gomerr) {
+	input := &dynamodb.GetItemInput{
+		Key:                    key,
+		ConsistentRead:         &trueVal,
+		TableName:              t.tableName,
+		ReturnConsumedCapacity: t.returnConsumedCapacity(),
+	}
+	output, err := t.ddb.GetItem(ctx, input)
+	if err != nil {
+		return reflect.Value{}, nil, t.writeError(err, input)
+	}
+	t.recordCapacity(p.TypeName(), false, output.ConsumedCapacity)
+
+	if output.Item == nil {
+		return reflect.Value{}, nil, nil
+	}
+
+	stored, ge := pt.resolver(output.Item)
+	if ge != nil {
+		return reflect.Value{}, nil, ge
+	}
+
+	if ge = pt.populateKeyFieldsFromAttributes(stored.(data.Persistable), output.Item, t.indexes, t.valueSeparatorChar, false); ge != nil {
+		return reflect.Value{}, nil, ge
+	}
+
+	return reflect.ValueOf(stored).Elem(), output.Item, nil
+}
+
+// unchangedCondition returns a condition that's true if the attributes storing the unique tuples' values still have
+// the values in the stored item. If the item didn't exist, the condition is that it still doesn't.
+func (t *table) unchangedCondition(pt *persistableType, stored map[string]types.AttributeValue, names map[string]string, values map[string]types.AttributeValue) string {
+	if stored == nil {
+		return "attribute_not_exists(" + attributePath([]string{t.pk.name}, names) + ")"
+	}
+
+	conditions := []string{"attribute_exists(" + attributePath([]string{t.pk.name}, names) + ")"}
+	for _, attribute := range pt.uniqueAttributes {
+		path := attributePath([]string{attribute}, names)
+		if av, ok := stored[attribute]; ok {
+			conditions = append(conditions, path+"="+valueAlias(av, values))
+		} else {
+			conditions = append(conditions, "attribute_not_exists("+path+")")
+		}
+	}
+
+	return strings.Join(conditions, " AND ")
+}
+
+// transactWrite writes p's item along with the sentinel operations. If the main item's condition fails, mainFailed
+// provides the error. The written function, if not nil, is called once the items have been written.
+func (t *table) transactWrite(ctx context.Context, p data.Persistable, main types.TransactWriteItem, owner string, ops []sentinelOp, mainFailed func() gomerr.Gomerr, written func()) gomerr.Gomerr {
+	items := []types.TransactWriteItem{main}
+	for _, op := range ops {
+		names := map[string]string{"#pk": t.pk.name, "#owner": ownerAttribute}
+		values := map[string]types.AttributeValue{":owner": &types.AttributeValueMemberS{Value: owner}}
+		condition := "attribute_not_exists(#pk) OR #owner=:owner" // a sentinel may already be claimed by this item
+
+		if op.claim {
+			item := map[string]types.AttributeValue{ownerAttribute: values[":owner"]}
+			for k, v := range op.key {
+				item[k] = v
+			}
+			items = append(items, types.TransactWriteItem{Put: &types.Put{
+				Item:                      item,
+				TableName:                 t.tableName,
+				ConditionExpression:       &condition,
+				ExpressionAttributeNames:  names,
+				ExpressionAttributeValues: values,
+			}})
+		} else {
+			items = append(items, types.TransactWriteItem{Delete: &types.Delete{
+				Key:                       op.key,
+				TableName:                 t.tableName,
+				ConditionExpression:       &condition,
+				ExpressionAttributeNames:  names,
+				ExpressionAttributeValues: values,
+			}})
+		}
+	}
+
+	conditionFailed := func(failed int, err error) gomerr.Gomerr {
+		return sentinelError(p, failed, ops, err, mainFailed)
+	}
+	if tx := t.stagingTransaction(ctx); tx != nil {
+		return tx.stage(p, items, conditionFailed, written)
+	}
+
+	input := &dynamodb.TransactWriteItemsInput{TransactItems: items, ReturnConsumedCapacity: t.returnConsumedCapacity()}
+	output, err := t.ddb.TransactWriteItems(ctx, input)
+	if err != nil {
+		if failed := failedItem(err); failed >= 0 {
+			return conditionFailed(failed, err)
+		}
+		return t.writeError(err, input)
+	}
+	t.recordCapacity(p.TypeName(), true, ptrs(output.ConsumedCapacity)...)
+
+	if written != nil {
+		written()
+	}
+
+	return nil
+}
+
+// failedItem returns the index of the first item whose condition failed if err is a cancelled transaction, or -1.
+func failedItem(err error) int {
+	var tce *types.TransactionCanceledException
+	if errors.As(err, &tce) {
+		for i, reason := range tce.CancellationReasons {
+			if reason.Code != nil && *reason.Code == conditionCheckFailed {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// sentinelError returns the error for a transactWrite whose item at the failed index (0 for the main item, 1 or more for
+// a sentinelOp) didn't satisfy its condition.
+func sentinelError(p data.Persistable, failed int, ops []sentinelOp, err error, mainFailed func() gomerr.Gomerr) gomerr.Gomerr {
+	if failed == 0 || failed > len(ops) {
+		return mainFailed().Wrap(err)
+	}
+
+	op := ops[failed-1]
+	if op.claim {
+		return constraint.New("unique", op.tuple, func(any) gomerr.Gomerr {
+			return constraint.NotSatisfied(p)
+		}).Test(p).Wrap(err)
+	}
+	return gomerr.Conflict(p.TypeName(), "", "unique_value_claimed_by_another").WithSource(p).AddAttribute("fields", op.tuple).Wrap(err)
+}
+
+func nilIfEmpty(values map[string]types.AttributeValue) map[string]types.AttributeValue {
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+func (t *table) writeError(err error, input any) gomerr.Gomerr {
+	var notFoundErr *types.ResourceNotFoundException
+	if errors.As(err, &notFoundErr) {
+		return gomerr.Unprocessable("table", *t.tableName).Wrap(err)
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "RequestLimitExceeded", "ProvisionedThroughputExceededException":
+			return limit.UnquantifiedExcess("DynamoDB", "throughput").Wrap(err)
+		case "ItemCollectionSizeLimitExceededException":
+			return limit.Exceeded("DynamoDB", "item.size()", maxItemSize, limit.NotApplicable, limit.Unknown)
+		}
+	}
+
+	return gomerr.Dependency("DynamoDB", input).Wrap(err)
+}
+
+// putWithSentinels writes the item described by input and moves its sentinels as needed.
+func (t *table) putWithSentinels(ctx context.Context, p data.Persistable, pt *persistableType, input *dynamodb.PutItemInput, ensureUniqueId bool, versioned bool, expectedVersion uint64) gomerr.Gomerr {
+	key := make(map[string]types.AttributeValue, 2)
+	if ge := t.populateKeyValues(key, p, t.valueSeparatorChar, true); ge != nil {
+		return ge
+	}
+
+	if input.ExpressionAttributeNames == nil {
+		input.ExpressionAttributeNames = make(map[string]string)
+	}
+	if input.ExpressionAttributeValues == nil {
+		input.ExpressionAttributeValues = make(map[string]types.AttributeValue)
+	}
+
+	var before reflect.Value
+	if !ensureUniqueId {
+		var stored map[string]types.AttributeValue
+		var ge gomerr.Gomerr
+		if before, stored, ge = t.storedItem(ctx, p, pt, key); ge != nil {
+			return ge
+		}
+
+		unchanged := t.unchangedCondition(pt, stored, input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+		if input.ConditionExpression != nil {
+			unchanged = "(" + *input.ConditionExpression + ") AND " + unchanged
+		}
+		input.ConditionExpression = &unchanged
+	}
+
+	if len(input.ExpressionAttributeNames) == 0 {
+		input.ExpressionAttributeNames = nil
+	}
+	input.ExpressionAttributeValues = nilIfEmpty(input.ExpressionAttributeValues)
+
+	ops := t.sentinelOps(pt, before, reflect.ValueOf(p).Elem())
+	return t.transactWrite(ctx, p, types.TransactWriteItem{Put: &types.Put{
+		Item:                      input.Item,
+		TableName:                 input.TableName,
+		ConditionExpression:       input.ConditionExpression,
+		ExpressionAttributeNames:  input.ExpressionAttributeNames,
+		ExpressionAttributeValues: input.ExpressionAttributeValues,
+	}}, t.owner(key), ops, func() gomerr.Gomerr {
+		if ensureUniqueId {
+			return constraint.New("uniqueKeys", nil, func(any) gomerr.Gomerr {
+				return constraint.NotSatisfied(key)
+			}).Test(key).AddAttribute("persistable", p)
+		}
+		if versioned {
+			return gomerr.Conflict(p.TypeName(), "", "version_mismatch").WithSource(p).AddAttribute("expectedVersion", expectedVersion)
+		}
+		return gomerr.Conflict(p.TypeName(), "", "concurrent_modification").WithSource(p)
+	}, nil)
+}
+
+// updateWithSentinels performs the update along with moving the sentinels of any unique tuples whose values differ from
+// the stored item's. Returns false if no sentinels need to change, in which case nothing is written.
+func (t *table) updateWithSentinels(ctx context.Context, p data.Persistable, pt *persistableType, update *types.Update, versioned bool, expectedVersion uint64) (bool, gomerr.Gomerr) {
+	stored, storedItem, ge := t.storedItem(ctx, p, pt, update.Key)
+	if ge != nil {
+		return true, ge
+	}
+	if storedItem == nil {
+		return true, dataerr.PersistableNotFound(p.TypeName(), update.Key)
+	}
+
+	ops := t.sentinelOps(pt, stored, reflect.ValueOf(p).Elem())
+	if len(ops) == 0 {
+		return false, nil
+	}
+
+	condition := *update.ConditionExpression + " AND " + t.unchangedCondition(pt, storedItem, update.ExpressionAttributeNames, update.ExpressionAttributeValues)
+	update.ConditionExpression = &condition
+
+	return true, t.transactWrite(ctx, p, types.TransactWriteItem{Update: update}, t.owner(update.Key), ops, func() gomerr.Gomerr {
+		if versioned {
+			return gomerr.Conflict(p.TypeName(), "", "version_mismatch").WithSource(p).AddAttribute("expectedVersion", expectedVersion)
+		}
+		return gomerr.Conflict(p.TypeName(), "", "concurrent_modification").WithSource(p)
+	}, func() {
+		if versioned {
+			pt.versionField.SetVersion(reflect.ValueOf(p).Elem(), expectedVersion+1)
+		}
+	})
+}
+
+// deleteWithSentinels deletes p's item and releases its sentinels. Returns false if the item doesn't exist, in which
+// case nothing is written.
+func (t *table) deleteWithSentinels(ctx context.Context, p data.Persistable, pt *persistableType, key map[string]types.AttributeValue) (bool, gomerr.Gomerr) {
+	stored, storedItem, ge := t.storedItem(ctx, p, pt, key)
+	if ge != nil {
+		return true, ge
+	}
+	if storedItem == nil {
+		return false, nil
+	}
+
+	names := make(map[string]string)
+	values := make(map[string]types.AttributeValue)
+	condition := t.unchangedCondition(pt, storedItem, names, values)
+
+	ops := t.sentinelOps(pt, stored, reflect.Value{})
+	return true, t.transactWrite(ctx, p, types.TransactWriteItem{Delete: &types.Delete{
+		Key:                       key,
+		TableName:                 t.tableName,
+		ConditionExpression:       &condition,
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: nilIfEmpty(values),
+	}}, t.owner(key), ops, func() gomerr.Gomerr {
+		return gomerr.Conflict(p.TypeName(), "", "concurrent_modification").WithSource(p)
+	}, nil)
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
@@ -27,7 +28,7 @@ import (
 type table struct {
 	index
 	tableName                   *string
-	ddb                         *dynamodb.Client
+	ddb                         Client
 	defaultLimit                *int64
 	maxLimit                    *int64
 	defaultConsistencyType      ConsistencyType
@@ -38,12 +39,15 @@ type table struct {
 	escapeChar                  byte
 	failDeleteIfNotPresent      bool
 	validateKeyFieldConsistency bool
+	uniqueness                  UniquenessEnforcement
 	constraintTool              *structs.Tool
-	typeDiscriminator           *typeDiscriminator // For multi-type queries with nested Queryables
+	typeDiscriminator           *typeDiscriminator // For multi-type queries with nested Queryables and scans
+	metrics                     MetricsRecorder
+	retryPolicy                 RetryPolicy
 }
 
 type Configuration struct {
-	DynamoDb                    *dynamodb.Client
+	DynamoDb                    Client
 	MaxResultsDefault           int64
 	MaxResultsMax               int64
 	ConsistencyDefault          ConsistencyType
@@ -52,6 +56,9 @@ type Configuration struct {
 	QueryWildcardChar           byte
 	FailDeleteIfNotPresent      bool
 	ValidateKeyFieldConsistency bool
+	UniquenessEnforcement       UniquenessEnforcement
+	Metrics                     MetricsRecorder
+	RetryPolicy                 *RetryPolicy // If nil, DefaultRetryPolicy is used
 }
 
 var tables = make(map[string]data.Store)
@@ -90,6 +97,15 @@ func Store(tableName string, config *Configuration /* resolver data.ItemResolver
 		nextTokenizer:               nextTokenizer{cipher: config.NextTokenCipher},
 		failDeleteIfNotPresent:      config.FailDeleteIfNotPresent,
 		validateKeyFieldConsistency: config.ValidateKeyFieldConsistency,
+		uniqueness:                  config.UniquenessEnforcement,
+		metrics:                     config.Metrics,
+	}
+	t.retryPolicy = DefaultRetryPolicy
+	if config.RetryPolicy != nil {
+		t.retryPolicy = *config.RetryPolicy
+	}
+	if t.ddb != nil && t.retryPolicy.MaxAttempts > 1 {
+		t.ddb = retryingClient{t.ddb, t.retryPolicy}
 	}
 
 	if t.valueSeparatorChar, ge = validOrDefaultChar(config.ValueSeparatorChar, ValueSeparatorCharDefault); ge != nil {
@@ -158,6 +174,10 @@ func (t *table) prepare(persistables []data.Persistable) gomerr.Gomerr {
 
 	t.indexes[""] = &t.index
 
+	if t.uniqueness == SentinelUniqueness && (t.pk.attributeType != string(types.ScalarAttributeTypeS) || t.sk != nil && t.sk.attributeType != string(types.ScalarAttributeTypeS)) {
+		return gomerr.Configuration("SentinelUniqueness requires the table's keys to be strings").AddAttribute("table", *t.tableName)
+	}
+
 	for _, lsid := range output.Table.LocalSecondaryIndexes {
 		lsi := &index{
 			name:                lsid.IndexName,
@@ -189,36 +209,21 @@ func (t *table) prepare(persistables []data.Persistable) gomerr.Gomerr {
 	}
 
 	for _, persistable := range persistables {
-		pType := reflect.TypeOf(persistable)
-		pElem := pType.Elem()
-
-		unqualifiedPersistableName := pElem.String()
-		unqualifiedPersistableName = unqualifiedPersistableName[strings.Index(unqualifiedPersistableName, ".")+1:]
+		pElem := reflect.TypeOf(persistable).Elem()
+		unqualifiedPersistableName := unqualifiedName(pElem)
 
 		pt, ge := newPersistableType(t, unqualifiedPersistableName, pElem)
 		if ge != nil {
 			return ge
 		}
 
-		// Validate that each key in each index has fully defined key fields for this persistable
-		for _, idx := range t.indexes {
-			for _, attribute := range idx.keyAttributes() {
-				// NB: require types to declare all key fields, even if name matches what's defined by ddb
-				// TODO: support 'KeyField string `db.keys=""`' to avoid re-typing field name
-				if keyFields := attribute.keyFieldsByPersistable[unqualifiedPersistableName]; keyFields != nil {
-					for i, kf := range keyFields {
-						if kf == nil {
-							return gomerr.Configuration(fmt.Sprintf("index %s is missing a key field: %s[%s][%d]",
-								idx.friendlyName(), attribute.name, unqualifiedPersistableName, i),
-							).AddAttribute("keyFields", keyFields)
-						}
-					}
-				}
-			}
+		if ge = t.checkKeyFields(unqualifiedPersistableName); ge != nil {
+			return ge
 		}
 
 		t.persistableTypes[unqualifiedPersistableName] = pt
 	}
+	t.typeDiscriminator = t.buildTypeDiscriminator()
 
 	t.constraintTool = NewConstraintTool(t)
 	for _, p := range persistables {
@@ -234,6 +239,33 @@ func (t *table) Name() string {
 	return *t.tableName
 }
 
+// unqualifiedName returns the name of the persistable type without its package qualifier.
+func unqualifiedName(pElem reflect.Type) string {
+	name := pElem.String()
+	return name[strings.Index(name, ".")+1:]
+}
+
+// checkKeyFields validates that each key in each index has fully defined key fields for the persistable.
+func (t *table) checkKeyFields(persistableName string) gomerr.Gomerr {
+	for _, idx := range t.indexes {
+		for _, attribute := range idx.keyAttributes() {
+			// NB: require types to declare all key fields, even if name matches what's defined by ddb
+			// TODO: support 'KeyField string `db.keys=""`' to avoid re-typing field name
+			if keyFields := attribute.keyFieldsByPersistable[persistableName]; keyFields != nil {
+				for i, kf := range keyFields {
+					if kf == nil {
+						return gomerr.Configuration(fmt.Sprintf("index %s is missing a key field: %s[%s][%d]",
+							idx.friendlyName(), attribute.name, persistableName, i),
+						).AddAttribute("keyFields", keyFields)
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
 func (t *table) Create(ctx context.Context, p data.Persistable) (ge gomerr.Gomerr) {
 	defer func() {
 		if ge != nil {
@@ -241,6 +273,7 @@ func (t *table) Create(ctx context.Context, p data.Persistable) (ge gomerr.Gomer
 			ge = dataerr.Store("Create", p).Wrap(ge)
 		}
 	}()
+	defer t.recordOperation("Create", p.TypeName(), time.Now(), &ge)
 
 	// Always validate constraints on create
 	ge = t.put(ctx, p, true, true)
@@ -248,30 +281,49 @@ func (t *table) Create(ctx context.Context, p data.Persistable) (ge gomerr.Gomer
 	return
 }
 
+// Update applies the update's non-zero field values to p and then writes only the changed attributes using UpdateItem,
+// leaving attributes changed by other writers intact. Secondary index key attributes that depend on a changed field are
+// recomputed (or removed if the index no longer applies). If update is nil, p is written in its entirety.
 func (t *table) Update(ctx context.Context, p data.Persistable, update data.Persistable) (ge gomerr.Gomerr) {
 	defer func() {
 		if ge != nil {
 			ge = dataerr.Store("Update", p).Wrap(ge)
 		}
 	}()
+	defer t.recordOperation("Update", p.TypeName(), time.Now(), &ge)
 
-	// TODO:p1 support partial update vs put()
+	if update == nil {
+		return t.put(ctx, p, false, false)
+	}
 
-	validateConstraints := false
 	pt := t.persistableTypes[p.TypeName()]
+	var changes []fieldChange
+	validateConstraints := mergeFields(reflect.ValueOf(update).Elem(), reflect.ValueOf(p).Elem(), pt, nil, &changes)
+	if len(changes) == 0 {
+		return nil
+	}
 
-	if update != nil {
-		uv := reflect.ValueOf(update).Elem()
-		pv := reflect.ValueOf(p).Elem()
-		validateConstraints = mergeFields(uv, pv, pt)
+	if validateConstraints && t.uniqueness == QueryUniqueness {
+		if ge = structs.ApplyTools(p, structs.EnsureContext().With("ctx", ctx), t.constraintTool); ge != nil {
+			return ge
+		}
 	}
 
-	ge = t.put(ctx, p, validateConstraints, false)
+	return t.updateItem(ctx, p, pt, changes, validateConstraints)
+}
 
-	return
+// fieldChange is a field whose value was changed by an update. The path holds the names of the fields leading to it
+// from the persistable, excluding any embedded structs.
+type fieldChange struct {
+	path  []string
+	value reflect.Value
 }
 
-func mergeFields(uv, pv reflect.Value, pt *persistableType) bool {
+// mergeFields applies the non-zero values of uv onto pv. Fields in the update that match the current value are
+// cleared so the update reflects only what changed. If changes is non-nil, each changed field is appended to it. A
+// nested struct pointer that was nil in pv is recorded as a single change. Returns true if any changed field
+// participates in a constraint.
+func mergeFields(uv, pv reflect.Value, pt *persistableType, path []string, changes *[]fieldChange) bool {
 	validateConstraints := false
 
 	for i := 0; i < uv.NumField(); i++ {
@@ -281,9 +333,19 @@ func mergeFields(uv, pv reflect.Value, pt *persistableType) bool {
 		}
 
 		pField := pv.Field(i)
-		fieldName := uv.Type().Field(i).Name
-		if uField.Kind() == reflect.Struct {
-			mergeFields(uField, pField, nil)
+		field := uv.Type().Field(i)
+		fieldName := field.Name
+		fieldPath := path
+		if !field.Anonymous {
+			fieldPath = append(path[:len(path):len(path)], fieldName)
+		}
+
+		if uField.Kind() == reflect.Struct && uField.Type() != timeType {
+			if field.Anonymous {
+				validateConstraints = mergeFields(uField, pField, pt, fieldPath, changes) || validateConstraints
+			} else {
+				mergeFields(uField, pField, nil, fieldPath, changes)
+			}
 			continue
 		}
 
@@ -293,17 +355,21 @@ func mergeFields(uv, pv reflect.Value, pt *persistableType) bool {
 			if uField.IsNil() {
 				continue
 			}
-			if uField.Elem().Kind() == reflect.Struct {
+			if uField.Elem().Kind() == reflect.Struct && uField.Elem().Type() != timeType {
 				if pField.IsNil() {
 					pField.Set(reflect.New(uField.Elem().Type()))
+					mergeFields(uField.Elem(), pField.Elem(), nil, nil, nil)
+					recordChange(changes, fieldPath, pField)
+				} else {
+					mergeFields(uField.Elem(), pField.Elem(), nil, fieldPath, changes)
 				}
-				mergeFields(uField.Elem(), pField.Elem(), nil)
 				continue
 			}
 			if !pField.IsNil() && reflect.DeepEqual(uField.Elem().Interface(), pField.Elem().Interface()) {
 				uField.Set(reflect.Zero(uField.Type()))
 			} else {
 				pField.Set(uField)
+				recordChange(changes, fieldPath, pField)
 				if pt != nil && pt.constraintFields[fieldName] {
 					validateConstraints = true
 				}
@@ -313,6 +379,7 @@ func mergeFields(uv, pv reflect.Value, pt *persistableType) bool {
 				continue
 			}
 			pField.Set(uField)
+			recordChange(changes, fieldPath, pField)
 			if pt != nil && pt.constraintFields[fieldName] {
 				validateConstraints = true
 			}
@@ -322,62 +389,330 @@ func mergeFields(uv, pv reflect.Value, pt *persistableType) bool {
 	return validateConstraints
 }
 
+func recordChange(changes *[]fieldChange, path []string, value reflect.Value) {
+	if changes != nil {
+		*changes = append(*changes, fieldChange{path: path, value: value})
+	}
+}
+
+// updateItem writes the changed fields of p with SET and REMOVE actions. The update is conditioned on the item
+// existing and, for versioned types, on the stored version matching p's. The resulting item's values are copied back
+// into p.
+func (t *table) updateItem(ctx context.Context, p data.Persistable, pt *persistableType, changes []fieldChange, uniqueValuesChanged bool) gomerr.Gomerr {
+	key := make(map[string]types.AttributeValue, 2)
+	if ge := t.populateKeyValues(key, p, t.valueSeparatorChar, true); ge != nil {
+		return ge
+	}
+
+	names := make(map[string]string)
+	values := make(map[string]types.AttributeValue)
+	var sets, removes []string
+
+	changedFields := make(map[string]bool, len(changes))
+	for _, change := range changes {
+		fieldName := change.path[0]
+		changedFields[fieldName] = true
+
+		// Key fields are only stored in key attributes, which are handled below. The version is handled separately.
+		if pt.keyFields[fieldName] || pt.dbNames[fieldName] == "-" || fieldName == string(pt.versionField) {
+			continue
+		}
+
+		// A time.Time TTL field is stored as the expiry attribute, which is also handled below
+		if fieldName == pt.ttlField && pt.attributeName(fieldName) == pt.ttlAttribute {
+			continue
+		}
+
+		av, err := attributevalue.Marshal(change.value.Interface())
+		if err != nil {
+			return gomerr.Marshal(p.TypeName(), p).AddAttribute("field", strings.Join(change.path, ".")).Wrap(err)
+		}
+
+		segments := append([]string{pt.attributeName(fieldName)}, change.path[1:]...)
+		sets = append(sets, attributePath(segments, names)+"="+valueAlias(av, values))
+	}
+
+	// Keep key attributes consistent with the fields they're composed from
+	pv := reflect.ValueOf(p).Elem()
+	updatedKeys := make(map[string]bool)
+	for _, idx := range t.indexes {
+		for _, attribute := range idx.keyAttributes() {
+			keyFields := attribute.keyFieldsByPersistable[pt.name]
+			if updatedKeys[attribute.name] || !anyChanged(keyFields, changedFields) {
+				continue
+			}
+
+			if attribute == t.pk || attribute == t.sk {
+				return gomerr.Unprocessable("table key fields can't be changed by an update", keyFieldNames(keyFields)).AddAttribute("persistable", p)
+			}
+
+			if av := attribute.attributeValue(pv, pt.name, t.valueSeparatorChar, 0); av != nil {
+				sets = append(sets, attributePath([]string{attribute.name}, names)+"="+valueAlias(av, values))
+			} else {
+				removes = append(removes, attributePath([]string{attribute.name}, names))
+			}
+			updatedKeys[attribute.name] = true
+		}
+	}
+
+	// The expiry is rewritten by every update so that a time.Duration TTL is extended from the time of the write
+	if pt.ttlField != "" && (len(sets) > 0 || len(removes) > 0 || changedFields[pt.ttlField]) {
+		ttlPath := attributePath([]string{pt.ttlAttribute}, names)
+		if expiry, ok := pt.expiry(pv, time.Now()); ok {
+			sets = append(sets, ttlPath+"="+valueAlias(expiry, values))
+		} else {
+			removes = append(removes, ttlPath)
+		}
+	}
+
+	if len(sets) == 0 && len(removes) == 0 {
+		return nil
+	}
+
+	conditionExpression := "attribute_exists(" + attributePath([]string{t.pk.name}, names) + ")"
+
+	expectedVersion, versioned := pt.versionField.Version(pv)
+	if versioned {
+		versionPath := attributePath([]string{pt.attributeName(string(pt.versionField))}, names)
+		if expectedVersion == 0 {
+			conditionExpression += " AND attribute_not_exists(" + versionPath + ")"
+		} else {
+			conditionExpression += " AND " + versionPath + "=" + valueAlias(&types.AttributeValueMemberN{Value: strconv.FormatUint(expectedVersion, 10)}, values)
+		}
+		sets = append(sets, versionPath+"="+valueAlias(&types.AttributeValueMemberN{Value: strconv.FormatUint(expectedVersion+1, 10)}, values))
+	}
+
+	updateExpression := ""
+	if len(sets) > 0 {
+		updateExpression = "SET " + strings.Join(sets, ", ")
+	}
+	if len(removes) > 0 {
+		updateExpression += " REMOVE " + strings.Join(removes, ", ")
+	}
+
+	if uniqueValuesChanged && t.usesSentinels(pt) {
+		// Transactions can't return the updated item, so p keeps its merged values
+		if written, ge := t.updateWithSentinels(ctx, p, pt, &types.Update{
+			Key:                       key,
+			TableName:                 t.tableName,
+			UpdateExpression:          aws.String(strings.TrimSpace(updateExpression)),
+			ConditionExpression:       &conditionExpression,
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+		}, versioned, expectedVersion); written {
+			return ge
+		}
+	}
+
+	conditionFailed := func(err error) gomerr.Gomerr {
+		if versioned {
+			return gomerr.Conflict(p.TypeName(), "", "version_mismatch").WithSource(p).AddAttribute("expectedVersion", expectedVersion).Wrap(err)
+		}
+		return dataerr.PersistableNotFound(p.TypeName(), key).Wrap(err)
+	}
+
+	if tx := t.stagingTransaction(ctx); tx != nil {
+		// As with sentinels, p keeps its merged values since the transaction can't return the updated item
+		return tx.stage(p, []types.TransactWriteItem{{Update: &types.Update{
+			Key:                       key,
+			TableName:                 t.tableName,
+			UpdateExpression:          aws.String(strings.TrimSpace(updateExpression)),
+			ConditionExpression:       &conditionExpression,
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: nilIfEmpty(values),
+		}}}, func(_ int, err error) gomerr.Gomerr { return conditionFailed(err) }, func() {
+			if versioned {
+				pt.versionField.SetVersion(pv, expectedVersion+1)
+			}
+		})
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		Key:                       key,
+		TableName:                 t.tableName,
+		UpdateExpression:          aws.String(strings.TrimSpace(updateExpression)),
+		ConditionExpression:       &conditionExpression,
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: nilIfEmpty(values),
+		ReturnValues:              types.ReturnValueAllNew,
+		ReturnConsumedCapacity:    t.returnConsumedCapacity(),
+	}
+	output, err := t.ddb.UpdateItem(ctx, input)
+	if err == nil {
+		t.recordCapacity(p.TypeName(), true, output.ConsumedCapacity)
+	}
+	if err != nil {
+		if ge := conditionalCheckFailure.Test(err); ge != nil {
+			return conditionFailed(err)
+		}
+
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) {
+			switch apiErr.ErrorCode() {
+			case "RequestLimitExceeded", "ProvisionedThroughputExceededException":
+				return limit.UnquantifiedExcess("DynamoDB", "throughput").Wrap(err)
+			case "ItemCollectionSizeLimitExceededException":
+				return limit.Exceeded("DynamoDB", "item.size()", maxItemSize, limit.NotApplicable, limit.Unknown)
+			}
+		}
+
+		return gomerr.Dependency("DynamoDB", input).Wrap(err)
+	}
+
+	if err = attributevalue.UnmarshalMap(output.Attributes, p); err != nil {
+		return gomerr.Unmarshal(p.TypeName(), output.Attributes, p).Wrap(err)
+	}
+
+	return pt.populateKeyFieldsFromAttributes(p, output.Attributes, t.indexes, t.valueSeparatorChar, t.validateKeyFieldConsistency)
+}
+
+func anyChanged(keyFields []*keyField, changedFields map[string]bool) bool {
+	for _, kf := range keyFields {
+		if changedFields[kf.name] {
+			return true
+		}
+	}
+	return false
+}
+
+// attributePath returns a document path for the segments using expression attribute names.
+func attributePath(segments []string, names map[string]string) string {
+	aliases := make([]string, len(segments))
+	for i, segment := range segments {
+		alias := "#u" + strconv.Itoa(len(names))
+		names[alias] = segment
+		aliases[i] = alias
+	}
+	return strings.Join(aliases, ".")
+}
+
+func valueAlias(av types.AttributeValue, values map[string]types.AttributeValue) string {
+	alias := ":u" + strconv.Itoa(len(values))
+	values[alias] = av
+	return alias
+}
+
 var conditionalCheckFailure = constraint.New("uniqueKeys", nil, func(toTest any) gomerr.Gomerr {
 	if ccf := gomerr.ErrorAs[*types.ConditionalCheckFailedException](toTest.(error)); ccf != nil {
 		return constraint.NotSatisfied(ccf)
 	}
+	// Within a transaction, a failed condition cancels the transaction instead
+	if tce := gomerr.ErrorAs[*types.TransactionCanceledException](toTest.(error)); tce != nil && failedItem(tce) >= 0 {
+		return constraint.NotSatisfied(tce)
+	}
 	return nil
 })
 
 func (t *table) put(ctx context.Context, p data.Persistable, validateConstraints bool, ensureUniqueId bool) gomerr.Gomerr {
-	// Validate constraints using tool framework
-	if validateConstraints {
+	// Validate constraints using tool framework. Sentinels enforce them as part of the write instead.
+	if validateConstraints && t.uniqueness == QueryUniqueness {
 		if ge := structs.ApplyTools(p, structs.EnsureContext().With("ctx", ctx), t.constraintTool); ge != nil {
 			return ge
 		}
 	}
 
-	av, err := attributevalue.MarshalMap(p)
-	if err != nil {
-		return gomerr.Marshal(p.TypeName(), p).Wrap(err)
-	}
-
 	pt := t.persistableTypes[p.TypeName()]
-	pt.convertFieldNamesToDbNames(&av)
 
-	for _, i := range t.indexes {
-		_ = i.populateKeyValues(av, p, t.valueSeparatorChar, false)
+	// A versioned type's version is incremented on each put. An update is conditioned on the stored version still being
+	// the one that was read, so concurrent updates can't overwrite each other's changes.
+	pv := reflect.ValueOf(p).Elem()
+	expectedVersion, versioned := pt.versionField.Version(pv)
+	if versioned {
+		if ensureUniqueId {
+			pt.versionField.SetVersion(pv, 1)
+		} else {
+			pt.versionField.SetVersion(pv, expectedVersion+1)
+		}
 	}
 
-	// Remove key fields from attributes - they're stored in composite keys only
-	// TODO:p0 this should be opt-in or performed only if STD is being used with names not present in the persistable type
-	pt.removeKeyFieldsFromAttributes(&av)
+	ge := t.putItem(ctx, p, pt, ensureUniqueId, versioned, expectedVersion)
+	if versioned {
+		if ge != nil {
+			pt.versionField.SetVersion(pv, expectedVersion)
+		} else if tx := t.stagingTransaction(ctx); tx != nil {
+			tx.onRollback(func() { pt.versionField.SetVersion(pv, expectedVersion) })
+		}
+	}
+
+	return ge
+}
+
+func (t *table) putItem(ctx context.Context, p data.Persistable, pt *persistableType, ensureUniqueId, versioned bool, expectedVersion uint64) gomerr.Gomerr {
+	av, ge := t.marshalItem(p, pt)
+	if ge != nil {
+		return ge
+	}
 
 	// TODO: here we could compare the current av map w/ one we stashed into the object somewhere
 
-	var uniqueIdConditionExpression *string
+	var conditionExpression string
+	var expressionAttributeNames map[string]string
+	var expressionAttributeValues map[string]types.AttributeValue
 	if ensureUniqueId {
-		expression := fmt.Sprintf("attribute_not_exists(%s)", t.pk.name)
+		conditionExpression = fmt.Sprintf("attribute_not_exists(%s)", t.pk.name)
 		if t.sk != nil {
-			expression += fmt.Sprintf(" AND attribute_not_exists(%s)", t.sk.name)
+			conditionExpression += fmt.Sprintf(" AND attribute_not_exists(%s)", t.sk.name)
+		}
+		if pt.ttlField != "" {
+			// An expired item that DynamoDB hasn't yet deleted can be replaced
+			expressionAttributeNames = make(map[string]string, 1)
+			expressionAttributeValues = make(map[string]types.AttributeValue, 1)
+			now := &types.AttributeValueMemberN{Value: strconv.FormatInt(time.Now().Unix(), 10)}
+			conditionExpression = "(" + conditionExpression + ") OR " + attributePath([]string{pt.ttlAttribute}, expressionAttributeNames) + "<=" + valueAlias(now, expressionAttributeValues)
+		}
+	} else if versioned {
+		expressionAttributeNames = make(map[string]string, 1)
+		versionName := safeName(pt.attributeName(string(pt.versionField)), expressionAttributeNames)
+		if expectedVersion == 0 {
+			// Allows versioning to be added to a type with existing items
+			conditionExpression = "attribute_not_exists(" + versionName + ")"
+		} else {
+			conditionExpression = versionName + "=:version"
+			expressionAttributeValues = map[string]types.AttributeValue{
+				":version": &types.AttributeValueMemberN{Value: strconv.FormatUint(expectedVersion, 10)},
+			}
+		}
+		if len(expressionAttributeNames) == 0 {
+			expressionAttributeNames = nil
 		}
-		uniqueIdConditionExpression = &expression
 	}
 
-	// TODO:p1 optimistic locking
-
 	input := &dynamodb.PutItemInput{
-		Item:                av,
-		TableName:           t.tableName,
-		ConditionExpression: uniqueIdConditionExpression,
+		Item:                      av,
+		TableName:                 t.tableName,
+		ConditionExpression:       ptrOrNil(conditionExpression),
+		ExpressionAttributeNames:  expressionAttributeNames,
+		ExpressionAttributeValues: expressionAttributeValues,
 	}
-	_, err = t.ddb.PutItem(ctx, input) // TODO:p3 look at result data to track capacity or other info?
+	if t.usesSentinels(pt) {
+		return t.putWithSentinels(ctx, p, pt, input, ensureUniqueId, versioned, expectedVersion)
+	}
+
+	conditionFailed := func(err error) gomerr.Gomerr {
+		if ensureUniqueId {
+			return conditionalCheckFailure.Test(err).AddAttribute("persistable", p)
+		}
+		if versioned {
+			return gomerr.Conflict(p.TypeName(), "", "version_mismatch").WithSource(p).AddAttribute("expectedVersion", expectedVersion).Wrap(err)
+		}
+		return gomerr.Dependency("DynamoDB", input).Wrap(err)
+	}
+
+	if tx := t.stagingTransaction(ctx); tx != nil {
+		return tx.stage(p, []types.TransactWriteItem{{Put: &types.Put{
+			Item:                      input.Item,
+			TableName:                 input.TableName,
+			ConditionExpression:       input.ConditionExpression,
+			ExpressionAttributeNames:  input.ExpressionAttributeNames,
+			ExpressionAttributeValues: input.ExpressionAttributeValues,
+		}}}, func(_ int, err error) gomerr.Gomerr { return conditionFailed(err) }, nil)
+	}
+
+	input.ReturnConsumedCapacity = t.returnConsumedCapacity()
+	output, err := t.ddb.PutItem(ctx, input)
 	if err != nil {
 		if ge := conditionalCheckFailure.Test(err); ge != nil {
-			if ensureUniqueId {
-				return ge.AddAttribute("persistable", p)
-			}
-			return gomerr.Dependency("DynamoDB", input).Wrap(err)
+			return conditionFailed(err)
 		}
 
 		var apiErr smithy.APIError
@@ -393,34 +728,68 @@ func (t *table) put(ctx context.Context, p data.Persistable, validateConstraints
 		return gomerr.Dependency("DynamoDB", input).Wrap(err)
 	}
 
+	t.recordCapacity(p.TypeName(), true, output.ConsumedCapacity)
+
 	return nil
 }
 
+// partialKey returns true if one of the key's composite values ends with an unset key field. Such an item can only be
+// read by querying for it.
+func (t *table) partialKey(typeName string, key map[string]types.AttributeValue) bool {
+	if len(t.pk.keyFieldsByPersistable[typeName]) > 1 {
+		if pk, ok := key[t.pk.name].(*types.AttributeValueMemberS); ok && pk.Value[len(pk.Value)-1] == t.valueSeparatorChar {
+			return true
+		}
+	}
+	if t.sk != nil && len(t.sk.keyFieldsByPersistable[typeName]) > 1 {
+		if sk, ok := key[t.sk.name].(*types.AttributeValueMemberS); ok && sk.Value[len(sk.Value)-1] == t.valueSeparatorChar {
+			return true
+		}
+	}
+	return false
+}
+
+// marshalItem returns the attributes to store for p, including the key attributes of each index that applies to it.
+func (t *table) marshalItem(p data.Persistable, pt *persistableType) (map[string]types.AttributeValue, gomerr.Gomerr) {
+	av, err := attributevalue.MarshalMap(p)
+	if err != nil {
+		return nil, gomerr.Marshal(p.TypeName(), p).Wrap(err)
+	}
+
+	pt.convertFieldNamesToDbNames(&av)
+
+	if expiry, ok := pt.expiry(reflect.ValueOf(p).Elem(), time.Now()); ok {
+		av[pt.ttlAttribute] = expiry
+	} else if pt.ttlField != "" {
+		delete(av, pt.ttlAttribute)
+	}
+
+	for _, i := range t.indexes {
+		_ = i.populateKeyValues(av, p, t.valueSeparatorChar, false)
+	}
+
+	// Remove key fields from attributes - they're stored in composite keys only
+	// TODO:p0 this should be opt-in or performed only if STD is being used with names not present in the persistable type
+	pt.removeKeyFieldsFromAttributes(&av)
+
+	return av, nil
+}
+
 func (t *table) Read(ctx context.Context, p data.Persistable) (ge gomerr.Gomerr) {
 	defer func() {
 		if ge != nil {
 			ge = dataerr.Store("Read", p).Wrap(ge)
 		}
 	}()
+	defer t.recordOperation("Read", p.TypeName(), time.Now(), &ge)
 
 	key := make(map[string]types.AttributeValue, 2)
 	if ge = t.populateKeyValues(key, p, t.valueSeparatorChar, true); ge != nil {
 		return ge
 	}
 
-	useQuery := false
-	if len(t.pk.keyFieldsByPersistable[p.TypeName()]) > 1 {
-		if pk, ok := key[t.pk.name].(*types.AttributeValueMemberS); ok && pk.Value[len(pk.Value)-1] == t.valueSeparatorChar {
-			useQuery = true
-		}
-	}
-	if t.sk != nil && len(t.sk.keyFieldsByPersistable[p.TypeName()]) > 1 {
-		if sk, ok := key[t.sk.name].(*types.AttributeValueMemberS); ok && sk.Value[len(sk.Value)-1] == t.valueSeparatorChar {
-			useQuery = true
-		}
-	}
-
-	if useQuery {
+	partial := t.partialKey(p.TypeName(), key)
+	if partial {
 		pt, ok := t.persistableTypes[p.TypeName()]
 		if !ok {
 			return gomerr.Configuration("no persistable type for " + p.TypeName())
@@ -437,24 +806,35 @@ func (t *table) Read(ctx context.Context, p data.Persistable) (ge gomerr.Gomerr)
 		}
 
 		if ge = t.Query(ctx, q); ge != nil {
-			return ge
-		}
+			// If no index can query the partial key (e.g. its empty field is part of the partition key), read the
+			// item whose key has the field empty.
+			if gomerr.ErrorAs[*dataerr.NoIndexMatchError](ge) == nil {
+				return ge
+			}
+			partial = false
+		} else {
+			results := q.Results()
+			if len(results) == 0 {
+				return dataerr.PersistableNotFound(p.TypeName(), key)
+			} else if len(results) > 1 {
+				return gomerr.Conflict(p.TypeName(), "", "multiple_matches")
+			}
 
-		results := q.Results()
-		if len(results) == 0 {
-			return dataerr.PersistableNotFound(p.TypeName(), key)
-		} else if len(results) > 1 {
-			return gomerr.Conflict(p.TypeName(), "", "multiple_matches")
+			copyFields(reflect.ValueOf(p).Elem(), reflect.ValueOf(results[0]).Elem())
 		}
+	}
 
-		copyFields(reflect.ValueOf(p).Elem(), reflect.ValueOf(results[0]).Elem())
-	} else {
+	if !partial {
 		input := &dynamodb.GetItemInput{
-			Key:            key,
-			ConsistentRead: consistentRead(t.consistencyType(p), true),
-			TableName:      t.tableName,
+			Key:                    key,
+			ConsistentRead:         consistentRead(t.consistencyType(p), true),
+			TableName:              t.tableName,
+			ReturnConsumedCapacity: t.returnConsumedCapacity(),
 		}
 		output, err := t.ddb.GetItem(ctx, input)
+		if err == nil {
+			t.recordCapacity(p.TypeName(), false, output.ConsumedCapacity)
+		}
 		if err != nil {
 			var notFoundErr *types.ResourceNotFoundException
 			if errors.As(err, &notFoundErr) {
@@ -472,18 +852,11 @@ func (t *table) Read(ctx context.Context, p data.Persistable) (ge gomerr.Gomerr)
 			return gomerr.Dependency("DynamoDB", input).Wrap(err)
 		}
 
-		if output.Item == nil {
+		if output.Item == nil || t.persistableTypes[p.TypeName()].expired(output.Item, time.Now()) {
 			return dataerr.PersistableNotFound(p.TypeName(), key)
 		}
 
-		err = attributevalue.UnmarshalMap(output.Item, p)
-		if err != nil {
-			return gomerr.Unmarshal(p.TypeName(), output.Item, p).Wrap(err)
-		}
-
-		// Populate key fields from composite keys
-		pt := t.persistableTypes[p.TypeName()]
-		if ge = pt.populateKeyFieldsFromAttributes(p, output.Item, t.indexes, t.valueSeparatorChar, t.validateKeyFieldConsistency); ge != nil {
+		if ge = t.unmarshalItem(p, output.Item); ge != nil {
 			return ge
 		}
 	}
@@ -498,6 +871,16 @@ func (t *table) Read(ctx context.Context, p data.Persistable) (ge gomerr.Gomerr)
 	return nil
 }
 
+// unmarshalItem populates p from a stored item, including the key fields that are only stored in composite keys.
+func (t *table) unmarshalItem(p data.Persistable, item map[string]types.AttributeValue) gomerr.Gomerr {
+	if err := attributevalue.UnmarshalMap(item, p); err != nil {
+		return gomerr.Unmarshal(p.TypeName(), item, p).Wrap(err)
+	}
+
+	pt := t.persistableTypes[p.TypeName()]
+	return pt.populateKeyFieldsFromAttributes(p, item, t.indexes, t.valueSeparatorChar, t.validateKeyFieldConsistency)
+}
+
 var queryableType = reflect.TypeFor[data.Queryable]()
 var timeType = reflect.TypeFor[time.Time]()
 
@@ -539,6 +922,7 @@ func (t *table) Delete(ctx context.Context, p data.Persistable) (ge gomerr.Gomer
 			ge = dataerr.Store("Delete", p).Wrap(ge)
 		}
 	}()
+	defer t.recordOperation("Delete", p.TypeName(), time.Now(), &ge)
 
 	// TODO:p2 support a soft-delete option
 
@@ -548,6 +932,12 @@ func (t *table) Delete(ctx context.Context, p data.Persistable) (ge gomerr.Gomer
 		return ge
 	}
 
+	if pt := t.persistableTypes[p.TypeName()]; pt != nil && t.usesSentinels(pt) {
+		if written, sge := t.deleteWithSentinels(ctx, p, pt, key); written {
+			return sge
+		}
+	}
+
 	var existenceCheckExpression *string
 	if t.failDeleteIfNotPresent {
 		expression := fmt.Sprintf("attribute_exists(%s)", t.pk.name)
@@ -557,12 +947,23 @@ func (t *table) Delete(ctx context.Context, p data.Persistable) (ge gomerr.Gomer
 		existenceCheckExpression = &expression
 	}
 
+	if tx := t.stagingTransaction(ctx); tx != nil {
+		return tx.stage(p, []types.TransactWriteItem{{Delete: &types.Delete{
+			Key:                 key,
+			TableName:           t.tableName,
+			ConditionExpression: existenceCheckExpression,
+		}}}, func(_ int, err error) gomerr.Gomerr {
+			return dataerr.PersistableNotFound(p.TypeName(), key).Wrap(err)
+		}, nil)
+	}
+
 	input := &dynamodb.DeleteItemInput{
-		Key:                 key,
-		TableName:           t.tableName,
-		ConditionExpression: existenceCheckExpression,
+		Key:                    key,
+		TableName:              t.tableName,
+		ConditionExpression:    existenceCheckExpression,
+		ReturnConsumedCapacity: t.returnConsumedCapacity(),
 	}
-	_, err := t.ddb.DeleteItem(ctx, input)
+	output, err := t.ddb.DeleteItem(ctx, input)
 	if err != nil {
 		var notFoundErr *types.ResourceNotFoundException
 		var condCheckErr *types.ConditionalCheckFailedException
@@ -581,6 +982,8 @@ func (t *table) Delete(ctx context.Context, p data.Persistable) (ge gomerr.Gomer
 		return gomerr.Dependency("DynamoDB", input).Wrap(err)
 	}
 
+	t.recordCapacity(p.TypeName(), true, output.ConsumedCapacity)
+
 	return nil
 }
 
@@ -590,6 +993,7 @@ func (t *table) Query(ctx context.Context, q data.Queryable) (ge gomerr.Gomerr)
 			ge = dataerr.Store("Query", q).Wrap(ge)
 		}
 	}()
+	defer t.recordOperation("Query", q.TypeName(), time.Now(), &ge)
 
 	// Check for nested Queryables (enables multi-type STD queries)
 	nested := nestedQueryables(q)
@@ -605,7 +1009,7 @@ func (t *table) Query(ctx context.Context, q data.Queryable) (ge gomerr.Gomerr)
 	}
 
 	var output *dynamodb.QueryOutput
-	output, ge = t.runQuery(ctx, input)
+	output, ge = t.runQuery(ctx, q.TypeName(), input)
 	if ge != nil {
 		return ge
 	}
@@ -615,10 +1019,19 @@ func (t *table) Query(ctx context.Context, q data.Queryable) (ge gomerr.Gomerr)
 		return gomerr.Internal("unable to generate nextToken").Wrap(ge)
 	}
 
-	items := make([]any, len(output.Items))
-	for i, item := range output.Items {
+	keyFieldConditions, ge := t.keyFieldConditions(q)
+	if ge != nil {
+		return ge
+	}
+
+	now := time.Now()
+	items := make([]any, 0, len(output.Items))
+	for _, item := range output.Items {
 		name := q.TypeName()
 		pt := t.persistableTypes[name]
+		if pt.expired(item, now) {
+			continue
+		}
 
 		var resolvedItem any
 		if resolvedItem, ge = pt.resolver(item); ge != nil {
@@ -632,7 +1045,9 @@ func (t *table) Query(ctx context.Context, q data.Queryable) (ge gomerr.Gomerr)
 			}
 		}
 
-		items[i] = resolvedItem
+		if data.Satisfies(resolvedItem, keyFieldConditions) {
+			items = append(items, resolvedItem)
+		}
 	}
 
 	q.SetResults(items)
@@ -661,7 +1076,7 @@ func (t *table) checkFieldTupleUnique(ctx context.Context, p data.Persistable, f
 		input.Limit = &queryLimit
 
 		var output *dynamodb.QueryOutput
-		if output, ge = t.runQuery(ctx, input); ge != nil {
+		if output, ge = t.runQuery(ctx, p.TypeName(), input); ge != nil {
 			return ge
 		}
 
@@ -732,7 +1147,12 @@ type UniqueConstraint struct {
 // buildQueryInput Builds the DynamoDB QueryInput types based on the provided queryable. See indexFor and
 // nextTokenizer.untokenize for possible error types.
 func (t *table) buildQueryInput(ctx context.Context, q data.Queryable) (*dynamodb.QueryInput, gomerr.Gomerr) {
-	idx, ascending, consistent, ge := indexFor(t, q)
+	conditions, ge := data.ConditionsOf(q)
+	if ge != nil {
+		return nil, ge
+	}
+
+	idx, ascending, consistent, ge := indexFor(t, q, conditions)
 	if ge != nil {
 		return nil, ge
 	}
@@ -748,7 +1168,9 @@ func (t *table) buildQueryInput(ctx context.Context, q data.Queryable) (*dynamod
 
 	// TODO: customers should opt-in to wildcard matches on a field-by-field basis
 	// TODO: need to provide a way to sanitize, both when saving and querying data, the delimiter char
-	if idx.sk != nil {
+	if rc, position := idx.rangeCondition(qElem, q.TypeName(), conditions); rc != nil {
+		keyConditionExpression += " AND " + t.rangeKeyCondition(idx, qElem, q.TypeName(), rc, position, expressionAttributeNames, expressionAttributeValues)
+	} else if idx.sk != nil {
 		if eav := idx.sk.attributeValue(qElem, q.TypeName(), t.valueSeparatorChar, t.queryWildcardChar); eav != nil {
 			if s, ok := eav.(*types.AttributeValueMemberS); ok {
 				if len(s.Value) > 0 && (s.Value[len(s.Value)-1] == t.queryWildcardChar || s.Value[len(s.Value)-1] == t.valueSeparatorChar) {
@@ -766,6 +1188,20 @@ func (t *table) buildQueryInput(ctx context.Context, q data.Queryable) (*dynamod
 	if fe, ge = t.filterExpression(q, idx, expressionAttributeNames, expressionAttributeValues); ge != nil {
 		return nil, ge
 	}
+	pt := t.persistableTypes[q.TypeName()]
+	for c := range conditions {
+		// Key fields aren't stored as attributes, so conditions on them are checked once the items are read (see Query)
+		if condition := &conditions[c]; pt == nil || !pt.keyFields[condition.Field] {
+			var ce string
+			if ce, ge = t.conditionExpression(q.TypeName(), condition, expressionAttributeNames, expressionAttributeValues); ge != nil {
+				return nil, ge
+			}
+			if len(fe) > 0 {
+				fe += " AND "
+			}
+			fe += ce
+		}
+	}
 
 	// for _, attribute := range q.ResponseFields() {
 	// 	safeName(attribute, expressionAttributeNames)
@@ -860,7 +1296,137 @@ func (t *table) filterExpression(q data.Queryable, idx *index, expressionAttribu
 	return exp, nil
 }
 
-func (t *table) runQuery(ctx context.Context, input *dynamodb.QueryInput) (*dynamodb.QueryOutput, gomerr.Gomerr) {
+// keyFieldConditions returns q's conditions on key fields. Since key fields are only stored as part of the keys, these
+// are checked against the items after they're read rather than in a filter expression.
+func (t *table) keyFieldConditions(q data.Queryable) ([]data.Condition, gomerr.Gomerr) {
+	conditions, ge := data.ConditionsOf(q)
+	if ge != nil {
+		return nil, ge
+	}
+
+	pt := t.persistableTypes[q.TypeName()]
+	if pt == nil {
+		return nil, nil
+	}
+
+	var keyFieldConditions []data.Condition
+	for _, c := range conditions {
+		if pt.keyFields[c.Field] {
+			keyFieldConditions = append(keyFieldConditions, c)
+		}
+	}
+	return keyFieldConditions, nil
+}
+
+// maxKeyChar sorts after any other character, so a prefix followed by it bounds the keys that begin with the prefix.
+const maxKeyChar = "\U0010FFFF"
+
+// rangeKeyCondition returns the sort key part of a key condition expression for a condition on the sort key field at
+// the given position. The fields before it are all present, and together form a prefix of every key that can match.
+// When there's a prefix, a one-sided comparison needs both bounds, so it's expressed with BETWEEN. Since BETWEEN's
+// bounds are inclusive, a strict comparison (GT or LT) on the key's last field then also matches the bound itself, which
+// Query excludes when it checks the key field conditions against the items read.
+func (t *table) rangeKeyCondition(idx *index, qv reflect.Value, ptName string, c *data.Condition, position int, names map[string]string, values map[string]types.AttributeValue) string {
+	sk := safeName(idx.sk.name, names)
+	keyFields := idx.sk.keyFieldsByPersistable[ptName]
+	separator, escape := t.valueSeparatorChar, t.valueSeparatorChar+1
+
+	prefix := ""
+	for _, kf := range keyFields[:position] {
+		prefix += fieldValue(kf.name, qv, separator, escape) + string(separator)
+	}
+
+	// A field's value is followed by the separator unless it's the last field, so for the former, the keys with a given
+	// value for the field are those between value+separator and value+separator+maxKeyChar.
+	last := position == len(keyFields)-1
+	value := func(i int) string {
+		return prefix + formatKeyValue(indirect(c.Values[i]), separator, escape)
+	}
+	through := func(i int) string {
+		if last {
+			return value(i)
+		}
+		return value(i) + string(separator) + maxKeyChar
+	}
+	alias := func(v string) string {
+		return valueAlias(idx.sk.valueOf(v), values)
+	}
+
+	var lower, upper string
+	switch c.Type {
+	case data.EQ:
+		if last {
+			return sk + "=" + alias(value(0))
+		}
+		return "begins_with(" + sk + "," + alias(value(0)+string(separator)) + ")"
+	case data.PREFIX:
+		return "begins_with(" + sk + "," + alias(value(0)) + ")"
+	case data.BETWEEN:
+		return sk + " BETWEEN " + alias(value(0)) + " AND " + alias(through(1))
+	case data.GTE:
+		lower = value(0)
+	case data.GT:
+		lower = through(0)
+	case data.LTE:
+		upper = through(0)
+	case data.LT:
+		upper = value(0)
+	}
+
+	if prefix == "" {
+		operator := map[data.QueryTypes]string{data.GTE: ">=", data.GT: ">", data.LTE: "<=", data.LT: "<"}[c.Type]
+		return sk + operator + alias(lower+upper)
+	}
+
+	if upper == "" {
+		upper = prefix + maxKeyChar
+	} else {
+		lower = prefix
+	}
+	return sk + " BETWEEN " + alias(lower) + " AND " + alias(upper)
+}
+
+// conditionExpression returns the filter expression for a condition on an attribute of the type's items.
+func (t *table) conditionExpression(typeName string, c *data.Condition, names map[string]string, values map[string]types.AttributeValue) (string, gomerr.Gomerr) {
+	attributeName := c.Field
+	if pt, ok := t.persistableTypes[typeName]; ok {
+		attributeName = pt.attributeName(c.Field)
+	}
+	path := attributePath([]string{attributeName}, names)
+
+	aliases := make([]string, len(c.Values))
+	for i, v := range c.Values {
+		av, err := attributevalue.Marshal(indirect(v))
+		if err != nil {
+			return "", gomerr.Marshal(c.Field, v).Wrap(err)
+		}
+		aliases[i] = valueAlias(av, values)
+	}
+
+	switch c.Type {
+	case data.BETWEEN:
+		return path + " BETWEEN " + aliases[0] + " AND " + aliases[1], nil
+	case data.CONTAINS:
+		return "contains(" + path + "," + aliases[0] + ")", nil
+	case data.PREFIX:
+		return "begins_with(" + path + "," + aliases[0] + ")", nil
+	}
+
+	operator := map[data.QueryTypes]string{data.EQ: "=", data.NEQ: "<>", data.GTE: ">=", data.GT: ">", data.LTE: "<=", data.LT: "<"}[c.Type]
+	return path + operator + aliases[0], nil
+}
+
+// indirect returns the value v points to, if it's a non-nil pointer, or otherwise v.
+func indirect(v any) any {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		return rv.Elem().Interface()
+	}
+	return v
+}
+
+// runQuery runs a query made for the type, or for several types if typeName is "".
+func (t *table) runQuery(ctx context.Context, typeName string, input *dynamodb.QueryInput) (*dynamodb.QueryOutput, gomerr.Gomerr) {
+	input.ReturnConsumedCapacity = t.returnConsumedCapacity()
 	output, err := t.ddb.Query(ctx, input)
 	if err != nil {
 		var notFoundErr *types.ResourceNotFoundException
@@ -883,6 +1449,8 @@ func (t *table) runQuery(ctx context.Context, input *dynamodb.QueryInput) (*dyna
 		return nil, gomerr.Dependency("DynamoDB", input).Wrap(err)
 	}
 
+	t.recordCapacity(typeName, false, output.ConsumedCapacity)
+
 	return output, nil
 }
 
@@ -3,6 +3,8 @@ package dynamodb_test
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
 	"testing"
 	"time"
 
@@ -11,17 +13,20 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
 	"github.com/jt0/gomer/_test/assert"
+	"github.com/jt0/gomer/constraint"
 	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/data/dataerr"
 	ddb "github.com/jt0/gomer/data/dynamodb"
 	ddbtest "github.com/jt0/gomer/data/dynamodb/_test"
 	testentities "github.com/jt0/gomer/data/dynamodb/_test"
+	"github.com/jt0/gomer/gomerr"
 )
 
 const crudTestTableName = "gomer_crud_test"
 
 // Setup/teardown helpers
 
-func setupCrudStore(t *testing.T, persistables ...data.Persistable) (data.Store, *dynamodb.Client) {
+func setupCrudStore(t *testing.T, persistables ...data.Persistable) (data.Store, ddbtest.Client) {
 	client, isLocal, err := ddbtest.NewClient()
 	assert.Success(t, err)
 
@@ -70,12 +75,12 @@ func setupCrudStore(t *testing.T, persistables ...data.Persistable) (data.Store,
 	return store, client
 }
 
-func cleanupCrudTable(t *testing.T, client *dynamodb.Client) {
+func cleanupCrudTable(t *testing.T, client ddbtest.Client) {
 	err := ddbtest.DeleteAllTableData(client, crudTestTableName)
 	assert.Success(t, err)
 }
 
-func verifyEntityExists(t *testing.T, client *dynamodb.Client, pk, sk string) bool {
+func verifyEntityExists(t *testing.T, client ddbtest.Client, pk, sk string) bool {
 	key := map[string]types.AttributeValue{
 		"PK": &types.AttributeValueMemberS{Value: pk},
 	}
@@ -91,14 +96,14 @@ func verifyEntityExists(t *testing.T, client *dynamodb.Client, pk, sk string) bo
 	return len(result.Item) > 0
 }
 
-func verifyEntityNotExists(t *testing.T, client *dynamodb.Client, pk, sk string) {
+func verifyEntityNotExists(t *testing.T, client ddbtest.Client, pk, sk string) {
 	exists := verifyEntityExists(t, client, pk, sk)
 	if exists {
 		t.Errorf("Entity should not exist with PK=%s, SK=%s", pk, sk)
 	}
 }
 
-func getRawItem(t *testing.T, client *dynamodb.Client, pk, sk string) map[string]types.AttributeValue {
+func getRawItem(t *testing.T, client ddbtest.Client, pk, sk string) map[string]types.AttributeValue {
 	key := map[string]types.AttributeValue{
 		"PK": &types.AttributeValueMemberS{Value: pk},
 	}
@@ -123,7 +128,7 @@ func TestCreate(t *testing.T) {
 		expectError bool
 		errorType   any
 		setupFunc   func(store data.Store)
-		verifyFunc  func(t *testing.T, store data.Store, client *dynamodb.Client)
+		verifyFunc  func(t *testing.T, store data.Store, client ddbtest.Client)
 	}{
 		{
 			name: "create composite key entity",
@@ -134,7 +139,7 @@ func TestCreate(t *testing.T) {
 				Active:       true,
 			},
 			expectError: false,
-			verifyFunc: func(t *testing.T, store data.Store, client *dynamodb.Client) {
+			verifyFunc: func(t *testing.T, store data.Store, client ddbtest.Client) {
 				if !verifyEntityExists(t, client, "partition1", "sort1") {
 					t.Error("CompositeKeyEntity should exist")
 				}
@@ -155,7 +160,7 @@ func TestCreate(t *testing.T) {
 				Payload:    "payload-data",
 			},
 			expectError: false,
-			verifyFunc: func(t *testing.T, store data.Store, client *dynamodb.Client) {
+			verifyFunc: func(t *testing.T, store data.Store, client ddbtest.Client) {
 				if !verifyEntityExists(t, client, "tenant1#TYPE1", "id1") {
 					t.Error("MultiPartKeyEntity should exist")
 				}
@@ -175,7 +180,7 @@ func TestCreate(t *testing.T) {
 				Detail: "test-detail",
 			},
 			expectError: false,
-			verifyFunc: func(t *testing.T, store data.Store, client *dynamodb.Client) {
+			verifyFunc: func(t *testing.T, store data.Store, client ddbtest.Client) {
 				if !verifyEntityExists(t, client, "ITEM#item1", "STATUS#active") {
 					t.Error("StaticKeyEntity should exist")
 				}
@@ -197,7 +202,7 @@ func TestCreate(t *testing.T) {
 				Status:   "active",
 			},
 			expectError: false,
-			verifyFunc: func(t *testing.T, store data.Store, client *dynamodb.Client) {
+			verifyFunc: func(t *testing.T, store data.Store, client ddbtest.Client) {
 				// User keys: PK = TenantId#USER, SK = Id
 				if !verifyEntityExists(t, client, "tenant1#USER", "user1") {
 					t.Error("User should exist")
@@ -224,7 +229,7 @@ func TestCreate(t *testing.T) {
 				Description: "Test description",
 			},
 			expectError: false,
-			verifyFunc: func(t *testing.T, store data.Store, client *dynamodb.Client) {
+			verifyFunc: func(t *testing.T, store data.Store, client ddbtest.Client) {
 				if !verifyEntityExists(t, client, "tenant1#PRODUCT", "prod1") {
 					t.Error("Product should exist")
 				}
@@ -610,7 +615,7 @@ func TestDelete(t *testing.T) {
 		expectError  bool
 		expectedPK   string
 		expectedSK   string
-		verifyFunc   func(t *testing.T, store data.Store, client *dynamodb.Client)
+		verifyFunc   func(t *testing.T, store data.Store, client ddbtest.Client)
 		failIfAbsent bool
 	}{
 		{
@@ -678,7 +683,7 @@ func TestDelete(t *testing.T) {
 			entityType:   &testentities.CompositeKeyEntity{},
 			setupEntity:  &testentities.CompositeKeyEntity{PartitionKey: "recreate-test", SortKey: "sk1", Data: "First", Active: true},
 			deleteEntity: &testentities.CompositeKeyEntity{PartitionKey: "recreate-test", SortKey: "sk1"},
-			verifyFunc: func(t *testing.T, store data.Store, client *dynamodb.Client) {
+			verifyFunc: func(t *testing.T, store data.Store, client ddbtest.Client) {
 				ctx := context.Background()
 				entity := &testentities.CompositeKeyEntity{PartitionKey: "recreate-test", SortKey: "sk1", Data: "Second", Active: false}
 				ge := store.Create(ctx, entity)
@@ -735,7 +740,7 @@ func TestCRUD_EmptyAndZeroValues(t *testing.T) {
 		name        string
 		entity      data.Persistable
 		expectError bool
-		verifyFunc  func(t *testing.T, store data.Store, client *dynamodb.Client, entity data.Persistable)
+		verifyFunc  func(t *testing.T, store data.Store, client ddbtest.Client, entity data.Persistable)
 	}{
 		{
 			name: "create with empty string in PK",
@@ -754,7 +759,7 @@ func TestCRUD_EmptyAndZeroValues(t *testing.T) {
 				ZeroInt:     5,
 			},
 			expectError: false,
-			verifyFunc: func(t *testing.T, store data.Store, client *dynamodb.Client, entity data.Persistable) {
+			verifyFunc: func(t *testing.T, store data.Store, client ddbtest.Client, entity data.Persistable) {
 				// Verify entity exists - SK is "#5" (EmptyString="" + separator + ZeroInt=5)
 				item := getRawItem(t, client, "id1", "#5")
 				if item == nil || len(item) == 0 {
@@ -779,7 +784,7 @@ func TestCRUD_EmptyAndZeroValues(t *testing.T) {
 				ZeroInt:     0, // Zero in middle
 			},
 			expectError: false,
-			verifyFunc: func(t *testing.T, store data.Store, client *dynamodb.Client, entity data.Persistable) {
+			verifyFunc: func(t *testing.T, store data.Store, client ddbtest.Client, entity data.Persistable) {
 				// Read back and verify zero is preserved as empty
 				read := &testentities.EmptyValueEntity{Id: "id3", EmptyString: "prefix"}
 				ge := store.Read(context.Background(), read)
@@ -797,7 +802,7 @@ func TestCRUD_EmptyAndZeroValues(t *testing.T) {
 				ZeroInt:     10,
 			},
 			expectError: false,
-			verifyFunc: func(t *testing.T, store data.Store, client *dynamodb.Client, entity data.Persistable) {
+			verifyFunc: func(t *testing.T, store data.Store, client ddbtest.Client, entity data.Persistable) {
 				read := &testentities.EmptyValueEntity{Id: "id4", EmptyString: "", ZeroInt: 10}
 				ge := store.Read(context.Background(), read)
 				assert.Success(t, ge)
@@ -817,7 +822,7 @@ func TestCRUD_EmptyAndZeroValues(t *testing.T) {
 				ZeroInt:     5,
 			},
 			expectError: false,
-			verifyFunc: func(t *testing.T, store data.Store, client *dynamodb.Client, entity data.Persistable) {
+			verifyFunc: func(t *testing.T, store data.Store, client ddbtest.Client, entity data.Persistable) {
 				// Update to empty string
 				updated := &testentities.EmptyValueEntity{
 					Id:          "id5",
@@ -844,7 +849,7 @@ func TestCRUD_EmptyAndZeroValues(t *testing.T) {
 				ZeroInt:     5,
 			},
 			expectError: false,
-			verifyFunc: func(t *testing.T, store data.Store, client *dynamodb.Client, entity data.Persistable) {
+			verifyFunc: func(t *testing.T, store data.Store, client ddbtest.Client, entity data.Persistable) {
 				// Update from empty to value
 				updated := &testentities.EmptyValueEntity{
 					Id:          "id6",
@@ -871,7 +876,7 @@ func TestCRUD_EmptyAndZeroValues(t *testing.T) {
 				ZeroInt:     0,
 			},
 			expectError: false,
-			verifyFunc: func(t *testing.T, store data.Store, client *dynamodb.Client, entity data.Persistable) {
+			verifyFunc: func(t *testing.T, store data.Store, client ddbtest.Client, entity data.Persistable) {
 				// Update zero to non-zero
 				updated := &testentities.EmptyValueEntity{
 					Id:          "id7",
@@ -1087,7 +1092,7 @@ func TestCRUD_PointerFields(t *testing.T) {
 		name        string
 		entity      data.Persistable
 		expectError bool
-		verifyFunc  func(t *testing.T, store data.Store, client *dynamodb.Client, entity data.Persistable)
+		verifyFunc  func(t *testing.T, store data.Store, client ddbtest.Client, entity data.Persistable)
 	}{
 		{
 			name: "create with actual zero value using *int",
@@ -1101,7 +1106,7 @@ func TestCRUD_PointerFields(t *testing.T) {
 				}
 			}(),
 			expectError: false,
-			verifyFunc: func(t *testing.T, store data.Store, client *dynamodb.Client, entity data.Persistable) {
+			verifyFunc: func(t *testing.T, store data.Store, client ddbtest.Client, entity data.Persistable) {
 				// Read back with zero value
 				zero := 0
 				id := "test-id"
@@ -1161,7 +1166,7 @@ func TestCRUD_TimeFields(t *testing.T) {
 		name        string
 		entity      data.Persistable
 		expectError bool
-		verifyFunc  func(t *testing.T, store data.Store, client *dynamodb.Client, entity data.Persistable)
+		verifyFunc  func(t *testing.T, store data.Store, client ddbtest.Client, entity data.Persistable)
 	}{
 		{
 			name: "create order with time.Time in key",
@@ -1174,7 +1179,7 @@ func TestCRUD_TimeFields(t *testing.T) {
 				Total:     100.0,
 			},
 			expectError: false,
-			verifyFunc: func(t *testing.T, store data.Store, client *dynamodb.Client, entity data.Persistable) {
+			verifyFunc: func(t *testing.T, store data.Store, client ddbtest.Client, entity data.Persistable) {
 				read := &testentities.Order{TenantId: "tenant1", OrderId: "order1"}
 				ge := store.Read(context.Background(), read)
 				assert.Success(t, ge)
@@ -1196,7 +1201,7 @@ func TestCRUD_TimeFields(t *testing.T) {
 				Total:     0.0,
 			},
 			expectError: false,
-			verifyFunc: func(t *testing.T, store data.Store, client *dynamodb.Client, entity data.Persistable) {
+			verifyFunc: func(t *testing.T, store data.Store, client ddbtest.Client, entity data.Persistable) {
 				read := &testentities.Order{TenantId: "tenant2", OrderId: "order2"}
 				ge := store.Read(context.Background(), read)
 				assert.Success(t, ge)
@@ -1214,7 +1219,7 @@ func TestCRUD_TimeFields(t *testing.T) {
 				Total:     100.0,
 			},
 			expectError: false,
-			verifyFunc: func(t *testing.T, store data.Store, client *dynamodb.Client, entity data.Persistable) {
+			verifyFunc: func(t *testing.T, store data.Store, client ddbtest.Client, entity data.Persistable) {
 				// Create multiple orders with different dates
 				dates := []time.Time{
 					time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC),
@@ -1255,7 +1260,7 @@ func TestCRUD_TimeFields(t *testing.T) {
 				Total:     50.0,
 			},
 			expectError: false,
-			verifyFunc: func(t *testing.T, store data.Store, client *dynamodb.Client, entity data.Persistable) {
+			verifyFunc: func(t *testing.T, store data.Store, client ddbtest.Client, entity data.Persistable) {
 				// Update with new OrderDate
 				updated := &testentities.Order{
 					TenantId:  "tenant4",
@@ -1408,6 +1413,302 @@ func TestTableName_Consistency(t *testing.T) {
 	}
 }
 
+// TestUpdate_Partial tests that updates only write changed attributes and keep index keys consistent
+func TestUpdate_Partial(t *testing.T) {
+	store, client := setupCrudStore(t, &testentities.CompositeKeyEntity{}, &testentities.User{})
+	defer cleanupCrudTable(t, client)
+
+	ctx := context.Background()
+
+	assert.Success(t, store.Create(ctx, &testentities.CompositeKeyEntity{PartitionKey: "pk1", SortKey: "sk1", Data: "original", Status: "new"}))
+
+	first := &testentities.CompositeKeyEntity{PartitionKey: "pk1", SortKey: "sk1"}
+	assert.Success(t, store.Read(ctx, first))
+	second := &testentities.CompositeKeyEntity{PartitionKey: "pk1", SortKey: "sk1"}
+	assert.Success(t, store.Read(ctx, second))
+
+	// Each writer changes a different attribute, so neither overwrites the other
+	assert.Success(t, store.Update(ctx, first, &testentities.CompositeKeyEntity{Data: "updated"}))
+	assert.Success(t, store.Update(ctx, second, &testentities.CompositeKeyEntity{Status: "active"}))
+
+	read := &testentities.CompositeKeyEntity{PartitionKey: "pk1", SortKey: "sk1"}
+	assert.Success(t, store.Read(ctx, read))
+	assert.Equals(t, "updated", read.Data)
+	assert.Equals(t, "active", read.Status)
+
+	// Changing a field that's part of a secondary index key updates the index key attribute
+	user := &testentities.User{TenantId: "t1", Id: "u1", Email: "old@example.com", Name: "User"}
+	assert.Success(t, store.Create(ctx, user))
+	assert.Success(t, store.Update(ctx, user, &testentities.User{Email: "new@example.com"}))
+
+	item := getRawItem(t, client, "t1#USER", "u1")
+	assert.Equals(t, "USER#new@example.com", item["GSI_1_PK"].(*types.AttributeValueMemberS).Value)
+	assert.Equals(t, "User", item["Name"].(*types.AttributeValueMemberS).Value)
+
+	// Table key fields identify the item and can't be changed
+	ge := store.Update(ctx, read, &testentities.CompositeKeyEntity{SortKey: "sk2"})
+	assert.ErrorType(t, ge, &gomerr.UnprocessableError{})
+}
+
+// TestUpdate_Version tests that a stale version prevents an update from overwriting a newer one
+func TestUpdate_Version(t *testing.T) {
+	store, client := setupCrudStore(t, &testentities.VersionedEntity{})
+	defer cleanupCrudTable(t, client)
+
+	ctx := context.Background()
+
+	entity := &testentities.VersionedEntity{Id: "v1", Data: "original"}
+	assert.Success(t, store.Create(ctx, entity))
+	assert.Equals(t, int64(1), entity.Version)
+
+	first := &testentities.VersionedEntity{Id: "v1"}
+	assert.Success(t, store.Read(ctx, first))
+	second := &testentities.VersionedEntity{Id: "v1"}
+	assert.Success(t, store.Read(ctx, second))
+
+	assert.Success(t, store.Update(ctx, first, &testentities.VersionedEntity{Data: "first"}))
+	assert.Equals(t, int64(2), first.Version)
+
+	ge := store.Update(ctx, second, &testentities.VersionedEntity{Data: "second"})
+	assert.ErrorType(t, ge, &gomerr.ConflictError{}, "Update with a stale version should fail")
+	assert.Equals(t, int64(1), second.Version, "Version should be unchanged after a failed update")
+
+	read := &testentities.VersionedEntity{Id: "v1"}
+	assert.Success(t, store.Read(ctx, read))
+	assert.Equals(t, "first", read.Data)
+	assert.Equals(t, int64(2), read.Version)
+}
+
+func TestTtl(t *testing.T) {
+	store, client := setupCrudStore(t, &testentities.Session{}, &testentities.IdempotencyRecord{})
+	defer cleanupCrudTable(t, client)
+
+	ctx := context.Background()
+
+	// A time.Time expiry is stored in epoch seconds
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	assert.Success(t, store.Create(ctx, &testentities.Session{Id: "s1", UserId: "u1", ExpiresAt: expiresAt}))
+	assert.Equals(t, &types.AttributeValueMemberN{Value: strconv.FormatInt(expiresAt.Unix(), 10)}, getRawItem(t, client, "s1", "SESSION")["ExpiresAt"])
+
+	read := &testentities.Session{Id: "s1"}
+	assert.Success(t, store.Read(ctx, read))
+	assert.Assert(t, expiresAt.Equal(read.ExpiresAt), "ExpiresAt should be read back")
+
+	// Expired items are hidden until DynamoDB deletes them, and can be created again
+	assert.Success(t, store.Create(ctx, &testentities.Session{Id: "s2", ExpiresAt: time.Now().Add(-time.Minute)}))
+	assert.ErrorType(t, store.Read(ctx, &testentities.Session{Id: "s2"}), &dataerr.PersistableNotFoundError{})
+
+	q := &testentities.Sessions{Id: "s2"}
+	assert.Success(t, store.Query(ctx, q))
+	assert.Equals(t, 0, len(q.Results()))
+
+	assert.Success(t, store.Create(ctx, &testentities.Session{Id: "s2", ExpiresAt: expiresAt}))
+	assert.Success(t, store.Read(ctx, &testentities.Session{Id: "s2"}))
+
+	assert.Success(t, store.Update(ctx, read, &testentities.Session{ExpiresAt: time.Now().Add(-time.Minute)}))
+	assert.ErrorType(t, store.Read(ctx, &testentities.Session{Id: "s1"}), &dataerr.PersistableNotFoundError{})
+
+	// A time.Duration's expiry is counted from each write and stored in the named attribute
+	before := time.Now().Unix()
+	assert.Success(t, store.Create(ctx, &testentities.IdempotencyRecord{Token: "k1", Response: "ok", Lifetime: time.Hour}))
+	stored, err := strconv.ParseInt(getRawItem(t, client, "k1", "IDEMPOTENCY")["ExpiresAt"].(*types.AttributeValueMemberN).Value, 10, 64)
+	assert.Success(t, err)
+	assert.Assert(t, stored >= before+3600 && stored <= time.Now().Unix()+3600, "expiry should be an hour after the write")
+
+	record := &testentities.IdempotencyRecord{Token: "k1"}
+	assert.Success(t, store.Read(ctx, record))
+	assert.Equals(t, time.Hour, record.Lifetime)
+
+	assert.Success(t, store.Create(ctx, &testentities.IdempotencyRecord{Token: "k2", Lifetime: -time.Minute}))
+	assert.ErrorType(t, store.Read(ctx, &testentities.IdempotencyRecord{Token: "k2"}), &dataerr.PersistableNotFoundError{})
+}
+
+func TestUniqueness_Sentinel(t *testing.T) {
+	_, client := setupCrudStore(t)
+	defer cleanupCrudTable(t, client)
+
+	store, ge := ddb.Store(crudTestTableName, &ddb.Configuration{
+		DynamoDb:              client,
+		MaxResultsDefault:     100,
+		MaxResultsMax:         1000,
+		ConsistencyDefault:    ddb.Preferred,
+		UniquenessEnforcement: ddb.SentinelUniqueness,
+	}, &testentities.User{})
+	assert.Success(t, ge)
+
+	ctx := context.Background()
+
+	// Concurrent creates with the same email: exactly one should succeed
+	const writers = 5
+	results := make(chan gomerr.Gomerr, writers)
+	for i := 0; i < writers; i++ {
+		go func(i int) {
+			results <- store.Create(ctx, &testentities.User{TenantId: "t1", Id: fmt.Sprintf("u%d", i), Email: "same@example.com"})
+		}(i)
+	}
+	var succeeded int
+	for i := 0; i < writers; i++ {
+		if ge := <-results; ge == nil {
+			succeeded++
+		} else {
+			assert.ErrorType(t, ge, &constraint.NotSatisfiedError{}, "Losing create should fail the unique constraint")
+		}
+	}
+	assert.Equals(t, 1, succeeded)
+
+	// The same email in another tenant doesn't conflict
+	assert.Success(t, store.Create(ctx, &testentities.User{TenantId: "t2", Id: "u0", Email: "same@example.com"}))
+
+	// Changing the email releases the old value
+	owner := &testentities.User{TenantId: "t1", Email: "same@example.com"}
+	users := &testentities.Users{TenantId: "t1", Email: "same@example.com"}
+	assert.Success(t, store.Query(ctx, users))
+	assert.Equals(t, 1, len(users.Results()))
+	owner.Id = users.Results()[0].(*testentities.User).Id
+	assert.Success(t, store.Read(ctx, owner))
+	assert.Success(t, store.Update(ctx, owner, &testentities.User{Email: "new@example.com"}))
+
+	other := &testentities.User{TenantId: "t1", Id: "other", Email: "same@example.com"}
+	assert.Success(t, store.Create(ctx, other))
+
+	ge = store.Create(ctx, &testentities.User{TenantId: "t1", Id: "another", Email: "new@example.com"})
+	assert.ErrorType(t, ge, &constraint.NotSatisfiedError{}, "New email should be claimed")
+
+	// Deleting releases the value
+	assert.Success(t, store.Delete(ctx, other))
+	assert.Success(t, store.Create(ctx, &testentities.User{TenantId: "t1", Id: "another", Email: "same@example.com"}))
+}
+
+func TestBatch(t *testing.T) {
+	store, client := setupCrudStore(t, &testentities.CompositeKeyEntity{})
+	defer cleanupCrudTable(t, client)
+
+	ctx := context.Background()
+	batchStore, ok := store.(data.BatchStore)
+	assert.Assert(t, ok, "Store should implement data.BatchStore")
+
+	// More than a single BatchWriteItem request can hold
+	const count = 30
+	entities := make([]data.Persistable, count)
+	for i := range entities {
+		entities[i] = &testentities.CompositeKeyEntity{PartitionKey: "batch", SortKey: fmt.Sprintf("item-%02d", i), Data: fmt.Sprintf("data-%d", i)}
+	}
+	assert.Success(t, batchStore.BatchCreate(ctx, entities))
+
+	reads := make([]data.Persistable, count+1)
+	for i := 0; i < count; i++ {
+		reads[i] = &testentities.CompositeKeyEntity{PartitionKey: "batch", SortKey: fmt.Sprintf("item-%02d", i)}
+	}
+	reads[count] = &testentities.CompositeKeyEntity{PartitionKey: "batch", SortKey: "missing"}
+
+	ge := batchStore.BatchRead(ctx, reads)
+	assert.ErrorType(t, ge, &dataerr.StoreError{}, "Missing item should fail")
+	assert.ErrorType(t, ge, &dataerr.PersistableNotFoundError{}, "Missing item should not be found")
+	for i := 0; i < count; i++ {
+		assert.Equals(t, fmt.Sprintf("data-%d", i), reads[i].(*testentities.CompositeKeyEntity).Data)
+	}
+
+	assert.Success(t, batchStore.BatchDelete(ctx, entities))
+	for i := 0; i < count; i++ {
+		verifyEntityNotExists(t, client, "batch", fmt.Sprintf("item-%02d", i))
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	store, client := setupCrudStore(t, &testentities.CompositeKeyEntity{}, &testentities.VersionedEntity{})
+	defer cleanupCrudTable(t, client)
+
+	ctx := context.Background()
+	assert.Success(t, store.Create(ctx, &testentities.CompositeKeyEntity{PartitionKey: "tx", SortKey: "existing"}))
+
+	v := &testentities.VersionedEntity{Id: "v1", Data: "original"}
+	assert.Success(t, store.Create(ctx, v))
+
+	// A failed condition means nothing is written
+	tx := store.(data.Transactional).NewTransaction()
+	assert.Success(t, tx.Create(ctx, &testentities.CompositeKeyEntity{PartitionKey: "tx", SortKey: "new"}))
+	assert.Success(t, tx.Update(ctx, v, &testentities.VersionedEntity{Data: "updated"}))
+	assert.Success(t, tx.Create(ctx, &testentities.CompositeKeyEntity{PartitionKey: "tx", SortKey: "existing"}))
+	assert.ErrorType(t, tx.Commit(ctx), &dataerr.StoreError{}, "Transaction with a duplicate create should fail")
+	assert.Equals(t, int64(1), v.Version, "Version should be restored after a failed commit")
+	verifyEntityNotExists(t, client, "tx", "new")
+
+	read := &testentities.VersionedEntity{Id: "v1"}
+	assert.Success(t, store.Read(ctx, read))
+	assert.Equals(t, "original", read.Data)
+
+	tx = store.(data.Transactional).NewTransaction()
+	assert.Success(t, tx.ConditionCheck(ctx, read))
+	assert.Success(t, tx.Create(ctx, &testentities.CompositeKeyEntity{PartitionKey: "tx", SortKey: "new"}))
+	assert.Success(t, tx.Delete(ctx, &testentities.CompositeKeyEntity{PartitionKey: "tx", SortKey: "existing"}))
+	assert.Success(t, tx.Commit(ctx))
+	assert.Assert(t, verifyEntityExists(t, client, "tx", "new"))
+	verifyEntityNotExists(t, client, "tx", "existing")
+}
+
+func TestQuery_Conditions(t *testing.T) {
+	store, client := setupCrudStore(t, &testentities.Order{})
+	defer cleanupCrudTable(t, client)
+
+	ctx := context.Background()
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, o := range []*testentities.Order{
+		{TenantId: "t1", OrderId: "o1", UserId: "u1", Total: 5},
+		{TenantId: "t1", OrderId: "o2", UserId: "u1", Total: 20},
+		{TenantId: "t1", OrderId: "o3", UserId: "u2", Total: 12.5},
+		{TenantId: "t1", OrderId: "o4", UserId: "u1", Total: 40},
+	} {
+		o.OrderDate = day.AddDate(0, 0, i)
+		assert.Success(t, store.Create(ctx, o))
+	}
+
+	orderIds := func(q data.Queryable) (ids []string) {
+		for _, r := range q.Results() {
+			ids = append(ids, r.(*testentities.Order).OrderId)
+		}
+		return ids
+	}
+
+	q := &testentities.Orders{TenantId: "t1"}
+	q.Where("OrderDate", data.BETWEEN, day.AddDate(0, 0, 1), day.AddDate(0, 0, 2))
+	assert.Success(t, store.Query(ctx, q))
+	assert.Equals(t, []string{"o3", "o2"}, orderIds(q))
+
+	// The bound of a strict comparison on the last field of a composite key is filtered out
+	q = &testentities.Orders{TenantId: "t1", UserId: "u1"}
+	q.Where("OrderDate", data.GT, day)
+	assert.Success(t, store.Query(ctx, q))
+	assert.Equals(t, []string{"o4", "o2"}, orderIds(q))
+
+	q = &testentities.Orders{TenantId: "t1", UserId: "u1"}
+	q.Where("OrderDate", data.LTE, day.AddDate(0, 0, 2))
+	q.Where("Total", data.GTE, 10)
+	assert.Success(t, store.Query(ctx, q))
+	assert.Equals(t, []string{"o2"}, orderIds(q))
+}
+
+func TestQuery_NumericConditionOnStringKey(t *testing.T) {
+	store, client := setupCrudStore(t, &testentities.Player{})
+	defer cleanupCrudTable(t, client)
+
+	ctx := context.Background()
+	for _, points := range []int{5, 10, 20} {
+		assert.Success(t, store.Create(ctx, &testentities.Player{TenantId: "t1", PlayerId: fmt.Sprintf("p%d", points), Points: points}))
+	}
+
+	// The key values sort as "10" < "20" < "5", so the condition can't be part of the key condition expression
+	q := &testentities.Players{TenantId: "t1"}
+	q.Where("Points", data.GT, 9)
+	assert.Success(t, store.Query(ctx, q))
+
+	var points []int
+	for _, r := range q.Results() {
+		points = append(points, r.(*testentities.Player).Points)
+	}
+	sort.Ints(points)
+	assert.Equals(t, []int{10, 20}, points)
+}
+
 // ==============================================================================
 // Tier 2: Error Path Tests for Delete
 // ==============================================================================
@@ -1548,6 +1849,24 @@ func TestRead_MissingKeyFields(t *testing.T) {
 	}
 }
 
+// TestRead_UnqueryablePartialKey tests that an item whose partition key has an empty part is read with its key as is
+// since no index can query the partial key.
+func TestRead_UnqueryablePartialKey(t *testing.T) {
+	store, client := setupCrudStore(t, &testentities.MultiPartKeyEntity{})
+	defer cleanupCrudTable(t, client)
+
+	ctx := context.Background()
+	assert.Success(t, store.Create(ctx, &testentities.MultiPartKeyEntity{TenantId: "T1", Id: "123", Payload: "data"}))
+	assert.Assert(t, verifyEntityExists(t, client, "T1#", "123"))
+
+	read := &testentities.MultiPartKeyEntity{TenantId: "T1", Id: "123"}
+	assert.Success(t, store.Read(ctx, read))
+	assert.Equals(t, "data", read.Payload)
+
+	ge := store.Read(ctx, &testentities.MultiPartKeyEntity{TenantId: "T2", Id: "123"})
+	assert.ErrorType(t, ge, dataerr.PersistableNotFound("", nil))
+}
+
 // ==============================================================================
 // Tier 2: Error Path Tests for Query (runQuery coverage)
 // ==============================================================================
@@ -0,0 +1,311 @@
+package dynamodb
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/gomerr"
+)
+
+// TableDefinition returns the input to create a table with the keys and indexes used by the persistables' `db.keys`
+// tags. Key attributes are named after their index and key: PK and SK for the table, and e.g. GSI_1_PK and GSI_1_SK
+// for an index named "gsi_1". An index whose partition key isn't part of any tag shares the table's partition key, so
+// is defined as a local secondary index, while the others are global. All key attributes are strings, all indexes
+// project all attributes, and the table uses on-demand billing; callers can adjust the input before creating the
+// table. Possible errors:
+//
+//	gomerr.Configuration:
+//	    if a tag is invalid, or if an index is missing a key field for a type
+func TableDefinition(tableName string, persistables ...data.Persistable) (*dynamodb.CreateTableInput, gomerr.Gomerr) {
+	t := &table{tableName: &tableName, indexes: make(map[string]*index)}
+
+	// First find the indexes and keys the tags use, and then process the tags against them.
+	for _, persistable := range persistables {
+		for _, statement := range keyStatements(reflect.TypeOf(persistable).Elem(), nil) {
+			groups := ddbKeyStatementRegexp.FindStringSubmatch(statement)
+			if groups == nil {
+				continue // reported by newPersistableType
+			}
+
+			idx, ok := t.indexes[groups[3]]
+			if !ok {
+				idx = &index{}
+				if groups[3] != "" {
+					idx.name = &groups[3]
+				}
+				t.indexes[groups[3]] = idx
+			}
+
+			key := &idx.pk
+			if groups[4] == "sk" {
+				key = &idx.sk
+			}
+			if *key == nil {
+				*key = &keyAttribute{
+					name:                   keyAttributeName(groups[3], groups[4]),
+					attributeType:          string(types.ScalarAttributeTypeS),
+					keyFieldsByPersistable: make(map[string][]*keyField),
+				}
+			}
+		}
+	}
+
+	tableIndex, ok := t.indexes[""]
+	if !ok || tableIndex.pk == nil {
+		return nil, gomerr.Configuration("no type defines the table's partition key").AddAttribute("table", tableName)
+	}
+
+	local := make(map[string]bool)
+	for name, idx := range t.indexes {
+		if idx.pk == nil {
+			if tableIndex.sk == nil {
+				return nil, gomerr.Configuration("local secondary index "+name+" requires the table to have a sort key").AddAttribute("table", tableName)
+			}
+			idx.pk, local[name] = tableIndex.pk, true
+		}
+	}
+
+	for _, persistable := range persistables {
+		pElem := reflect.TypeOf(persistable).Elem()
+		name := unqualifiedName(pElem)
+
+		if _, ge := newPersistableType(t, name, pElem); ge != nil {
+			return nil, ge
+		}
+
+		if ge := t.checkKeyFields(name); ge != nil {
+			return nil, ge
+		}
+	}
+
+	input := &dynamodb.CreateTableInput{
+		TableName:   &tableName,
+		KeySchema:   keySchema(tableIndex),
+		BillingMode: types.BillingModePayPerRequest,
+	}
+
+	names := make([]string, 0, len(t.indexes))
+	for name := range t.indexes {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	attributes := keyAttributeDefinitions(tableIndex, nil)
+	for _, name := range names {
+		idx := t.indexes[name]
+		if local[name] {
+			input.LocalSecondaryIndexes = append(input.LocalSecondaryIndexes, types.LocalSecondaryIndex{
+				IndexName:  idx.name,
+				KeySchema:  keySchema(idx),
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+			})
+		} else {
+			input.GlobalSecondaryIndexes = append(input.GlobalSecondaryIndexes, types.GlobalSecondaryIndex{
+				IndexName:  idx.name,
+				KeySchema:  keySchema(idx),
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+			})
+		}
+		attributes = keyAttributeDefinitions(idx, attributes)
+	}
+	input.AttributeDefinitions = attributes
+
+	return input, nil
+}
+
+// keyStatements returns the statements from the `db.keys` tags of the struct's exported and embedded fields.
+func keyStatements(structType reflect.Type, statements []string) []string {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.Type.Kind() == reflect.Struct && field.Anonymous {
+			statements = keyStatements(field.Type, statements)
+		} else if !unicode.IsLower([]rune(field.Name)[0]) {
+			if tag := strings.ReplaceAll(field.Tag.Get("db.keys"), " ", ""); tag != "" {
+				statements = append(statements, strings.Split(tag, ",")...)
+			}
+		}
+	}
+
+	return statements
+}
+
+// keyAttributeName returns the name TableDefinition uses for the index's key attribute.
+func keyAttributeName(indexName, key string) string {
+	if indexName == "" {
+		return strings.ToUpper(key)
+	}
+	return strings.ToUpper(indexName + "_" + key)
+}
+
+func keySchema(idx *index) []types.KeySchemaElement {
+	elements := []types.KeySchemaElement{{AttributeName: &idx.pk.name, KeyType: types.KeyTypeHash}}
+	if idx.sk != nil {
+		elements = append(elements, types.KeySchemaElement{AttributeName: &idx.sk.name, KeyType: types.KeyTypeRange})
+	}
+	return elements
+}
+
+// keyAttributeDefinitions appends definitions for the index's key attributes that aren't already in definitions.
+func keyAttributeDefinitions(idx *index, definitions []types.AttributeDefinition) []types.AttributeDefinition {
+outer:
+	for _, attribute := range idx.keyAttributes() {
+		for _, definition := range definitions {
+			if *definition.AttributeName == attribute.name {
+				continue outer
+			}
+		}
+		definitions = append(definitions, types.AttributeDefinition{
+			AttributeName: &attribute.name,
+			AttributeType: types.ScalarAttributeType(attribute.attributeType),
+		})
+	}
+
+	return definitions
+}
+
+// IndexDifference describes how an existing table's index differs from one in a table definition. The table's own key
+// schema is reported with an empty IndexName.
+type IndexDifference struct {
+	IndexName string
+	Missing   bool   // true if the table doesn't have the index
+	Reason    string // if not missing, how the existing index is incompatible with the definition
+}
+
+func (d IndexDifference) String() string {
+	name := d.IndexName
+	if name == "" {
+		name = "__table__"
+	}
+	if d.Missing {
+		return name + ": missing"
+	}
+	return name + ": " + d.Reason
+}
+
+// CompareTable returns the differences between a table definition, such as one from TableDefinition, and an existing
+// table's description. An existing index is incompatible if its keys have different names or types, if it's local
+// rather than global (or vice versa), or if it doesn't project the attributes the definition does. Indexes that the
+// table has but the definition doesn't aren't reported, nor are differences in billing or throughput.
+func CompareTable(definition *dynamodb.CreateTableInput, description *types.TableDescription) []IndexDifference {
+	expectedTypes := make(map[string]types.ScalarAttributeType, len(definition.AttributeDefinitions))
+	for _, ad := range definition.AttributeDefinitions {
+		expectedTypes[*ad.AttributeName] = ad.AttributeType
+	}
+	actualTypes := make(map[string]types.ScalarAttributeType, len(description.AttributeDefinitions))
+	for _, ad := range description.AttributeDefinitions {
+		actualTypes[*ad.AttributeName] = ad.AttributeType
+	}
+
+	compareKeys := func(expected, actual []types.KeySchemaElement) string {
+		if e, a := keySchemaString(expected, expectedTypes), keySchemaString(actual, actualTypes); e != a {
+			return fmt.Sprintf("key schema is %s, expected %s", a, e)
+		}
+		return ""
+	}
+
+	var differences []IndexDifference
+	if reason := compareKeys(definition.KeySchema, description.KeySchema); reason != "" {
+		differences = append(differences, IndexDifference{Reason: reason})
+	}
+
+	type existingIndex struct {
+		keySchema  []types.KeySchemaElement
+		projection *types.Projection
+		local      bool
+	}
+	existing := make(map[string]existingIndex)
+	for _, lsi := range description.LocalSecondaryIndexes {
+		existing[*lsi.IndexName] = existingIndex{lsi.KeySchema, lsi.Projection, true}
+	}
+	for _, gsi := range description.GlobalSecondaryIndexes {
+		existing[*gsi.IndexName] = existingIndex{gsi.KeySchema, gsi.Projection, false}
+	}
+
+	compareIndex := func(name string, keySchema []types.KeySchemaElement, projection *types.Projection, local bool) {
+		actual, ok := existing[name]
+		if !ok {
+			differences = append(differences, IndexDifference{IndexName: name, Missing: true})
+			return
+		}
+
+		reason := compareKeys(keySchema, actual.keySchema)
+		if actual.local != local {
+			reason = map[bool]string{true: "is global, expected local", false: "is local, expected global"}[local]
+		} else if reason == "" && !projects(actual.projection, projection) {
+			reason = fmt.Sprintf("projection is %s, expected %s", projectionString(actual.projection), projectionString(projection))
+		}
+
+		if reason != "" {
+			differences = append(differences, IndexDifference{IndexName: name, Reason: reason})
+		}
+	}
+	for _, lsi := range definition.LocalSecondaryIndexes {
+		compareIndex(*lsi.IndexName, lsi.KeySchema, lsi.Projection, true)
+	}
+	for _, gsi := range definition.GlobalSecondaryIndexes {
+		compareIndex(*gsi.IndexName, gsi.KeySchema, gsi.Projection, false)
+	}
+
+	return differences
+}
+
+// keySchemaString describes a key schema and its attribute types, e.g. "PK(S)/SK(S)".
+func keySchemaString(elements []types.KeySchemaElement, attributeTypes map[string]types.ScalarAttributeType) string {
+	var hash, rangeKey string
+	for _, element := range elements {
+		s := *element.AttributeName + "(" + string(attributeTypes[*element.AttributeName]) + ")"
+		if element.KeyType == types.KeyTypeHash {
+			hash = s
+		} else {
+			rangeKey = "/" + s
+		}
+	}
+	return hash + rangeKey
+}
+
+// projects returns true if the actual projection includes every attribute the expected one does.
+func projects(actual, expected *types.Projection) bool {
+	rank := func(p *types.Projection) int {
+		if p == nil {
+			return 0
+		}
+		return map[types.ProjectionType]int{types.ProjectionTypeKeysOnly: 0, types.ProjectionTypeInclude: 1, types.ProjectionTypeAll: 2}[p.ProjectionType]
+	}
+
+	switch a, e := rank(actual), rank(expected); {
+	case a != e:
+		return a > e
+	case a != 1:
+		return true
+	}
+
+	included := make(map[string]bool, len(actual.NonKeyAttributes))
+	for _, attribute := range actual.NonKeyAttributes {
+		included[attribute] = true
+	}
+	for _, attribute := range expected.NonKeyAttributes {
+		if !included[attribute] {
+			return false
+		}
+	}
+	return true
+}
+
+func projectionString(p *types.Projection) string {
+	if p == nil {
+		return string(types.ProjectionTypeKeysOnly)
+	}
+	if p.ProjectionType == types.ProjectionTypeInclude {
+		return string(p.ProjectionType) + "(" + strings.Join(p.NonKeyAttributes, ",") + ")"
+	}
+	return string(p.ProjectionType)
+}
@@ -0,0 +1,191 @@
+package dynamodb_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/jt0/gomer/_test/assert"
+	"github.com/jt0/gomer/data"
+	ddb "github.com/jt0/gomer/data/dynamodb"
+	ddbtest "github.com/jt0/gomer/data/dynamodb/_test"
+	testentities "github.com/jt0/gomer/data/dynamodb/_test"
+)
+
+type missingKeyField struct {
+	Id   string `db.keys:"pk"`
+	Name string `db.keys:"sk.1"`
+}
+
+func (m *missingKeyField) TypeName() string             { return "missingKeyField" }
+func (m *missingKeyField) NewQueryable() data.Queryable { return nil }
+
+type sortKeyOnly struct {
+	Id string `db.keys:"sk"`
+}
+
+func (s *sortKeyOnly) TypeName() string             { return "sortKeyOnly" }
+func (s *sortKeyOnly) NewQueryable() data.Queryable { return nil }
+
+type lsiWithoutSk struct {
+	Id   string `db.keys:"pk"`
+	Name string `db.keys:"lsi_1:sk"`
+}
+
+func (l *lsiWithoutSk) TypeName() string             { return "lsiWithoutSk" }
+func (l *lsiWithoutSk) NewQueryable() data.Queryable { return nil }
+
+func TestTableDefinition(t *testing.T) {
+	input, ge := ddb.TableDefinition("orders", &testentities.Order{}, &testentities.Product{}, &testentities.User{})
+	assert.Success(t, ge)
+
+	assert.Equals(t, "orders", aws.ToString(input.TableName))
+	assert.Equals(t, types.BillingModePayPerRequest, input.BillingMode)
+	assert.Equals(t, "PK/SK", keyNames(input.KeySchema))
+
+	var attributes []string
+	for _, ad := range input.AttributeDefinitions {
+		assert.Equals(t, types.ScalarAttributeTypeS, ad.AttributeType)
+		attributes = append(attributes, aws.ToString(ad.AttributeName))
+	}
+	assert.Equals(t, []string{"PK", "SK", "GSI_1_PK", "GSI_1_SK", "LSI_1_SK", "LSI_2_SK"}, attributes)
+
+	assert.Equals(t, 2, len(input.LocalSecondaryIndexes))
+	assert.Equals(t, "lsi_1", aws.ToString(input.LocalSecondaryIndexes[0].IndexName))
+	assert.Equals(t, "PK/LSI_1_SK", keyNames(input.LocalSecondaryIndexes[0].KeySchema))
+	assert.Equals(t, types.ProjectionTypeAll, input.LocalSecondaryIndexes[0].Projection.ProjectionType)
+	assert.Equals(t, "lsi_2", aws.ToString(input.LocalSecondaryIndexes[1].IndexName))
+	assert.Equals(t, "PK/LSI_2_SK", keyNames(input.LocalSecondaryIndexes[1].KeySchema))
+
+	assert.Equals(t, 1, len(input.GlobalSecondaryIndexes))
+	assert.Equals(t, "gsi_1", aws.ToString(input.GlobalSecondaryIndexes[0].IndexName))
+	assert.Equals(t, "GSI_1_PK/GSI_1_SK", keyNames(input.GlobalSecondaryIndexes[0].KeySchema))
+	assert.Equals(t, types.ProjectionTypeAll, input.GlobalSecondaryIndexes[0].Projection.ProjectionType)
+}
+
+func TestTableDefinition_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		persistables []data.Persistable
+	}{
+		{"missing key field", []data.Persistable{&missingKeyField{}}},
+		{"no partition key", []data.Persistable{&sortKeyOnly{}}},
+		{"local index without table sort key", []data.Persistable{&lsiWithoutSk{}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ge := ddb.TableDefinition("t", tt.persistables...)
+			assert.Error(t, ge)
+		})
+	}
+}
+
+func TestCompareTable(t *testing.T) {
+	definition, ge := ddb.TableDefinition("orders", &testentities.Order{}, &testentities.Product{}, &testentities.User{})
+	assert.Success(t, ge)
+
+	tests := []struct {
+		name        string
+		modify      func(*types.TableDescription)
+		differences []string
+	}{
+		{"matching", func(*types.TableDescription) {}, nil},
+		{"extra index", func(d *types.TableDescription) {
+			d.GlobalSecondaryIndexes = append(d.GlobalSecondaryIndexes, types.GlobalSecondaryIndexDescription{
+				IndexName: aws.String("gsi_2"),
+				KeySchema: []types.KeySchemaElement{{AttributeName: aws.String("GSI_2_PK"), KeyType: types.KeyTypeHash}},
+			})
+		}, nil},
+		{"missing index", func(d *types.TableDescription) {
+			d.GlobalSecondaryIndexes = nil
+		}, []string{"gsi_1: missing"}},
+		{"different key", func(d *types.TableDescription) {
+			d.LocalSecondaryIndexes[1].KeySchema[1].AttributeName = aws.String("OrderDate")
+			d.AttributeDefinitions = append(d.AttributeDefinitions, types.AttributeDefinition{AttributeName: aws.String("OrderDate"), AttributeType: types.ScalarAttributeTypeS})
+		}, []string{"lsi_2: key schema is PK(S)/OrderDate(S), expected PK(S)/LSI_2_SK(S)"}},
+		{"different key type", func(d *types.TableDescription) {
+			d.AttributeDefinitions[1].AttributeType = types.ScalarAttributeTypeN
+		}, []string{"__table__: key schema is PK(S)/SK(N), expected PK(S)/SK(S)"}},
+		{"global instead of local", func(d *types.TableDescription) {
+			lsi := d.LocalSecondaryIndexes[0]
+			d.LocalSecondaryIndexes = d.LocalSecondaryIndexes[1:]
+			d.GlobalSecondaryIndexes = append(d.GlobalSecondaryIndexes, types.GlobalSecondaryIndexDescription{IndexName: lsi.IndexName, KeySchema: lsi.KeySchema, Projection: lsi.Projection})
+		}, []string{"lsi_1: is global, expected local"}},
+		{"smaller projection", func(d *types.TableDescription) {
+			d.GlobalSecondaryIndexes[0].Projection = &types.Projection{ProjectionType: types.ProjectionTypeInclude, NonKeyAttributes: []string{"Name"}}
+		}, []string{"gsi_1: projection is INCLUDE(Name), expected ALL"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			description := describe(definition)
+			tt.modify(description)
+
+			var differences []string
+			for _, d := range ddb.CompareTable(definition, description) {
+				differences = append(differences, d.String())
+			}
+			assert.Equals(t, tt.differences, differences)
+		})
+	}
+}
+
+func TestTableDefinition_CreateTable(t *testing.T) {
+	client, isLocal, err := ddbtest.NewClient()
+	assert.Success(t, err)
+
+	if !isLocal {
+		t.Skip("Skipping test: DDB_LOCAL not set")
+	}
+
+	persistables := []data.Persistable{&testentities.Order{}, &testentities.Product{}, &testentities.User{}}
+	input, ge := ddb.TableDefinition("gomer_table_definition_test", persistables...)
+	assert.Success(t, ge)
+
+	_, err = client.CreateTable(context.Background(), input)
+	assert.Success(t, err)
+	defer client.DeleteTable(context.Background(), &dynamodb.DeleteTableInput{TableName: input.TableName})
+
+	output, err := client.DescribeTable(context.Background(), &dynamodb.DescribeTableInput{TableName: input.TableName})
+	assert.Success(t, err)
+	assert.Equals(t, 0, len(ddb.CompareTable(input, output.Table)))
+
+	_, ge = ddb.Store(*input.TableName, &ddb.Configuration{DynamoDb: client}, persistables...)
+	assert.Success(t, ge)
+}
+
+func keyNames(elements []types.KeySchemaElement) string {
+	names := aws.ToString(elements[0].AttributeName)
+	if len(elements) > 1 {
+		names += "/" + aws.ToString(elements[1].AttributeName)
+	}
+	return names
+}
+
+// describe returns a copy of the definition as DescribeTable would return it for the created table.
+func describe(definition *dynamodb.CreateTableInput) *types.TableDescription {
+	description := &types.TableDescription{
+		TableName:            definition.TableName,
+		AttributeDefinitions: append([]types.AttributeDefinition(nil), definition.AttributeDefinitions...),
+		KeySchema:            definition.KeySchema,
+	}
+	for _, lsi := range definition.LocalSecondaryIndexes {
+		description.LocalSecondaryIndexes = append(description.LocalSecondaryIndexes, types.LocalSecondaryIndexDescription{
+			IndexName:  lsi.IndexName,
+			KeySchema:  append([]types.KeySchemaElement(nil), lsi.KeySchema...),
+			Projection: lsi.Projection,
+		})
+	}
+	for _, gsi := range definition.GlobalSecondaryIndexes {
+		description.GlobalSecondaryIndexes = append(description.GlobalSecondaryIndexes, types.GlobalSecondaryIndexDescription{
+			IndexName:  gsi.IndexName,
+			KeySchema:  append([]types.KeySchemaElement(nil), gsi.KeySchema...),
+			Projection: gsi.Projection,
+		})
+	}
+	return description
+}
@@ -0,0 +1,280 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/data/dataerr"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/limit"
+)
+
+const transactionMaxItems = 100 // TransactWriteItems limit
+
+// transaction stages the items that the table's Create, Update, and Delete operations would otherwise write directly,
+// and writes them with a single TransactWriteItems call on Commit. Operations find the transaction they're staging for
+// in their context.
+type transaction struct {
+	t         *table
+	groups    []stagedGroup
+	items     []types.TransactWriteItem
+	keys      map[string]bool
+	operation string
+	rollbacks []func()
+	committed bool
+}
+
+// stagedGroup is the set of items staged by one operation.
+type stagedGroup struct {
+	operation string
+	p         data.Persistable
+	first     int // index of the group's first item in the transaction
+
+	// conditionFailed returns the error when the condition of the group's item at the given index fails
+	conditionFailed func(int, error) gomerr.Gomerr
+
+	// written, if not nil, is called after the transaction is committed
+	written func()
+}
+
+type transactionCtxKey struct{}
+
+// NewTransaction returns a data.Transaction that commits its writes using TransactWriteItems, which limits a transaction
+// to 100 items. Each staged write uses one item, plus one for each uniqueness sentinel it claims or releases when using
+// SentinelUniqueness. Staging doesn't write anything, but it may read: a Create checks unique constraints with a query
+// when using QueryUniqueness, and writes that move sentinels read the current item. A transaction must not be used
+// concurrently.
+func (t *table) NewTransaction() data.Transaction {
+	return &transaction{t: t, keys: make(map[string]bool)}
+}
+
+func (tx *transaction) Create(ctx context.Context, p data.Persistable) gomerr.Gomerr {
+	return tx.with("Create", func() gomerr.Gomerr {
+		return tx.t.Create(context.WithValue(ctx, transactionCtxKey{}, tx), p)
+	})
+}
+
+func (tx *transaction) Update(ctx context.Context, p data.Persistable, update data.Persistable) gomerr.Gomerr {
+	return tx.with("Update", func() gomerr.Gomerr {
+		return tx.t.Update(context.WithValue(ctx, transactionCtxKey{}, tx), p, update)
+	})
+}
+
+func (tx *transaction) Delete(ctx context.Context, p data.Persistable) gomerr.Gomerr {
+	return tx.with("Delete", func() gomerr.Gomerr {
+		return tx.t.Delete(context.WithValue(ctx, transactionCtxKey{}, tx), p)
+	})
+}
+
+func (tx *transaction) ConditionCheck(_ context.Context, p data.Persistable) gomerr.Gomerr {
+	return tx.with("ConditionCheck", func() (ge gomerr.Gomerr) {
+		defer func() {
+			if ge != nil {
+				ge = dataerr.Store("ConditionCheck", p).Wrap(ge)
+			}
+		}()
+
+		pt, ge := tx.t.batchPersistableType(p)
+		if ge != nil {
+			return ge
+		}
+
+		key := make(map[string]types.AttributeValue, 2)
+		if ge = tx.t.populateKeyValues(key, p, tx.t.valueSeparatorChar, true); ge != nil {
+			return ge
+		}
+
+		names := make(map[string]string)
+		values := make(map[string]types.AttributeValue)
+		condition := "attribute_exists(" + attributePath([]string{tx.t.pk.name}, names) + ")"
+
+		expectedVersion, versioned := pt.versionField.Version(reflect.ValueOf(p).Elem())
+		if versioned {
+			versionPath := attributePath([]string{pt.attributeName(string(pt.versionField))}, names)
+			if expectedVersion == 0 {
+				condition += " AND attribute_not_exists(" + versionPath + ")"
+			} else {
+				condition += " AND " + versionPath + "=" + valueAlias(&types.AttributeValueMemberN{Value: strconv.FormatUint(expectedVersion, 10)}, values)
+			}
+		}
+
+		return tx.stage(p, []types.TransactWriteItem{{ConditionCheck: &types.ConditionCheck{
+			Key:                       key,
+			TableName:                 tx.t.tableName,
+			ConditionExpression:       &condition,
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: nilIfEmpty(values),
+		}}}, func(_ int, err error) gomerr.Gomerr {
+			if versioned {
+				return gomerr.Conflict(p.TypeName(), "", "version_mismatch").WithSource(p).AddAttribute("expectedVersion", expectedVersion).Wrap(err)
+			}
+			return dataerr.PersistableNotFound(p.TypeName(), key).Wrap(err)
+		}, nil)
+	})
+}
+
+// Commit writes the staged items. If a condition fails, the error for each failed write is returned (with more than
+// one combined using gomerr.Batcher) and the persistables' versions are restored.
+func (tx *transaction) Commit(ctx context.Context) (ge gomerr.Gomerr) {
+	defer tx.t.recordOperation("Commit", "", time.Now(), &ge)
+
+	if tx.committed {
+		return gomerr.Internal("transaction already committed")
+	}
+	tx.committed = true
+
+	ge = tx.write(ctx)
+	if ge != nil {
+		for i := len(tx.rollbacks) - 1; i >= 0; i-- {
+			tx.rollbacks[i]()
+		}
+		return ge
+	}
+
+	for _, group := range tx.groups {
+		if group.written != nil {
+			group.written()
+		}
+	}
+
+	return nil
+}
+
+func (tx *transaction) write(ctx context.Context) gomerr.Gomerr {
+	if len(tx.items) == 0 {
+		return nil
+	}
+
+	if len(tx.items) > transactionMaxItems {
+		return limit.Exceeded("DynamoDB", "TransactWriteItems.items", limit.Count(transactionMaxItems), limit.Unknown, limit.Count(len(tx.items)))
+	}
+
+	input := &dynamodb.TransactWriteItemsInput{TransactItems: tx.items, ReturnConsumedCapacity: tx.t.returnConsumedCapacity()}
+	output, err := tx.t.ddb.TransactWriteItems(ctx, input)
+	if err == nil {
+		tx.t.recordCapacity(tx.typeName(), true, ptrs(output.ConsumedCapacity)...)
+		return nil
+	}
+
+	var tce *types.TransactionCanceledException
+	if !errors.As(err, &tce) {
+		return tx.t.writeError(err, input)
+	}
+
+	var errs []gomerr.Gomerr
+	for i, reason := range tce.CancellationReasons {
+		if reason.Code == nil || *reason.Code != conditionCheckFailed {
+			continue
+		}
+
+		group := tx.groupFor(i)
+		errs = append(errs, dataerr.Store(group.operation, group.p).Wrap(group.conditionFailed(i-group.first, err)))
+	}
+	if len(errs) == 0 {
+		return tx.t.writeError(err, input)
+	}
+
+	return gomerr.Batcher(errs)
+}
+
+// typeName returns the type of the staged persistables, or "" if they're of several types.
+func (tx *transaction) typeName() string {
+	ps := make([]data.Persistable, len(tx.groups))
+	for i, group := range tx.groups {
+		ps[i] = group.p
+	}
+	return batchTypeName(ps)
+}
+
+// groupFor returns the group that staged the item at the given index.
+func (tx *transaction) groupFor(index int) stagedGroup {
+	group := tx.groups[0]
+	for _, g := range tx.groups[1:] {
+		if g.first > index {
+			break
+		}
+		group = g
+	}
+	return group
+}
+
+// with runs the staging function for the named operation. Nothing is staged if it fails.
+func (tx *transaction) with(operation string, stageFn func() gomerr.Gomerr) gomerr.Gomerr {
+	if tx.committed {
+		return gomerr.Internal("transaction already committed")
+	}
+
+	tx.operation = operation
+	groups, items, rollbacks := len(tx.groups), len(tx.items), len(tx.rollbacks)
+
+	ge := stageFn()
+	if ge != nil {
+		for i := len(tx.rollbacks) - 1; i >= rollbacks; i-- {
+			tx.rollbacks[i]()
+		}
+		for _, item := range tx.items[items:] {
+			delete(tx.keys, tx.t.keyId(writeItemKey(item)))
+		}
+		tx.groups, tx.items, tx.rollbacks = tx.groups[:groups], tx.items[:items], tx.rollbacks[:rollbacks]
+	}
+
+	return ge
+}
+
+// stage adds the items written on p's behalf by the current operation. An item can only be written once per
+// transaction.
+func (tx *transaction) stage(p data.Persistable, items []types.TransactWriteItem, conditionFailed func(int, error) gomerr.Gomerr, written func()) gomerr.Gomerr {
+	for _, item := range items {
+		if key := writeItemKey(item); tx.keys[tx.t.keyId(key)] {
+			return gomerr.Conflict(p.TypeName(), "", "item_already_in_transaction").AddAttribute("key", key)
+		}
+	}
+	for _, item := range items {
+		tx.keys[tx.t.keyId(writeItemKey(item))] = true
+	}
+
+	tx.groups = append(tx.groups, stagedGroup{
+		operation:       tx.operation,
+		p:               p,
+		first:           len(tx.items),
+		conditionFailed: conditionFailed,
+		written:         written,
+	})
+	tx.items = append(tx.items, items...)
+
+	return nil
+}
+
+func (tx *transaction) onRollback(fn func()) {
+	tx.rollbacks = append(tx.rollbacks, fn)
+}
+
+// stagingTransaction returns the transaction that the table's writes should be staged in, if any.
+func (t *table) stagingTransaction(ctx context.Context) *transaction {
+	if tx, ok := ctx.Value(transactionCtxKey{}).(*transaction); ok && tx.t == t {
+		return tx
+	}
+	return nil
+}
+
+func writeItemKey(item types.TransactWriteItem) map[string]types.AttributeValue {
+	switch {
+	case item.Put != nil:
+		return item.Put.Item
+	case item.Update != nil:
+		return item.Update.Key
+	case item.Delete != nil:
+		return item.Delete.Key
+	case item.ConditionCheck != nil:
+		return item.ConditionCheck.Key
+	default:
+		return nil
+	}
+}
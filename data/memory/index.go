@@ -0,0 +1,326 @@
+package memory
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/data/dataerr"
+	"github.com/jt0/gomer/gomerr"
+)
+
+// index mirrors the dynamodb store's notion of an index. Unlike DynamoDB, there is no table description to read the
+// key schema from, so indexes are derived from the `db.keys` tags of the registered persistables. The table's own
+// index has the empty name.
+type index struct {
+	name string
+	pk   *keyAttribute
+	sk   *keyAttribute
+}
+
+type keyAttribute struct {
+	name                   string
+	keyFieldsByPersistable map[string][]*keyField // persistable type name -> key fields
+}
+
+type keyField struct {
+	name      string
+	preferred bool
+	ascending bool
+}
+
+type candidate struct {
+	index      *index
+	preferred  bool
+	ascending  bool
+	orderField string // the sort key field that orders the results
+	skLength   int
+	skMissing  int
+}
+
+func newIndex(name string) *index {
+	return &index{
+		name: name,
+		pk:   &keyAttribute{name: keyName(name, "pk"), keyFieldsByPersistable: make(map[string][]*keyField)},
+		sk:   &keyAttribute{name: keyName(name, "sk"), keyFieldsByPersistable: make(map[string][]*keyField)},
+	}
+}
+
+func keyName(indexName, key string) string {
+	if indexName == "" {
+		return key
+	}
+	return indexName + ":" + key
+}
+
+func (i *index) friendlyName() string {
+	if i.name == "" {
+		return "__table__"
+	}
+	return i.name
+}
+
+func (i *index) keyAttributes() []*keyAttribute {
+	if i.sk == nil {
+		return []*keyAttribute{i.pk}
+	} else {
+		return []*keyAttribute{i.pk, i.sk}
+	}
+}
+
+// indexFor finds the best index match for the provided queryable using the same rules as the dynamodb store: an
+// index is viable if every partition key field has a value and the sort key fields that are provided have no gaps.
+// Amongst the viable candidates, preferred indexes win, followed by those with fewer missing and longer sort keys. A
+// sort key field with a range condition (see rangeField) counts as provided. If q is data.Sorted, only indexes ordered
+// by the sort field are considered.
+func (s *store) indexFor(q data.Queryable, conditions []data.Condition) (*index, bool, gomerr.Gomerr) {
+	candidates := make([]*candidate, 0, len(s.indexes))
+	qv := reflect.ValueOf(q.ItemTemplate()).Elem()
+	sortBy := data.SortOf(q)
+
+	for _, idx := range s.indexes {
+		if c := idx.candidate(qv, q.TypeName(), s.queryWildcardChar, conditions); c != nil && (sortBy == nil || c.orderField == sortBy.Field) {
+			candidates = append(candidates, c)
+		}
+	}
+
+	if len(candidates) == 0 {
+		available := make(map[string]any, len(s.indexes))
+		for _, idx := range s.indexes {
+			available[idx.friendlyName()] = idx
+		}
+		return nil, false, dataerr.NoIndexMatch(available, q)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		c1 := candidates[i]
+		c2 := candidates[j]
+
+		if c1.preferred != c2.preferred {
+			return c1.preferred
+		}
+
+		if c1.skMissing != c2.skMissing {
+			return c1.skMissing < c2.skMissing
+		}
+
+		if c1.skLength != c2.skLength {
+			return c1.skLength > c2.skLength
+		}
+
+		if c1.index.name == "" || c2.index.name == "" {
+			return c1.index.name == "" // favor the table's index over others
+		}
+
+		return c1.index.name < c2.index.name // keeps the choice stable across map iteration orders
+	})
+
+	if sortBy != nil {
+		return candidates[0].index, !sortBy.Descending, nil
+	}
+	return candidates[0].index, candidates[0].ascending, nil
+}
+
+func (i *index) candidate(qv reflect.Value, ptName string, queryWildcardChar byte, conditions []data.Condition) *candidate {
+	var keyFields []*keyField
+	if keyFields = i.pk.keyFieldsByPersistable[ptName]; keyFields == nil {
+		return nil
+	}
+	for _, kf := range keyFields {
+		if isStaticKeyField(kf.name) {
+			continue
+		}
+
+		fv := qv.FieldByName(kf.name)
+		if !fv.IsValid() || fv.IsZero() {
+			return nil
+		}
+
+		if queryWildcardChar != 0 && endsWithWildcard(fv, queryWildcardChar) {
+			return nil
+		}
+	}
+
+	c := &candidate{index: i, ascending: true}
+
+	if i.sk != nil {
+		if keyFields = i.sk.keyFieldsByPersistable[ptName]; keyFields == nil {
+			return nil
+		}
+		applySort := true
+		hasWildcard := false
+		rangePosition := i.rangeField(qv, ptName, conditions)
+		for n, kf := range keyFields {
+			if applySort {
+				c.ascending = kf.ascending
+			}
+
+			if isStaticKeyField(kf.name) {
+				c.preferred = kf.preferred
+				continue
+			}
+			if applySort {
+				c.orderField = kf.name
+			}
+
+			fv := qv.FieldByName(kf.name)
+			if n == rangePosition {
+				if hasWildcard {
+					return nil
+				}
+				// Like a wildcard, the condition matches a range of values for the field
+				applySort = false
+				hasWildcard = true
+				c.preferred = kf.preferred
+			} else if !fv.IsValid() || fv.IsZero() {
+				applySort = false
+				c.skMissing++
+			} else if c.skMissing > 0 || hasWildcard { // Cannot have gaps in the middle of the sort key
+				return nil
+			} else if queryWildcardChar != 0 && endsWithWildcard(fv, queryWildcardChar) {
+				applySort = false
+				hasWildcard = true
+				c.preferred = kf.preferred
+			} else {
+				c.preferred = kf.preferred
+			}
+		}
+		c.skLength = len(keyFields)
+	}
+
+	return c
+}
+
+// rangeField returns the position of the sort key field whose condition narrows the range of sort key values read. The
+// field must be the first non-static sort key field without a value, and the condition must compare a prefix of the
+// key's value, so neither NEQ nor CONTAINS qualifies. Returns -1 if there's no such condition.
+func (i *index) rangeField(qv reflect.Value, ptName string, conditions []data.Condition) int {
+	if len(conditions) == 0 || i.sk == nil {
+		return -1
+	}
+
+	for n, kf := range i.sk.keyFieldsByPersistable[ptName] {
+		if isStaticKeyField(kf.name) {
+			continue
+		}
+		if fv := qv.FieldByName(kf.name); fv.IsValid() && !fv.IsZero() {
+			continue
+		}
+
+		for _, condition := range conditions {
+			if condition.Field == kf.name && condition.Type != data.NEQ && condition.Type != data.CONTAINS {
+				return n
+			}
+		}
+		break
+	}
+
+	return -1
+}
+
+// endsWithWildcard checks if a reflect.Value (string or *string) ends with the wildcard character.
+func endsWithWildcard(fv reflect.Value, wildcardChar byte) bool {
+	if fv.Kind() == reflect.Ptr {
+		fv = fv.Elem()
+	}
+	if s, ok := fv.Interface().(string); ok && s != "" {
+		return s[len(s)-1] == wildcardChar
+	}
+	return false
+}
+
+func isStaticKeyField(name string) bool {
+	return len(name) >= 2 && name[0] == '\'' && name[len(name)-1] == '\''
+}
+
+// keyValue builds the composite key value for the given key attribute in the same format the dynamodb store uses,
+// so that prefix matching and sort order behave identically.
+func (k *keyAttribute) keyValue(ev reflect.Value, ptName string, separator, queryWildcardChar byte) string {
+	keyFields := k.keyFieldsByPersistable[ptName]
+	if keyFields == nil {
+		return ""
+	}
+
+	escape := separator + 1
+	keyValue := fieldValue(keyFields[0].name, ev, separator, escape)
+	if len(keyFields) > 1 {
+		sep := string(separator)
+		lastFieldIndex := 0
+		for i, separators := 1, sep; i < len(keyFields); i, separators = i+1, separators+sep {
+			if nextField := fieldValue(keyFields[i].name, ev, separator, escape); nextField != "" {
+				keyValue += separators
+				keyValue += nextField
+				lastFieldIndex, separators = i, ""
+			}
+		}
+
+		if lastFieldIndex == 0 && keyValue == "" {
+			for i := 1; i < len(keyFields); i++ {
+				keyValue += sep
+			}
+		} else if lastFieldIndex < len(keyFields)-1 && len(keyValue) > 0 && keyValue[len(keyValue)-1] != queryWildcardChar {
+			keyValue += sep
+		}
+	}
+
+	return keyValue
+}
+
+// complete returns true if every non-static key field for the persistable type has a value.
+func (k *keyAttribute) complete(ev reflect.Value, ptName string) bool {
+	for _, kf := range k.keyFieldsByPersistable[ptName] {
+		if isStaticKeyField(kf.name) {
+			continue
+		}
+		if fv := ev.FieldByName(kf.name); !fv.IsValid() || fv.IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+func fieldValue(fieldName string, sv reflect.Value, separator, escape byte) string {
+	if isStaticKeyField(fieldName) {
+		return fieldName[1 : len(fieldName)-1]
+	}
+
+	v := sv.FieldByName(fieldName)
+	if !v.IsValid() || v.IsZero() {
+		return ""
+	}
+
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	return escapeKeyValue(fmtValue(v), separator, escape)
+}
+
+func fmtValue(v reflect.Value) string {
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// escapeKeyValue escapes separator and escape characters in field values to prevent ambiguity in composite keys.
+func escapeKeyValue(value string, separator, escape byte) string {
+	result := make([]byte, 0, len(value))
+	for i := 0; i < len(value); i++ {
+		if b := value[i]; b == separator || b == escape {
+			result = append(result, escape)
+		}
+		result = append(result, value[i])
+	}
+	return string(result)
+}
+
+func keyFieldNames(keyFields []*keyField) []string {
+	names := make([]string, len(keyFields))
+	for i, kf := range keyFields {
+		names[i] = kf.name
+	}
+	return names
+}
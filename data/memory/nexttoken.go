@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/gomerr"
+)
+
+// token records the position of the last item returned in a page. Since the store is in-process, the token is only
+// encoded rather than encrypted.
+type token struct {
+	Index key `json:"i"`
+	Table key `json:"t"`
+}
+
+func (k key) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]string{k.pk, k.sk})
+}
+
+func (k *key) UnmarshalJSON(b []byte) error {
+	var values [2]string
+	if err := json.Unmarshal(b, &values); err != nil {
+		return err
+	}
+	k.pk, k.sk = values[0], values[1]
+	return nil
+}
+
+const NextPageToken = "NextPageToken"
+
+func (t *token) tokenize() (*string, gomerr.Gomerr) {
+	if t == nil {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(t)
+	if err != nil {
+		return nil, gomerr.Marshal(NextPageToken, t).Wrap(err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(b)
+	return &encoded, nil
+}
+
+func untokenize(q data.Queryable) (*token, gomerr.Gomerr) {
+	nextPageToken := q.NextPageToken()
+	if nextPageToken == nil || *nextPageToken == "" {
+		return nil, nil
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(*nextPageToken)
+	if err != nil {
+		return nil, gomerr.MalformedValue(NextPageToken, nil).Wrap(err)
+	}
+
+	t := &token{}
+	if err = json.Unmarshal(decoded, t); err != nil {
+		return nil, gomerr.MalformedValue(NextPageToken, nil).Wrap(err)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,284 @@
+package memory
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/gomerr"
+)
+
+type persistableType struct {
+	name             string
+	elemType         reflect.Type
+	dbNames          map[string]string // field name -> storage name
+	uniqueTuples     [][]string        // field tuples from `db.constraints:"unique(...)"`
+	constraintFields map[string]bool   // field names that participate in any constraint (used for update optimization)
+	versionField     data.VersionField // the field with a `db.version` tag, if any
+	ttlField         string            // name of the field with a `db.ttl` tag, if any
+}
+
+var (
+	ddbKeyStatementRegexp = regexp.MustCompile(`(!)?([+-])?(?:([\w-.]+):)?(pk|sk)(?:.(\d))?(?:=('\w+')(\+)?)?`)
+	constraintsRegexp     = regexp.MustCompile(`(unique)(\(([\w,]+)\))?`)
+)
+
+func newPersistableType(s *store, persistableName string, elemType reflect.Type) (*persistableType, gomerr.Gomerr) {
+	pt := &persistableType{
+		name:             persistableName,
+		elemType:         elemType,
+		dbNames:          make(map[string]string),
+		constraintFields: make(map[string]bool),
+	}
+
+	if errors := pt.processFields(elemType, s, make([]gomerr.Gomerr, 0)); len(errors) > 0 {
+		return nil, gomerr.Configuration("'db' tag errors found for type: " + persistableName).Wrap(gomerr.Batcher(errors))
+	}
+
+	return pt, nil
+}
+
+func (pt *persistableType) processFields(structType reflect.Type, s *store, errors []gomerr.Gomerr) []gomerr.Gomerr {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		fieldName := field.Name
+
+		if field.Type.Kind() == reflect.Struct && field.Anonymous {
+			errors = pt.processFields(field.Type, s, errors)
+		} else if unicode.IsLower([]rune(fieldName)[0]) {
+			continue
+		} else {
+			if tag := field.Tag.Get("db.name"); tag != "" {
+				pt.dbNames[fieldName] = tag
+			}
+
+			errors = pt.processConstraintsTag(fieldName, field.Tag.Get("db.constraints"), errors)
+			errors = pt.processKeysTag(fieldName, field.Tag.Get("db.keys"), s, errors)
+
+			if _, ok := field.Tag.Lookup("db.version"); ok {
+				errors = pt.versionField.ProcessVersionTag(field, errors)
+			}
+
+			if tag, ok := field.Tag.Lookup("db.ttl"); ok {
+				errors = pt.processTtlTag(field, tag, errors)
+			}
+		}
+	}
+
+	return errors
+}
+
+func (pt *persistableType) processConstraintsTag(fieldName string, tag string, errors []gomerr.Gomerr) []gomerr.Gomerr {
+	if tag == "" {
+		return errors
+	}
+
+	constraints := constraintsRegexp.FindAllStringSubmatch(tag, -1)
+	if constraints == nil {
+		return append(errors, gomerr.Configuration("invalid `db.constraints` value: "+tag).AddAttribute("field", fieldName))
+	}
+
+	for _, c := range constraints {
+		if c[1] != "unique" {
+			continue
+		}
+
+		fieldTuple := []string{fieldName}
+		if c[3] != "" {
+			fieldTuple = append(fieldTuple, strings.Split(strings.ReplaceAll(c[3], " ", ""), ",")...)
+		}
+
+		for _, f := range fieldTuple {
+			if _, ok := pt.elemType.FieldByName(f); !ok {
+				return append(errors, gomerr.Configuration("unknown field in `db.constraints` value: "+f).AddAttribute("field", fieldName))
+			}
+			pt.constraintFields[f] = true
+		}
+
+		pt.uniqueTuples = append(pt.uniqueTuples, fieldTuple)
+	}
+
+	return errors
+}
+
+var (
+	timeType     = reflect.TypeFor[time.Time]()
+	durationType = reflect.TypeFor[time.Duration]()
+)
+
+// processTtlTag validates a `db.ttl` tag the same way as the dynamodb store. The tag's value, which names the expiry
+// attribute for a time.Duration field, isn't otherwise used.
+func (pt *persistableType) processTtlTag(field reflect.StructField, tag string, errors []gomerr.Gomerr) []gomerr.Gomerr {
+	if pt.ttlField != "" {
+		return append(errors, gomerr.Configuration("multiple `db.ttl` fields").AddAttributes("field", field.Name, "existing", pt.ttlField))
+	}
+
+	switch {
+	case field.Type == timeType && tag != "":
+		return append(errors, gomerr.Configuration("`db.ttl` time field can't name another attribute; use `db.name`").AddAttribute("field", field.Name))
+	case field.Type == timeType && field.Tag.Get("db.name") == "-":
+		return append(errors, gomerr.Configuration("`db.ttl` field must be stored").AddAttribute("field", field.Name))
+	case field.Type == durationType && tag == "":
+		return append(errors, gomerr.Configuration("`db.ttl` duration field must name the expiry attribute").AddAttribute("field", field.Name))
+	case field.Type != timeType && field.Type != durationType:
+		return append(errors, gomerr.Configuration("`db.ttl` field must be a time.Time or time.Duration").AddAttributes("field", field.Name, "type", field.Type.String()))
+	}
+
+	pt.ttlField = field.Name
+
+	return errors
+}
+
+// expiry returns when an item written now with the persistable's values expires, or the zero time if it doesn't.
+func (pt *persistableType) expiry(pv reflect.Value, now time.Time) time.Time {
+	if pt.ttlField == "" {
+		return time.Time{}
+	}
+
+	switch fv := pv.FieldByName(pt.ttlField).Interface().(type) {
+	case time.Time:
+		return fv
+	case time.Duration:
+		if fv != 0 {
+			return now.Add(fv)
+		}
+	}
+	return time.Time{}
+}
+
+func (pt *persistableType) processKeysTag(fieldName string, tag string, s *store, errors []gomerr.Gomerr) []gomerr.Gomerr {
+	if tag == "" {
+		return errors
+	}
+
+	for _, keyStatement := range strings.Split(strings.ReplaceAll(tag, " ", ""), ",") {
+		groups := ddbKeyStatementRegexp.FindStringSubmatch(keyStatement)
+		if groups == nil {
+			return append(errors, gomerr.Configuration("invalid `db.keys` value: "+keyStatement).AddAttribute("field", fieldName))
+		}
+
+		idx, ok := s.indexes[groups[3]]
+		if !ok {
+			idx = newIndex(groups[3])
+			s.indexes[groups[3]] = idx
+		}
+
+		key := idx.pk
+		if groups[4] == "sk" {
+			key = idx.sk
+		}
+
+		var partIndex int
+		if groups[5] != "" {
+			partIndex, _ = strconv.Atoi(groups[5])
+		}
+
+		kfName := fieldName
+		if groups[6] != "" { // If non-empty, this field has a static value. Replace with that value.
+			kfName = groups[6]
+		}
+
+		kf := &keyField{name: kfName, preferred: groups[1] == "!", ascending: groups[2] != "-"}
+		keyFields, ge := insertAtIndex(key.keyFieldsByPersistable[pt.name], kf, partIndex)
+		if ge != nil {
+			return append(errors, ge.AddAttribute("field", fieldName))
+		}
+		key.keyFieldsByPersistable[pt.name] = keyFields
+	}
+
+	return errors
+}
+
+func insertAtIndex(slice []*keyField, value *keyField, index int) ([]*keyField, gomerr.Gomerr) {
+	if index < len(slice) {
+		if slice[index] != nil {
+			return nil, gomerr.Configuration(fmt.Sprintf("already found value '%s' at index %d", slice[index].name, index))
+		}
+	} else {
+		slice = append(slice, make([]*keyField, index+1-len(slice))...)
+	}
+
+	slice[index] = value
+
+	return slice, nil
+}
+
+func (pt *persistableType) stored(fieldName string) bool {
+	return pt.dbNames[fieldName] != "-"
+}
+
+var queryableType = reflect.TypeFor[data.Queryable]()
+
+// copyFields deep copies the exported field values from src to dst. Embedded structs are descended into rather than
+// copied wholesale so that unexported state (e.g. a resource's registration data) is never shared between the stored
+// item and the caller's value. Queryable fields and fields with a `db.name:"-"` tag are not persisted and so are
+// skipped.
+func (pt *persistableType) copyFields(dst, src reflect.Value) {
+	for i := 0; i < dst.NumField(); i++ {
+		f := dst.Type().Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			pt.copyFields(dst.Field(i), src.Field(i))
+			continue
+		}
+
+		if !pt.stored(f.Name) || f.Type.Implements(queryableType) || reflect.PointerTo(f.Type).Implements(queryableType) {
+			continue
+		}
+
+		copyValue(dst.Field(i), src.Field(i))
+	}
+}
+
+func copyValue(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return
+		}
+		v := reflect.New(src.Type().Elem())
+		copyValue(v.Elem(), src.Elem())
+		dst.Set(v)
+	case reflect.Slice:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return
+		}
+		v := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			copyValue(v.Index(i), src.Index(i))
+		}
+		dst.Set(v)
+	case reflect.Map:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return
+		}
+		v := reflect.MakeMapWithSize(src.Type(), src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			mv := reflect.New(src.Type().Elem()).Elem()
+			copyValue(mv, iter.Value())
+			v.SetMapIndex(iter.Key(), mv)
+		}
+		dst.Set(v)
+	case reflect.Struct:
+		dst.Set(src) // copies unexported fields (e.g. time.Time's) as-is
+		for i := 0; i < src.NumField(); i++ {
+			if src.Type().Field(i).IsExported() {
+				copyValue(dst.Field(i), src.Field(i))
+			}
+		}
+	default:
+		dst.Set(src)
+	}
+}
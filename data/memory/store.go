@@ -0,0 +1,727 @@
+package memory
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jt0/gomer/constraint"
+	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/data/dataerr"
+	"github.com/jt0/gomer/gomerr"
+)
+
+// Configuration mirrors the subset of dynamodb.Configuration options that affect observable store behavior.
+type Configuration struct {
+	MaxResultsDefault      int
+	MaxResultsMax          int
+	ValueSeparatorChar     byte
+	QueryWildcardChar      byte
+	FailDeleteIfNotPresent bool
+}
+
+const (
+	SymbolChars                    = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`"
+	ValueSeparatorCharDefault      = '#'
+	QueryWildcardCharDefault  byte = 0
+)
+
+type store struct {
+	mu                     sync.RWMutex
+	indexes                map[string]*index
+	persistableTypes       map[string]*persistableType
+	items                  map[key]*item
+	defaultLimit           int
+	maxLimit               int
+	valueSeparatorChar     byte
+	queryWildcardChar      byte
+	failDeleteIfNotPresent bool
+}
+
+// key holds the partition and sort key values of an item within an index.
+type key struct {
+	pk string
+	sk string
+}
+
+type item struct {
+	typeName string
+	value    reflect.Value  // pointer to the stored copy
+	keys     map[string]key // index name -> key values. Only includes indexes the item is a member of
+	expires  time.Time      // zero if the item doesn't expire
+}
+
+// expired returns true if the item has a `db.ttl` expiry that has passed. Expired items are treated as though they've
+// been deleted, except that (as with DynamoDB's TTL) they can still be deleted or checked in a transaction.
+func (i *item) expired(now time.Time) bool {
+	return !i.expires.IsZero() && !i.expires.After(now)
+}
+
+// Store returns a data.Store that keeps persistables in memory. It interprets the same `db.keys`, `db.name`,
+// `db.constraints`, `db.version`, and `db.ttl` tags as the dynamodb store, but derives its indexes from the tags rather than from
+// a table description. An index that declares a sort key but no partition key for a type is treated as a local secondary
+// index and uses the table's partition key. A nil config uses the defaults.
+//
+// Queries only return items of the queryable's type, and unlike DynamoDB the page size limit is applied after
+// filtering.
+func Store(config *Configuration, persistables ...data.Persistable) (data.Store, gomerr.Gomerr) {
+	if config == nil {
+		config = &Configuration{}
+	}
+
+	s := &store{
+		indexes:                map[string]*index{"": newIndex("")},
+		persistableTypes:       make(map[string]*persistableType),
+		items:                  make(map[key]*item),
+		defaultLimit:           config.MaxResultsDefault,
+		maxLimit:               config.MaxResultsMax,
+		failDeleteIfNotPresent: config.FailDeleteIfNotPresent,
+	}
+
+	var ge gomerr.Gomerr
+	if s.valueSeparatorChar, ge = validOrDefaultChar(config.ValueSeparatorChar, ValueSeparatorCharDefault); ge != nil {
+		return nil, ge
+	}
+
+	if s.queryWildcardChar, ge = validOrDefaultChar(config.QueryWildcardChar, QueryWildcardCharDefault); ge != nil {
+		return nil, ge
+	}
+
+	if ge = s.prepare(persistables); ge != nil {
+		return nil, ge
+	}
+
+	return s, nil
+}
+
+func validOrDefaultChar(ch byte, _default byte) (byte, gomerr.Gomerr) {
+	if ch == 0 {
+		return _default, nil
+	}
+
+	if strings.IndexByte(SymbolChars, ch) == -1 {
+		return 0, gomerr.Configuration("character " + string(ch) + " not in the valid set: " + SymbolChars)
+	}
+
+	return ch, nil
+}
+
+func (s *store) prepare(persistables []data.Persistable) gomerr.Gomerr {
+	for _, persistable := range persistables {
+		elemType := reflect.TypeOf(persistable).Elem()
+		name := unqualifiedTypeName(elemType)
+
+		pt, ge := newPersistableType(s, name, elemType)
+		if ge != nil {
+			return ge
+		}
+
+		s.persistableTypes[name] = pt
+	}
+
+	table := s.indexes[""]
+	for _, idx := range s.indexes {
+		if idx != table {
+			// An index with only sort key fields for a type is a local secondary index
+			for name := range idx.sk.keyFieldsByPersistable {
+				if idx.pk.keyFieldsByPersistable[name] == nil {
+					idx.pk.keyFieldsByPersistable[name] = table.pk.keyFieldsByPersistable[name]
+				}
+			}
+		}
+
+		if len(idx.sk.keyFieldsByPersistable) == 0 {
+			idx.sk = nil
+		}
+	}
+
+	for name := range s.persistableTypes {
+		if table.pk.keyFieldsByPersistable[name] == nil {
+			return gomerr.Configuration("persistable type does not define a partition key: " + name)
+		}
+
+		for _, idx := range s.indexes {
+			for _, attribute := range idx.keyAttributes() {
+				for i, kf := range attribute.keyFieldsByPersistable[name] {
+					if kf == nil {
+						return gomerr.Configuration("index "+idx.friendlyName()+" is missing a key field").
+							AddAttributes("key", attribute.name, "type", name, "position", i)
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func unqualifiedTypeName(t reflect.Type) string {
+	s := t.String()
+	return s[strings.Index(s, ".")+1:]
+}
+
+func (s *store) Create(_ context.Context, p data.Persistable) (ge gomerr.Gomerr) {
+	defer func() {
+		if ge != nil {
+			ge = dataerr.Store("Create", p).Wrap(ge)
+		}
+	}()
+
+	return s.put(p, true, true)
+}
+
+func (s *store) Update(_ context.Context, p data.Persistable, update data.Persistable) (ge gomerr.Gomerr) {
+	defer func() {
+		if ge != nil {
+			ge = dataerr.Store("Update", p).Wrap(ge)
+		}
+	}()
+
+	pt, ge := s.persistableType(p)
+	if ge != nil {
+		return ge
+	}
+
+	validateConstraints := false
+	if update != nil {
+		validateConstraints = mergeFields(reflect.ValueOf(update).Elem(), reflect.ValueOf(p).Elem(), pt)
+	}
+
+	return s.put(p, validateConstraints, false)
+}
+
+// mergeFields applies the non-zero values of uv onto pv using the same rules as the dynamodb store: time.Time values
+// are replaced rather than merged, and the fields of anonymous (embedded) structs are treated as the persistable's own.
+// Fields in the update that match the current value are cleared so the update reflects only what changed. Returns true
+// if any changed field participates in a constraint.
+func mergeFields(uv, pv reflect.Value, pt *persistableType) bool {
+	validateConstraints := false
+
+	for i := 0; i < uv.NumField(); i++ {
+		uField := uv.Field(i)
+		if !uField.CanSet() {
+			continue
+		}
+
+		pField := pv.Field(i)
+		field := uv.Type().Field(i)
+		fieldName := field.Name
+		if uField.Kind() == reflect.Struct && uField.Type() != timeType {
+			if field.Anonymous {
+				validateConstraints = mergeFields(uField, pField, pt) || validateConstraints
+			} else {
+				mergeFields(uField, pField, nil)
+			}
+			continue
+		}
+
+		if reflect.DeepEqual(uField.Interface(), pField.Interface()) {
+			uField.Set(reflect.Zero(uField.Type()))
+		} else if uField.Kind() == reflect.Ptr {
+			if uField.IsNil() {
+				continue
+			}
+			if uField.Elem().Kind() == reflect.Struct && uField.Elem().Type() != timeType {
+				if pField.IsNil() {
+					pField.Set(reflect.New(uField.Elem().Type()))
+				}
+				mergeFields(uField.Elem(), pField.Elem(), nil)
+				continue
+			}
+			if !pField.IsNil() && reflect.DeepEqual(uField.Elem().Interface(), pField.Elem().Interface()) {
+				uField.Set(reflect.Zero(uField.Type()))
+			} else {
+				pField.Set(uField)
+				validateConstraints = validateConstraints || pt != nil && pt.constraintFields[fieldName]
+			}
+		} else {
+			if uField.IsZero() {
+				continue
+			}
+			pField.Set(uField)
+			validateConstraints = validateConstraints || pt != nil && pt.constraintFields[fieldName]
+		}
+	}
+
+	return validateConstraints
+}
+
+func (s *store) put(p data.Persistable, validateConstraints bool, ensureUniqueId bool) gomerr.Gomerr {
+	pt, ge := s.persistableType(p)
+	if ge != nil {
+		return ge
+	}
+
+	pv := reflect.ValueOf(p).Elem()
+	tableKey, ge := s.tableKey(pt, pv, p)
+	if ge != nil {
+		return ge
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.putLocked(pt, pv, tableKey, p, validateConstraints, ensureUniqueId)
+}
+
+// putLocked stores p's values. Callers must hold the lock.
+func (s *store) putLocked(pt *persistableType, pv reflect.Value, tableKey key, p data.Persistable, validateConstraints bool, ensureUniqueId bool) gomerr.Gomerr {
+	now := time.Now()
+	existing, exists := s.items[tableKey]
+	exists = exists && !existing.expired(now)
+	if exists && ensureUniqueId {
+		return constraint.NotSatisfied(tableKey).AddAttribute("persistable", p)
+	}
+
+	// Mirrors the dynamodb store's conditional put: an update succeeds only if the stored version is still the one that
+	// was read. An item without a stored version is treated as version 0.
+	expectedVersion, versioned := pt.versionField.Version(pv)
+	if versioned && !ensureUniqueId {
+		var storedVersion uint64
+		if exists {
+			storedVersion, _ = pt.versionField.Version(existing.value.Elem())
+		}
+		if storedVersion != expectedVersion {
+			return gomerr.Conflict(p.TypeName(), "", "version_mismatch").WithSource(p).AddAttribute("expectedVersion", expectedVersion)
+		}
+	}
+
+	if validateConstraints {
+		if ge := s.checkUniqueTuples(pt, pv, tableKey, p); ge != nil {
+			return ge
+		}
+	}
+
+	if versioned {
+		if ensureUniqueId {
+			pt.versionField.SetVersion(pv, 1)
+		} else {
+			pt.versionField.SetVersion(pv, expectedVersion+1)
+		}
+	}
+
+	stored := reflect.New(pt.elemType)
+	pt.copyFields(stored.Elem(), pv)
+
+	s.items[tableKey] = &item{
+		typeName: pt.name,
+		value:    stored,
+		keys:     s.indexKeys(pt, stored.Elem()),
+		expires:  pt.expiry(pv, now),
+	}
+
+	return nil
+}
+
+// checkUniqueTuples verifies that no other item of the same type has the same values for any of the type's
+// uniqueness constraints. A tuple with any empty value is not checked, which matches the behavior of the dynamodb
+// store where such items are absent from the index used to perform the check. Callers must hold the lock.
+func (s *store) checkUniqueTuples(pt *persistableType, pv reflect.Value, tableKey key, p data.Persistable) gomerr.Gomerr {
+	for _, fieldTuple := range pt.uniqueTuples {
+		unique := constraint.New("unique", fieldTuple, func(any) gomerr.Gomerr {
+			for _, fieldName := range fieldTuple {
+				if pv.FieldByName(fieldName).IsZero() {
+					return nil
+				}
+			}
+
+			now := time.Now()
+			for k, i := range s.items {
+				if k == tableKey || i.typeName != pt.name || i.expired(now) {
+					continue
+				}
+
+				matches := true
+				for _, fieldName := range fieldTuple {
+					if !reflect.DeepEqual(pv.FieldByName(fieldName).Interface(), i.value.Elem().FieldByName(fieldName).Interface()) {
+						matches = false
+						break
+					}
+				}
+
+				if matches {
+					return constraint.NotSatisfied(p).AddAttribute("existing", s.resolve(pt, i))
+				}
+			}
+
+			return nil
+		})
+
+		if ge := unique.Test(p); ge != nil {
+			return ge
+		}
+	}
+
+	return nil
+}
+
+func (s *store) Read(ctx context.Context, p data.Persistable) (ge gomerr.Gomerr) {
+	defer func() {
+		if ge != nil {
+			ge = dataerr.Store("Read", p).Wrap(ge)
+		}
+	}()
+
+	pt, ge := s.persistableType(p)
+	if ge != nil {
+		return ge
+	}
+
+	pv := reflect.ValueOf(p).Elem()
+	table := s.indexes[""]
+	if table.pk.complete(pv, pt.name) && (table.sk == nil || table.sk.complete(pv, pt.name)) {
+		tableKey, kge := s.tableKey(pt, pv, p)
+		if kge != nil {
+			return kge
+		}
+
+		s.mu.RLock()
+		i, ok := s.items[tableKey]
+		ok = ok && i.typeName == pt.name && !i.expired(time.Now())
+		if ok {
+			pt.copyFields(pv, i.value.Elem())
+		}
+		s.mu.RUnlock()
+
+		if !ok {
+			return dataerr.PersistableNotFound(p.TypeName(), tableKey)
+		}
+	} else {
+		// Some key fields are missing, so look for a single item that matches the ones that are present
+		q := p.NewQueryable()
+		if q == nil {
+			return gomerr.Configuration("unable to create queryable for read").AddAttribute("type", p.TypeName())
+		}
+		copyKeyFields(reflect.ValueOf(q.ItemTemplate()).Elem(), pv, s.indexes, pt.name)
+
+		if ge = s.query(q); ge != nil {
+			return ge
+		}
+
+		results := q.Results()
+		if len(results) == 0 {
+			return dataerr.PersistableNotFound(p.TypeName(), nil)
+		} else if len(results) > 1 {
+			return gomerr.Conflict(p.TypeName(), "", "multiple_matches")
+		}
+
+		pt.copyFields(pv, reflect.ValueOf(results[0]).Elem())
+	}
+
+	return s.queryNested(ctx, p)
+}
+
+func copyKeyFields(dst, src reflect.Value, indexes map[string]*index, ptName string) {
+	for _, idx := range indexes {
+		for _, attribute := range idx.keyAttributes() {
+			for _, kf := range attribute.keyFieldsByPersistable[ptName] {
+				if isStaticKeyField(kf.name) {
+					continue
+				}
+				if df, sf := dst.FieldByName(kf.name), src.FieldByName(kf.name); df.IsValid() && sf.IsValid() && df.CanSet() && df.Type() == sf.Type() {
+					df.Set(sf)
+				}
+			}
+		}
+	}
+}
+
+// queryNested runs a query for each non-nil Queryable field of p after copying over the key field values the two types
+// have in common.
+func (s *store) queryNested(_ context.Context, p data.Persistable) gomerr.Gomerr {
+	pv := reflect.ValueOf(p).Elem()
+	for i := 0; i < pv.NumField(); i++ {
+		f := pv.Type().Field(i)
+		fv := pv.Field(i)
+		if !f.IsExported() || f.Anonymous || fv.Kind() != reflect.Ptr || fv.IsNil() || !f.Type.Implements(queryableType) {
+			continue
+		}
+
+		nested := fv.Interface().(data.Queryable)
+		if _, ok := s.persistableTypes[nested.TypeName()]; !ok {
+			continue
+		}
+
+		copyKeyFields(reflect.ValueOf(nested.ItemTemplate()).Elem(), pv, s.indexes, nested.TypeName())
+		if ge := s.query(nested); ge != nil {
+			return ge
+		}
+	}
+
+	return nil
+}
+
+func (s *store) Delete(_ context.Context, p data.Persistable) (ge gomerr.Gomerr) {
+	defer func() {
+		if ge != nil {
+			ge = dataerr.Store("Delete", p).Wrap(ge)
+		}
+	}()
+
+	pt, ge := s.persistableType(p)
+	if ge != nil {
+		return ge
+	}
+
+	tableKey, ge := s.tableKey(pt, reflect.ValueOf(p).Elem(), p)
+	if ge != nil {
+		return ge
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deleteLocked(tableKey, p)
+}
+
+// deleteLocked removes the item with the table key. Callers must hold the lock.
+func (s *store) deleteLocked(tableKey key, p data.Persistable) gomerr.Gomerr {
+	if _, exists := s.items[tableKey]; !exists {
+		if s.failDeleteIfNotPresent {
+			return dataerr.PersistableNotFound(p.TypeName(), tableKey)
+		}
+		return nil
+	}
+
+	delete(s.items, tableKey)
+
+	return nil
+}
+
+func (s *store) Query(_ context.Context, q data.Queryable) (ge gomerr.Gomerr) {
+	defer func() {
+		if ge != nil {
+			ge = dataerr.Store("Query", q).Wrap(ge)
+		}
+	}()
+
+	return s.query(q)
+}
+
+func (s *store) query(q data.Queryable) gomerr.Gomerr {
+	pt, ok := s.persistableTypes[q.TypeName()]
+	if !ok {
+		return gomerr.Configuration("no persistable type for " + q.TypeName())
+	}
+
+	conditions, ge := data.ConditionsOf(q)
+	if ge != nil {
+		return ge
+	}
+
+	idx, ascending, ge := s.indexFor(q, conditions)
+	if ge != nil {
+		return ge
+	}
+
+	after, ge := untokenize(q)
+	if ge != nil {
+		return ge
+	}
+
+	qv := reflect.ValueOf(q.ItemTemplate()).Elem()
+	pk := idx.pk.keyValue(qv, pt.name, s.valueSeparatorChar, 0)
+
+	var skValue string
+	var skPrefix bool
+	if idx.sk != nil {
+		skValue = idx.sk.keyValue(qv, pt.name, s.valueSeparatorChar, s.queryWildcardChar)
+		if l := len(skValue); l > 0 && (skValue[l-1] == s.queryWildcardChar || skValue[l-1] == s.valueSeparatorChar) {
+			skValue, skPrefix = skValue[:l-1], true
+		}
+	}
+
+	filters := s.filters(q, qv, idx)
+
+	s.mu.RLock()
+	now := time.Now()
+	matches := make([]*item, 0)
+	for _, i := range s.items {
+		k, inIndex := i.keys[idx.name]
+		if !inIndex || i.typeName != pt.name || k.pk != pk || i.expired(now) {
+			continue
+		}
+		if skPrefix && !strings.HasPrefix(k.sk, skValue) || !skPrefix && skValue != "" && k.sk != skValue {
+			continue
+		}
+		if !filters.match(i.value.Elem()) || !data.Satisfies(i.value.Interface(), conditions) {
+			continue
+		}
+		matches = append(matches, i)
+	}
+
+	sort.Slice(matches, func(a, b int) bool {
+		return less(matches[a], matches[b], idx.name, ascending)
+	})
+
+	if after != nil {
+		start := sort.Search(len(matches), func(n int) bool {
+			return less(&item{keys: map[string]key{idx.name: after.Index, "": after.Table}}, matches[n], idx.name, ascending)
+		})
+		matches = matches[start:]
+	}
+
+	var next *token
+	if limit := s.limit(q.MaximumPageSize()); limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+		last := matches[limit-1]
+		next = &token{Index: last.keys[idx.name], Table: last.keys[""]}
+	}
+
+	results := make([]any, len(matches))
+	for n, i := range matches {
+		results[n] = s.resolve(pt, i)
+	}
+	s.mu.RUnlock()
+
+	nt, ge := next.tokenize()
+	if ge != nil {
+		return ge
+	}
+
+	q.SetResults(results)
+	q.SetNextPageToken(nt)
+
+	return nil
+}
+
+// less orders items by their sort key in the queried index, using the table key to break ties.
+func less(i1, i2 *item, indexName string, ascending bool) bool {
+	k1, k2 := i1.keys[indexName], i2.keys[indexName]
+	if k1.sk == k2.sk {
+		t1, t2 := i1.keys[""], i2.keys[""]
+		if t1.pk != t2.pk {
+			return t1.pk < t2.pk == ascending
+		}
+		return t1.sk < t2.sk == ascending
+	}
+	return k1.sk < k2.sk == ascending
+}
+
+type filter struct {
+	fieldName string
+	value     string
+	prefix    bool
+}
+
+type filters []filter
+
+func (s *store) filters(q data.Queryable, qv reflect.Value, idx *index) filters {
+	keyFields := map[string]bool{}
+	for _, ka := range idx.keyAttributes() {
+		for _, kf := range ka.keyFieldsByPersistable[q.TypeName()] {
+			keyFields[kf.name] = true
+		}
+	}
+
+	var fs filters
+	qt := qv.Type()
+	for i := 0; i < qt.NumField(); i++ {
+		sf := qt.Field(i)
+		qfv := qv.Field(i)
+		if keyFields[sf.Name] || !sf.IsExported() || qfv.IsZero() {
+			continue
+		}
+		if qfv.Kind() == reflect.Ptr {
+			qfv = qfv.Elem()
+		}
+		if qfv.Kind() == reflect.Struct {
+			continue
+		}
+
+		f := filter{fieldName: sf.Name, value: fmtValue(qfv)}
+		if f.value == "" {
+			continue
+		}
+		if f.value[len(f.value)-1] == s.queryWildcardChar {
+			f.value, f.prefix = f.value[:len(f.value)-1], true
+		}
+		fs = append(fs, f)
+	}
+
+	return fs
+}
+
+func (fs filters) match(v reflect.Value) bool {
+	for _, f := range fs {
+		fv := v.FieldByName(f.fieldName)
+		if !fv.IsValid() {
+			return false
+		}
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				return false
+			}
+			fv = fv.Elem()
+		}
+
+		if value := fmtValue(fv); f.prefix && !strings.HasPrefix(value, f.value) || !f.prefix && value != f.value {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *store) limit(maximumPageSize int) int {
+	if maximumPageSize > 0 && s.maxLimit > 0 {
+		if maximumPageSize <= s.maxLimit {
+			return maximumPageSize
+		}
+		return s.maxLimit
+	}
+	return s.defaultLimit
+}
+
+func (s *store) persistableType(p data.Persistable) (*persistableType, gomerr.Gomerr) {
+	pt, ok := s.persistableTypes[p.TypeName()]
+	if !ok {
+		return nil, gomerr.Configuration("no persistable type for " + p.TypeName())
+	}
+	return pt, nil
+}
+
+func (s *store) tableKey(pt *persistableType, pv reflect.Value, p data.Persistable) (key, gomerr.Gomerr) {
+	table := s.indexes[""]
+
+	var k key
+	if k.pk = table.pk.keyValue(pv, pt.name, s.valueSeparatorChar, 0); k.pk == "" {
+		return k, dataerr.KeyValueNotFound(table.pk.name, keyFieldNames(table.pk.keyFieldsByPersistable[pt.name]), p)
+	}
+
+	if table.sk != nil {
+		if k.sk = table.sk.keyValue(pv, pt.name, s.valueSeparatorChar, 0); k.sk == "" {
+			return k, dataerr.KeyValueNotFound(table.sk.name, keyFieldNames(table.sk.keyFieldsByPersistable[pt.name]), p)
+		}
+	}
+
+	return k, nil
+}
+
+// indexKeys computes the key values for each index the item belongs to. As with DynamoDB's sparse indexes, an item
+// is only a member of an index if it has values for the index's keys.
+func (s *store) indexKeys(pt *persistableType, ev reflect.Value) map[string]key {
+	keys := make(map[string]key, len(s.indexes))
+	for name, idx := range s.indexes {
+		var k key
+		if k.pk = idx.pk.keyValue(ev, pt.name, s.valueSeparatorChar, 0); k.pk == "" {
+			continue
+		}
+		if idx.sk != nil {
+			if k.sk = idx.sk.keyValue(ev, pt.name, s.valueSeparatorChar, 0); k.sk == "" {
+				continue
+			}
+		}
+		keys[name] = k
+	}
+	return keys
+}
+
+// resolve returns a new persistable populated from the stored item. Callers must hold the lock.
+func (s *store) resolve(pt *persistableType, i *item) any {
+	resolved := reflect.New(pt.elemType)
+	pt.copyFields(resolved.Elem(), i.value.Elem())
+	return resolved.Interface()
+}
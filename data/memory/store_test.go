@@ -0,0 +1,333 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jt0/gomer/_test/assert"
+	"github.com/jt0/gomer/constraint"
+	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/data/dataerr"
+	. "github.com/jt0/gomer/data/dynamodb/_test"
+	"github.com/jt0/gomer/data/memory"
+	"github.com/jt0/gomer/gomerr"
+)
+
+var ctx = context.Background()
+
+func newStore(t *testing.T, config *memory.Configuration) data.Store {
+	s, ge := memory.Store(config, &CompositeKeyEntity{}, &User{}, &Product{}, &Order{}, &StaticKeyEntity{}, &VersionedEntity{}, &Session{}, &IdempotencyRecord{})
+	assert.Success(t, ge)
+	return s
+}
+
+func TestCRUD(t *testing.T) {
+	s := newStore(t, nil)
+
+	e := &CompositeKeyEntity{PartitionKey: "p1", SortKey: "s1", Data: "data", Nested: &Nested{Foo: "foo"}}
+	assert.Success(t, s.Create(ctx, e))
+	assert.ErrorType(t, s.Create(ctx, e), constraint.NotSatisfied(nil), "Duplicate create should fail")
+
+	read := &CompositeKeyEntity{PartitionKey: "p1", SortKey: "s1"}
+	assert.Success(t, s.Read(ctx, read))
+	assert.Equals(t, e, read)
+
+	read.Nested.Foo = "changed"
+	reread := &CompositeKeyEntity{PartitionKey: "p1", SortKey: "s1"}
+	assert.Success(t, s.Read(ctx, reread))
+	assert.Equals(t, "foo", reread.Nested.Foo, "Stored item should not share memory with read values")
+
+	update := &CompositeKeyEntity{Data: "updated", Status: "active"}
+	assert.Success(t, s.Update(ctx, reread, update))
+	assert.Success(t, s.Read(ctx, read))
+	assert.Equals(t, "updated", read.Data)
+	assert.Equals(t, "active", read.Status)
+
+	assert.Success(t, s.Delete(ctx, read))
+	assert.ErrorType(t, s.Read(ctx, &CompositeKeyEntity{PartitionKey: "p1", SortKey: "s1"}), dataerr.PersistableNotFound("", nil))
+}
+
+func TestUpdate_Version(t *testing.T) {
+	s := newStore(t, nil)
+
+	e := &VersionedEntity{Id: "v1", Data: "original", Version: 7}
+	assert.Success(t, s.Create(ctx, e))
+	assert.Equals(t, int64(1), e.Version, "Create should start at version 1")
+
+	first, second := &VersionedEntity{Id: "v1"}, &VersionedEntity{Id: "v1"}
+	assert.Success(t, s.Read(ctx, first))
+	assert.Success(t, s.Read(ctx, second))
+
+	assert.Success(t, s.Update(ctx, first, &VersionedEntity{Data: "first"}))
+	assert.Equals(t, int64(2), first.Version)
+
+	assert.ErrorType(t, s.Update(ctx, second, &VersionedEntity{Data: "second"}), &gomerr.ConflictError{}, "Update with a stale version should fail")
+	assert.Equals(t, int64(1), second.Version, "Version should be unchanged after a failed update")
+
+	read := &VersionedEntity{Id: "v1"}
+	assert.Success(t, s.Read(ctx, read))
+	assert.Equals(t, "first", read.Data)
+	assert.Equals(t, int64(2), read.Version)
+}
+
+func TestUpdate_Time(t *testing.T) {
+	s := newStore(t, nil)
+
+	ordered, shipped := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC), time.Date(2024, 1, 16, 9, 0, 0, 0, time.UTC)
+	o := &Order{TenantId: "t1", OrderId: "o1", UserId: "u1", OrderDate: ordered, Status: "pending"}
+	assert.Success(t, s.Create(ctx, o))
+
+	assert.Success(t, s.Update(ctx, o, &Order{OrderDate: shipped, Status: "shipped"}))
+
+	read := &Order{TenantId: "t1", OrderId: "o1"}
+	assert.Success(t, s.Read(ctx, read))
+	assert.Equals(t, "shipped", read.Status)
+	assert.Assert(t, shipped.Equal(read.OrderDate), "OrderDate should be updated, got %v", read.OrderDate)
+}
+
+func TestTtl(t *testing.T) {
+	s := newStore(t, nil)
+
+	assert.Success(t, s.Create(ctx, &Session{Id: "s1", ExpiresAt: time.Now().Add(time.Hour)}))
+	assert.Success(t, s.Read(ctx, &Session{Id: "s1"}))
+
+	assert.Success(t, s.Create(ctx, &Session{Id: "s2", ExpiresAt: time.Now().Add(-time.Minute)}))
+	assert.ErrorType(t, s.Read(ctx, &Session{Id: "s2"}), dataerr.PersistableNotFound("", nil), "Expired items should be hidden")
+	assert.Success(t, s.Create(ctx, &Session{Id: "s2"})) // expired items can be created again
+	assert.Success(t, s.Read(ctx, &Session{Id: "s2"}))
+
+	assert.Success(t, s.Create(ctx, &IdempotencyRecord{Token: "k1", Lifetime: -time.Minute}))
+	assert.ErrorType(t, s.Read(ctx, &IdempotencyRecord{Token: "k1"}), dataerr.PersistableNotFound("", nil))
+}
+
+type badTtl struct {
+	Id        string `db.keys:"pk"`
+	ExpiresAt int64  `db.ttl:""`
+}
+
+func (b *badTtl) TypeName() string             { return "badTtl" }
+func (b *badTtl) NewQueryable() data.Queryable { return nil }
+
+func TestTtl_InvalidField(t *testing.T) {
+	_, ge := memory.Store(nil, &badTtl{})
+	assert.ErrorType(t, ge, gomerr.Configuration(""))
+}
+
+func TestTransaction(t *testing.T) {
+	s := newStore(t, nil)
+	assert.Success(t, s.Create(ctx, &CompositeKeyEntity{PartitionKey: "p1", SortKey: "existing"}))
+
+	v := &VersionedEntity{Id: "v1", Data: "original"}
+	assert.Success(t, s.Create(ctx, v))
+
+	// A failed write leaves everything as it was
+	tx := s.(data.Transactional).NewTransaction()
+	assert.Success(t, tx.Create(ctx, &CompositeKeyEntity{PartitionKey: "p1", SortKey: "new"}))
+	assert.Success(t, tx.Update(ctx, v, &VersionedEntity{Data: "updated"}))
+	assert.Success(t, tx.Create(ctx, &CompositeKeyEntity{PartitionKey: "p1", SortKey: "existing"}))
+	assert.ErrorType(t, tx.Commit(ctx), constraint.NotSatisfied(nil), "Transaction with a duplicate create should fail")
+	assert.Equals(t, int64(1), v.Version, "Version should be restored after a failed commit")
+	assert.ErrorType(t, s.Read(ctx, &CompositeKeyEntity{PartitionKey: "p1", SortKey: "new"}), dataerr.PersistableNotFound("", nil))
+
+	read := &VersionedEntity{Id: "v1"}
+	assert.Success(t, s.Read(ctx, read))
+	assert.Equals(t, "original", read.Data)
+
+	// An item can only be written once per transaction
+	tx = s.(data.Transactional).NewTransaction()
+	assert.Success(t, tx.ConditionCheck(ctx, read))
+	assert.ErrorType(t, tx.Delete(ctx, &VersionedEntity{Id: "v1"}), &gomerr.ConflictError{})
+
+	assert.Success(t, tx.Create(ctx, &CompositeKeyEntity{PartitionKey: "p1", SortKey: "new"}))
+	assert.Success(t, tx.Delete(ctx, &CompositeKeyEntity{PartitionKey: "p1", SortKey: "existing"}))
+	assert.Success(t, tx.Commit(ctx))
+	assert.Success(t, s.Read(ctx, &CompositeKeyEntity{PartitionKey: "p1", SortKey: "new"}))
+	assert.ErrorType(t, s.Read(ctx, &CompositeKeyEntity{PartitionKey: "p1", SortKey: "existing"}), dataerr.PersistableNotFound("", nil))
+
+	// A condition check fails if the version has changed
+	tx = s.(data.Transactional).NewTransaction()
+	assert.Success(t, tx.ConditionCheck(ctx, &VersionedEntity{Id: "v1", Version: 7}))
+	assert.ErrorType(t, tx.Commit(ctx), &gomerr.ConflictError{})
+}
+
+func TestCreate_MissingKey(t *testing.T) {
+	s := newStore(t, nil)
+	assert.ErrorType(t, s.Create(ctx, &CompositeKeyEntity{SortKey: "s1"}), dataerr.KeyValueNotFound("", nil, nil))
+}
+
+func TestDelete_FailDeleteIfNotPresent(t *testing.T) {
+	assert.Success(t, newStore(t, nil).Delete(ctx, &CompositeKeyEntity{PartitionKey: "p", SortKey: "s"}))
+
+	s := newStore(t, &memory.Configuration{FailDeleteIfNotPresent: true})
+	assert.ErrorType(t, s.Delete(ctx, &CompositeKeyEntity{PartitionKey: "p", SortKey: "s"}), dataerr.PersistableNotFound("", nil))
+}
+
+func TestUniqueConstraint(t *testing.T) {
+	s := newStore(t, nil)
+
+	assert.Success(t, s.Create(ctx, &User{TenantId: "t1", Id: "u1", Email: "a@example.com"}))
+	assert.Success(t, s.Create(ctx, &User{TenantId: "t2", Id: "u1", Email: "a@example.com"}))
+	assert.ErrorType(t, s.Create(ctx, &User{TenantId: "t1", Id: "u2", Email: "a@example.com"}), constraint.NotSatisfied(nil))
+
+	u2 := &User{TenantId: "t1", Id: "u2", Email: "b@example.com"}
+	assert.Success(t, s.Create(ctx, u2))
+	assert.ErrorType(t, s.Update(ctx, u2, &User{Email: "a@example.com"}), constraint.NotSatisfied(nil))
+	assert.Success(t, s.Update(ctx, u2, &User{Name: "Bee"}))
+}
+
+func TestRead_ByIndex(t *testing.T) {
+	s := newStore(t, nil)
+	assert.Success(t, s.Create(ctx, &User{TenantId: "t1", Id: "u1", Email: "a@example.com", Name: "A"}))
+
+	u := &User{TenantId: "t1", Email: "a@example.com"}
+	assert.Success(t, s.Read(ctx, u))
+	assert.Equals(t, "u1", u.Id)
+	assert.Equals(t, "A", u.Name)
+}
+
+func TestQuery(t *testing.T) {
+	s := newStore(t, nil)
+	for _, id := range []string{"c", "a", "b"} {
+		assert.Success(t, s.Create(ctx, &CompositeKeyEntity{PartitionKey: "p1", SortKey: id, Status: "open"}))
+	}
+	assert.Success(t, s.Create(ctx, &CompositeKeyEntity{PartitionKey: "p1", SortKey: "d", Status: "closed"}))
+	assert.Success(t, s.Create(ctx, &CompositeKeyEntity{PartitionKey: "p2", SortKey: "a", Status: "open"}))
+
+	q := &CompositeKeyEntities{PartitionKey: "p1", Status: "open"}
+	assert.Success(t, s.Query(ctx, q))
+	assert.Equals(t, 3, len(q.Results()))
+	for i, sk := range []string{"a", "b", "c"} {
+		assert.Equals(t, sk, q.Results()[i].(*CompositeKeyEntity).SortKey)
+	}
+	assert.Nil(t, q.NextPageToken())
+
+	assert.ErrorType(t, s.Query(ctx, &CompositeKeyEntities{SortKey: "a"}), dataerr.NoIndexMatch(nil, nil))
+}
+
+func TestQuery_Pagination(t *testing.T) {
+	s := newStore(t, &memory.Configuration{MaxResultsMax: 2})
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		assert.Success(t, s.Create(ctx, &CompositeKeyEntity{PartitionKey: "p1", SortKey: id}))
+	}
+
+	var sks []string
+	q := &CompositeKeyEntities{PartitionKey: "p1"}
+	for pages := 1; ; pages++ {
+		assert.Success(t, s.Query(ctx, q))
+		for _, r := range q.Results() {
+			sks = append(sks, r.(*CompositeKeyEntity).SortKey)
+		}
+		if q.NextPageToken() == nil {
+			assert.Equals(t, 3, pages)
+			break
+		}
+	}
+	assert.Equals(t, []string{"a", "b", "c", "d", "e"}, sks)
+
+	bad := "!!"
+	q = &CompositeKeyEntities{PartitionKey: "p1"}
+	q.SetNextPageToken(&bad)
+	assert.ErrorType(t, s.Query(ctx, q), gomerr.MalformedValue("", nil))
+}
+
+func TestQuery_SecondaryIndexes(t *testing.T) {
+	s := newStore(t, nil)
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	orders := []*Order{
+		{TenantId: "t1", OrderId: "o1", UserId: "u1", OrderDate: day},
+		{TenantId: "t1", OrderId: "o2", UserId: "u1", OrderDate: day.AddDate(0, 0, 2)},
+		{TenantId: "t1", OrderId: "o3", UserId: "u2", OrderDate: day.AddDate(0, 0, 1)},
+	}
+	for _, o := range orders {
+		assert.Success(t, s.Create(ctx, o))
+	}
+
+	// lsi_1 is sorted by descending order date within a user
+	q := &Orders{TenantId: "t1", UserId: "u1"}
+	assert.Success(t, s.Query(ctx, q))
+	assert.Equals(t, 2, len(q.Results()))
+	assert.Equals(t, "o2", q.Results()[0].(*Order).OrderId)
+	assert.Equals(t, "o1", q.Results()[1].(*Order).OrderId)
+
+	products := []*Product{
+		{TenantId: "t1", Id: "p1", Sku: "s1", Category: "books", Name: "Zebra"},
+		{TenantId: "t1", Id: "p2", Sku: "s2", Category: "books", Name: "Aardvark"},
+		{TenantId: "t1", Id: "p3", Sku: "s3", Category: "games", Name: "Chess"},
+	}
+	for _, p := range products {
+		assert.Success(t, s.Create(ctx, p))
+	}
+
+	pq := &Products{TenantId: "t1", Category: "books"}
+	assert.Success(t, s.Query(ctx, pq))
+	assert.Equals(t, 2, len(pq.Results()))
+	assert.Equals(t, "Aardvark", pq.Results()[0].(*Product).Name)
+}
+
+func TestQuery_Conditions(t *testing.T) {
+	s := newStore(t, nil)
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, o := range []*Order{
+		{TenantId: "t1", OrderId: "o1", UserId: "u1", Total: 5},
+		{TenantId: "t1", OrderId: "o2", UserId: "u1", Total: 20},
+		{TenantId: "t1", OrderId: "o3", UserId: "u2", Total: 12.5},
+		{TenantId: "t1", OrderId: "o4", UserId: "u1", Total: 40},
+	} {
+		o.OrderDate = day.AddDate(0, 0, i)
+		assert.Success(t, s.Create(ctx, o))
+	}
+
+	orderIds := func(q data.Queryable) (ids []string) {
+		for _, r := range q.Results() {
+			ids = append(ids, r.(*Order).OrderId)
+		}
+		return ids
+	}
+
+	// A range of dates uses lsi_2, which is sorted by descending order date
+	q := &Orders{TenantId: "t1"}
+	q.Where("OrderDate", data.BETWEEN, day.AddDate(0, 0, 1), day.AddDate(0, 0, 2))
+	assert.Success(t, s.Query(ctx, q))
+	assert.Equals(t, []string{"o3", "o2"}, orderIds(q))
+
+	// Within a user, the date is the last field of lsi_1's sort key
+	q = &Orders{TenantId: "t1", UserId: "u1"}
+	q.Where("OrderDate", data.GT, day)
+	assert.Success(t, s.Query(ctx, q))
+	assert.Equals(t, []string{"o4", "o2"}, orderIds(q))
+
+	// Conditions on other fields filter
+	q = &Orders{TenantId: "t1", UserId: "u1"}
+	q.Where("OrderDate", data.LTE, day.AddDate(0, 0, 2))
+	q.Where("Total", data.GTE, 10)
+	assert.Success(t, s.Query(ctx, q))
+	assert.Equals(t, []string{"o2"}, orderIds(q))
+
+	q = &Orders{TenantId: "t1"}
+	q.Where("Status", data.NEQ, "shipped")
+	q.Where("UserId", data.PREFIX, "u")
+	q.Where("Total", data.LT, 15.0)
+	assert.Success(t, s.Query(ctx, q))
+	assert.Equals(t, 2, len(q.Results()))
+
+	q = &Orders{TenantId: "t1"}
+	q.Where("OrderDate", data.BETWEEN, day)
+	assert.ErrorType(t, s.Query(ctx, q), gomerr.InvalidValue("", nil, nil))
+}
+
+func TestQuery_Wildcard(t *testing.T) {
+	s := newStore(t, &memory.Configuration{QueryWildcardChar: '*'})
+	for _, sk := range []string{"apple", "apricot", "banana"} {
+		assert.Success(t, s.Create(ctx, &CompositeKeyEntity{PartitionKey: "p1", SortKey: sk}))
+	}
+
+	q := &CompositeKeyEntities{PartitionKey: "p1", SortKey: "ap*"}
+	assert.Success(t, s.Query(ctx, q))
+	assert.Equals(t, 2, len(q.Results()))
+}
+
+func TestStore_InvalidConfiguration(t *testing.T) {
+	_, ge := memory.Store(&memory.Configuration{ValueSeparatorChar: 'x'}, &CompositeKeyEntity{})
+	assert.ErrorType(t, ge, gomerr.Configuration(""))
+}
@@ -0,0 +1,146 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"reflect"
+
+	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/data/dataerr"
+	"github.com/jt0/gomer/gomerr"
+)
+
+// transaction stages writes and applies them together under the store's lock. As with the dynamodb store, an item can
+// only be written once per transaction.
+type transaction struct {
+	s         *store
+	writes    []stagedWrite
+	keys      map[key]bool
+	committed bool
+}
+
+type stagedWrite struct {
+	operation string
+	p         data.Persistable
+	pt        *persistableType
+	apply     func() gomerr.Gomerr // called with the lock held
+}
+
+// NewTransaction returns a data.Transaction whose writes are checked and applied when it's committed. If any write
+// fails, the store is left as it was.
+func (s *store) NewTransaction() data.Transaction {
+	return &transaction{s: s, keys: make(map[key]bool)}
+}
+
+func (tx *transaction) Create(_ context.Context, p data.Persistable) gomerr.Gomerr {
+	return tx.stage("Create", p, func(pt *persistableType, pv reflect.Value, tableKey key) func() gomerr.Gomerr {
+		return func() gomerr.Gomerr {
+			return tx.s.putLocked(pt, pv, tableKey, p, true, true)
+		}
+	})
+}
+
+func (tx *transaction) Update(_ context.Context, p data.Persistable, update data.Persistable) gomerr.Gomerr {
+	return tx.stage("Update", p, func(pt *persistableType, pv reflect.Value, tableKey key) func() gomerr.Gomerr {
+		validateConstraints := false
+		if update != nil {
+			validateConstraints = mergeFields(reflect.ValueOf(update).Elem(), pv, pt)
+		}
+
+		return func() gomerr.Gomerr {
+			return tx.s.putLocked(pt, pv, tableKey, p, validateConstraints, false)
+		}
+	})
+}
+
+func (tx *transaction) Delete(_ context.Context, p data.Persistable) gomerr.Gomerr {
+	return tx.stage("Delete", p, func(_ *persistableType, _ reflect.Value, tableKey key) func() gomerr.Gomerr {
+		return func() gomerr.Gomerr {
+			return tx.s.deleteLocked(tableKey, p)
+		}
+	})
+}
+
+func (tx *transaction) ConditionCheck(_ context.Context, p data.Persistable) gomerr.Gomerr {
+	return tx.stage("ConditionCheck", p, func(pt *persistableType, pv reflect.Value, tableKey key) func() gomerr.Gomerr {
+		return func() gomerr.Gomerr {
+			existing, exists := tx.s.items[tableKey]
+			if !exists {
+				return dataerr.PersistableNotFound(p.TypeName(), tableKey)
+			}
+
+			if expectedVersion, versioned := pt.versionField.Version(pv); versioned {
+				if storedVersion, _ := pt.versionField.Version(existing.value.Elem()); storedVersion != expectedVersion {
+					return gomerr.Conflict(p.TypeName(), "", "version_mismatch").WithSource(p).AddAttribute("expectedVersion", expectedVersion)
+				}
+			}
+
+			return nil
+		}
+	})
+}
+
+// Commit applies the staged writes in order. If one fails, its error is returned and the store's items and the
+// persistables' versions are restored.
+func (tx *transaction) Commit(context.Context) gomerr.Gomerr {
+	if tx.committed {
+		return gomerr.Internal("transaction already committed")
+	}
+	tx.committed = true
+
+	tx.s.mu.Lock()
+	defer tx.s.mu.Unlock()
+
+	items := maps.Clone(tx.s.items)
+	versions := make([]uint64, len(tx.writes))
+	for i, w := range tx.writes {
+		versions[i], _ = w.pt.versionField.Version(reflect.ValueOf(w.p).Elem())
+	}
+
+	for _, w := range tx.writes {
+		if ge := w.apply(); ge != nil {
+			tx.s.items = items
+			for i, rw := range tx.writes {
+				if rw.pt.versionField != "" {
+					rw.pt.versionField.SetVersion(reflect.ValueOf(rw.p).Elem(), versions[i])
+				}
+			}
+			return dataerr.Store(w.operation, w.p).Wrap(ge)
+		}
+	}
+
+	return nil
+}
+
+// stage validates p and adds the write returned by prepare.
+func (tx *transaction) stage(operation string, p data.Persistable, prepare func(*persistableType, reflect.Value, key) func() gomerr.Gomerr) (ge gomerr.Gomerr) {
+	defer func() {
+		if ge != nil {
+			ge = dataerr.Store(operation, p).Wrap(ge)
+		}
+	}()
+
+	if tx.committed {
+		return gomerr.Internal("transaction already committed")
+	}
+
+	pt, ge := tx.s.persistableType(p)
+	if ge != nil {
+		return ge
+	}
+
+	pv := reflect.ValueOf(p).Elem()
+	tableKey, ge := tx.s.tableKey(pt, pv, p)
+	if ge != nil {
+		return ge
+	}
+
+	if tx.keys[tableKey] {
+		return gomerr.Conflict(p.TypeName(), "", "item_already_in_transaction").AddAttribute("key", tableKey)
+	}
+	tx.keys[tableKey] = true
+
+	tx.writes = append(tx.writes, stagedWrite{operation: operation, p: p, pt: pt, apply: prepare(pt, pv, tableKey)})
+
+	return nil
+}
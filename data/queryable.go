@@ -1,5 +1,7 @@
 package data
 
+import "github.com/jt0/gomer/gomerr"
+
 type Queryable interface {
 	TypeName() string
 	ItemTemplate() any
@@ -14,21 +16,93 @@ type QueryTypes uint16
 
 const (
 	EQ QueryTypes = iota + 1
-	// NEQ
-	// GTE
-	// GT
-	// LTE
-	// LT
-	// BETWEEN
-	// CONTAINS
+	NEQ
+	GTE
+	GT
+	LTE
+	LT
+	BETWEEN
+	CONTAINS
+	PREFIX
 )
 
+// Condition compares the value of an item's field with one or more values. BETWEEN takes two values, the lower and
+// upper bounds (both inclusive), while the other types take one. Values have the same type as the field (or its element
+// type, for a pointer field).
+type Condition struct {
+	Field  string
+	Type   QueryTypes
+	Values []any
+}
+
+// Conditional is implemented by a Queryable that has conditions in addition to the values set on its item template. A
+// store matches items that satisfy all of them. Stores use a condition on a sort key field to select the index and to
+// narrow the range of the key read, and apply the others as filters after the items are read.
+type Conditional interface {
+	Conditions() []Condition
+}
+
+// ConditionsOf returns q's conditions, if any, after checking that each is well-formed. Possible errors:
+//
+//	gomerr.BadValue:
+//	    if a condition has an unknown type or the wrong number of values for its type
+func ConditionsOf(q Queryable) ([]Condition, gomerr.Gomerr) {
+	c, ok := q.(Conditional)
+	if !ok {
+		return nil, nil
+	}
+
+	conditions := c.Conditions()
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+
+	for _, condition := range conditions {
+		expected := 1
+		switch condition.Type {
+		case BETWEEN:
+			expected = 2
+		case EQ, NEQ, GTE, GT, LTE, LT, CONTAINS, PREFIX:
+		default:
+			return nil, gomerr.InvalidValue("Condition.Type", condition.Type, "a data.QueryTypes value")
+		}
+		if len(condition.Values) != expected {
+			return nil, gomerr.InvalidValue("Condition.Values", condition.Values, expected).AddAttribute("Field", condition.Field)
+		}
+	}
+
+	return conditions, nil
+}
+
+// Sort orders a query's results by the value of a field.
+type Sort struct {
+	Field      string
+	Descending bool
+}
+
+// Sorted is implemented by a Queryable whose results must be ordered by a particular field. Stores can only order
+// results by the field that follows the sort key fields the query provides, so one that can't select an index ordered
+// by the field fails the query rather than return the results in a different order.
+type Sorted interface {
+	SortBy() *Sort
+}
+
+// SortOf returns q's sort, or nil if it doesn't have one.
+func SortOf(q Queryable) *Sort {
+	if s, ok := q.(Sorted); ok {
+		return s.SortBy()
+	}
+	return nil
+}
+
 var MaxResultsDefault = 100
 
 type BaseQueryable struct {
 	results    []any
 	nextToken  *string
 	maxResults *int
+	conditions []Condition
+	sort       *Sort
 }
 
 func (b *BaseQueryable) Results() []any {
@@ -57,3 +131,22 @@ func (b *BaseQueryable) MaximumPageSize() int {
 func (b *BaseQueryable) SetMaximumPageSize(size int) {
 	b.maxResults = &size
 }
+
+// Where adds a condition on the named field of the queried items. Conditions combine with each other and with the
+// template's values, so an item must satisfy all of them to match.
+func (b *BaseQueryable) Where(field string, queryType QueryTypes, values ...any) {
+	b.conditions = append(b.conditions, Condition{Field: field, Type: queryType, Values: values})
+}
+
+func (b *BaseQueryable) Conditions() []Condition {
+	return b.conditions
+}
+
+// OrderBy requires the results be ordered by the named field.
+func (b *BaseQueryable) OrderBy(field string, descending bool) {
+	b.sort = &Sort{Field: field, Descending: descending}
+}
+
+func (b *BaseQueryable) SortBy() *Sort {
+	return b.sort
+}
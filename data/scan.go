@@ -0,0 +1,62 @@
+package data
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jt0/gomer/gomerr"
+)
+
+// Segments returns the segments of a scan divided into totalSegments parts, each starting at its beginning.
+func Segments(totalSegments int) []ScanSegment {
+	segments := make([]ScanSegment, max(totalSegments, 1))
+	for i := range segments {
+		segments[i] = ScanSegment{Segment: i, TotalSegments: totalSegments}
+	}
+	return segments
+}
+
+// ScanAll reads the segments in parallel, calling fn with each page of persistables and the segment updated with the
+// cursor to resume after that page. A segment is complete once fn is called with an empty cursor, so a caller that
+// records each next segment can resume an interrupted scan by passing the incomplete ones to a later call. fn is
+// called concurrently for different segments.
+//
+// If reading a page or fn returns an error, the segment stops, and the errors from all segments are returned together
+// once the others complete.
+func ScanAll(ctx context.Context, s Scanner, prototype Persistable, segments []ScanSegment, fn func(ps []Persistable, next ScanSegment) gomerr.Gomerr) gomerr.Gomerr {
+	errs := make([]gomerr.Gomerr, len(segments))
+
+	var wg sync.WaitGroup
+	for i, segment := range segments {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				ps, cursor, ge := s.Scan(ctx, prototype, segment)
+				if ge != nil {
+					errs[i] = ge.AddAttribute("segment", segment.Segment)
+					return
+				}
+
+				segment.Cursor = cursor
+				if ge = fn(ps, segment); ge != nil {
+					errs[i] = ge.AddAttribute("segment", segment.Segment)
+					return
+				}
+
+				if cursor == "" {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var failed []gomerr.Gomerr
+	for _, ge := range errs {
+		if ge != nil {
+			failed = append(failed, ge)
+		}
+	}
+	return gomerr.Batcher(failed)
+}
@@ -14,6 +14,53 @@ type Store interface {
 	Query(ctx context.Context, q Queryable) gomerr.Gomerr
 }
 
+// BatchStore is optionally implemented by a Store that can read or write many persistables in a single round trip.
+// Each persistable is processed independently: a failure for one doesn't prevent the others from being processed, and
+// the failures are returned together in a gomerr.BatchError.
+type BatchStore interface {
+	BatchCreate(ctx context.Context, ps []Persistable) gomerr.Gomerr
+	BatchRead(ctx context.Context, ps []Persistable) gomerr.Gomerr
+	BatchDelete(ctx context.Context, ps []Persistable) gomerr.Gomerr
+}
+
+// Transactional is optionally implemented by a Store that can write several persistables atomically.
+type Transactional interface {
+	NewTransaction() Transaction
+}
+
+// Transaction stages writes to one or more persistables, which may be of different types, and commits them atomically.
+// Staging a write validates it and applies any changes to the persistable as the Store's corresponding operation would,
+// but nothing is written until Commit. If Commit fails, none of the writes are applied and the persistables' versions
+// are restored. A Transaction must not be used after Commit is called.
+type Transaction interface {
+	Create(ctx context.Context, p Persistable) gomerr.Gomerr
+	Update(ctx context.Context, p Persistable, update Persistable) gomerr.Gomerr
+	Delete(ctx context.Context, p Persistable) gomerr.Gomerr
+
+	// ConditionCheck requires p's item to exist, and if p's type is versioned, to still have p's version. Nothing is
+	// written for it.
+	ConditionCheck(ctx context.Context, p Persistable) gomerr.Gomerr
+
+	Commit(ctx context.Context) gomerr.Gomerr
+}
+
+// Scanner is optionally implemented by a Store that can read every persistable of a type, e.g. for backfills,
+// migrations, and audits. A scan can be divided into segments that are read in parallel (see ScanAll), and each segment
+// is read a page at a time so that it can be resumed from the cursor returned with the last page that was processed.
+type Scanner interface {
+	// Scan reads the next page of the segment's persistables of prototype's type. It returns them along with the
+	// cursor to resume the segment after them, or "" if the segment is complete. A page may be empty even if the
+	// segment isn't complete.
+	Scan(ctx context.Context, prototype Persistable, segment ScanSegment) ([]Persistable, string, gomerr.Gomerr)
+}
+
+// ScanSegment is one of the TotalSegments parts of a scan. A TotalSegments of 0 or 1 scans everything in one segment.
+type ScanSegment struct {
+	Segment       int
+	TotalSegments int
+	Cursor        string // Where to resume the segment, or "" to start at its beginning
+}
+
 type Persistable interface {
 	TypeName() string
 	NewQueryable() Queryable
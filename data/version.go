@@ -0,0 +1,58 @@
+package data
+
+import (
+	"reflect"
+
+	"github.com/jt0/gomer/gomerr"
+)
+
+// VersionField is the name of a persistable type's `db.version` field, or "" if the type isn't versioned. Stores set
+// the field to 1 when an item is created and increment it with each update, which is conditioned on the stored item
+// still having the version that was read.
+type VersionField string
+
+// ProcessVersionTag validates a field tagged with `db.version` and sets it as the type's version field. The field must
+// be an int or uint type that's stored, and a type can have only one.
+func (f *VersionField) ProcessVersionTag(field reflect.StructField, errors []gomerr.Gomerr) []gomerr.Gomerr {
+	if *f != "" {
+		return append(errors, gomerr.Configuration("multiple `db.version` fields").AddAttributes("field", field.Name, "existing", string(*f)))
+	}
+
+	switch field.Type.Kind() {
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+	default:
+		return append(errors, gomerr.Configuration("`db.version` field must be an int or uint type").AddAttributes("field", field.Name, "type", field.Type.String()))
+	}
+
+	if field.Tag.Get("db.name") == "-" {
+		return append(errors, gomerr.Configuration("`db.version` field must be stored").AddAttribute("field", field.Name))
+	}
+
+	*f = VersionField(field.Name)
+
+	return errors
+}
+
+// Version returns the value of the version field of the persistable's struct value. Returns false if the type isn't
+// versioned.
+func (f VersionField) Version(pv reflect.Value) (uint64, bool) {
+	if f == "" {
+		return 0, false
+	}
+
+	fv := pv.FieldByName(string(f))
+	if fv.CanInt() {
+		return uint64(fv.Int()), true
+	}
+	return fv.Uint(), true
+}
+
+// SetVersion sets the version field of the persistable's struct value.
+func (f VersionField) SetVersion(pv reflect.Value, version uint64) {
+	fv := pv.FieldByName(string(f))
+	if fv.CanInt() {
+		fv.SetInt(int64(version))
+	} else {
+		fv.SetUint(version)
+	}
+}
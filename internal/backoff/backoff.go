@@ -0,0 +1,35 @@
+// Package backoff computes the delays between retries.
+package backoff
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// uncapped is the largest backoff for a delay without a maximum. Doubling stops before it can overflow.
+const uncapped = time.Duration(math.MaxInt64 / 2)
+
+// Jittered returns a random duration between zero and the exponential backoff for the attempt, starting at 1 ("full
+// jitter"). The backoff is base, doubled for each attempt after the first, up to maxDelay if it's positive. Returns zero
+// if base isn't positive.
+func Jittered(base, maxDelay time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+
+	limit := maxDelay
+	if limit <= 0 || limit > uncapped {
+		limit = uncapped
+	}
+
+	backoff := base
+	for i := 1; i < attempt && backoff < limit; i++ {
+		backoff *= 2
+	}
+	if backoff > limit {
+		backoff = limit
+	}
+
+	return time.Duration(rand.Int64N(int64(backoff) + 1))
+}
@@ -0,0 +1,30 @@
+package backoff_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jt0/gomer/_test/assert"
+	"github.com/jt0/gomer/internal/backoff"
+)
+
+func TestJittered(t *testing.T) {
+	for attempt := 1; attempt <= 5; attempt++ {
+		delay := backoff.Jittered(10*time.Millisecond, 0, attempt)
+		assert.Assert(t, delay >= 0 && delay <= 10*time.Millisecond<<(attempt-1), "attempt %d: delay %v out of range", attempt, delay)
+	}
+
+	for attempt := 1; attempt <= 5; attempt++ {
+		delay := backoff.Jittered(10*time.Millisecond, 25*time.Millisecond, attempt)
+		assert.Assert(t, delay >= 0 && delay <= 25*time.Millisecond, "attempt %d: delay %v exceeds the maximum", attempt, delay)
+	}
+
+	assert.Equals(t, time.Duration(0), backoff.Jittered(0, time.Second, 3))
+}
+
+func TestJittered_Uncapped(t *testing.T) {
+	for _, attempt := range []int{64, 100, 1000} {
+		delay := backoff.Jittered(time.Second, 0, attempt)
+		assert.Assert(t, delay >= 0, "attempt %d: delay %v should not overflow", attempt, delay)
+	}
+}
@@ -0,0 +1,50 @@
+package resource
+
+import (
+	"reflect"
+
+	"github.com/jt0/gomer/auth"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/structs"
+)
+
+// usesFieldAccess returns true if any field of the struct type (including those promoted from embedded structs) has
+// an `access` tag. Types that don't declare field permissions are left untouched by the access tool since it would
+// otherwise deny access to every field.
+func usesFieldAccess(st reflect.Type) bool {
+	for i := 0; i < st.NumField(); i++ {
+		sf := st.Field(i)
+		if _, ok := sf.Tag.Lookup("access"); ok {
+			return true
+		}
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct && usesFieldAccess(sf.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// clearDeniedFields zeroes the fields of the instance that the subject's AccessPrincipal is not granted the specified
+// permission for. If the subject has no AccessPrincipal, all access-controlled fields are cleared.
+func clearDeniedFields(rt *registeredType, sub auth.Subject, i any, permission auth.AccessPermissions) gomerr.Gomerr {
+	if rt == nil || !rt.fieldAccess || permission == auth.NoPermissions {
+		return nil
+	}
+
+	return structs.ApplyTools(i, auth.AddClearIfDeniedToContext(sub, permission), auth.DefaultAccessTool)
+}
+
+// deniedFields returns the names of the fields of the type that the subject's AccessPrincipal is not granted the
+// specified permission for.
+func deniedFields(rt *registeredType, sub auth.Subject, permission auth.AccessPermissions) (map[string]bool, gomerr.Gomerr) {
+	if rt == nil || !rt.fieldAccess {
+		return nil, nil
+	}
+
+	denied := make(map[string]bool)
+	if ge := structs.ApplyTools(rt.newInstance(sub), auth.AddCollectDeniedToContext(sub, permission, denied), auth.DefaultAccessTool); ge != nil {
+		return nil, ge
+	}
+
+	return denied, nil
+}
@@ -0,0 +1,121 @@
+package resource_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jt0/gomer/_test/assert"
+	"github.com/jt0/gomer/auth"
+	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/data/memory"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/resource"
+)
+
+type Account struct {
+	resource.BaseInstance[*Account]
+	AccountId string `db.keys:"pk" id:"+" access:"rpr-"`
+	Name      string `access:"rwrw"`
+	Plan      string `access:"rwrc"`
+	Secret    string `access:"rwr-"`
+	Notes     string `access:"rw--"`
+}
+
+var (
+	admin = auth.NewFieldAccessPrincipal("admin")
+	user  = auth.NewFieldAccessPrincipal("user")
+)
+
+func newAccountContext(t *testing.T) context.Context {
+	auth.RegisterFieldAccessPrincipals(admin, user)
+
+	store, ge := memory.Store(nil, &Account{})
+	assert.Success(t, ge)
+
+	registry := resource.NewRegistry()
+	resource.Register[*Account](registry, resource.WithStore(store))
+
+	return context.WithValue(context.Background(), resource.RegistryCtxKey, registry)
+}
+
+func newAccount(t *testing.T, ctx context.Context, subject auth.Subject, a Account) *Account {
+	i, ge := resource.NewInstance[*Account](ctx, subject)
+	assert.Success(t, ge)
+	i.AccountId, i.Name, i.Plan, i.Secret, i.Notes = a.AccountId, a.Name, a.Plan, a.Secret, a.Notes
+	return i
+}
+
+func TestFieldAccess_Create(t *testing.T) {
+	ctx := newAccountContext(t)
+	asUser := auth.NewSubject(user)
+
+	created, ge := newAccount(t, ctx, asUser, Account{AccountId: "a1", Name: "n", Plan: "p", Secret: "s", Notes: "x"}).Create(ctx)
+	assert.Success(t, ge)
+	assert.Equals(t, "a1", created.AccountId, "Provided fields should be retained")
+	assert.Equals(t, "p", created.Plan)
+	assert.Equals(t, "", created.Secret, "Non-creatable field should be cleared")
+	assert.Equals(t, "", created.Notes)
+
+	read, ge := newAccount(t, ctx, auth.NewSubject(admin), Account{AccountId: "a1"}).Read(ctx)
+	assert.Success(t, ge)
+	assert.Equals(t, "", read.Secret, "Non-creatable field should not have been stored")
+}
+
+func TestFieldAccess_UpdateAndRead(t *testing.T) {
+	ctx := newAccountContext(t)
+	asAdmin, asUser := auth.NewSubject(admin), auth.NewSubject(user)
+
+	_, ge := newAccount(t, ctx, asAdmin, Account{AccountId: "a1", Name: "n", Plan: "p", Secret: "s", Notes: "x"}).Create(ctx)
+	assert.Success(t, ge)
+
+	updated, ge := newAccount(t, ctx, asUser, Account{AccountId: "a1", Name: "n2", Plan: "p2", Secret: "s2"}).Update(ctx)
+	assert.Success(t, ge)
+	assert.Equals(t, "n2", updated.Name)
+	assert.Equals(t, "p", updated.Plan, "Non-updatable field should not change")
+	assert.Equals(t, "s", updated.Secret)
+	assert.Equals(t, "", updated.Notes, "Non-readable field should be cleared from the result")
+
+	read, ge := newAccount(t, ctx, asAdmin, Account{AccountId: "a1"}).Read(ctx)
+	assert.Success(t, ge)
+	assert.Equals(t, "x", read.Notes)
+
+	read, ge = newAccount(t, ctx, auth.NewSubject(), Account{AccountId: "a1"}).Read(ctx)
+	assert.Success(t, ge)
+	assert.Equals(t, Account{}.Name, read.Name, "A subject without an AccessPrincipal cannot read any fields")
+}
+
+func TestFieldAccess_List(t *testing.T) {
+	ctx := newAccountContext(t)
+	asAdmin := auth.NewSubject(admin)
+
+	for _, id := range []string{"a1", "a2"} {
+		_, ge := newAccount(t, ctx, asAdmin, Account{AccountId: id, Name: "n", Notes: "x"}).Create(ctx)
+		assert.Success(t, ge)
+	}
+
+	c := resource.NewCollection(newAccount(t, ctx, auth.NewSubject(user), Account{AccountId: "a1"}))
+	c, ge := c.DoAction(ctx, resource.ListAction[*Account]())
+	assert.Success(t, ge)
+	assert.Equals(t, 1, len(c.Items))
+	assert.Equals(t, "n", c.Items[0].Name)
+	assert.Equals(t, "", c.Items[0].Notes)
+}
+
+func TestFieldAccess_ListQuery(t *testing.T) {
+	auth.RegisterFieldAccessPrincipals(admin, user)
+
+	store, ge := memory.Store(nil, &Account{})
+	assert.Success(t, ge)
+
+	registry := resource.NewRegistry()
+	resource.Register[*Account](registry, resource.WithStore(store), resource.WithFilterableFields("Name", "Notes"), resource.WithSortableFields("Notes"))
+	ctx := context.WithValue(context.Background(), resource.RegistryCtxKey, registry)
+
+	asUser := resource.NewCollection(newAccount(t, ctx, auth.NewSubject(user), Account{}))
+	assert.Success(t, asUser.SetListQuery(resource.ListQuery{Filters: []resource.Filter{{Field: "Name", Type: data.EQ, Values: []string{"n"}}}}))
+	assert.ErrorType(t, asUser.SetListQuery(resource.ListQuery{Filters: []resource.Filter{{Field: "Notes", Type: data.EQ, Values: []string{"x"}}}}), &gomerr.BadValueError{}, "A field the subject can't read should not be filterable")
+	assert.ErrorType(t, asUser.SetListQuery(resource.ListQuery{Sort: []data.Sort{{Field: "Notes"}}}), &gomerr.BadValueError{}, "A field the subject can't read should not be sortable")
+
+	asAdmin := resource.NewCollection(newAccount(t, ctx, auth.NewSubject(admin), Account{}))
+	assert.Success(t, asAdmin.SetListQuery(resource.ListQuery{Filters: []resource.Filter{{Field: "Notes", Type: data.EQ, Values: []string{"x"}}}, Sort: []data.Sort{{Field: "Notes"}}}))
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/jt0/gomer/auth"
 	"github.com/jt0/gomer/data/dataerr"
 	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/limit"
 	"github.com/jt0/gomer/structs"
 )
 
@@ -46,7 +47,9 @@ func CreateAction[I Instance[I]]() Action[I] {
 	return &createAction[I]{}
 }
 
-type createAction[I Instance[I]] struct{}
+type createAction[I Instance[I]] struct {
+	limitUpdate *limitUpdate
+}
 
 func (*createAction[I]) Name() string {
 	return "resource.CreateAction"
@@ -60,12 +63,28 @@ func (*createAction[I]) FieldAccessPermissions() auth.AccessPermissions {
 	return auth.CreatePermission
 }
 
-func (*createAction[I]) Pre(ctx context.Context, i I) gomerr.Gomerr {
-	return i.PreCreate(ctx)
+func (a *createAction[I]) Pre(ctx context.Context, i I) gomerr.Gomerr {
+	if ge := i.PreCreate(ctx); ge != nil {
+		return ge
+	}
+
+	limitUpdate, ge := applyLimitAction(ctx, reserve, i, i.Subject())
+	if ge != nil {
+		return ge
+	}
+	a.limitUpdate = limitUpdate
+
+	// The limiter is saved before the instance is created so that concurrent creates can't both fit within the limit
+	return saveLimiter(ctx, limitUpdate)
 }
 
 func (*createAction[I]) Do(ctx context.Context, i I) gomerr.Gomerr {
-	return i.registeredType().store.Create(ctx, i)
+	w, ge := i.registeredType().writer(ctx)
+	if ge != nil {
+		return ge
+	}
+
+	return w.Create(ctx, i)
 }
 
 func (*createAction[I]) Retry(ctx context.Context, i I, ge gomerr.Gomerr) gomerr.Gomerr {
@@ -76,7 +95,9 @@ func (*createAction[I]) OnDoSuccess(ctx context.Context, i I) (I, gomerr.Gomerr)
 	return i, i.PostCreate(ctx)
 }
 
-func (*createAction[I]) OnDoFailure(_ context.Context, _ I, ge gomerr.Gomerr) gomerr.Gomerr {
+func (a *createAction[I]) OnDoFailure(ctx context.Context, _ I, ge gomerr.Gomerr) gomerr.Gomerr {
+	revertLimiter(ctx, a.limitUpdate)
+
 	return ge
 }
 
@@ -172,6 +193,8 @@ func (*readAction[I]) Pre(ctx context.Context, i I) gomerr.Gomerr {
 	return nil
 }
 
+func (*readAction[I]) readOnly() {}
+
 func (*readAction[I]) Do(ctx context.Context, i I) gomerr.Gomerr {
 	return i.registeredType().store.Read(ctx, i)
 }
@@ -184,8 +207,8 @@ func (*readAction[I]) OnDoSuccess(ctx context.Context, i I) (I, gomerr.Gomerr) {
 	return i, i.PostRead(ctx)
 }
 
-func (*readAction[I]) OnDoFailure(_ context.Context, _ I, ge gomerr.Gomerr) gomerr.Gomerr {
-	return ge
+func (*readAction[I]) OnDoFailure(_ context.Context, i I, ge gomerr.Gomerr) gomerr.Gomerr {
+	return convertPersistableNotFoundIfApplicable(i, ge)
 }
 
 func (a *readAction[T]) ExecuteOn(ctx context.Context, resource any) (any, gomerr.Gomerr) {
@@ -198,7 +221,8 @@ func UpdateAction[I Instance[I]]() Action[I] {
 }
 
 type updateAction[I Instance[I]] struct {
-	current I // The current state, read from store
+	current  I   // The current state, read from store
+	previous any // A copy of current made before it's updated, if events are published
 }
 
 func (*updateAction[I]) Name() string {
@@ -231,13 +255,25 @@ func (a *updateAction[I]) Pre(ctx context.Context, update I) gomerr.Gomerr {
 	}
 
 	a.current = current
+	if rt.publishesEvents() {
+		a.previous = rt.snapshot(current, update.Subject())
+	}
 
 	// Call PreUpdate hook
 	return current.PreUpdate(ctx, update)
 }
 
+func (a *updateAction[I]) before() any {
+	return a.previous
+}
+
 func (a *updateAction[I]) Do(ctx context.Context, update I) gomerr.Gomerr {
-	return update.registeredType().store.Update(ctx, a.current, update)
+	w, ge := update.registeredType().writer(ctx)
+	if ge != nil {
+		return ge
+	}
+
+	return w.Update(ctx, a.current, update)
 }
 
 func (a *updateAction[I]) Retry(ctx context.Context, update I, ge gomerr.Gomerr) gomerr.Gomerr {
@@ -248,8 +284,8 @@ func (a *updateAction[I]) OnDoSuccess(ctx context.Context, update I) (I, gomerr.
 	return a.current, a.current.PostUpdate(ctx, update)
 }
 
-func (a *updateAction[I]) OnDoFailure(_ context.Context, _ I, ge gomerr.Gomerr) gomerr.Gomerr {
-	return ge
+func (a *updateAction[I]) OnDoFailure(_ context.Context, i I, ge gomerr.Gomerr) gomerr.Gomerr {
+	return convertPersistableNotFoundIfApplicable(i, ge)
 }
 
 func (a *updateAction[T]) ExecuteOn(ctx context.Context, resource any) (any, gomerr.Gomerr) {
@@ -261,7 +297,9 @@ func DeleteAction[I Instance[I]]() Action[I] {
 	return &deleteAction[I]{}
 }
 
-type deleteAction[I Instance[I]] struct{}
+type deleteAction[I Instance[I]] struct {
+	limitUpdate *limitUpdate
+}
 
 func (*deleteAction[I]) Name() string {
 	return "resource.DeleteAction"
@@ -275,24 +313,54 @@ func (*deleteAction[I]) FieldAccessPermissions() auth.AccessPermissions {
 	return auth.NoPermissions
 }
 
-func (*deleteAction[I]) Pre(ctx context.Context, i I) gomerr.Gomerr {
-	return i.PreDelete(ctx)
+func (a *deleteAction[I]) Pre(ctx context.Context, i I) gomerr.Gomerr {
+	if ge := i.PreDelete(ctx); ge != nil {
+		return ge
+	}
+
+	// Children are handled first so that a failure leaves the parent in place rather than orphaning them
+	if ge := applyDeleteBehaviors(ctx, i.registeredType(), i, i.Subject()); ge != nil {
+		return ge
+	}
+
+	// Only an instance that's present is counted by its limiter, so check before decrementing
+	if _, ok := any(i).(limit.Limited); ok {
+		if present, ge := isPresent(ctx, i); ge != nil || !present {
+			return ge
+		}
+	}
+
+	limitUpdate, ge := applyLimitAction(ctx, release, i, i.Subject())
+	if ge != nil {
+		return ge
+	}
+	a.limitUpdate = limitUpdate
+
+	return nil
 }
 
 func (*deleteAction[I]) Do(ctx context.Context, i I) gomerr.Gomerr {
-	return i.registeredType().store.Delete(ctx, i)
+	w, ge := i.registeredType().writer(ctx)
+	if ge != nil {
+		return ge
+	}
+
+	return w.Delete(ctx, i)
 }
 
 func (*deleteAction[I]) Retry(ctx context.Context, i I, ge gomerr.Gomerr) gomerr.Gomerr {
 	return i.RetryDelete(ctx, ge)
 }
 
-func (*deleteAction[I]) OnDoSuccess(ctx context.Context, i I) (I, gomerr.Gomerr) {
+func (a *deleteAction[I]) OnDoSuccess(ctx context.Context, i I) (I, gomerr.Gomerr) {
+	// The instance has been deleted, so a limiter that can't be saved doesn't fail the action
+	logIfUnsaved(ctx, a.limitUpdate, saveLimiter(ctx, a.limitUpdate))
+
 	return i, i.PostDelete(ctx)
 }
 
-func (*deleteAction[I]) OnDoFailure(_ context.Context, _ I, ge gomerr.Gomerr) gomerr.Gomerr {
-	return ge
+func (*deleteAction[I]) OnDoFailure(_ context.Context, i I, ge gomerr.Gomerr) gomerr.Gomerr {
+	return convertPersistableNotFoundIfApplicable(i, ge)
 }
 
 func (a *deleteAction[T]) ExecuteOn(ctx context.Context, resource any) (any, gomerr.Gomerr) {
@@ -314,8 +382,10 @@ func (*listAction[I]) AppliesToCategory() Category {
 	return CollectionCategory
 }
 
+// FieldAccessPermissions returns ReadPermission since a list's prototype values act as filters, and filtering on a
+// field the subject cannot read would reveal its value.
 func (*listAction[I]) FieldAccessPermissions() auth.AccessPermissions {
-	return auth.WritePermissions
+	return auth.ReadPermission
 }
 
 func (*listAction[I]) Pre(ctx context.Context, c *Collection[I]) gomerr.Gomerr {
@@ -407,6 +477,20 @@ func (NoOpAction[T]) OnDoFailure(_ context.Context, _ T, ge gomerr.Gomerr) gomer
 	return ge
 }
 
+// isPresent reads a copy of the instance to determine whether it's in the store without changing the instance itself.
+func isPresent[I Instance[I]](ctx context.Context, i I) (bool, gomerr.Gomerr) {
+	rt := i.registeredType()
+	current := rt.snapshot(i, i.Subject()).(I)
+	if ge := rt.store.Read(ctx, current); ge != nil {
+		if errors.Is(ge, persistableNotFound) {
+			return false, nil
+		}
+		return false, ge
+	}
+
+	return true, nil
+}
+
 var persistableNotFound = &dataerr.PersistableNotFoundError{}
 
 func convertPersistableNotFoundIfApplicable[I Instance[I]](i I, ge gomerr.Gomerr) gomerr.Gomerr {
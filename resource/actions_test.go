@@ -0,0 +1,44 @@
+package resource_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jt0/gomer/_test/assert"
+	"github.com/jt0/gomer/auth"
+	"github.com/jt0/gomer/data/memory"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/resource"
+)
+
+type Lease struct {
+	resource.BaseInstance[*Lease]
+	LeaseId   string    `db.keys:"pk,sk='LEASE'" id:"+"`
+	ExpiresAt time.Time `db.ttl:""`
+}
+
+func TestReadAction_NotFound(t *testing.T) {
+	store, ge := memory.Store(nil, &Lease{})
+	assert.Success(t, ge)
+
+	registry := resource.NewRegistry()
+	resource.Register[*Lease](registry, resource.WithStore(store))
+	ctx := context.WithValue(context.Background(), resource.RegistryCtxKey, registry)
+
+	lease := func(id string, expiresAt time.Time) *Lease {
+		l, ge := resource.NewInstance[*Lease](ctx, auth.NewSubject())
+		assert.Success(t, ge)
+		l.LeaseId, l.ExpiresAt = id, expiresAt
+		return l
+	}
+
+	_, ge = lease("l1", time.Now().Add(-time.Minute)).Create(ctx)
+	assert.Success(t, ge)
+
+	_, ge = lease("l1", time.Time{}).Read(ctx)
+	assert.ErrorType(t, ge, &gomerr.NotFoundError{}, "Expired items should be reported as not found")
+
+	_, ge = lease("l2", time.Time{}).Read(ctx)
+	assert.ErrorType(t, ge, &gomerr.NotFoundError{})
+}
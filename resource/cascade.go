@@ -0,0 +1,185 @@
+package resource
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/jt0/gomer/auth"
+	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/structs"
+)
+
+// DeleteBehavior determines what happens to a child type's instances when their parent is deleted.
+type DeleteBehavior int
+
+const (
+	// Orphan leaves the child instances in place. This is the default.
+	Orphan DeleteBehavior = iota
+	// Restrict prevents the parent from being deleted while any child instances exist.
+	Restrict
+	// Cascade deletes the child instances (using their DeleteAction) before the parent is deleted.
+	Cascade
+)
+
+// WithDeleteBehavior specifies how instances of a child type are handled when their parent is deleted. It has no effect
+// unless combined with WithParent.
+func WithDeleteBehavior(behavior DeleteBehavior) Option {
+	return func(rt *registeredType) {
+		rt.deleteBehavior = behavior
+	}
+}
+
+// applyDeleteBehaviors checks the restricted child types of the parent and of its cascading descendants, and then
+// deletes the instances of the cascading ones. All restrictions are checked before anything is deleted. Deletion
+// failures don't stop the remaining children from being deleted, and are returned together.
+func applyDeleteBehaviors(ctx context.Context, rt *registeredType, parent any, sub auth.Subject) gomerr.Gomerr {
+	if rt == nil {
+		return nil
+	}
+
+	if ge := checkRestrictions(ctx, rt, parent, sub); ge != nil {
+		return ge
+	}
+
+	var errors []gomerr.Gomerr
+	for _, child := range rt.children {
+		if child.deleteBehavior != Cascade {
+			continue
+		}
+
+		q, ge := childQueryable(rt, child, parent, sub)
+		if ge != nil {
+			return ge
+		}
+
+		for {
+			if ge = child.store.Query(ctx, q); ge != nil {
+				errors = append(errors, ge)
+				break
+			}
+
+			for _, result := range q.Results() {
+				if ge = child.deleteInstance(ctx, result); ge != nil {
+					errors = append(errors, ge)
+				}
+			}
+
+			if q.NextPageToken() == nil {
+				break
+			}
+		}
+	}
+
+	return gomerr.Batcher(errors)
+}
+
+// checkRestrictions returns a ConflictError if the parent has instances of a restricted child type, or if any of its
+// cascading children do (recursively).
+func checkRestrictions(ctx context.Context, rt *registeredType, parent any, sub auth.Subject) gomerr.Gomerr {
+	for _, child := range rt.children {
+		if child.deleteBehavior != Restrict {
+			continue
+		}
+
+		q, ge := childQueryable(rt, child, parent, sub)
+		if ge != nil {
+			return ge
+		}
+
+		// A page can be empty (e.g. if the store filtered out its items) while later ones aren't
+		for {
+			if ge = child.store.Query(ctx, q); ge != nil {
+				return ge
+			}
+
+			if len(q.Results()) > 0 {
+				id, _ := Id(reflect.ValueOf(parent).Elem())
+				return gomerr.Conflict(rt.instanceName, id, "has_children").AddAttribute("child", child.instanceName)
+			}
+
+			if q.NextPageToken() == nil {
+				break
+			}
+		}
+	}
+
+	for _, child := range rt.children {
+		if child.deleteBehavior != Cascade || !hasRestrictions(child) {
+			continue
+		}
+
+		q, ge := childQueryable(rt, child, parent, sub)
+		if ge != nil {
+			return ge
+		}
+
+		for {
+			if ge = child.store.Query(ctx, q); ge != nil {
+				return ge
+			}
+
+			for _, result := range q.Results() {
+				if ge = checkRestrictions(ctx, child, result, sub); ge != nil {
+					return ge
+				}
+			}
+
+			if q.NextPageToken() == nil {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// hasRestrictions returns whether the type has a restricted child type, or a cascading one that does (recursively).
+func hasRestrictions(rt *registeredType) bool {
+	for _, child := range rt.children {
+		if child.deleteBehavior == Restrict || child.deleteBehavior == Cascade && hasRestrictions(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// childQueryable returns a queryable for the child type with the id fields of the parent and its ancestors copied
+// from the parent instance.
+func childQueryable(rt, child *registeredType, parent any, sub auth.Subject) (data.Queryable, gomerr.Gomerr) {
+	proto := child.newInstance(sub)
+
+	pv := reflect.ValueOf(parent).Elem()
+	cv := reflect.ValueOf(proto).Elem()
+	for ancestor := rt; ancestor != nil; ancestor = ancestor.parent {
+		idFields, ge := idFieldNames(ancestor.instanceType)
+		if ge != nil {
+			return nil, ge
+		}
+
+		for _, name := range idFields {
+			pf, cf := pv.FieldByName(name), cv.FieldByName(name)
+			if pf.IsValid() && cf.IsValid() && cf.CanSet() && pf.Type() == cf.Type() {
+				cf.Set(pf)
+			}
+		}
+	}
+
+	return child.newCollection(proto).(data.Queryable), nil
+}
+
+func idFieldNames(instanceType reflect.Type) ([]string, gomerr.Gomerr) {
+	typeName := instanceType.Elem().String()
+	if _, ok := structIdFields[typeName]; !ok {
+		if ge := structs.Preprocess(reflect.New(instanceType.Elem()).Interface(), DefaultIdFieldTool); ge != nil {
+			return nil, ge
+		}
+	}
+
+	idfa, ok := structIdFields[typeName]
+	if !ok {
+		return nil, gomerr.Configuration("no field marked as an 'id' for type: " + typeName)
+	}
+
+	return idfa.idFields, nil
+}
@@ -0,0 +1,188 @@
+package resource_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jt0/gomer/_test/assert"
+	"github.com/jt0/gomer/auth"
+	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/data/dataerr"
+	"github.com/jt0/gomer/data/memory"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/resource"
+)
+
+type Folder struct {
+	resource.BaseInstance[*Folder]
+	FolderId string `db.keys:"pk,sk='FOLDER'" id:"+"`
+}
+
+type Document struct {
+	resource.BaseInstance[*Document]
+	FolderId   string `db.keys:"pk"`
+	DocumentId string `db.keys:"sk.0" id:"+"`
+}
+
+type Page struct {
+	resource.BaseInstance[*Page]
+	FolderId   string `db.keys:"pk"`
+	DocumentId string `db.keys:"sk.0"`
+	PageId     string `db.keys:"sk.1" id:"+"`
+}
+
+func newFolderContext(t *testing.T, documents, pages resource.DeleteBehavior) context.Context {
+	return newFolderContextWithDocumentStore(t, documents, pages, func(s data.Store) data.Store { return s })
+}
+
+func newFolderContextWithDocumentStore(t *testing.T, documents, pages resource.DeleteBehavior, documentStore func(data.Store) data.Store) context.Context {
+	// A small page size ensures cascading deletes work through multiple pages
+	store, ge := memory.Store(&memory.Configuration{MaxResultsMax: 2}, &Folder{}, &Document{}, &Page{})
+	assert.Success(t, ge)
+
+	registry := resource.NewRegistry()
+	resource.Register[*Folder](registry, resource.WithStore(store))
+	resource.Register[*Document](registry, resource.WithStore(documentStore(store)), resource.WithParent[*Folder](), resource.WithDeleteBehavior(documents))
+	resource.Register[*Page](registry, resource.WithStore(store), resource.WithParent[*Document](), resource.WithDeleteBehavior(pages))
+	ctx := context.WithValue(context.Background(), resource.RegistryCtxKey, registry)
+
+	folder := newInstance[*Folder](t, ctx)
+	folder.FolderId = "f1"
+	_, ge = folder.Create(ctx)
+	assert.Success(t, ge)
+
+	for _, documentId := range []string{"d1", "d2", "d3"} {
+		document := newInstance[*Document](t, ctx)
+		document.FolderId, document.DocumentId = "f1", documentId
+		_, ge = document.Create(ctx)
+		assert.Success(t, ge)
+
+		for _, pageId := range []string{"p1", "p2", "p3"} {
+			page := newInstance[*Page](t, ctx)
+			page.FolderId, page.DocumentId, page.PageId = "f1", documentId, pageId
+			_, ge = page.Create(ctx)
+			assert.Success(t, ge)
+		}
+	}
+
+	return ctx
+}
+
+func newInstance[I resource.Instance[I]](t *testing.T, ctx context.Context) I {
+	i, ge := resource.NewInstance[I](ctx, auth.NewSubject())
+	assert.Success(t, ge)
+	return i
+}
+
+func countPages(t *testing.T, ctx context.Context, documentId string) int {
+	// Read each page rather than listing them since list results are limited by the store's page size
+	count := 0
+	for _, pageId := range []string{"p1", "p2", "p3"} {
+		page := newInstance[*Page](t, ctx)
+		page.FolderId, page.DocumentId, page.PageId = "f1", documentId, pageId
+		if _, ge := page.Read(ctx); ge == nil {
+			count++
+		}
+	}
+	return count
+}
+
+func deleteFolder(t *testing.T, ctx context.Context) gomerr.Gomerr {
+	folder := newInstance[*Folder](t, ctx)
+	folder.FolderId = "f1"
+	_, ge := folder.Delete(ctx)
+	return ge
+}
+
+func TestDeleteBehavior_Orphan(t *testing.T) {
+	ctx := newFolderContext(t, resource.Orphan, resource.Cascade)
+
+	assert.Success(t, deleteFolder(t, ctx))
+	assert.Equals(t, 3, countPages(t, ctx, "d1"))
+}
+
+func TestDeleteBehavior_Cascade(t *testing.T) {
+	ctx := newFolderContext(t, resource.Cascade, resource.Cascade)
+
+	assert.Success(t, deleteFolder(t, ctx))
+	for _, documentId := range []string{"d1", "d2", "d3"} {
+		document := newInstance[*Document](t, ctx)
+		document.FolderId, document.DocumentId = "f1", documentId
+		_, ge := document.Read(ctx)
+		assert.ErrorType(t, ge, dataerr.PersistableNotFound("", nil))
+		assert.Equals(t, 0, countPages(t, ctx, documentId))
+	}
+}
+
+func TestDeleteBehavior_Restrict(t *testing.T) {
+	ctx := newFolderContext(t, resource.Cascade, resource.Restrict)
+
+	ge := deleteFolder(t, ctx)
+	assert.ErrorType(t, ge, &gomerr.ConflictError{})
+
+	folder := newInstance[*Folder](t, ctx)
+	folder.FolderId = "f1"
+	_, ge = folder.Read(ctx)
+	assert.Success(t, ge)
+	assert.Equals(t, 3, countPages(t, ctx, "d2"))
+}
+
+// emptyFirstPageStore returns an empty first page of query results with a next page token, as a store that filters the
+// items it reads (e.g. with a DynamoDB filter expression) can.
+type emptyFirstPageStore struct {
+	data.Store
+}
+
+const emptyPageToken = "empty"
+
+func (s emptyFirstPageStore) Query(ctx context.Context, q data.Queryable) gomerr.Gomerr {
+	token := q.NextPageToken()
+	if token == nil {
+		q.SetResults(nil)
+		nextToken := emptyPageToken
+		q.SetNextPageToken(&nextToken)
+		return nil
+	}
+	if *token == emptyPageToken {
+		q.SetNextPageToken(nil)
+	}
+
+	return s.Store.Query(ctx, q)
+}
+
+func TestDeleteBehavior_RestrictEmptyPage(t *testing.T) {
+	ctx := newFolderContextWithDocumentStore(t, resource.Restrict, resource.Cascade, func(s data.Store) data.Store { return emptyFirstPageStore{s} })
+
+	ge := deleteFolder(t, ctx)
+	assert.ErrorType(t, ge, &gomerr.ConflictError{}, "Children on a later page should prevent the delete")
+
+	folder := newInstance[*Folder](t, ctx)
+	folder.FolderId = "f1"
+	_, ge = folder.Read(ctx)
+	assert.Success(t, ge)
+}
+
+func TestDeleteBehavior_RestrictedDescendant(t *testing.T) {
+	ctx := newFolderContext(t, resource.Cascade, resource.Restrict)
+
+	// Only d3 has pages left, so deleting d1 and d2 before finding them would leave the folder partially deleted
+	for _, documentId := range []string{"d1", "d2"} {
+		for _, pageId := range []string{"p1", "p2", "p3"} {
+			page := newInstance[*Page](t, ctx)
+			page.FolderId, page.DocumentId, page.PageId = "f1", documentId, pageId
+			_, ge := page.Delete(ctx)
+			assert.Success(t, ge)
+		}
+	}
+
+	ge := deleteFolder(t, ctx)
+	assert.ErrorType(t, ge, &gomerr.ConflictError{})
+	assert.Nil(t, gomerr.ErrorAs[*gomerr.BatchError](ge), "Restrictions should be found before anything is deleted")
+
+	for _, documentId := range []string{"d1", "d2", "d3"} {
+		document := newInstance[*Document](t, ctx)
+		document.FolderId, document.DocumentId = "f1", documentId
+		_, ge = document.Read(ctx)
+		assert.Success(t, ge)
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/jt0/gomer/auth"
 	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/data/dataerr"
 	"github.com/jt0/gomer/data/dynamodb"
 	"github.com/jt0/gomer/gomerr"
 )
@@ -23,6 +24,10 @@ type Collection[I Instance[I]] struct {
 	NextToken  *string `in:"query.next_token" out:"+"`
 	MaxResults int     `in:"query.max_results" validate:"intbetween(1,100)"` // TODO:  "intbetween(1,$.MaximumPageSize())
 
+	listQuery  *ListQuery
+	conditions []data.Condition // from the ListQuery
+	sort       *data.Sort       // from the ListQuery
+
 	// TODO: move to `data` package
 	consistencyType dynamodb.ConsistencyType
 }
@@ -40,26 +45,41 @@ func (c *Collection[I]) initialize(rt *registeredType, sub auth.Subject) {
 }
 
 func (c *Collection[I]) DoAction(ctx context.Context, action Action[*Collection[I]]) (*Collection[I], gomerr.Gomerr) {
-	if ge := action.Pre(ctx, c); ge != nil {
+	// The prototype's values are used to filter the results, so drop any the subject isn't permitted to use
+	if ge := clearDeniedFields(c.registeredType(), c.Subject(), c.proto, action.FieldAccessPermissions()); ge != nil {
 		return nil, ge
 	}
 
-	ge := action.Do(ctx, c)
-	for i := 0; i < 2 && ge != nil; i++ { // up to 2 retries
-		if ge = action.Retry(ctx, c, ge); ge != nil {
-			break
-		}
-		ge = action.Do(ctx, c)
+	if ge := action.Pre(ctx, c); ge != nil {
+		return nil, ge
 	}
-	if ge != nil {
+
+	if ge := do(ctx, action, c, c.registeredType().retryPolicyFor(action.Name())); ge != nil {
 		return nil, action.OnDoFailure(ctx, c, ge)
 	}
 
-	return action.OnDoSuccess(ctx, c)
+	result, ge := action.OnDoSuccess(ctx, c)
+	if ge != nil || result == nil {
+		return result, ge
+	}
+
+	for _, item := range result.Items {
+		if ge = clearDeniedFields(c.registeredType(), c.Subject(), item, auth.ReadPermission); ge != nil {
+			return nil, ge
+		}
+	}
+
+	return result, nil
 }
 
+// Query runs the collection's query. If it has a ListQuery that the store has no index to satisfy, it fails with a
+// gomerr.BadValue.
 func (c *Collection[I]) Query(ctx context.Context) gomerr.Gomerr {
-	return c.proto.registeredType().store.Query(ctx, c)
+	ge := c.proto.registeredType().store.Query(ctx, c)
+	if ge != nil && c.listQuery != nil && gomerr.ErrorAs[*dataerr.NoIndexMatchError](ge) != nil {
+		return gomerr.BadValue(gomerr.GenericBadValueType, "ListQuery", *c.listQuery).WithReason("no index supports the filter and sort").Wrap(ge)
+	}
+	return ge
 }
 
 func (c *Collection[I]) TypeName() string {
@@ -0,0 +1,235 @@
+package resource
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/jt0/gomer/auth"
+	"github.com/jt0/gomer/gomerr"
+)
+
+// Event describes a successful action on an instance. Events are published for every instance action other than
+// ReadAction; list actions don't publish events.
+type Event struct {
+	TypeName   string
+	Id         string
+	ActionName string
+	Subject    auth.Subject
+	Time       time.Time
+
+	// Before is a copy of the instance's state prior to an UpdateAction, and nil for other actions.
+	Before any
+
+	// After is a copy of the instance's state as returned by the action. Copies are shallow, so sinks must not modify
+	// any maps, slices, or pointers they contain.
+	After any
+}
+
+// EventSink receives the events published by a Registry.
+type EventSink interface {
+	Publish(context.Context, Event) gomerr.Gomerr
+}
+
+// EventHandler processes a published event.
+type EventHandler func(context.Context, Event) gomerr.Gomerr
+
+// AddEventSinks adds sinks that receive an Event after each successful instance action on the registry's types. Sinks
+// are called in the order they were added. Since the action has already been applied, errors returned by the sinks
+// don't fail it. They're passed to the registry's event error handler instead (see SetEventErrorHandler).
+func (r *Registry) AddEventSinks(sinks ...EventSink) {
+	r.sinks = append(r.sinks, sinks...)
+}
+
+// SetEventErrorHandler sets the function that's called with the errors the registry's sinks return for an event. By
+// default, they're logged with slog.
+func (r *Registry) SetEventErrorHandler(handler func(context.Context, Event, gomerr.Gomerr)) {
+	r.onEventError = handler
+}
+
+func (r *Registry) publish(ctx context.Context, event Event) {
+	var errors []gomerr.Gomerr
+	for _, sink := range r.sinks {
+		if ge := sink.Publish(ctx, event); ge != nil {
+			errors = append(errors, ge)
+		}
+	}
+
+	ge := gomerr.Batcher(errors)
+	if ge == nil {
+		return
+	}
+
+	if r.onEventError != nil {
+		r.onEventError(ctx, event, ge)
+	} else {
+		slog.ErrorContext(ctx, "failed to publish event", "type", event.TypeName, "id", event.Id, "action", event.ActionName, "error", ge)
+	}
+}
+
+// SyncSink calls its handlers in the publishing goroutine in the order they were added. All handlers are called even
+// if some fail.
+type SyncSink struct {
+	handlers []EventHandler
+}
+
+func NewSyncSink(handlers ...EventHandler) *SyncSink {
+	return &SyncSink{handlers: handlers}
+}
+
+// Subscribe adds handlers to the sink.
+func (s *SyncSink) Subscribe(handlers ...EventHandler) {
+	s.handlers = append(s.handlers, handlers...)
+}
+
+func (s *SyncSink) Publish(ctx context.Context, event Event) gomerr.Gomerr {
+	var errors []gomerr.Gomerr
+	for _, handler := range s.handlers {
+		if ge := handler(ctx, event); ge != nil {
+			errors = append(errors, ge)
+		}
+	}
+
+	return gomerr.Batcher(errors)
+}
+
+// AsyncSink buffers events and passes them to another sink from a separate goroutine. Publish blocks while the buffer
+// is full unless the context is done. Errors from the underlying sink are passed to the OnError function, if provided.
+type AsyncSink struct {
+	OnError func(Event, gomerr.Gomerr)
+
+	sink   EventSink
+	events chan asyncEvent
+	done   chan struct{}
+	mu     sync.RWMutex
+	closed bool
+}
+
+type asyncEvent struct {
+	ctx   context.Context
+	event Event
+}
+
+// NewAsyncSink returns an AsyncSink that buffers up to bufferSize events before they are passed to the sink.
+func NewAsyncSink(sink EventSink, bufferSize int) *AsyncSink {
+	s := &AsyncSink{
+		sink:   sink,
+		events: make(chan asyncEvent, bufferSize),
+		done:   make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s
+}
+
+func (s *AsyncSink) run() {
+	defer close(s.done)
+
+	for ae := range s.events {
+		if ge := s.sink.Publish(ae.ctx, ae.event); ge != nil && s.OnError != nil {
+			s.OnError(ae.event, ge)
+		}
+	}
+}
+
+// Publish queues the event. The context's values are kept, but it's detached from the publisher's cancellation since
+// the event may be delivered after the publisher's request completes.
+func (s *AsyncSink) Publish(ctx context.Context, event Event) gomerr.Gomerr {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return gomerr.Internal("event sink is closed").AddAttribute("Event", event)
+	}
+
+	select {
+	case s.events <- asyncEvent{context.WithoutCancel(ctx), event}:
+		return nil
+	case <-ctx.Done():
+		return gomerr.Unprocessable("event not published", event).Wrap(ctx.Err())
+	}
+}
+
+// Close stops accepting events and waits until those already queued have been passed to the underlying sink or the
+// context is done.
+func (s *AsyncSink) Close(ctx context.Context) gomerr.Gomerr {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return gomerr.Unprocessable("event sink not drained", len(s.events)).Wrap(ctx.Err())
+	}
+}
+
+// readOnlyAction is implemented by actions that don't publish events.
+type readOnlyAction interface {
+	readOnly()
+}
+
+// beforeSnapshotter is implemented by actions that capture the instance's state before it's modified.
+type beforeSnapshotter interface {
+	before() any
+}
+
+// publishEvent publishes an event for the action's result if the registry has any sinks.
+func publishEvent(ctx context.Context, rt *registeredType, action AnyAction, sub auth.Subject, result any) {
+	if !rt.publishesEvents() {
+		return
+	}
+	if _, ok := action.(readOnlyAction); ok {
+		return
+	}
+
+	event := Event{
+		TypeName:   rt.instanceName,
+		ActionName: action.Name(),
+		Subject:    sub,
+		Time:       time.Now(),
+		After:      rt.snapshot(result, sub),
+	}
+	if identifiable, ok := event.After.(interface{ Id() string }); ok {
+		event.Id = identifiable.Id()
+	}
+	if bs, ok := action.(beforeSnapshotter); ok {
+		event.Before = bs.before()
+	}
+
+	publish := func(ctx context.Context) {
+		rt.registry.publish(ctx, event)
+	}
+	if deferUntilCommitted(ctx, publish) {
+		return
+	}
+
+	publish(ctx)
+}
+
+func (m *registeredType) publishesEvents() bool {
+	return m != nil && m.registry != nil && len(m.registry.sinks) > 0
+}
+
+// snapshot returns a shallow copy of the instance.
+func (m *registeredType) snapshot(instance any, sub auth.Subject) any {
+	iv := reflect.ValueOf(instance)
+	if iv.Kind() != reflect.Pointer || iv.IsNil() || iv.Type() != m.instanceType {
+		return nil
+	}
+
+	c := m.newInstance(sub)
+	reflect.ValueOf(c).Elem().Set(iv.Elem())
+	c.(interface {
+		initialize(*registeredType, auth.Subject)
+	}).initialize(m, sub) // the copied BaseResource refers to the original instance
+
+	return c
+}
@@ -0,0 +1,127 @@
+package resource_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jt0/gomer/_test/assert"
+	"github.com/jt0/gomer/auth"
+	"github.com/jt0/gomer/data/memory"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/resource"
+)
+
+type Note struct {
+	resource.BaseInstance[*Note]
+	NoteId string `db.keys:"pk,sk='NOTE'" id:"+"`
+	Text   string
+}
+
+func newNoteContext(t *testing.T, sinks ...resource.EventSink) context.Context {
+	store, ge := memory.Store(nil, &Note{})
+	assert.Success(t, ge)
+
+	registry := resource.NewRegistry()
+	registry.AddEventSinks(sinks...)
+	resource.Register[*Note](registry, resource.WithStore(store))
+	return context.WithValue(context.Background(), resource.RegistryCtxKey, registry)
+}
+
+func note(t *testing.T, ctx context.Context, id, text string) *Note {
+	n, ge := resource.NewInstance[*Note](ctx, auth.NewSubject())
+	assert.Success(t, ge)
+	n.NoteId, n.Text = id, text
+	return n
+}
+
+func TestEvents_SyncSink(t *testing.T) {
+	var events []resource.Event
+	ctx := newNoteContext(t, resource.NewSyncSink(func(_ context.Context, e resource.Event) gomerr.Gomerr {
+		events = append(events, e)
+		return nil
+	}))
+
+	_, ge := note(t, ctx, "n1", "draft").Create(ctx)
+	assert.Success(t, ge)
+	_, ge = note(t, ctx, "n1", "").Read(ctx)
+	assert.Success(t, ge)
+	_, ge = note(t, ctx, "n1", "final").Update(ctx)
+	assert.Success(t, ge)
+	_, ge = note(t, ctx, "n1", "").Delete(ctx)
+	assert.Success(t, ge)
+
+	assert.Equals(t, 3, len(events), "Reads should not publish events")
+
+	assert.Equals(t, "resource.CreateAction", events[0].ActionName)
+	assert.Equals(t, "Note", events[0].TypeName)
+	assert.Equals(t, "n1", events[0].Id)
+	assert.Equals(t, nil, events[0].Before)
+	assert.Equals(t, "draft", events[0].After.(*Note).Text)
+
+	assert.Equals(t, "resource.UpdateAction", events[1].ActionName)
+	assert.Equals(t, "draft", events[1].Before.(*Note).Text)
+	assert.Equals(t, "final", events[1].After.(*Note).Text)
+
+	assert.Equals(t, "resource.DeleteAction", events[2].ActionName)
+	assert.Equals(t, "n1", events[2].Id)
+}
+
+func TestEvents_SinkError(t *testing.T) {
+	ctx := newNoteContext(t, resource.NewSyncSink(func(context.Context, resource.Event) gomerr.Gomerr {
+		return gomerr.Internal("indexing failed")
+	}))
+
+	var failed []resource.Event
+	var errors []gomerr.Gomerr
+	registry := ctx.Value(resource.RegistryCtxKey).(*resource.Registry)
+	registry.SetEventErrorHandler(func(_ context.Context, e resource.Event, ge gomerr.Gomerr) {
+		failed = append(failed, e)
+		errors = append(errors, ge)
+	})
+
+	n, ge := note(t, ctx, "n1", "draft").Create(ctx)
+	assert.Success(t, ge)
+	assert.Equals(t, "n1", n.NoteId)
+	assert.Equals(t, 1, len(failed))
+	assert.Equals(t, "n1", failed[0].Id)
+	assert.ErrorType(t, errors[0], &gomerr.InternalError{})
+
+	_, ge = note(t, ctx, "n1", "").Read(ctx)
+	assert.Success(t, ge)
+}
+
+func TestEvents_AsyncSink(t *testing.T) {
+	var ids []string
+	var failed []resource.Event
+	async := resource.NewAsyncSink(resource.NewSyncSink(func(_ context.Context, e resource.Event) gomerr.Gomerr {
+		ids = append(ids, e.Id)
+		if e.Id == "n2" {
+			return gomerr.Internal("notification failed")
+		}
+		return nil
+	}), 1)
+	async.OnError = func(e resource.Event, _ gomerr.Gomerr) {
+		failed = append(failed, e)
+	}
+	ctx := newNoteContext(t, async)
+
+	for _, id := range []string{"n1", "n2", "n3"} {
+		_, ge := note(t, ctx, id, "draft").Create(ctx)
+		assert.Success(t, ge)
+	}
+
+	assert.Success(t, async.Close(context.Background()))
+	assert.Equals(t, []string{"n1", "n2", "n3"}, ids)
+	assert.Equals(t, 1, len(failed))
+	assert.Equals(t, "n2", failed[0].Id)
+
+	var publishErrors []gomerr.Gomerr
+	ctx.Value(resource.RegistryCtxKey).(*resource.Registry).SetEventErrorHandler(func(_ context.Context, _ resource.Event, ge gomerr.Gomerr) {
+		publishErrors = append(publishErrors, ge)
+	})
+
+	_, ge := note(t, ctx, "n4", "draft").Create(ctx)
+	assert.Success(t, ge)
+	assert.Equals(t, 1, len(publishErrors))
+	assert.ErrorType(t, publishErrors[0], &gomerr.InternalError{}, "Publishing to a closed sink should fail")
+}
@@ -2,8 +2,10 @@ package resource
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
+	"reflect"
 
+	"github.com/jt0/gomer/auth"
 	"github.com/jt0/gomer/data"
 	"github.com/jt0/gomer/gomerr"
 	"github.com/jt0/gomer/limit"
@@ -11,17 +13,25 @@ import (
 
 type limitAction func(limit.Limiter, limit.Limited) gomerr.Gomerr
 
-func checkAndIncrement(limiter limit.Limiter, limited limit.Limited) gomerr.Gomerr {
+// checkLimit returns a limit.ExceededError if incrementing the limiter's current amount would exceed its maximum.
+func checkLimit(limiter limit.Limiter, limited limit.Limited) gomerr.Gomerr {
 	current := limiter.Current(limited)
 	maximum := limiter.Maximum(limited)
 	newAmount := current.Increment(limited.LimitAmount())
 
-	if newAmount.Equals(current) {
-		return nil
+	if !newAmount.Equals(current) && newAmount.Exceeds(maximum) {
+		return limit.Exceeded(limiter, limited, maximum, current, newAmount)
 	}
 
-	if newAmount.Exceeds(maximum) {
-		return limit.Exceeded(limiter, limited, maximum, current, newAmount)
+	return nil
+}
+
+func increment(limiter limit.Limiter, limited limit.Limited) gomerr.Gomerr {
+	current := limiter.Current(limited)
+	newAmount := current.Increment(limited.LimitAmount())
+
+	if newAmount.Equals(current) {
+		return nil
 	}
 
 	limiter.SetCurrent(limited, newAmount)
@@ -29,34 +39,59 @@ func checkAndIncrement(limiter limit.Limiter, limited limit.Limited) gomerr.Gome
 	return nil
 }
 
+// decrement lowers the limiter's current amount, but not below zero.
 func decrement(limiter limit.Limiter, limited limit.Limited) gomerr.Gomerr {
 	current := limiter.Current(limited)
 	newAmount := current.Decrement(limited.LimitAmount())
+	if zero := newAmount.Zero(); zero.Exceeds(newAmount) {
+		newAmount = zero
+	}
 
 	if newAmount.Equals(current) {
 		return nil
 	}
 
-	// This could go below zero, though there may be valid application cases to support this. For now, no extra checks to verify.
 	limiter.SetCurrent(limited, newAmount)
 
 	return nil
 }
 
-// resourceLike is an internal interface that abstracts over generic Resource types.
-type resourceLike interface {
-	Metadata() *registeredType
-	Subject(context.Context) any
+// limitChange describes how an action changes a Limiter's current amount: check (which may be nil) must allow it
+// before change makes it, and undo reverses it if the action fails.
+type limitChange struct {
+	check  limitAction
+	change limitAction
+	undo   limitAction
 }
 
-// instanceLike is an internal interface that abstracts over generic Instance types.
-type instanceLike interface {
-	resourceLike
+var (
+	reserve = limitChange{check: checkLimit, change: increment, undo: decrement} // for creating a Limited instance
+	release = limitChange{change: decrement, undo: increment}                    // for deleting one
+)
+
+// limitUpdate is a limitChange made to a Limiter on behalf of a Limited instance.
+type limitUpdate struct {
+	limitChange
+	limiter       limit.Limiter
+	limited       limit.Limited
+	saved         bool
+	inTransaction bool // if so, the limiter is written when the transaction is committed
+}
+
+// limiterInstance is satisfied by any Instance type. It allows a Limiter returned by a limit.Limited to be loaded and
+// saved without knowing its concrete type.
+type limiterInstance interface {
 	data.Persistable
 	Id() string
+	registeredType() *registeredType
+	initialize(rt *registeredType, sub auth.Subject)
 }
 
-func applyLimitAction(ctx context.Context, limitAction limitAction, i resourceLike) (limit.Limiter, gomerr.Gomerr) {
+// applyLimitAction applies the change if i implements limit.Limited. The Limiter it provides must be a registered
+// Instance type. If the Limiter hasn't been initialized (i.e. it's a new value with only its id fields set), it's read
+// from its store first. Within a transaction (see Transact), each limiter is read once and shared by the actions in it
+// so that each limit check sees the earlier changes. The returned limitUpdate is saved with saveLimiter.
+func applyLimitAction(ctx context.Context, change limitChange, i limiterInstance, sub auth.Subject) (*limitUpdate, gomerr.Gomerr) {
 	limited, ok := i.(limit.Limited)
 	if !ok {
 		return nil, nil
@@ -64,47 +99,120 @@ func applyLimitAction(ctx context.Context, limitAction limitAction, i resourceLi
 
 	limiter, ge := limited.Limiter()
 	if ge != nil {
-		return nil, gomerr.Configuration(i.Metadata().instanceName + " did not provide a Limiter for itself.").Wrap(ge)
+		return nil, gomerr.Configuration(i.registeredType().instanceName + " did not provide a Limiter for itself.").Wrap(ge)
 	}
 
-	li, ok := limiter.(instanceLike)
+	li, ok := limiter.(limiterInstance)
 	if !ok {
-		return nil, gomerr.Configuration("limiter from " + i.Metadata().instanceName + " does not implement resource.Instance")
+		return nil, gomerr.Configuration("limiter from " + i.registeredType().instanceName + " does not implement resource.Instance")
 	}
 
-	// If the registeredType isn't set, then this is a New object and needs to be loaded
-	var loaded bool
-	if li.Metadata() == nil {
-		// Note: this path requires a global registry lookup which is not available
-		// in the generic design. Consider requiring pre-initialized limiters.
-		return nil, gomerr.Configuration("limiter must be pre-initialized with registry")
+	// If the registeredType isn't set, then this is a new value and needs to be loaded
+	load := li.registeredType() == nil
+	if load {
+		registry, _ := ctx.Value(RegistryCtxKey).(*Registry)
+		if registry == nil {
+			return nil, gomerr.Configuration("no registry in context to load limiter")
+		}
+
+		rt := registry.registeredTypes[reflect.TypeOf(limiter)]
+		if rt == nil {
+			return nil, gomerr.Configuration("unregistered limiter type: " + reflect.TypeOf(limiter).String())
+		}
+
+		li.initialize(rt, sub)
 	}
 
-	if ge = limitAction(limiter, limited); ge != nil {
+	tx, _ := ctx.Value(transactionCtxKey{}).(*transaction)
+	if shared := tx.limiter(li); shared != nil {
+		li, limiter = shared, shared.(limit.Limiter)
+	} else {
+		if load {
+			if ge = li.registeredType().store.Read(ctx, li); ge != nil {
+				return nil, ge
+			}
+		}
+		tx.addLimiter(li)
+	}
+
+	u := &limitUpdate{limitChange: change, limiter: limiter, limited: limited, inTransaction: tx != nil}
+	if ge = u.apply(); ge != nil {
 		return nil, ge
 	}
 
-	// If we didn't load the updatable, we'll let other code handle the save
-	if !loaded {
-		limiter = nil
+	return u, nil
+}
+
+func (u *limitUpdate) apply() gomerr.Gomerr {
+	if u.check != nil {
+		if ge := u.check(u.limiter, u.limited); ge != nil {
+			return ge
+		}
+	}
+
+	return u.change(u.limiter, u.limited)
+}
+
+const limiterSaveAttempts = 3
+
+// saveLimiter saves the limiter if the update changed it and isn't part of a transaction.
+func saveLimiter(ctx context.Context, u *limitUpdate) gomerr.Gomerr {
+	if u == nil || u.inTransaction || !u.limiter.IsDirty() {
+		return nil
 	}
 
-	return limiter, nil
+	return u.save(ctx)
 }
 
-func saveLimiterIfDirty(ctx context.Context, limiter limit.Limiter) {
-	// TODO: need an optimistic lock mechanism to avoid overwriting
-	if limiter == nil || !limiter.IsDirty() {
+// save writes the limiter to its store. If the limiter's type has a `db.version` field, the write is conditioned on
+// the version that was read, and if another write changed the limiter since then, it's read again and the update,
+// including its check, is reapplied. Without a version field, concurrent saves can overwrite one another's changes.
+func (u *limitUpdate) save(ctx context.Context) gomerr.Gomerr {
+	li := u.limiter.(limiterInstance) // Should always be true
+	store := li.registeredType().store
+	for attempt := 1; ; attempt++ {
+		ge := store.Update(ctx, li, nil)
+		if ge == nil {
+			u.limiter.ClearDirty()
+			u.saved = true
+			return nil
+		}
+
+		if attempt == limiterSaveAttempts || gomerr.ErrorAs[*gomerr.ConflictError](ge) == nil {
+			return ge
+		}
+
+		if ge = store.Read(ctx, li); ge != nil {
+			return ge
+		}
+		if ge = u.apply(); ge != nil {
+			return ge
+		}
+	}
+}
+
+// revertLimiter undoes a saved (or shared, within a transaction) update after the action that made it fails.
+func revertLimiter(ctx context.Context, u *limitUpdate) {
+	if u == nil || !u.saved && !u.inTransaction {
 		return
 	}
 
-	li := limiter.(instanceLike) // Should always be true
-	ge := li.Metadata().store.Update(ctx, li, nil)
-	if ge != nil {
-		// TODO: use provided logger
-		fmt.Printf("Failed to save limiter (type: %s, id: %s). Error:\n%s\n", li.Metadata().instanceName, li.Id(), ge)
+	revert := &limitUpdate{limitChange: limitChange{change: u.undo}, limiter: u.limiter, limited: u.limited, inTransaction: u.inTransaction}
+	if ge := revert.apply(); ge != nil {
+		logIfUnsaved(ctx, revert, ge)
+		return
+	}
+	logIfUnsaved(ctx, revert, saveLimiter(ctx, revert))
+}
+
+// logIfUnsaved logs a limiter that couldn't be saved after its action succeeded (or reverted after it failed). Since
+// creates are counted before they're made and deletes after, the limiter is left counting more than are present
+// rather than fewer.
+func logIfUnsaved(ctx context.Context, u *limitUpdate, ge gomerr.Gomerr) {
+	if ge == nil {
 		return
 	}
 
-	limiter.ClearDirty()
+	li := u.limiter.(limiterInstance)
+	slog.ErrorContext(ctx, "failed to save limiter", "type", li.registeredType().instanceName, "id", li.Id(), "error", ge)
 }
@@ -0,0 +1,246 @@
+package resource_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jt0/gomer/_test/assert"
+	"github.com/jt0/gomer/auth"
+	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/data/memory"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/limit"
+	"github.com/jt0/gomer/resource"
+)
+
+type Tenant struct {
+	resource.BaseInstance[*Tenant]
+	limit.TrackingLimiter
+	TenantId string `db.keys:"pk,sk='TENANT'" id:"+"`
+	Version  int    `db.version:""`
+}
+
+type Widget struct {
+	resource.BaseInstance[*Widget]
+	TenantId string `db.keys:"pk"`
+	WidgetId string `db.keys:"sk" id:"+"`
+}
+
+func (w *Widget) Limiter() (limit.Limiter, gomerr.Gomerr) {
+	return &Tenant{TenantId: w.TenantId}, nil
+}
+
+func (*Widget) DefaultLimit() limit.Amount {
+	return limit.Count(2)
+}
+
+func (*Widget) LimitAmount() limit.Amount {
+	return limit.Count(1)
+}
+
+func newLimitContext(t *testing.T) context.Context {
+	store, ge := memory.Store(nil, &Tenant{}, &Widget{})
+	assert.Success(t, ge)
+
+	return registerLimitTypes(t, store, store)
+}
+
+func registerLimitTypes(t *testing.T, tenantStore, widgetStore data.Store) context.Context {
+	registry := resource.NewRegistry()
+	resource.Register[*Tenant](registry, resource.WithStore(tenantStore))
+	resource.Register[*Widget](registry, resource.WithParent[*Tenant](), resource.WithStore(widgetStore))
+
+	ctx := context.WithValue(context.Background(), resource.RegistryCtxKey, registry)
+
+	tenant, ge := resource.NewInstance[*Tenant](ctx, auth.NewSubject())
+	assert.Success(t, ge)
+	tenant.TenantId = "t1"
+	_, ge = tenant.Create(ctx)
+	assert.Success(t, ge)
+
+	return ctx
+}
+
+func widget(t *testing.T, ctx context.Context, id string) *Widget {
+	w, ge := resource.NewInstance[*Widget](ctx, auth.NewSubject())
+	assert.Success(t, ge)
+	w.TenantId, w.WidgetId = "t1", id
+	return w
+}
+
+func widgetCount(t *testing.T, ctx context.Context) limit.Amount {
+	tenant, ge := resource.NewInstance[*Tenant](ctx, auth.NewSubject())
+	assert.Success(t, ge)
+	tenant.TenantId = "t1"
+	tenant, ge = tenant.Read(ctx)
+	assert.Success(t, ge)
+	return tenant.Current(&Widget{})
+}
+
+func TestLimit_CreateAndDelete(t *testing.T) {
+	ctx := newLimitContext(t)
+
+	for _, id := range []string{"w1", "w2"} {
+		_, ge := widget(t, ctx, id).Create(ctx)
+		assert.Success(t, ge)
+	}
+	assert.Equals(t, limit.Count(2), widgetCount(t, ctx))
+
+	_, ge := widget(t, ctx, "w3").Create(ctx)
+	assert.ErrorType(t, ge, &limit.ExceededError{}, "Create beyond the limit should fail")
+	assert.Equals(t, limit.Count(2), widgetCount(t, ctx))
+
+	_, ge = widget(t, ctx, "w1").Delete(ctx)
+	assert.Success(t, ge)
+	assert.Equals(t, limit.Count(1), widgetCount(t, ctx))
+
+	_, ge = widget(t, ctx, "w3").Create(ctx)
+	assert.Success(t, ge)
+	assert.Equals(t, limit.Count(2), widgetCount(t, ctx))
+}
+
+func TestLimit_DeleteNotPresent(t *testing.T) {
+	ctx := newLimitContext(t)
+
+	_, ge := widget(t, ctx, "w1").Create(ctx)
+	assert.Success(t, ge)
+
+	for i := 0; i < 3; i++ {
+		_, ge = widget(t, ctx, "missing").Delete(ctx)
+		assert.Success(t, ge)
+	}
+	assert.Equals(t, limit.Count(1), widgetCount(t, ctx), "Deleting an absent widget should not change the count")
+
+	for _, id := range []string{"w2", "w3"} {
+		_, ge = widget(t, ctx, id).Create(ctx)
+		if id == "w3" {
+			assert.ErrorType(t, ge, &limit.ExceededError{}, "Create beyond the limit should fail")
+		} else {
+			assert.Success(t, ge)
+		}
+	}
+	assert.Equals(t, limit.Count(2), widgetCount(t, ctx))
+}
+
+// racingStore counts another widget for the tenant before each of the next races updates to a tenant made through it,
+// as a concurrent create might between when the tenant is read and when it's updated.
+type racingStore struct {
+	data.Store
+	races *int
+}
+
+func (s racingStore) Update(ctx context.Context, p data.Persistable, update data.Persistable) gomerr.Gomerr {
+	if *s.races > 0 {
+		*s.races--
+
+		tenant, ge := resource.NewInstance[*Tenant](ctx, auth.NewSubject())
+		if ge != nil {
+			return ge
+		}
+		tenant.TenantId = "t1"
+		if ge = s.Store.Read(ctx, tenant); ge != nil {
+			return ge
+		}
+		tenant.SetCurrent(&Widget{}, tenant.Current(&Widget{}).Increment(limit.Count(1)))
+		if ge = s.Store.Update(ctx, tenant, nil); ge != nil {
+			return ge
+		}
+	}
+
+	return s.Store.Update(ctx, p, update)
+}
+
+func newRacingLimitContext(t *testing.T) (context.Context, *int) {
+	store, ge := memory.Store(nil, &Tenant{}, &Widget{})
+	assert.Success(t, ge)
+
+	races := new(int)
+	return registerLimitTypes(t, racingStore{store, races}, store), races
+}
+
+func TestLimit_ConcurrentSave(t *testing.T) {
+	ctx, races := newRacingLimitContext(t)
+
+	*races = 1
+	_, ge := widget(t, ctx, "w1").Create(ctx)
+	assert.Success(t, ge)
+	assert.Equals(t, limit.Count(2), widgetCount(t, ctx), "The concurrent change should not be overwritten")
+}
+
+func TestLimit_ConcurrentSaveAtLimit(t *testing.T) {
+	ctx, races := newRacingLimitContext(t)
+
+	_, ge := widget(t, ctx, "w1").Create(ctx)
+	assert.Success(t, ge)
+
+	*races = 1
+	_, ge = widget(t, ctx, "w2").Create(ctx)
+	assert.ErrorType(t, ge, &limit.ExceededError{}, "Create should fail once a concurrent change reaches the limit")
+	assert.Equals(t, limit.Count(2), widgetCount(t, ctx))
+
+	_, ge = widget(t, ctx, "w2").Read(ctx)
+	assert.ErrorType(t, ge, &gomerr.NotFoundError{}, "The rejected widget should not have been created")
+}
+
+func TestLimit_CreateFailureReverts(t *testing.T) {
+	ctx := newLimitContext(t)
+
+	_, ge := widget(t, ctx, "w1").Create(ctx)
+	assert.Success(t, ge)
+
+	_, ge = widget(t, ctx, "w1").Create(ctx)
+	assert.Error(t, ge, "Creating a duplicate widget should fail")
+	assert.Equals(t, limit.Count(1), widgetCount(t, ctx), "A failed create should not be counted")
+}
+
+func TestLimit_Transact(t *testing.T) {
+	ctx := newLimitContext(t)
+
+	ge := resource.Transact(ctx, func(ctx context.Context) gomerr.Gomerr {
+		if _, ge := widget(t, ctx, "w1").Create(ctx); ge != nil {
+			return ge
+		}
+		return gomerr.Unprocessable("abandoned", nil)
+	})
+	assert.ErrorType(t, ge, &gomerr.UnprocessableError{})
+	assert.Equals(t, limit.Count(0), widgetCount(t, ctx), "The limiter should not change if the transaction isn't committed")
+
+	ge = resource.Transact(ctx, func(ctx context.Context) gomerr.Gomerr {
+		if _, ge := widget(t, ctx, "w1").Create(ctx); ge != nil {
+			return ge
+		}
+		assert.Equals(t, limit.Count(0), widgetCount(t, ctx), "The limiter should be saved after the commit")
+		return nil
+	})
+	assert.Success(t, ge)
+	assert.Equals(t, limit.Count(1), widgetCount(t, ctx))
+}
+
+func TestLimit_TransactSeveral(t *testing.T) {
+	ctx := newLimitContext(t)
+
+	createWidgets := func(ids ...string) func(context.Context) gomerr.Gomerr {
+		return func(ctx context.Context) gomerr.Gomerr {
+			for _, id := range ids {
+				if _, ge := widget(t, ctx, id).Create(ctx); ge != nil {
+					return ge
+				}
+			}
+			return nil
+		}
+	}
+
+	ge := resource.Transact(ctx, createWidgets("w1", "w2"))
+	assert.Success(t, ge)
+	assert.Equals(t, limit.Count(2), widgetCount(t, ctx), "Each create in the transaction should be counted")
+
+	_, ge = widget(t, ctx, "w1").Delete(ctx)
+	assert.Success(t, ge)
+
+	ge = resource.Transact(ctx, createWidgets("w3", "w4"))
+	assert.ErrorType(t, ge, &limit.ExceededError{}, "Creates in a transaction should not together exceed the limit")
+	assert.Equals(t, limit.Count(1), widgetCount(t, ctx))
+
+	_, ge = widget(t, ctx, "w3").Read(ctx)
+	assert.ErrorType(t, ge, &gomerr.NotFoundError{}, "The transaction should not have been committed")
+}
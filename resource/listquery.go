@@ -0,0 +1,179 @@
+package resource
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/jt0/gomer/auth"
+	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/flect"
+	"github.com/jt0/gomer/gomerr"
+)
+
+// WithFilterableFields allows a collection of the type to be filtered (see ListQuery) by the named fields.
+func WithFilterableFields(fields ...string) Option {
+	return func(rt *registeredType) {
+		rt.filterableFields = append(rt.filterableFields, fields...)
+	}
+}
+
+// WithSortableFields allows a collection of the type to be sorted (see ListQuery) by the named fields. Whether a
+// particular request can be sorted by one also depends on the store having an index ordered by it.
+func WithSortableFields(fields ...string) Option {
+	return func(rt *registeredType) {
+		rt.sortableFields = append(rt.sortableFields, fields...)
+	}
+}
+
+// ListQuery is the filtering and ordering requested for a Collection in addition to the values of its prototype.
+type ListQuery struct {
+	Filters []Filter
+	Sort    []data.Sort
+}
+
+// Filter compares a field with one or more values. The values are converted to the field's type (or for CONTAINS on a
+// slice, its element type) when the filter is applied to a collection.
+type Filter struct {
+	Field  string
+	Type   data.QueryTypes
+	Values []string
+}
+
+// SetListQuery applies q to the collection after checking it against the fields registered as filterable and sortable
+// for the collection's type. Since filtering or sorting by a field reveals its values, fields that the collection's
+// subject isn't permitted to read are neither. Field names are matched without regard to case. A GTE and LTE filter on
+// the same field are combined into a BETWEEN. Possible errors:
+//
+//	gomerr.BadValue:
+//	    if a field isn't filterable or sortable, a value can't be converted to the field's type, or more than one
+//	    sort is requested
+func (c *Collection[I]) SetListQuery(q ListQuery) gomerr.Gomerr {
+	rt := c.registeredType()
+
+	denied, ge := deniedFields(rt, c.Subject(), auth.ReadPermission)
+	if ge != nil {
+		return ge
+	}
+	filterable, sortable := permitted(rt.filterableFields, denied), permitted(rt.sortableFields, denied)
+
+	var conditions []data.Condition
+	for _, f := range q.Filters {
+		field, ok := fieldNamed(filterable, f.Field)
+		if !ok {
+			return gomerr.InvalidValue("filter", f.Field, filterable).WithReason("field is not filterable")
+		}
+
+		sf, _ := rt.instanceType.Elem().FieldByName(field)
+		valueType := sf.Type
+		if valueType.Kind() == reflect.Ptr {
+			valueType = valueType.Elem()
+		}
+		if f.Type == data.CONTAINS && (valueType.Kind() == reflect.Slice || valueType.Kind() == reflect.Array) {
+			valueType = valueType.Elem()
+		} else if f.Type == data.CONTAINS || f.Type == data.PREFIX {
+			valueType = reflect.TypeFor[string]()
+		}
+
+		values := make([]any, len(f.Values))
+		for i, value := range f.Values {
+			typed, ge := flect.StringToType(value, valueType)
+			if ge != nil || typed == nil {
+				return gomerr.InvalidValue("filter", value, valueType.String()).AddAttribute("Field", f.Field)
+			}
+			values[i] = typed
+		}
+
+		conditions = append(conditions, data.Condition{Field: field, Type: f.Type, Values: values})
+	}
+
+	var sort *data.Sort
+	switch len(q.Sort) {
+	case 0:
+	case 1:
+		field, ok := fieldNamed(sortable, q.Sort[0].Field)
+		if !ok {
+			return gomerr.InvalidValue("sort", q.Sort[0].Field, sortable).WithReason("field is not sortable")
+		}
+		sort = &data.Sort{Field: field, Descending: q.Sort[0].Descending}
+	default:
+		return gomerr.InvalidValue("sort", q.Sort, "one field").WithReason("results can only be sorted by one field")
+	}
+
+	c.listQuery, c.conditions, c.sort = &q, between(conditions), sort
+
+	return nil
+}
+
+// Conditions implements data.Conditional with the filters from the collection's ListQuery.
+func (c *Collection[I]) Conditions() []data.Condition {
+	return c.conditions
+}
+
+// SortBy implements data.Sorted with the sort from the collection's ListQuery.
+func (c *Collection[I]) SortBy() *data.Sort {
+	return c.sort
+}
+
+// fieldNamed returns the field from fields that matches name without regard to case.
+func fieldNamed(fields []string, name string) (string, bool) {
+	for _, field := range fields {
+		if strings.EqualFold(field, name) {
+			return field, true
+		}
+	}
+	return "", false
+}
+
+// permitted returns the fields that aren't denied.
+func permitted(fields []string, denied map[string]bool) []string {
+	if len(denied) == 0 {
+		return fields
+	}
+
+	allowed := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if !denied[field] {
+			allowed = append(allowed, field)
+		}
+	}
+	return allowed
+}
+
+// between combines a GTE and an LTE condition on the same field into a BETWEEN condition, which stores can use to
+// narrow the range of a sort key.
+func between(conditions []data.Condition) []data.Condition {
+	combined := make([]data.Condition, 0, len(conditions))
+	merged := make(map[int]bool)
+	for i, c := range conditions {
+		if merged[i] {
+			continue
+		}
+
+		if c.Type == data.GTE || c.Type == data.LTE {
+			for j := i + 1; j < len(conditions); j++ {
+				if o := conditions[j]; !merged[j] && o.Field == c.Field && (o.Type == data.GTE || o.Type == data.LTE) && o.Type != c.Type {
+					if c.Type == data.LTE {
+						c, o = o, c
+					}
+					c = data.Condition{Field: c.Field, Type: data.BETWEEN, Values: []any{c.Values[0], o.Values[0]}}
+					merged[j] = true
+					break
+				}
+			}
+		}
+		combined = append(combined, c)
+	}
+
+	return combined
+}
+
+// validateQueryFields panics if a filterable or sortable field isn't a field of the registered type.
+func (m *registeredType) validateQueryFields() {
+	for _, fields := range [][]string{m.filterableFields, m.sortableFields} {
+		for _, field := range fields {
+			if _, ok := m.instanceType.Elem().FieldByName(field); !ok {
+				panic(gomerr.Configuration("unknown query field for " + m.instanceName + ": " + field).String())
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"context"
 	"reflect"
 	"strings"
 
@@ -52,6 +53,7 @@ func Register[I Instance[I]](r *Registry, opts ...Option) {
 	rt := &registeredType{
 		instanceType: reflect.TypeFor[I](),
 		baseOffset:   findBaseResourceOffset[I](),
+		registry:     r,
 	}
 
 	for _, opt := range opts {
@@ -68,6 +70,9 @@ func Register[I Instance[I]](r *Registry, opts ...Option) {
 		rt.collectionName = rt.instanceName + "s" // default to most common pluralization form
 	}
 
+	rt.fieldAccess = usesFieldAccess(rt.instanceType.Elem())
+	rt.validateQueryFields()
+
 	// Create closures while we know the type of I.
 	rt.newInstance = func(sub auth.Subject) any {
 		i := reflect.New(reflect.TypeFor[I]().Elem()).Interface().(I)
@@ -75,6 +80,11 @@ func Register[I Instance[I]](r *Registry, opts ...Option) {
 		return i
 	}
 
+	rt.deleteInstance = func(ctx context.Context, instance any) gomerr.Gomerr {
+		_, ge := instance.(I).DoAction(ctx, DeleteAction[I]())
+		return ge
+	}
+
 	rt.newCollection = func(proto any) any {
 		i, ok := proto.(I)
 		if !ok || i.registeredType() != rt {
@@ -90,6 +100,7 @@ func Register[I Instance[I]](r *Registry, opts ...Option) {
 			panic(gomerr.Configuration("unregistered parent type: " + rt.parentType.String()).String())
 		}
 		parent.children = append(parent.children, rt)
+		rt.parent = parent
 	} else {
 		r.rootTypes = append(r.rootTypes, rt)
 	}
@@ -135,9 +146,20 @@ type registeredType struct {
 	children       []*registeredType
 	store          data.Store
 	parentType     reflect.Type
+	parent         *registeredType
+	registry       *Registry
+	deleteBehavior DeleteBehavior // how instances are handled when the parent is deleted
+	fieldAccess    bool           // true if the type declares `access` field permissions
+
+	retryPolicy         *RetryPolicy
+	actionRetryPolicies map[string]RetryPolicy // action name -> policy
+
+	filterableFields []string
+	sortableFields   []string
 
-	newInstance   func(sub auth.Subject) any
-	newCollection func(proto any) any
+	newInstance    func(sub auth.Subject) any
+	newCollection  func(proto any) any
+	deleteInstance func(ctx context.Context, instance any) gomerr.Gomerr
 }
 
 func (m *registeredType) InstanceName() string {
@@ -186,6 +208,8 @@ var RegistryCtxKey = registryCtxKey{}
 type Registry struct {
 	registeredTypes map[reflect.Type]*registeredType
 	rootTypes       []RegisteredType
+	sinks           []EventSink
+	onEventError    func(context.Context, Event, gomerr.Gomerr)
 }
 
 // NewRegistry creates a new registry for resource registration.
@@ -33,22 +33,35 @@ func (b *BaseResource[T]) Subject() auth.Subject {
 func (b *BaseResource[T]) DoAction(ctx context.Context, action Action[T]) (T, gomerr.Gomerr) {
 	var zero T
 
+	// Inbound values the subject isn't permitted to write are dropped rather than rejected
+	if permission := action.FieldAccessPermissions(); permission&auth.WritePermissions != 0 {
+		if ge := clearDeniedFields(b.rt, b.sub, b.self, permission); ge != nil {
+			return zero, ge
+		}
+	}
+
 	if ge := action.Pre(ctx, b.self); ge != nil {
 		return zero, ge
 	}
 
-	ge := action.Do(ctx, b.self)
-	for i := 0; i < 2 && ge != nil; i++ { // up to 2 retries
-		if ge = action.Retry(ctx, b.self, ge); ge != nil {
-			break
-		}
-		ge = action.Do(ctx, b.self)
+	if ge := do(ctx, action, b.self, b.rt.retryPolicyFor(action.Name())); ge != nil {
+		return zero, action.OnDoFailure(ctx, b.self, ge)
 	}
+
+	result, ge := action.OnDoSuccess(ctx, b.self)
 	if ge != nil {
-		return zero, action.OnDoFailure(ctx, b.self, ge)
+		return result, ge
+	}
+
+	// Sinks receive the result before denied fields are cleared
+	publishEvent(ctx, b.rt, action, b.sub, result)
+
+	// The result may differ from b.self (e.g. an update returns the current state), so it's what gets cleared
+	if ge = clearDeniedFields(b.rt, b.sub, result, auth.ReadPermission); ge != nil {
+		return zero, ge
 	}
 
-	return action.OnDoSuccess(ctx, b.self)
+	return result, nil
 }
 
 func (b *BaseResource[T]) registeredType() *registeredType {
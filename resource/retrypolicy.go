@@ -0,0 +1,125 @@
+package resource
+
+import (
+	"context"
+	"time"
+
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/internal/backoff"
+	"github.com/jt0/gomer/limit"
+)
+
+// RetryPolicy controls how DoAction re-attempts an action's Do step after it fails. An attempt is retried if the
+// action's Retry hook returns nil, or if the error the hook returns is accepted by the policy's Retryable classifier.
+// Before each retry, DoAction waits for an exponentially increasing, jittered delay. No retry is attempted if the delay
+// would extend past the context's deadline.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of times Do may be called, including the first. Values less than 2 disable
+	// retries.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry. It doubles with each subsequent retry up to MaxDelay. If zero,
+	// retries are attempted immediately.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay between retries. If zero, the delay is not capped.
+	MaxDelay time.Duration
+
+	// Retryable classifies errors that should be retried regardless of the Retry hook's decision. If nil, only the
+	// Retry hook determines whether to retry.
+	Retryable func(gomerr.Gomerr) bool
+}
+
+// DefaultRetryPolicy is used for registered types and actions that don't specify a policy. It allows the Retry hooks
+// to request up to two immediate retries.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3}
+
+// WithRetryPolicy sets the retry policy for the registered type. If any action names are provided, the policy applies
+// only to those actions.
+func WithRetryPolicy(policy RetryPolicy, actionNames ...string) Option {
+	return func(rt *registeredType) {
+		if len(actionNames) == 0 {
+			rt.retryPolicy = &policy
+			return
+		}
+
+		if rt.actionRetryPolicies == nil {
+			rt.actionRetryPolicies = make(map[string]RetryPolicy)
+		}
+		for _, name := range actionNames {
+			rt.actionRetryPolicies[name] = policy
+		}
+	}
+}
+
+// IsThrottled returns true if the error is a limit.ExceededError for a limit that can't be quantified, which is how
+// stores report throttling by the underlying service. Errors for exceeding an application-defined limit are not
+// considered transient and return false.
+func IsThrottled(ge gomerr.Gomerr) bool {
+	ee := gomerr.ErrorAs[*limit.ExceededError](ge)
+	return ee != nil && ee.Limit == limit.Unknown
+}
+
+func (m *registeredType) retryPolicyFor(actionName string) RetryPolicy {
+	if m == nil {
+		return DefaultRetryPolicy
+	}
+
+	if policy, ok := m.actionRetryPolicies[actionName]; ok {
+		return policy
+	}
+
+	if m.retryPolicy != nil {
+		return *m.retryPolicy
+	}
+
+	return DefaultRetryPolicy
+}
+
+// do calls the action's Do, retrying per the policy.
+func do[T any](ctx context.Context, action Action[T], t T, policy RetryPolicy) gomerr.Gomerr {
+	ge := action.Do(ctx, t)
+	for attempt := 1; ge != nil && attempt < policy.MaxAttempts; attempt++ {
+		if rge := action.Retry(ctx, t, ge); rge != nil {
+			if policy.Retryable == nil || !policy.Retryable(rge) {
+				return rge
+			}
+		}
+
+		if !policy.wait(ctx, attempt) {
+			return ge
+		}
+
+		ge = action.Do(ctx, t)
+	}
+
+	return ge
+}
+
+// wait sleeps before the given retry attempt (starting at 1). Returns false if the context is done or its deadline
+// would pass before the delay elapses.
+func (p RetryPolicy) wait(ctx context.Context, attempt int) bool {
+	delay := p.delay(attempt)
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+		return false
+	}
+
+	if delay <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// delay returns a random duration between zero and the exponential backoff value for the attempt ("full jitter").
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	return backoff.Jittered(p.BaseDelay, p.MaxDelay, attempt)
+}
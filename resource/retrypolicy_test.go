@@ -0,0 +1,116 @@
+package resource_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jt0/gomer/_test/assert"
+	"github.com/jt0/gomer/auth"
+	"github.com/jt0/gomer/data/memory"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/limit"
+	"github.com/jt0/gomer/resource"
+)
+
+type flakyAction struct {
+	resource.NoOpAction[*Tenant]
+	failures int // number of times Do fails before succeeding
+	calls    int
+	retryErr gomerr.Gomerr // returned by the Retry hook
+}
+
+func (*flakyAction) Name() string {
+	return "FlakyAction"
+}
+
+func (a *flakyAction) Do(context.Context, *Tenant) gomerr.Gomerr {
+	if a.calls++; a.calls <= a.failures {
+		return limit.UnquantifiedExcess("store", "throughput")
+	}
+	return nil
+}
+
+func (a *flakyAction) Retry(_ context.Context, _ *Tenant, ge gomerr.Gomerr) gomerr.Gomerr {
+	if a.retryErr == nil {
+		return ge
+	}
+	return a.retryErr
+}
+
+func (a *flakyAction) ExecuteOn(ctx context.Context, r any) (any, gomerr.Gomerr) {
+	return r.(*Tenant).DoAction(ctx, a)
+}
+
+func newTenant(t *testing.T, opts ...resource.Option) (context.Context, *Tenant) {
+	store, ge := memory.Store(nil, &Tenant{})
+	assert.Success(t, ge)
+
+	registry := resource.NewRegistry()
+	resource.Register[*Tenant](registry, append(opts, resource.WithStore(store))...)
+	ctx := context.WithValue(context.Background(), resource.RegistryCtxKey, registry)
+
+	tenant, ge := resource.NewInstance[*Tenant](ctx, auth.NewSubject())
+	assert.Success(t, ge)
+	return ctx, tenant
+}
+
+func TestRetryPolicy_Default(t *testing.T) {
+	ctx, tenant := newTenant(t)
+
+	// Default hooks don't request a retry and the default policy has no classifier
+	action := &flakyAction{failures: 1}
+	_, ge := tenant.DoAction(ctx, action)
+	assert.ErrorType(t, ge, &limit.ExceededError{})
+	assert.Equals(t, 1, action.calls)
+}
+
+func TestRetryPolicy_Classifier(t *testing.T) {
+	policy := resource.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, Retryable: resource.IsThrottled}
+	ctx, tenant := newTenant(t, resource.WithRetryPolicy(policy))
+
+	action := &flakyAction{failures: 2}
+	_, ge := tenant.DoAction(ctx, action)
+	assert.Success(t, ge)
+	assert.Equals(t, 3, action.calls)
+
+	action = &flakyAction{failures: 3}
+	_, ge = tenant.DoAction(ctx, action)
+	assert.ErrorType(t, ge, &limit.ExceededError{})
+	assert.Equals(t, 3, action.calls, "Should stop after MaxAttempts")
+
+	action = &flakyAction{failures: 1, retryErr: gomerr.Unprocessable("not retryable", nil)}
+	_, ge = tenant.DoAction(ctx, action)
+	assert.ErrorType(t, ge, &gomerr.UnprocessableError{}, "Hook's non-retryable error should be returned")
+	assert.Equals(t, 1, action.calls)
+}
+
+func TestRetryPolicy_PerAction(t *testing.T) {
+	policy := resource.RetryPolicy{MaxAttempts: 5, Retryable: resource.IsThrottled}
+	ctx, tenant := newTenant(t, resource.WithRetryPolicy(resource.RetryPolicy{}), resource.WithRetryPolicy(policy, "FlakyAction"))
+
+	action := &flakyAction{failures: 4}
+	_, ge := tenant.DoAction(ctx, action)
+	assert.Success(t, ge)
+	assert.Equals(t, 5, action.calls)
+}
+
+func TestRetryPolicy_Deadline(t *testing.T) {
+	policy := resource.RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour, Retryable: resource.IsThrottled}
+	ctx, tenant := newTenant(t, resource.WithRetryPolicy(policy))
+
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+
+	action := &flakyAction{failures: 1}
+	start := time.Now()
+	_, ge := tenant.DoAction(ctx, action)
+	assert.ErrorType(t, ge, &limit.ExceededError{})
+	assert.Assert(t, time.Since(start) < time.Second, "Should not wait past the deadline")
+}
+
+func TestIsThrottled(t *testing.T) {
+	assert.Assert(t, resource.IsThrottled(limit.UnquantifiedExcess("store", "throughput")))
+	assert.Assert(t, !resource.IsThrottled(limit.Exceeded("limiter", "limited", limit.Count(1), limit.Count(1), limit.Count(2))))
+	assert.Assert(t, !resource.IsThrottled(gomerr.Internal("other")))
+}
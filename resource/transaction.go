@@ -0,0 +1,151 @@
+package resource
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/jt0/gomer/data"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/limit"
+)
+
+// Transact runs fn with a context in which the writes made by CreateAction, UpdateAction, and DeleteAction (including
+// cascading deletes) are staged in a single data.Transaction rather than applied. If fn succeeds, the transaction is
+// committed so that either all of the writes are applied or none are. The types written must share a store that
+// implements data.Transactional.
+//
+// Actions still run their hooks as they're staged, so post-action hooks (e.g. PostCreate) run before the commit. Events
+// are published once the commit succeeds. The limiters changed by the actions are shared within the transaction, so
+// each limit check sees the changes of the actions before it, and each is written once, in the transaction, so their
+// types must share the store as well. Reads aren't part of the transaction. Calling Transact from within fn joins the
+// enclosing transaction.
+func Transact(ctx context.Context, fn func(context.Context) gomerr.Gomerr) gomerr.Gomerr {
+	if _, ok := ctx.Value(transactionCtxKey{}).(*transaction); ok {
+		return fn(ctx)
+	}
+
+	tx := &transaction{}
+	txCtx := context.WithValue(ctx, transactionCtxKey{}, tx)
+	if ge := fn(txCtx); ge != nil {
+		return ge
+	}
+
+	for _, li := range tx.limiters {
+		if !li.(limit.Limiter).IsDirty() {
+			continue
+		}
+
+		w, ge := li.registeredType().writer(txCtx)
+		if ge != nil {
+			return ge
+		}
+		if ge = w.Update(txCtx, li, nil); ge != nil {
+			return ge
+		}
+	}
+
+	if tx.tx != nil {
+		if ge := tx.tx.Commit(ctx); ge != nil {
+			return ge
+		}
+	}
+
+	for _, li := range tx.limiters {
+		li.(limit.Limiter).ClearDirty()
+	}
+
+	for _, fn := range tx.afterCommit {
+		fn(ctx)
+	}
+
+	return nil
+}
+
+type transactionCtxKey struct{}
+
+type transaction struct {
+	store       data.Store
+	tx          data.Transaction
+	limiters    []limiterInstance
+	afterCommit []func(context.Context) // e.g. events to publish once committed
+}
+
+// storeWriter is the subset of data.Store's operations that are staged within a transaction.
+type storeWriter interface {
+	Create(context.Context, data.Persistable) gomerr.Gomerr
+	Update(context.Context, data.Persistable, data.Persistable) gomerr.Gomerr
+	Delete(context.Context, data.Persistable) gomerr.Gomerr
+}
+
+// writer returns where the type's writes should go: its store, or if ctx has one, the transaction.
+func (m *registeredType) writer(ctx context.Context) (storeWriter, gomerr.Gomerr) {
+	tx, _ := ctx.Value(transactionCtxKey{}).(*transaction)
+	if tx == nil {
+		return m.store, nil
+	}
+
+	if tx.tx == nil {
+		transactional, ok := m.store.(data.Transactional)
+		if !ok {
+			return nil, gomerr.Unprocessable("store does not support transactions", m.instanceName)
+		}
+		tx.store, tx.tx = m.store, transactional.NewTransaction()
+	} else if m.store != tx.store {
+		return nil, gomerr.Unprocessable("store differs from the transaction's", m.instanceName)
+	}
+
+	return tx.tx, nil
+}
+
+// limiter returns the limiter read within the transaction that has the same type and ids (including its ancestors') as
+// li, if any.
+func (tx *transaction) limiter(li limiterInstance) limiterInstance {
+	if tx == nil {
+		return nil
+	}
+
+	rt := li.registeredType()
+	for _, l := range tx.limiters {
+		if l.registeredType() == rt && sameIds(rt, l, li) {
+			return l
+		}
+	}
+
+	return nil
+}
+
+func (tx *transaction) addLimiter(li limiterInstance) {
+	if tx != nil {
+		tx.limiters = append(tx.limiters, li)
+	}
+}
+
+func sameIds(rt *registeredType, a, b any) bool {
+	av, bv := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	for ancestor := rt; ancestor != nil; ancestor = ancestor.parent {
+		idFields, ge := idFieldNames(ancestor.instanceType)
+		if ge != nil {
+			return false
+		}
+
+		for _, name := range idFields {
+			af, bf := av.FieldByName(name), bv.FieldByName(name)
+			if af.IsValid() != bf.IsValid() || af.IsValid() && !af.Equal(bf) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// deferUntilCommitted defers fn until the transaction in ctx, if any, is committed. Returns false if there isn't one.
+func deferUntilCommitted(ctx context.Context, fn func(context.Context)) bool {
+	tx, _ := ctx.Value(transactionCtxKey{}).(*transaction)
+	if tx == nil {
+		return false
+	}
+
+	tx.afterCommit = append(tx.afterCommit, fn)
+	return true
+}
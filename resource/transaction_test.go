@@ -0,0 +1,59 @@
+package resource_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jt0/gomer/_test/assert"
+	"github.com/jt0/gomer/constraint"
+	"github.com/jt0/gomer/data/dataerr"
+	"github.com/jt0/gomer/gomerr"
+	"github.com/jt0/gomer/resource"
+)
+
+func TestTransact(t *testing.T) {
+	var events []resource.Event
+	ctx := newNoteContext(t, resource.NewSyncSink(func(_ context.Context, e resource.Event) gomerr.Gomerr {
+		events = append(events, e)
+		return nil
+	}))
+
+	_, ge := note(t, ctx, "n1", "existing").Create(ctx)
+	assert.Success(t, ge)
+	events = nil
+
+	// One failed write means none are applied
+	ge = resource.Transact(ctx, func(ctx context.Context) gomerr.Gomerr {
+		if _, ge := note(t, ctx, "n2", "new").Create(ctx); ge != nil {
+			return ge
+		}
+		_, ge := note(t, ctx, "n1", "duplicate").Create(ctx)
+		return ge
+	})
+	assert.ErrorType(t, ge, constraint.NotSatisfied(nil))
+	assert.Equals(t, 0, len(events), "Events should not be published for an uncommitted transaction")
+
+	_, ge = note(t, ctx, "n2", "").Read(ctx)
+	assert.ErrorType(t, ge, &dataerr.PersistableNotFoundError{})
+	n1, ge := note(t, ctx, "n1", "").Read(ctx)
+	assert.Success(t, ge)
+	assert.Equals(t, "existing", n1.Text)
+
+	ge = resource.Transact(ctx, func(ctx context.Context) gomerr.Gomerr {
+		if _, ge := note(t, ctx, "n2", "new").Create(ctx); ge != nil {
+			return ge
+		}
+		if _, ge := note(t, ctx, "n1", "").Delete(ctx); ge != nil {
+			return ge
+		}
+		assert.Equals(t, 0, len(events), "Events should be published after the commit")
+		return nil
+	})
+	assert.Success(t, ge)
+	assert.Equals(t, 2, len(events))
+
+	_, ge = note(t, ctx, "n2", "").Read(ctx)
+	assert.Success(t, ge)
+	_, ge = note(t, ctx, "n1", "").Read(ctx)
+	assert.ErrorType(t, ge, &dataerr.PersistableNotFoundError{})
+}
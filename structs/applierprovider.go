@@ -113,6 +113,35 @@ func applyScopes(ap ApplierProvider, structType reflect.Type, structField reflec
 	return scopeSelect{appliers}, nil
 }
 
+// ScopedDirective returns the section of a directive (see the format above) that applies to the scope, as a tool
+// would select it: the section for the scope (or an alias of it) if there is one, and otherwise the section without a
+// scope. Returns false if no section applies.
+func ScopedDirective(directive, scope string) (string, bool) {
+	var anyScopeDirective string
+	var hasAnyScope bool
+	for _, match := range scopeRegexp.FindAllStringSubmatch(directive, -1) {
+		matchScope := match[1]
+		if matchScope == "" {
+			matchScope = anyScope
+		} else if actualScope, ok := scopeAliases[matchScope]; ok {
+			matchScope = actualScope
+		}
+
+		scopedDirective := match[2]
+		if strings.IndexAny(directive, "?&") == -1 {
+			scopedDirective = strings.ReplaceAll(scopedDirective, "\\:", ":")
+		}
+
+		if matchScope == scope && scope != anyScope {
+			return scopedDirective, true
+		} else if matchScope == anyScope {
+			anyScopeDirective, hasAnyScope = scopedDirective, true
+		}
+	}
+
+	return anyScopeDirective, hasAnyScope
+}
+
 type scopeSelect struct {
 	appliers map[string]Applier
 }
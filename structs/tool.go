@@ -149,6 +149,12 @@ func process(st reflect.Type, tools ...*Tool) (*preparedStruct, []gomerr.Gomerr)
 		preparedStructs[ps.typeName] = ps
 	}
 
+	// Mark the tools as applied before descending so that self-referencing types (e.g. a resource's back-reference to
+	// its containing instance) don't recurse indefinitely.
+	for _, tool := range toolsForStruct {
+		ps.applied[tool.Id()] = true
+	}
+
 	// TODO: descend into non-exported if tag value provided?
 
 	errors := make([]gomerr.Gomerr, 0)